@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/cmd/batcherctl/config"
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/logger"
+)
+
+const usage = `usage: batcherctl [-o table|json] <command> [flags] [args]
+
+commands:
+  list                              list batches, accepts the same filters as the history endpoint
+  show <batchId>                    show a batch with its transactions
+  force-ready [-dry-run] <batchId>  mark undispatched batches as ready to dispatch
+  dispatch [-dry-run] [-all-ready] [batchId...]
+                                    send ready batches to the bank
+  dlq list [-queue transaction|dispatch] [-limit n]
+                                    inspect messages dropped by a subscriber
+  dlq requeue [-dry-run] [-queue transaction|dispatch] [-limit n]
+                                    move dropped messages back to their queue
+  migrate                           move transaction ids of legacy batches into the ledger, run it once after upgrading`
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+)
+
+var log = logrus.New()
+
+type app struct {
+	batchSvc         batch.Service
+	deadLetterer     func() (deadLetterer, error)
+	deadLetterQueues map[string]string
+	out              printer
+}
+
+func main() {
+	flag.Usage = func() { fmt.Fprintln(flag.CommandLine.Output(), usage) }
+	format := flag.String("o", formatTable, "output format: table or json")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := logger.Configure(log, cfg.Logger); err != nil {
+		log.Fatal(err)
+	}
+	out, err := newPrinter(os.Stdout, *format)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := app{
+		out: out,
+		deadLetterQueues: map[string]string{
+			"transaction": cfg.MQTransactionSubscriber.DeadLetterQueue,
+			"dispatch":    cfg.MQDispatchSubscriber.DeadLetterQueue,
+		},
+		deadLetterer: func() (deadLetterer, error) {
+			return rabbitmq.NewClient(ctx, cfg.MQClient, clock.New(), log)
+		},
+	}
+
+	command, args := flag.Arg(0), flag.Args()[1:]
+	var fileBank *banksdk.FileClient
+	if command != "dlq" {
+		mongoClient, err := mongodb.New(ctx, cfg.MongoClient, log)
+		if err != nil {
+			log.Fatal(err)
+		}
+		/* without a bank configured dispatched transfers are only printed, same as in batcherd */
+		bank := banksdk.New()
+		switch {
+		case cfg.Bank.Mode == banksdk.ModeFile:
+			fileBank, err = banksdk.NewFile(cfg.Bank.File, clock.New(), log)
+			if err != nil {
+				log.Fatal(err)
+			}
+			bank = fileBank
+		case cfg.Bank.BaseURL != "":
+			bank, err = banksdk.NewHTTP(cfg.Bank, clock.New())
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		accountSvc, err := account.New(mongoClient, clock.New(), log)
+		if err != nil {
+			log.Fatal(err)
+		}
+		/* no transactions are batched from here, goals only route the new ones */
+		thresholds := map[string]string{"USD": cfg.ThresholdUSD}
+		a.batchSvc, err = batch.New(mongoClient, bank, accountSvc, nil, clock.New(), log, thresholds)
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	err = a.run(ctx, command, args)
+	/* dispatched transfers are written into the files right away, batcherd may not be running to do it */
+	if fileBank != nil {
+		files, errFlush := fileBank.Flush(ctx)
+		for _, file := range files {
+			log.Infof("payment file %s written", file)
+		}
+		if errFlush != nil {
+			log.Error(errFlush)
+		}
+	}
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+func (a *app) run(ctx context.Context, command string, args []string) error {
+	switch command {
+	case "list":
+		return a.list(ctx, args)
+	case "show":
+		return a.show(ctx, args)
+	case actionForceReady:
+		return a.forceReady(ctx, args)
+	case actionDispatch:
+		return a.dispatch(ctx, args)
+	case "dlq":
+		return a.dlq(ctx, args)
+	case "migrate":
+		return a.migrate(ctx)
+	default:
+		return errors.Wrap(ErrUnknownCommand, command)
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"net/url"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+)
+
+const (
+	actionForceReady = "force-ready"
+	actionDispatch   = "dispatch"
+)
+
+var (
+	ErrNoBatchIDs = errors.New("at least one batch id has to be provided")
+	ErrNotReady   = errors.New("batch is not ready to dispatch")
+)
+
+// list accepts the same filters as the history endpoint
+func (a *app) list(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", 20, "number of batches")
+	cursor := fs.String("cursor", "", "cursor of the page returned by the previous call")
+	sortField := fs.String("sort", "", "createdDate, updatedDate, amount or dispatchedDate")
+	order := fs.String("order", "", "1 for ascending, -1 for descending")
+	filters := map[string]*string{
+		"userId":         fs.String("user", "", "user id"),
+		"status":         fs.String("status", "", "undispatched, ready-to-dispatch or dispatched"),
+		"currency":       fs.String("currency", "", "ISO 4217 currency code"),
+		"flag":           fs.String("flag", "", "no-destination for batches held back until the user has a verified account"),
+		"goal":           fs.String("goal", "", "id of the savings goal the batches collect round-ups for"),
+		"amountMin":      fs.String("amount-min", "", "inclusive lower bound of the amount"),
+		"amountMax":      fs.String("amount-max", "", "inclusive upper bound of the amount"),
+		"createdFrom":    fs.String("created-from", "", "RFC 3339 date, inclusive"),
+		"createdTo":      fs.String("created-to", "", "RFC 3339 date, exclusive"),
+		"dispatchedFrom": fs.String("dispatched-from", "", "RFC 3339 date, inclusive"),
+		"dispatchedTo":   fs.String("dispatched-to", "", "RFC 3339 date, exclusive"),
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	for parameter, v := range filters {
+		if *v != "" {
+			query.Set(parameter, *v)
+		}
+	}
+	f, err := batch.FilterFrom(query)
+	if err != nil {
+		return err
+	}
+	sort, err := batch.SortFrom(*sortField, *order)
+	if err != nil {
+		return err
+	}
+
+	page, err := a.batchSvc.Browse(ctx, *limit, sort, *cursor, f)
+	if err != nil {
+		return err
+	}
+	return a.out.page(page)
+}
+
+func (a *app) show(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("show", flag.ContinueOnError)
+	limit := fs.Int("entries", 20, "number of transactions shown")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	b, err := a.batchSvc.Get(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	entries, err := a.batchSvc.Entries(ctx, b.ID, *limit, 0)
+	if err != nil {
+		return err
+	}
+	return a.out.batch(b, entries)
+}
+
+func (a *app) forceReady(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet(actionForceReady, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only check whether the batches can be forced")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return ErrNoBatchIDs
+	}
+
+	results := make([]result, 0, fs.NArg())
+	for _, batchID := range fs.Args() {
+		r := result{ID: batchID, Action: actionForceReady, DryRun: *dryRun}
+		b, err := a.batchSvc.Get(ctx, batchID)
+		switch {
+		case err != nil:
+			r.Error = err.Error()
+		case b.Status != batch.StatusUndispatched:
+			r.Status = string(b.Status)
+			r.Error = batch.ErrNotUndispatched.Error()
+		case *dryRun:
+			r.Status = batch.StatusReadyToDispatch
+		default:
+			forced, err := a.batchSvc.ForceReady(ctx, batchID)
+			if err != nil {
+				r.Error = err.Error()
+				break
+			}
+			r.Status = string(forced.Status)
+		}
+		results = append(results, r)
+	}
+	return a.out.results(results)
+}
+
+// dispatch sends given batches to the bank, or all the ready ones when -all-ready is set
+func (a *app) dispatch(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet(actionDispatch, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "only list the batches which would be dispatched")
+	allReady := fs.Bool("all-ready", false, "dispatch every batch which is ready to dispatch")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	batchIDs := fs.Args()
+	if *allReady {
+		status := batch.Status(batch.StatusReadyToDispatch)
+		f := batch.Filter{Status: &status}
+		/* ids are collected first, dispatching changes the status the cursor is filtered by */
+		err := a.batchSvc.Export(ctx, batch.Sort{Field: "createdDate", Asc: true}, f, func(b batch.Batch) error {
+			batchIDs = append(batchIDs, b.ID.Hex())
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	} else if len(batchIDs) == 0 {
+		return ErrNoBatchIDs
+	}
+
+	results := make([]result, 0, len(batchIDs))
+	for _, batchID := range batchIDs {
+		r := result{ID: batchID, Action: actionDispatch, DryRun: *dryRun}
+		b, err := a.batchSvc.Get(ctx, batchID)
+		switch {
+		case err != nil:
+			r.Error = err.Error()
+		case b.Status != batch.StatusReadyToDispatch:
+			r.Status = string(b.Status)
+			r.Error = ErrNotReady.Error()
+		case *dryRun:
+			r.Status = batch.StatusDispatched
+		default:
+			if err := a.batchSvc.Dispatch(ctx, batchID); err != nil {
+				r.Error = err.Error()
+				break
+			}
+			dispatched, err := a.batchSvc.Get(ctx, batchID)
+			if err != nil {
+				r.Error = err.Error()
+				break
+			}
+			r.Status = string(dispatched.Status)
+		}
+		results = append(results, r)
+	}
+	return a.out.results(results)
+}
+
+// migrate is kept out of batcherd startup, legacy batches are looked up without an index
+func (a *app) migrate(ctx context.Context) error {
+	if err := a.batchSvc.Migrate(ctx); err != nil {
+		return err
+	}
+	log.Info("legacy batches migrated")
+	return nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	mockBatch "github.com/mazxaxz/donut-batcher/internal/batch/mock"
+)
+
+func TestForceReady(t *testing.T) {
+	t.Run("should not modify batch in dry run", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		var out bytes.Buffer
+		a := app{batchSvc: mockBatchSvc, out: printer{w: &out, format: formatJSON}}
+
+		// expected calls
+		mockBatchSvc.EXPECT().Get(gomock.Any(), batchID.Hex()).Return(batch.Batch{ID: batchID, Status: batch.StatusUndispatched}, nil)
+
+		// act
+		err := a.run(context.Background(), actionForceReady, []string{"-dry-run", batchID.Hex()})
+
+		// assert
+		assert.NoError(t, err)
+		var results []result
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &results))
+		assert.Equal(t, []result{{ID: batchID.Hex(), Action: actionForceReady, DryRun: true, Status: batch.StatusReadyToDispatch}}, results)
+	})
+
+	t.Run("should force batch and report the ones which can not be forced", func(t *testing.T) {
+		// arrange
+		undispatched := primitive.NewObjectID()
+		dispatched := primitive.NewObjectID()
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		var out bytes.Buffer
+		a := app{batchSvc: mockBatchSvc, out: printer{w: &out, format: formatJSON}}
+
+		// expected calls
+		mockBatchSvc.EXPECT().Get(gomock.Any(), undispatched.Hex()).Return(batch.Batch{ID: undispatched, Status: batch.StatusUndispatched}, nil)
+		mockBatchSvc.EXPECT().ForceReady(gomock.Any(), undispatched.Hex()).Return(batch.Batch{ID: undispatched, Status: batch.StatusReadyToDispatch}, nil)
+		mockBatchSvc.EXPECT().Get(gomock.Any(), dispatched.Hex()).Return(batch.Batch{ID: dispatched, Status: batch.StatusDispatched}, nil)
+
+		// act
+		err := a.run(context.Background(), actionForceReady, []string{undispatched.Hex(), dispatched.Hex()})
+
+		// assert
+		assert.NoError(t, err)
+		var results []result
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &results))
+		assert.Len(t, results, 2)
+		assert.Equal(t, batch.StatusReadyToDispatch, results[0].Status)
+		assert.Empty(t, results[0].Error)
+		assert.Equal(t, batch.ErrNotUndispatched.Error(), results[1].Error)
+	})
+
+	t.Run("should return no batch ids error", func(t *testing.T) {
+		// arrange
+		a := app{out: printer{w: &bytes.Buffer{}, format: formatJSON}}
+
+		// act
+		err := a.run(context.Background(), actionForceReady, []string{"-dry-run"})
+
+		// assert
+		assert.Equal(t, ErrNoBatchIDs, err)
+	})
+}
+
+func TestDispatch(t *testing.T) {
+	t.Run("should list all ready batches in dry run", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		var out bytes.Buffer
+		a := app{batchSvc: mockBatchSvc, out: printer{w: &out, format: formatJSON}}
+
+		// expected calls
+		mockBatchSvc.EXPECT().Export(gomock.Any(), batch.Sort{Field: "createdDate", Asc: true}, gomock.Any(), gomock.Any()).
+			DoAndReturn(func(ctx context.Context, sort batch.Sort, f batch.Filter, fn func(b batch.Batch) error) error {
+				assert.Equal(t, batch.Status(batch.StatusReadyToDispatch), *f.Status)
+				return fn(batch.Batch{ID: batchID, Status: batch.StatusReadyToDispatch})
+			})
+		mockBatchSvc.EXPECT().Get(gomock.Any(), batchID.Hex()).Return(batch.Batch{ID: batchID, Status: batch.StatusReadyToDispatch}, nil)
+
+		// act
+		err := a.run(context.Background(), actionDispatch, []string{"-dry-run", "-all-ready"})
+
+		// assert
+		assert.NoError(t, err)
+		var results []result
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &results))
+		assert.Equal(t, []result{{ID: batchID.Hex(), Action: actionDispatch, DryRun: true, Status: batch.StatusDispatched}}, results)
+	})
+
+	t.Run("should dispatch ready batch", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		var out bytes.Buffer
+		a := app{batchSvc: mockBatchSvc, out: printer{w: &out, format: formatJSON}}
+
+		// expected calls
+		gomock.InOrder(
+			mockBatchSvc.EXPECT().Get(gomock.Any(), batchID.Hex()).Return(batch.Batch{ID: batchID, Status: batch.StatusReadyToDispatch}, nil),
+			mockBatchSvc.EXPECT().Dispatch(gomock.Any(), batchID.Hex()).Return(nil),
+			mockBatchSvc.EXPECT().Get(gomock.Any(), batchID.Hex()).Return(batch.Batch{ID: batchID, Status: batch.StatusDispatched}, nil),
+		)
+
+		// act
+		err := a.run(context.Background(), actionDispatch, []string{batchID.Hex()})
+
+		// assert
+		assert.NoError(t, err)
+		var results []result
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &results))
+		assert.Equal(t, []result{{ID: batchID.Hex(), Action: actionDispatch, Status: batch.StatusDispatched}}, results)
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	t.Run("should return migration error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		a := app{batchSvc: mockBatchSvc}
+
+		// expected calls
+		mockBatchSvc.EXPECT().Migrate(gomock.Any()).Return(mongoOrg.ErrClientDisconnected)
+
+		// act
+		err := a.run(context.Background(), "migrate", nil)
+
+		// assert
+		assert.Equal(t, mongoOrg.ErrClientDisconnected, err)
+	})
+}
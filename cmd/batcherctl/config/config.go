@@ -0,0 +1,30 @@
+package config
+
+import (
+	"github.com/Netflix/go-env"
+
+	mongoConfig "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/config"
+	rabbitConfig "github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/logger"
+)
+
+// Config shares the environment variables with batcherd, so both can run with the same setup
+type Config struct {
+	ThresholdUSD            string                  `env:"THRESHOLD_USD,default=100"`
+	MongoClient             mongoConfig.Config      `env:"MONGO_CLIENT,required=true"`
+	MQClient                rabbitConfig.Config     `env:"MQ_CLIENT"`
+	MQTransactionSubscriber rabbitConfig.Subscriber `env:"MQ_TRANSACTION_SUBSCRIBER"`
+	MQDispatchSubscriber    rabbitConfig.Subscriber `env:"MQ_DISPATCH_SUBSCRIBER"`
+	Bank                    banksdk.Config          `env:"BANK"`
+	Logger                  logger.Config           `env:"LOGGER"`
+}
+
+func Load() (Config, error) {
+	var cfg Config
+	_, err := env.UnmarshalFromEnviron(&cfg)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
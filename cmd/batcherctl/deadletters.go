@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+)
+
+const (
+	actionRequeue = "requeue"
+)
+
+var (
+	ErrUnknownDLQCommand = errors.New("dlq command has to be either list or requeue")
+	ErrUnknownQueue      = errors.New("queue has to be either transaction or dispatch")
+	ErrNoDeadLetterQueue = errors.New("dead letter queue is not configured for the subscriber")
+)
+
+type deadLetterer interface {
+	DeadLetters(ctx context.Context, queue string, limit int) ([]transport.DeadLetter, error)
+	Requeue(ctx context.Context, queue string, limit int) ([]transport.DeadLetter, error)
+}
+
+// dlq inspects or requeues messages dropped by the subscribers
+func (a *app) dlq(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return ErrUnknownDLQCommand
+	}
+	command := args[0]
+
+	fs := flag.NewFlagSet("dlq "+command, flag.ContinueOnError)
+	queue := fs.String("queue", "transaction", "subscriber the messages were dropped by: transaction or dispatch")
+	limit := fs.Int("limit", 20, "number of messages")
+	dryRun := fs.Bool("dry-run", false, "only list the messages which would be requeued")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	deadLetterQueue, exists := a.deadLetterQueues[*queue]
+	if !exists {
+		return ErrUnknownQueue
+	}
+	if deadLetterQueue == "" {
+		return errors.Wrap(ErrNoDeadLetterQueue, *queue)
+	}
+	dl, err := a.deadLetterer()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "list":
+		letters, err := dl.DeadLetters(ctx, deadLetterQueue, *limit)
+		if err != nil {
+			return err
+		}
+		return a.out.deadLetters(letters)
+	case actionRequeue:
+		var letters []transport.DeadLetter
+		if *dryRun {
+			letters, err = dl.DeadLetters(ctx, deadLetterQueue, *limit)
+		} else {
+			letters, err = dl.Requeue(ctx, deadLetterQueue, *limit)
+		}
+		results := make([]result, 0, len(letters))
+		for _, l := range letters {
+			r := result{
+				ID:     l.CorrelationID,
+				Action: actionRequeue,
+				DryRun: *dryRun,
+				Status: fmt.Sprintf("%s -> %s", l.Queue, l.OriginalQueue),
+			}
+			results = append(results, r)
+		}
+		/* messages requeued before the failure are still reported */
+		if printErr := a.out.results(results); printErr != nil {
+			return printErr
+		}
+		return err
+	default:
+		return ErrUnknownDLQCommand
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+)
+
+type fakeDeadLetterer struct {
+	letters  []transport.DeadLetter
+	requeued bool
+}
+
+func (f *fakeDeadLetterer) DeadLetters(ctx context.Context, queue string, limit int) ([]transport.DeadLetter, error) {
+	return f.letters, nil
+}
+
+func (f *fakeDeadLetterer) Requeue(ctx context.Context, queue string, limit int) ([]transport.DeadLetter, error) {
+	f.requeued = true
+	return f.letters, nil
+}
+
+func TestDLQ(t *testing.T) {
+	letters := []transport.DeadLetter{{Queue: "Q.Dead", OriginalQueue: "Q", CorrelationID: "1", Error: "boom"}}
+
+	t.Run("should not requeue messages in dry run", func(t *testing.T) {
+		// arrange
+		dl := &fakeDeadLetterer{letters: letters}
+		var out bytes.Buffer
+		a := app{
+			out:              printer{w: &out, format: formatJSON},
+			deadLetterQueues: map[string]string{"transaction": "Q.Dead"},
+			deadLetterer:     func() (deadLetterer, error) { return dl, nil },
+		}
+
+		// act
+		err := a.run(context.Background(), "dlq", []string{"requeue", "-dry-run"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, dl.requeued)
+		var results []result
+		assert.NoError(t, json.Unmarshal(out.Bytes(), &results))
+		assert.Equal(t, []result{{ID: "1", Action: actionRequeue, DryRun: true, Status: "Q.Dead -> Q"}}, results)
+	})
+
+	t.Run("should requeue messages", func(t *testing.T) {
+		// arrange
+		dl := &fakeDeadLetterer{letters: letters}
+		a := app{
+			out:              printer{w: &bytes.Buffer{}, format: formatJSON},
+			deadLetterQueues: map[string]string{"transaction": "Q.Dead"},
+			deadLetterer:     func() (deadLetterer, error) { return dl, nil },
+		}
+
+		// act
+		err := a.run(context.Background(), "dlq", []string{"requeue"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, dl.requeued)
+	})
+
+	t.Run("should return no dead letter queue error", func(t *testing.T) {
+		// arrange
+		a := app{
+			out:              printer{w: &bytes.Buffer{}, format: formatJSON},
+			deadLetterQueues: map[string]string{"transaction": "", "dispatch": ""},
+		}
+
+		// act
+		err := a.run(context.Background(), "dlq", []string{"list", "-queue", "dispatch"})
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoDeadLetterQueue))
+	})
+}
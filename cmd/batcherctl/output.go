@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+var (
+	ErrInvalidFormat = errors.New("output has to be either table or json")
+)
+
+// result of a mutating command for a single batch or message
+type result struct {
+	ID     string `json:"id"`
+	Action string `json:"action"`
+	DryRun bool   `json:"dryRun"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+func newPrinter(w io.Writer, format string) (printer, error) {
+	switch format {
+	case formatTable, formatJSON:
+		return printer{w: w, format: format}, nil
+	default:
+		return printer{}, ErrInvalidFormat
+	}
+}
+
+func (p printer) json(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func (p printer) page(page batch.Page) error {
+	if p.format == formatJSON {
+		return p.json(page)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tUSER\tAMOUNT\tCURRENCY\tSTATUS\tTRANSACTIONS\tCREATED\tDISPATCHED")
+	for _, b := range page.Items {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			b.ID.Hex(), b.UserID, b.Amount, b.Currency, b.Status, b.TransactionCount,
+			formatDate(b.CreatedDate), formatDate(b.DispatchedDate))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(p.w, "total: %d\n", page.Total)
+	if page.Next != "" {
+		fmt.Fprintf(p.w, "next: %s\n", page.Next)
+	}
+	return nil
+}
+
+func (p printer) batch(b batch.Batch, entries []batch.Entry) error {
+	if p.format == formatJSON {
+		return p.json(struct {
+			batch.Batch
+			Entries []batch.Entry `json:"entries"`
+		}{b, entries})
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID\t%s\n", b.ID.Hex())
+	fmt.Fprintf(tw, "User\t%s\n", b.UserID)
+	fmt.Fprintf(tw, "Amount\t%s %s\n", b.Amount, b.Currency)
+	fmt.Fprintf(tw, "Status\t%s\n", b.Status)
+	if b.Goal != "" {
+		fmt.Fprintf(tw, "Goal\t%s\n", b.Goal)
+	}
+	fmt.Fprintf(tw, "Transactions\t%d\n", b.TransactionCount)
+	fmt.Fprintf(tw, "Created\t%s\n", formatDate(b.CreatedDate))
+	fmt.Fprintf(tw, "Updated\t%s\n", formatDate(b.UpdatedDate))
+	fmt.Fprintf(tw, "Dispatched\t%s\n", formatDate(b.DispatchedDate))
+	fmt.Fprintf(tw, "Reference\t%s\n", b.DispatchReference)
+	for _, l := range b.Legs {
+		fmt.Fprintf(tw, "Leg %d\t%s %s%% %s %s %s %s\n",
+			l.Number, l.Portfolio, l.Percent, l.Amount, l.Status, l.DispatchReference, l.Error)
+	}
+	for _, h := range b.History {
+		fmt.Fprintf(tw, "History\t%s %s\n", formatDate(h.Date), h.Status)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "TRANSACTION\tAMOUNT\tROUND UP\tSTRATEGY\tCREATED")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.TransactionID, e.Amount, e.RoundUp, e.Strategy, formatDate(e.CreatedDate))
+	}
+	return tw.Flush()
+}
+
+func (p printer) deadLetters(letters []transport.DeadLetter) error {
+	if p.format == formatJSON {
+		return p.json(letters)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "CORRELATION ID\tTYPE\tORIGINAL QUEUE\tFAILED\tERROR\tBODY")
+	for _, l := range letters {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			l.CorrelationID, l.Type, l.OriginalQueue, formatDate(l.FailedDate), l.Error, l.Body)
+	}
+	return tw.Flush()
+}
+
+func (p printer) results(results []result) error {
+	if p.format == formatJSON {
+		return p.json(results)
+	}
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tACTION\tDRY RUN\tSTATUS\tERROR")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%t\t%s\t%s\n", r.ID, r.Action, r.DryRun, r.Status, r.Error)
+	}
+	return tw.Flush()
+}
+
+func formatDate(d time.Time) string {
+	if d.IsZero() {
+		return "-"
+	}
+	return d.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,241 @@
+package adminhttphandler
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/loadgen"
+	"github.com/mazxaxz/donut-batcher/internal/reconcile"
+	"github.com/mazxaxz/donut-batcher/internal/replay"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+// loadgenRequest mirrors loadgen.Config, durations are given in time.ParseDuration format
+type loadgenRequest struct {
+	Users             int      `json:"users"`
+	Currencies        []string `json:"currencies"`
+	Rate              int      `json:"rate"`
+	Duration          string   `json:"duration"`
+	AmountMin         string   `json:"amountMin"`
+	AmountMax         string   `json:"amountMax"`
+	Distribution      string   `json:"distribution"`
+	DuplicateRatio    float64  `json:"duplicateRatio"`
+	InvalidRatio      float64  `json:"invalidRatio"`
+	Workers           int      `json:"workers"`
+	CompletionTimeout string   `json:"completionTimeout"`
+	PollInterval      string   `json:"pollInterval"`
+	Seed              int64    `json:"seed"`
+}
+
+type handlerContext struct {
+	accountSvc   account.Service
+	loadgenSvc   loadgen.Service
+	replaySvc    replay.Service
+	reconcileSvc reconcile.Service
+	logger       *logrus.Logger
+}
+
+func New(aSvc account.Service, lSvc loadgen.Service, rSvc replay.Service, recSvc reconcile.Service, l *logrus.Logger) rest.SetupRouterer {
+	c := handlerContext{
+		accountSvc:   aSvc,
+		loadgenSvc:   lSvc,
+		replaySvc:    rSvc,
+		reconcileSvc: recSvc,
+		logger:       l,
+	}
+	return &c
+}
+
+func (c *handlerContext) SetupRouter(r *gin.RouterGroup) {
+	r.POST("/admin/loadgen", c.StartLoadgen)
+	r.GET("/admin/loadgen/:id", c.GetLoadgen)
+	r.POST("/admin/replay", c.Replay)
+	r.POST("/admin/reconciliation", c.Reconcile)
+	r.GET("/admin/reconciliation", c.GetReconciliation)
+	r.GET("/admin/users/:userId/accounts", c.GetAccounts)
+	r.POST("/admin/users/:userId/accounts/:currency/verify", c.VerifyAccount)
+}
+
+func (c *handlerContext) StartLoadgen(cGin *gin.Context) {
+	var req loadgenRequest
+	if err := cGin.ShouldBindJSON(&req); err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	cfg := loadgen.Config{
+		Users:          req.Users,
+		Currencies:     req.Currencies,
+		Rate:           req.Rate,
+		AmountMin:      req.AmountMin,
+		AmountMax:      req.AmountMax,
+		Distribution:   req.Distribution,
+		DuplicateRatio: req.DuplicateRatio,
+		InvalidRatio:   req.InvalidRatio,
+		Workers:        req.Workers,
+		Seed:           req.Seed,
+	}
+	durations := []struct {
+		parameter string
+		value     string
+		dst       *time.Duration
+	}{
+		{"duration", req.Duration, &cfg.Duration},
+		{"completionTimeout", req.CompletionTimeout, &cfg.CompletionTimeout},
+		{"pollInterval", req.PollInterval, &cfg.PollInterval},
+	}
+	for _, d := range durations {
+		if d.value == "" {
+			continue
+		}
+		parsed, err := time.ParseDuration(d.value)
+		if err != nil {
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewParameterError(d.parameter, err))
+			return
+		}
+		*d.dst = parsed
+	}
+
+	run, err := c.loadgenSvc.Start(cfg)
+	if err != nil {
+		switch err {
+		case loadgen.ErrRunInProgress:
+			httpErr := rest.NewError("loadgen_in_progress", err)
+			cGin.AbortWithStatusJSON(http.StatusConflict, httpErr)
+		default:
+			httpErr := rest.NewError("invalid_loadgen_config", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusAccepted, run)
+}
+
+func (c *handlerContext) GetLoadgen(cGin *gin.Context) {
+	run, err := c.loadgenSvc.Get(cGin.Param("id"))
+	if err != nil {
+		httpErr := rest.NewError("loadgen_not_found", err)
+		cGin.AbortWithStatusJSON(http.StatusNotFound, httpErr)
+		return
+	}
+	cGin.JSON(http.StatusOK, run)
+}
+
+// Replay publishes an uploaded NDJSON file, either sent as the body or as "file" field of a multipart form
+func (c *handlerContext) Replay(cGin *gin.Context) {
+	rate, err := strconv.Atoi(cGin.DefaultQuery("rate", "0"))
+	if err != nil {
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewParameterError("rate", err))
+		return
+	}
+
+	body := cGin.Request.Body
+	mediaType, _, _ := mime.ParseMediaType(cGin.ContentType())
+	if mediaType == "multipart/form-data" {
+		file, _, err := cGin.Request.FormFile("file")
+		if err != nil {
+			httpErr := rest.NewError("invalid_body", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	summary, err := c.replaySvc.Replay(cGin, body, rate)
+	if err != nil {
+		switch err {
+		case replay.ErrInvalidRate:
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewParameterError("rate", err))
+		default:
+			httpErr := rest.NewError("replay_error", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, summary)
+}
+
+// Reconcile runs the reconciliation right away, from and to bound the dispatch dates in RFC3339 format
+func (c *handlerContext) Reconcile(cGin *gin.Context) {
+	var r reconcile.Range
+	bounds := []struct {
+		parameter string
+		dst       *time.Time
+	}{
+		{"from", &r.From},
+		{"to", &r.To},
+	}
+	for _, b := range bounds {
+		v := cGin.Query(b.parameter)
+		if v == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewParameterError(b.parameter, err))
+			return
+		}
+		*b.dst = parsed.UTC()
+	}
+
+	report, err := c.reconcileSvc.Run(cGin, r)
+	if err != nil {
+		switch err {
+		case reconcile.ErrInvalidRange:
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewParameterError("to", err))
+		case reconcile.ErrNoStatements, reconcile.ErrNoDirectory:
+			httpErr := rest.NewError("no_statements", err)
+			cGin.AbortWithStatusJSON(http.StatusUnprocessableEntity, httpErr)
+		default:
+			httpErr := rest.NewError("reconciliation_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, report)
+}
+
+func (c *handlerContext) GetReconciliation(cGin *gin.Context) {
+	report, err := c.reconcileSvc.Latest()
+	if err != nil {
+		httpErr := rest.NewError("reconciliation_not_found", err)
+		cGin.AbortWithStatusJSON(http.StatusNotFound, httpErr)
+		return
+	}
+	cGin.JSON(http.StatusOK, report)
+}
+
+func (c *handlerContext) GetAccounts(cGin *gin.Context) {
+	accounts, err := c.accountSvc.List(cGin, cGin.Param("userId"))
+	if err != nil {
+		httpErr := rest.NewError("accounts_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		return
+	}
+	cGin.JSON(http.StatusOK, accounts)
+}
+
+// VerifyAccount lets batches be dispatched to the account, flagged ones go out once their dead letters are requeued
+func (c *handlerContext) VerifyAccount(cGin *gin.Context) {
+	a, err := c.accountSvc.Verify(cGin, cGin.Param("userId"), money.Currency(cGin.Param("currency")))
+	if err != nil {
+		switch err {
+		case account.ErrAccountNotFound:
+			httpErr := rest.NewError("account_not_found", err)
+			cGin.AbortWithStatusJSON(http.StatusNotFound, httpErr)
+		default:
+			httpErr := rest.NewError("verification_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, a)
+}
@@ -0,0 +1,259 @@
+package adminhttphandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	mockAccount "github.com/mazxaxz/donut-batcher/internal/account/mock"
+	"github.com/mazxaxz/donut-batcher/internal/loadgen"
+	mockLoadgen "github.com/mazxaxz/donut-batcher/internal/loadgen/mock"
+	"github.com/mazxaxz/donut-batcher/internal/reconcile"
+	mockReconcile "github.com/mazxaxz/donut-batcher/internal/reconcile/mock"
+	"github.com/mazxaxz/donut-batcher/internal/replay"
+	mockReplay "github.com/mazxaxz/donut-batcher/internal/replay/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+type mocks struct {
+	accountSvc   *mockAccount.MockService
+	loadgenSvc   *mockLoadgen.MockService
+	replaySvc    *mockReplay.MockService
+	reconcileSvc *mockReconcile.MockService
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		expect     func(m mocks)
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "should return bad request, loadgen config is not json",
+			method:     http.MethodPost,
+			path:       "/v1/admin/loadgen",
+			body:       "}invalid{",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+		{
+			name:       "should return bad request, loadgen duration is malformed",
+			method:     http.MethodPost,
+			path:       "/v1/admin/loadgen",
+			body:       `{"users":10,"rate":100,"duration":"a minute"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__duration",
+		},
+		{
+			name:   "should return bad request, invalid loadgen config",
+			method: http.MethodPost,
+			path:   "/v1/admin/loadgen",
+			body:   `{"users":0,"rate":100,"duration":"1m"}`,
+			expect: func(m mocks) {
+				m.loadgenSvc.EXPECT().Start(gomock.Any()).Return(loadgen.Run{}, loadgen.ErrInvalidUsers)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_loadgen_config",
+		},
+		{
+			name:   "should return conflict, loadgen is running already",
+			method: http.MethodPost,
+			path:   "/v1/admin/loadgen",
+			body:   `{"users":10,"rate":100,"duration":"1m"}`,
+			expect: func(m mocks) {
+				m.loadgenSvc.EXPECT().Start(gomock.Any()).Return(loadgen.Run{}, loadgen.ErrRunInProgress)
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   "loadgen_in_progress",
+		},
+		{
+			name:   "should return accepted loadgen run",
+			method: http.MethodPost,
+			path:   "/v1/admin/loadgen",
+			body:   `{"users":10,"rate":100,"duration":"1m"}`,
+			expect: func(m mocks) {
+				m.loadgenSvc.EXPECT().Start(gomock.Any()).DoAndReturn(func(cfg loadgen.Config) (loadgen.Run, error) {
+					assert.Equal(t, time.Minute, cfg.Duration)
+					return loadgen.Run{ID: "run-1"}, nil
+				})
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:   "should return not found, unknown loadgen run",
+			method: http.MethodGet,
+			path:   "/v1/admin/loadgen/run-1",
+			expect: func(m mocks) {
+				m.loadgenSvc.EXPECT().Get("run-1").Return(loadgen.Run{}, loadgen.ErrRunNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "loadgen_not_found",
+		},
+		{
+			name:       "should return bad request, replay rate is not a number",
+			method:     http.MethodPost,
+			path:       "/v1/admin/replay?rate=fast",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__rate",
+		},
+		{
+			name:   "should return bad request, replay rate is negative",
+			method: http.MethodPost,
+			path:   "/v1/admin/replay?rate=-1",
+			expect: func(m mocks) {
+				m.replaySvc.EXPECT().Replay(gomock.Any(), gomock.Any(), -1).Return(replay.Summary{}, replay.ErrInvalidRate)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__rate",
+		},
+		{
+			name:   "should return ok, replayed transactions",
+			method: http.MethodPost,
+			path:   "/v1/admin/replay?rate=100",
+			body:   `{"id":"1","userId":"user:1","amount":"1.10","currency":"USD"}`,
+			expect: func(m mocks) {
+				m.replaySvc.EXPECT().Replay(gomock.Any(), gomock.Any(), 100).Return(replay.Summary{}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should return bad request, reconciliation start is malformed",
+			method:     http.MethodPost,
+			path:       "/v1/admin/reconciliation?from=yesterday",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__from",
+		},
+		{
+			name:   "should return bad request, reconciliation ends before it starts",
+			method: http.MethodPost,
+			path:   "/v1/admin/reconciliation?from=2021-03-02T00:00:00Z&to=2021-03-01T00:00:00Z",
+			expect: func(m mocks) {
+				want := reconcile.Range{From: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC), To: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)}
+				m.reconcileSvc.EXPECT().Run(gomock.Any(), want).Return(reconcile.Report{}, reconcile.ErrInvalidRange)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__to",
+		},
+		{
+			name:   "should return unprocessable entity, there are no statements",
+			method: http.MethodPost,
+			path:   "/v1/admin/reconciliation",
+			expect: func(m mocks) {
+				m.reconcileSvc.EXPECT().Run(gomock.Any(), reconcile.Range{}).Return(reconcile.Report{}, reconcile.ErrNoStatements)
+			},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "no_statements",
+		},
+		{
+			name:   "should return internal server error, reconciliation failed",
+			method: http.MethodPost,
+			path:   "/v1/admin/reconciliation",
+			expect: func(m mocks) {
+				m.reconcileSvc.EXPECT().Run(gomock.Any(), reconcile.Range{}).Return(reconcile.Report{}, errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "reconciliation_error",
+		},
+		{
+			name:   "should return not found, reconciliation has not run yet",
+			method: http.MethodGet,
+			path:   "/v1/admin/reconciliation",
+			expect: func(m mocks) {
+				m.reconcileSvc.EXPECT().Latest().Return(reconcile.Report{}, reconcile.ErrNoReport)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "reconciliation_not_found",
+		},
+		{
+			name:   "should return internal server error, accounts could not be listed",
+			method: http.MethodGet,
+			path:   "/v1/admin/users/user:1/accounts",
+			expect: func(m mocks) {
+				m.accountSvc.EXPECT().List(gomock.Any(), "user:1").Return(nil, errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "accounts_error",
+		},
+		{
+			name:   "should return not found, user has no account to verify",
+			method: http.MethodPost,
+			path:   "/v1/admin/users/user:1/accounts/USD/verify",
+			expect: func(m mocks) {
+				m.accountSvc.EXPECT().Verify(gomock.Any(), "user:1", money.Currency("USD")).Return(account.Account{}, account.ErrAccountNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "account_not_found",
+		},
+		{
+			name:   "should return internal server error, account could not be verified",
+			method: http.MethodPost,
+			path:   "/v1/admin/users/user:1/accounts/USD/verify",
+			expect: func(m mocks) {
+				m.accountSvc.EXPECT().Verify(gomock.Any(), "user:1", money.Currency("USD")).Return(account.Account{}, errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "verification_error",
+		},
+		{
+			name:   "should return verified account",
+			method: http.MethodPost,
+			path:   "/v1/admin/users/user:1/accounts/USD/verify",
+			expect: func(m mocks) {
+				m.accountSvc.EXPECT().Verify(gomock.Any(), "user:1", money.Currency("USD")).Return(account.Account{UserID: "user:1", Currency: "USD"}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			m := mocks{
+				accountSvc:   mockAccount.NewMockService(mockCtrl),
+				loadgenSvc:   mockLoadgen.NewMockService(mockCtrl),
+				replaySvc:    mockReplay.NewMockService(mockCtrl),
+				reconcileSvc: mockReconcile.NewMockService(mockCtrl),
+			}
+			router := gin.New()
+			New(m.accountSvc, m.loadgenSvc, m.replaySvc, m.reconcileSvc, logrus.New()).SetupRouter(router.Group("v1"))
+
+			// expected calls
+			if tt.expect != nil {
+				tt.expect(m)
+			}
+
+			// act
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rec, req)
+
+			// assert
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCode != "" {
+				var httpErr rest.Error
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &httpErr))
+				assert.Equal(t, tt.wantCode, httpErr.Code)
+			}
+		})
+	}
+}
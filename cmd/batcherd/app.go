@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/adminhttphandler"
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/bankhttphandler"
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/config"
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/dispatchmessagehandler"
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/transactionhttphandler"
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/transactionmessagehandler"
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/userhttphandler"
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/goal"
+	"github.com/mazxaxz/donut-batcher/internal/idempotency"
+	"github.com/mazxaxz/donut-batcher/internal/loadgen"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+	rabbitConfig "github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/internal/reconcile"
+	"github.com/mazxaxz/donut-batcher/internal/replay"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+type subscription struct {
+	cfg     rabbitConfig.Subscriber
+	handler transport.Handler
+}
+
+// app holds everything batcherd serves, it is assembled from the clients so tests can swap them for in-memory ones
+type app struct {
+	accountSvc    account.Service
+	batchSvc      batch.Service
+	reconcileSvc  reconcile.Service
+	handler       http.Handler
+	subscriptions []subscription
+	indexers      []mongodb.Indexer
+}
+
+func newApp(ctx context.Context, cfg config.Config, clk clock.Clock, mongoClient mongodb.Clienter, broker transport.Transport, bank banksdk.Clienter) (*app, error) {
+	// Services/Publishers
+	transactionPublisher, err := broker.NewPublisher(ctx, cfg.MQTransactionPublisher)
+	if err != nil {
+		return nil, err
+	}
+	dispatchPublisher, err := broker.NewPublisher(ctx, cfg.MQDispatchPublisher)
+	if err != nil {
+		return nil, err
+	}
+
+	accountService, err := account.New(mongoClient, clk, log)
+	if err != nil {
+		return nil, err
+	}
+
+	goalService, err := goal.New(mongoClient, clk, log)
+	if err != nil {
+		return nil, err
+	}
+
+	thresholds := map[string]string{"USD": cfg.ThresholdUSD}
+	batchService, err := batch.New(mongoClient, bank, accountService, goalService, clk, log, thresholds)
+	if err != nil {
+		return nil, err
+	}
+
+	idempotencyService, err := idempotency.New(mongoClient, clk, log, cfg.IdempotencyTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	loadgenService, err := loadgen.New(ctx, transactionPublisher, batchService, clk, log)
+	if err != nil {
+		return nil, err
+	}
+
+	replayService, err := replay.New(transactionPublisher, log)
+	if err != nil {
+		return nil, err
+	}
+
+	reconcileService, err := reconcile.New(batchService, cfg.Reconciliation, clk, log)
+	if err != nil {
+		return nil, err
+	}
+
+	// Message handlers
+	transactionMessageHandler := transactionmessagehandler.New(batchService, dispatchPublisher, log)
+	dispatchMessageHandler := dispatchmessagehandler.New(batchService, log)
+
+	// HTTP Handlers
+	transactionHTTPHandler := transactionhttphandler.New(batchService, transactionPublisher, log)
+	userHTTPHandler := userhttphandler.New(batchService, accountService, goalService, dispatchPublisher, log)
+	adminHTTPHandler := adminhttphandler.New(accountService, loadgenService, replayService, reconcileService, log)
+	httpHandlers := []rest.SetupRouterer{transactionHTTPHandler, userHTTPHandler, adminHTTPHandler}
+	if cfg.Bank.WebhookSecret != "" {
+		httpHandlers = append(httpHandlers, bankhttphandler.New(batchService, cfg.Bank.WebhookSecret, clk, log))
+	} else {
+		log.Warn("No bank webhook secret configured, dispatched batches are never settled")
+	}
+	middlewares := []gin.HandlerFunc{idempotency.Middleware(idempotencyService, log)}
+
+	a := app{
+		accountSvc:   accountService,
+		batchSvc:     batchService,
+		reconcileSvc: reconcileService,
+		handler:      setupRouting(middlewares, httpHandlers...),
+		subscriptions: []subscription{
+			{cfg: cfg.MQTransactionSubscriber, handler: transactionMessageHandler.Handle},
+			{cfg: cfg.MQDispatchSubscriber, handler: dispatchMessageHandler.Handle},
+		},
+		indexers: []mongodb.Indexer{accountService, goalService, batchService, idempotencyService},
+	}
+	return &a, nil
+}
@@ -0,0 +1,96 @@
+package bankhttphandler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+const (
+	// _maxEventSize is far more than any transfer event takes
+	_maxEventSize = 64 << 10
+)
+
+type handlerContext struct {
+	batchSvc batch.Service
+	secret   string
+	clock    clock.Clock
+	logger   *logrus.Logger
+}
+
+// New serves the webhook the bank posts transfer outcomes to, events are verified with the webhook secret
+func New(bSvc batch.Service, webhookSecret string, clk clock.Clock, l *logrus.Logger) rest.SetupRouterer {
+	c := handlerContext{
+		batchSvc: bSvc,
+		secret:   webhookSecret,
+		clock:    clk,
+		logger:   l,
+	}
+	return &c
+}
+
+func (c *handlerContext) SetupRouter(r *gin.RouterGroup) {
+	r.POST("/bank/transfers/events", c.PostTransferEvent)
+}
+
+func (c *handlerContext) PostTransferEvent(cGin *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(cGin.Request.Body, _maxEventSize))
+	if err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	if err := banksdk.VerifyRequest(cGin.Request, c.secret, c.clock.Now(), body); err != nil {
+		httpErr := rest.NewError("unauthorized", err)
+		cGin.AbortWithStatusJSON(http.StatusUnauthorized, httpErr)
+		return
+	}
+	var event banksdk.TransferEvent
+	if err := json.Unmarshal(body, &event); err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+
+	cf := batch.Confirmation{
+		BatchID:   event.TransferID,
+		Reference: event.Reference,
+		Status:    batch.Status(event.Status),
+		Reason:    event.Reason,
+		Date:      event.Date,
+	}
+	b, err := c.batchSvc.Confirm(cGin, cf)
+	if err != nil {
+		switch {
+		case errors.Is(err, batch.ErrNoBatchID), errors.Is(err, batch.ErrInvalidBatchID), errors.Is(err, batch.ErrInvalidOutcome),
+			errors.Is(err, batch.ErrSplitBatch):
+			httpErr := rest.NewError("invalid_event", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		case errors.Is(err, batch.ErrBatchNotFound), errors.Is(err, batch.ErrLegNotFound):
+			httpErr := rest.NewError("not_found", err)
+			cGin.AbortWithStatusJSON(http.StatusNotFound, httpErr)
+		case errors.Is(err, batch.ErrNotDispatched):
+			/* the event could have outrun the dispatch, the bank retries it later */
+			httpErr := rest.NewError("not_dispatched", err)
+			cGin.AbortWithStatusJSON(http.StatusConflict, httpErr)
+		case errors.Is(err, batch.ErrAlreadyReturned), errors.Is(err, batch.ErrReferenceMismatch):
+			c.logger.Warnf("transfer event of batch '%s' was refused: %s", event.TransferID, err)
+			httpErr := rest.NewError("unprocessable_event", err)
+			cGin.AbortWithStatusJSON(http.StatusUnprocessableEntity, httpErr)
+		default:
+			httpErr := rest.NewError("confirm_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, b)
+}
@@ -0,0 +1,85 @@
+package bankhttphandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	mockBatch "github.com/mazxaxz/donut-batcher/internal/batch/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+func TestPostTransferEvent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "webhook-secret"
+	now := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	batchID := primitive.NewObjectID().Hex()
+	settled := `{"transferId":"` + batchID + `","reference":"ref-1","status":"settled"}`
+
+	tests := []struct {
+		name       string
+		body       string
+		signedAt   time.Time
+		secret     string
+		confirmErr error
+		wantStatus int
+		wantCode   string
+	}{
+		{name: "should return unauthorized, signed with another secret", body: settled, signedAt: now, secret: "forged", wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+		{name: "should return unauthorized, signature is stale", body: settled, signedAt: now.Add(-time.Hour), secret: secret, wantStatus: http.StatusUnauthorized, wantCode: "unauthorized"},
+		{name: "should return bad request, event is not json", body: "}invalid{", signedAt: now, secret: secret, wantStatus: http.StatusBadRequest, wantCode: "invalid_body"},
+		{name: "should return bad request, status is not an outcome", body: settled, signedAt: now, secret: secret, confirmErr: batch.ErrInvalidOutcome, wantStatus: http.StatusBadRequest, wantCode: "invalid_event"},
+		{name: "should return bad request, split batch is confirmed as a whole", body: settled, signedAt: now, secret: secret, confirmErr: batch.ErrSplitBatch, wantStatus: http.StatusBadRequest, wantCode: "invalid_event"},
+		{name: "should return not found, unknown batch", body: settled, signedAt: now, secret: secret, confirmErr: batch.ErrBatchNotFound, wantStatus: http.StatusNotFound, wantCode: "not_found"},
+		{name: "should return not found, unknown leg", body: settled, signedAt: now, secret: secret, confirmErr: batch.ErrLegNotFound, wantStatus: http.StatusNotFound, wantCode: "not_found"},
+		{name: "should return conflict, batch was not dispatched yet", body: settled, signedAt: now, secret: secret, confirmErr: batch.ErrNotDispatched, wantStatus: http.StatusConflict, wantCode: "not_dispatched"},
+		{name: "should return unprocessable entity, batch was returned already", body: settled, signedAt: now, secret: secret, confirmErr: batch.ErrAlreadyReturned, wantStatus: http.StatusUnprocessableEntity, wantCode: "unprocessable_event"},
+		{name: "should return unprocessable entity, reference of another transfer", body: settled, signedAt: now, secret: secret, confirmErr: batch.ErrReferenceMismatch, wantStatus: http.StatusUnprocessableEntity, wantCode: "unprocessable_event"},
+		{name: "should return internal server error, confirmation failed", body: settled, signedAt: now, secret: secret, confirmErr: errors.New("random error"), wantStatus: http.StatusInternalServerError, wantCode: "confirm_error"},
+		{name: "should return confirmed batch", body: settled, signedAt: now, secret: secret, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+			router := gin.New()
+			New(mockBatchSvc, secret, clock.NewFake(now), logrus.New()).SetupRouter(router.Group("v1"))
+			req := httptest.NewRequest(http.MethodPost, "/v1/bank/transfers/events", strings.NewReader(tt.body))
+			banksdk.SignRequest(req, tt.secret, tt.signedAt, []byte(tt.body))
+
+			// expected calls
+			if tt.wantCode == "" || tt.confirmErr != nil {
+				want := batch.Confirmation{BatchID: batchID, Reference: "ref-1", Status: batch.StatusSettled}
+				mockBatchSvc.EXPECT().Confirm(gomock.Any(), want).Return(batch.Batch{Status: batch.StatusSettled}, tt.confirmErr)
+			}
+
+			// act
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			// assert
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCode != "" {
+				var httpErr rest.Error
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &httpErr))
+				assert.Equal(t, tt.wantCode, httpErr.Code)
+			}
+		})
+	}
+}
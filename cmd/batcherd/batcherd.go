@@ -4,25 +4,43 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"os"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/mazxaxz/donut-batcher/cmd/batcherd/config"
-	"github.com/mazxaxz/donut-batcher/cmd/batcherd/dispatchmessagehandler"
-	"github.com/mazxaxz/donut-batcher/cmd/batcherd/transactionhttphandler"
-	"github.com/mazxaxz/donut-batcher/cmd/batcherd/transactionmessagehandler"
-	"github.com/mazxaxz/donut-batcher/internal/batch"
 	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+	mongoConfig "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
 	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
+	rabbitConfig "github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	transportMemory "github.com/mazxaxz/donut-batcher/internal/platform/transport/memory"
 	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk/resilience"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
 	"github.com/mazxaxz/donut-batcher/pkg/logger"
 	"github.com/mazxaxz/donut-batcher/pkg/shutdown"
 )
 
+// _maxRedeliveries stops the in-memory broker from redelivering a nacked message forever
+const _maxRedeliveries = 10
+
 var log = logrus.New()
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "loadgen":
+			runLoadgen(os.Args[2:])
+			return
+		case "replay":
+			runReplay(os.Args[2:])
+			return
+		}
+	}
+
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatal(err)
@@ -34,56 +52,44 @@ func main() {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Clients
-	rabbitClient, err := rabbitmq.NewClient(ctx, cfg.MQClient, log)
-	if err != nil {
-		log.Fatal(err)
-	}
+	clk := clock.New()
 
-	mongoClient, err := mongodb.New(ctx, cfg.MongoClient, log)
+	broker, err := newTransport(ctx, cfg.MQClient, clk)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	// Services/Publishers
-	bankSDK := banksdk.New()
-
-	transactionPublisher, err := rabbitmq.NewPublisher(ctx, rabbitClient, cfg.MQTransactionPublisher)
+	mongoClient, err := newMongoClient(ctx, cfg.MongoClient)
 	if err != nil {
 		log.Fatal(err)
 	}
-	dispatchPublisher, err := rabbitmq.NewPublisher(ctx, rabbitClient, cfg.MQDispatchPublisher)
+
+	bank, err := newBank(ctx, cfg.Bank, cfg.BankResilience, clk)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	thresholds := map[string]string{"USD": cfg.ThresholdUSD}
-	batchService, err := batch.New(mongoClient, bankSDK, log, thresholds)
+	a, err := newApp(ctx, cfg, clk, mongoClient, broker, bank)
 	if err != nil {
 		log.Fatal(err)
 	}
+	go index(ctx, a.indexers...)
+	go a.reconcileSvc.Schedule(ctx)
 
-	go index(ctx, batchService)
-
-	// Message handlers
-	transactionMessageHandler := transactionmessagehandler.New(batchService, dispatchPublisher, log)
-	dispatchMessageHandler := dispatchmessagehandler.New(batchService, log)
-
-	go rabbitClient.Subscribe(ctx, cfg.MQTransactionSubscriber, transactionMessageHandler.Handle)
-	go rabbitClient.Subscribe(ctx, cfg.MQDispatchSubscriber, dispatchMessageHandler.Handle)
+	for _, sub := range a.subscriptions {
+		go broker.Subscribe(ctx, sub.cfg, sub.handler)
+	}
 
 	/*
 		Dispatching leftovers every n hours using cron should be handled here.
 		I see no point of doing that in here, it's just a function invocation.
 	*/
 
-	// HTTP Handlers
-	transactionHTTPHandler := transactionhttphandler.New(batchService, transactionPublisher, log)
-
 	srv := http.Server{
 		Addr:         fmt.Sprintf(":%d", cfg.HTTP.Port),
-		Handler:      setupRouting(transactionHTTPHandler),
+		Handler:      a.handler,
 		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 30 * time.Second,
+		WriteTimeout: cfg.HTTP.WriteTimeoutOrDefault(30 * time.Second),
 		IdleTimeout:  5 * time.Second,
 	}
 	go func(ctx context.Context, srv http.Server) {
@@ -101,3 +107,47 @@ func index(ctx context.Context, indexers ...mongodb.Indexer) {
 		idx.Index(ctx)
 	}
 }
+
+// newMongoClient falls back to the in-memory store, so the app can be run locally without a mongodb server
+func newMongoClient(ctx context.Context, cfg mongoConfig.Config) (mongodb.Clienter, error) {
+	if cfg.URI == memory.URI {
+		log.Warn("Using in-memory store, data is lost on restart")
+		return memory.New(), nil
+	}
+	return mongodb.New(ctx, cfg, log)
+}
+
+// newTransport falls back to the in-process broker, so the app can be run locally without a rabbitmq server
+func newTransport(ctx context.Context, cfg rabbitConfig.Config, clk clock.Clock) (transport.Transport, error) {
+	if cfg.URI == transportMemory.URI {
+		log.Warn("Using in-memory broker, messages are lost on restart")
+		return transportMemory.New(clk, log, _maxRedeliveries), nil
+	}
+	c, err := rabbitmq.NewClient(ctx, cfg, clk, log)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// newBank falls back to the client which only prints the transfers, when no bank is configured
+func newBank(ctx context.Context, cfg banksdk.Config, rCfg resilience.Config, clk clock.Clock) (banksdk.Clienter, error) {
+	if cfg.Mode == banksdk.ModeFile {
+		/* the files are only dropped into a directory, there is no bank to protect with the circuit */
+		bank, err := banksdk.NewFile(cfg.File, clk, log)
+		if err != nil {
+			return nil, err
+		}
+		go bank.Run(ctx)
+		return bank, nil
+	}
+	if cfg.BaseURL == "" {
+		log.Warn("No bank configured, transfers are only printed")
+		return banksdk.New(), nil
+	}
+	bank, err := banksdk.NewHTTP(cfg, clk)
+	if err != nil {
+		return nil, err
+	}
+	return resilience.New(bank, rCfg, clk, log), nil
+}
@@ -1,10 +1,15 @@
 package config
 
 import (
+	"time"
+
 	"github.com/Netflix/go-env"
 
 	mongoConfig "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/config"
 	rabbitConfig "github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/reconcile"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk/resilience"
 	"github.com/mazxaxz/donut-batcher/pkg/logger"
 	"github.com/mazxaxz/donut-batcher/pkg/rest"
 )
@@ -12,12 +17,16 @@ import (
 type Config struct {
 	HTTP                    rest.Config             `env:"HTTP,required=true"`
 	ThresholdUSD            string                  `env:"THRESHOLD_USD,default=100"`
+	IdempotencyTTL          time.Duration           `env:"IDEMPOTENCY_TTL,default=24h"`
 	MongoClient             mongoConfig.Config      `env:"MONGO_CLIENT,required=true"`
 	MQClient                rabbitConfig.Config     `env:"MQ_CLIENT,required=true"`
 	MQTransactionSubscriber rabbitConfig.Subscriber `env:"MQ_TRANSACTION_SUBSCRIBER,required=true"`
 	MQTransactionPublisher  rabbitConfig.Publisher  `env:"MQ_TRANSACTION_PUBLISHER,required=true"`
 	MQDispatchSubscriber    rabbitConfig.Subscriber `env:"MQ_DISPATCH_SUBSCRIBER,required=true"`
 	MQDispatchPublisher     rabbitConfig.Publisher  `env:"MQ_DISPATCH_PUBLISHER,required=true"`
+	Bank                    banksdk.Config          `env:"BANK"`
+	BankResilience          resilience.Config       `env:"BANK_RESILIENCE"`
+	Reconciliation          reconcile.Config        `env:"RECONCILIATION"`
 	Logger                  logger.Config           `env:"LOGGER"`
 }
 
@@ -4,30 +4,35 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 
 	"github.com/mazxaxz/donut-batcher/internal/batch"
-	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk/resilience"
 	"github.com/mazxaxz/donut-batcher/pkg/message/dispatch"
 )
 
 type handlerContext struct {
 	batchSvc batch.Service
 	logger   *logrus.Logger
+	// wait blocks the consumer for the duration or until the context is done
+	wait func(ctx context.Context, d time.Duration)
 }
 
 func New(bSvc batch.Service, l *logrus.Logger) *handlerContext {
 	c := handlerContext{
 		batchSvc: bSvc,
 		logger:   l,
+		wait:     wait,
 	}
 	return &c
 }
 
-func (c *handlerContext) Handle(ctx context.Context, delivery amqp.Delivery) (bool, error) {
+func (c *handlerContext) Handle(ctx context.Context, delivery transport.Message) (bool, error) {
 	switch delivery.Type {
 	case dispatch.MessageTypeDispatch:
 		var msg dispatch.Dispatch
@@ -36,8 +41,20 @@ func (c *handlerContext) Handle(ctx context.Context, delivery amqp.Delivery) (bo
 		}
 
 		if err := c.batchSvc.Dispatch(ctx, msg.BatchID); err != nil {
-			switch err {
-			case batch.ErrNoBatchID:
+			var open *resilience.OpenError
+			switch {
+			case errors.As(err, &open):
+				/* nacking right away would spin the message in a loop, the consumer holds it until a probe is let through */
+				c.logger.Warnf("bank circuit is open, dispatching is paused for %s", open.RetryAfter)
+				c.wait(ctx, open.RetryAfter)
+				return false, err
+			case errors.Is(err, batch.ErrNoBatchID):
+				return true, err
+			case errors.Is(err, batch.ErrNoDestination):
+				/* batch stays flagged until the user has a verified account, the dead letter is requeued after that */
+				return true, err
+			case banksdk.IsPermanent(err):
+				/* bank refused the transfer, redelivering would not change its mind */
 				return true, err
 			default:
 				return false, err
@@ -46,6 +63,15 @@ func (c *handlerContext) Handle(ctx context.Context, delivery amqp.Delivery) (bo
 		return true, nil
 	default:
 		c.logger.Warnf("unknown type: '%s'", delivery.Type)
-		return true, rabbitmq.ErrUnknownMessageType
+		return true, transport.ErrUnknownMessageType
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
 	}
 }
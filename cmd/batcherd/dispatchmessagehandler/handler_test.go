@@ -3,24 +3,28 @@ package dispatchmessagehandler
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/mazxaxz/donut-batcher/internal/batch"
 	mockBatch "github.com/mazxaxz/donut-batcher/internal/batch/mock"
-	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk/resilience"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
 	"github.com/mazxaxz/donut-batcher/pkg/message/dispatch"
 )
 
 func TestHandle(t *testing.T) {
 	t.Run("should return error, invalid message type", func(t *testing.T) {
 		// arrange
-		d := amqp.Delivery{Type: "invalid", Body: nil}
+		d := transport.Message{Type: "invalid", Body: nil}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
@@ -33,12 +37,12 @@ func TestHandle(t *testing.T) {
 
 		// assert
 		assert.True(t, ack)
-		assert.Error(t, err, rabbitmq.ErrUnknownMessageType)
+		assert.Error(t, err, transport.ErrUnknownMessageType)
 	})
 
 	t.Run("should return error, invalid payload", func(t *testing.T) {
 		// arrange
-		d := amqp.Delivery{Type: dispatch.MessageTypeDispatch, Body: []byte("}invalid{{{")}
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: []byte("}invalid{{{")}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
@@ -59,7 +63,7 @@ func TestHandle(t *testing.T) {
 		msg := dispatch.Dispatch{BatchID: "11111"}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: dispatch.MessageTypeDispatch, Body: body}
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
@@ -81,7 +85,7 @@ func TestHandle(t *testing.T) {
 		msg := dispatch.Dispatch{}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: dispatch.MessageTypeDispatch, Body: body}
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
@@ -98,12 +102,139 @@ func TestHandle(t *testing.T) {
 		assert.Error(t, err, batch.ErrNoBatchID)
 	})
 
+	t.Run("should ack message, user has no verified account", func(t *testing.T) {
+		// arrange
+		msg := dispatch.Dispatch{BatchID: "11111"}
+		body, err := json.Marshal(msg)
+		assert.NoError(t, err)
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: body}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		handler := New(mockBatchSvc, logrus.New())
+
+		// expected calls
+		mockBatchSvc.EXPECT().Dispatch(gomock.Any(), msg.BatchID).Return(batch.ErrNoDestination)
+
+		// act
+		ack, err := handler.Handle(context.Background(), d)
+
+		// assert
+		assert.True(t, ack)
+		assert.Equal(t, batch.ErrNoDestination, err)
+	})
+
+	t.Run("should ack message, bank refused the transfer", func(t *testing.T) {
+		// arrange
+		msg := dispatch.Dispatch{BatchID: "11111"}
+		body, err := json.Marshal(msg)
+		assert.NoError(t, err)
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: body}
+		bankErr := &banksdk.Error{StatusCode: http.StatusUnprocessableEntity, Code: "invalid_account"}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		handler := New(mockBatchSvc, logrus.New())
+
+		// expected calls
+		mockBatchSvc.EXPECT().Dispatch(gomock.Any(), msg.BatchID).Return(bankErr)
+
+		// act
+		ack, err := handler.Handle(context.Background(), d)
+
+		// assert
+		assert.True(t, ack)
+		assert.Equal(t, bankErr, err)
+	})
+
+	t.Run("should ack message, payment file can not take the transfer", func(t *testing.T) {
+		// arrange
+		msg := dispatch.Dispatch{BatchID: "11111"}
+		body, err := json.Marshal(msg)
+		assert.NoError(t, err)
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: body}
+		cfg := banksdk.FileConfig{
+			Dir:        t.TempDir(),
+			DebtorName: "Donut",
+			DebtorIBAN: "DE89370400440532013000",
+			ACH:        banksdk.ACHConfig{ImmediateDestination: "011000015", ODFI: "01100001"},
+		}
+		fileBank, err := banksdk.NewFile(cfg, clock.New(), logrus.New())
+		assert.NoError(t, err)
+		/* the user has only an IBAN, an ACH entry needs the routing and account number */
+		iban := &banksdk.Account{Name: "Jane Doe", IBAN: "DE89370400440532013000"}
+		_, fileErr := fileBank.Send(context.Background(), banksdk.Transfer{ID: "11111", UserID: "user:1", Amount: "1.40", Currency: "USD", Creditor: iban})
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		handler := New(mockBatchSvc, logrus.New())
+
+		// expected calls
+		mockBatchSvc.EXPECT().Dispatch(gomock.Any(), msg.BatchID).Return(fileErr)
+
+		// act
+		ack, err := handler.Handle(context.Background(), d)
+
+		// assert
+		assert.True(t, ack)
+		assert.True(t, errors.Is(err, banksdk.ErrNoCreditorAccount))
+	})
+
+	t.Run("should nack message, bank is unavailable", func(t *testing.T) {
+		// arrange
+		msg := dispatch.Dispatch{BatchID: "11111"}
+		body, err := json.Marshal(msg)
+		assert.NoError(t, err)
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: body}
+		bankErr := &banksdk.Error{StatusCode: http.StatusServiceUnavailable, Code: "unavailable", Retryable: true}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		handler := New(mockBatchSvc, logrus.New())
+
+		// expected calls
+		mockBatchSvc.EXPECT().Dispatch(gomock.Any(), msg.BatchID).Return(bankErr)
+
+		// act
+		ack, err := handler.Handle(context.Background(), d)
+
+		// assert
+		assert.False(t, ack)
+		assert.Equal(t, bankErr, err)
+	})
+
+	t.Run("should pause consumption and nack message, bank circuit is open", func(t *testing.T) {
+		// arrange
+		msg := dispatch.Dispatch{BatchID: "11111"}
+		body, err := json.Marshal(msg)
+		assert.NoError(t, err)
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: body}
+		openErr := &resilience.OpenError{RetryAfter: 20 * time.Second}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		handler := New(mockBatchSvc, logrus.New())
+		var paused time.Duration
+		handler.wait = func(_ context.Context, d time.Duration) { paused = d }
+
+		// expected calls
+		mockBatchSvc.EXPECT().Dispatch(gomock.Any(), msg.BatchID).Return(errors.Wrap(openErr, "could not send transfer"))
+
+		// act
+		ack, err := handler.Handle(context.Background(), d)
+
+		// assert
+		assert.False(t, ack)
+		assert.True(t, errors.Is(err, resilience.ErrCircuitOpen))
+		assert.Equal(t, 20*time.Second, paused)
+	})
+
 	t.Run("should ack message and return no error", func(t *testing.T) {
 		// arrange
 		msg := dispatch.Dispatch{BatchID: "11111"}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: dispatch.MessageTypeDispatch, Body: body}
+		d := transport.Message{Type: dispatch.MessageTypeDispatch, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/config"
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	rabbitConfig "github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	transportMemory "github.com/mazxaxz/donut-batcher/internal/platform/transport/memory"
+	"github.com/mazxaxz/donut-batcher/internal/reconcile"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+const (
+	// _maxSteps guards Settle against messages which are redelivered forever
+	_maxSteps = 10000
+
+	_harnessWebhookSecret = "webhook-secret"
+)
+
+// harnessStart is the time the fake clock of every harness starts at
+var harnessStart = time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+type transfer struct {
+	ID        string
+	UserID    string
+	Amount    string
+	Currency  string
+	Portfolio string
+	Reference string
+	Date      time.Time
+}
+
+// fakeBank accepts every transfer unless it was told to fail
+type fakeBank struct {
+	mu        sync.Mutex
+	clock     clock.Clock
+	err       error
+	transfers []transfer
+}
+
+func (b *fakeBank) Send(_ context.Context, bt banksdk.Transfer) (banksdk.Receipt, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.err != nil {
+		return banksdk.Receipt{}, b.err
+	}
+	t := transfer{
+		ID:        bt.ID,
+		UserID:    bt.UserID,
+		Amount:    bt.Amount,
+		Currency:  bt.Currency,
+		Portfolio: bt.Portfolio,
+		Reference: uuid.NewString(),
+		Date:      b.clock.Now(),
+	}
+	b.transfers = append(b.transfers, t)
+	return banksdk.Receipt{Reference: t.Reference, EndToEndID: bt.ID}, nil
+}
+
+// Fail makes every following transfer fail with the error, nil restores the bank
+func (b *fakeBank) Fail(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.err = err
+}
+
+func (b *fakeBank) Transfers() []transfer {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	transfers := make([]transfer, len(b.transfers))
+	copy(transfers, b.transfers)
+	return transfers
+}
+
+// harness runs the whole batcherd in-process: real handlers and services on top of the in-memory store and broker.
+// Messages are not consumed in the background, Settle delivers them on the test goroutine, so runs are deterministic.
+type harness struct {
+	t      *testing.T
+	ctx    context.Context
+	cfg    config.Config
+	clock  *clock.Fake
+	store  mongodb.Clienter
+	broker *transportMemory.Broker
+	bank   *fakeBank
+	app    *app
+}
+
+func newHarness(t *testing.T, thresholdUSD string) *harness {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	log.SetOutput(ioutil.Discard)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	h := harness{
+		t:     t,
+		ctx:   ctx,
+		cfg:   harnessConfig(thresholdUSD, t.TempDir()),
+		clock: clock.NewFake(harnessStart),
+		store: memory.New(),
+	}
+	h.broker = transportMemory.New(h.clock, log, 3)
+	h.bank = &fakeBank{clock: h.clock}
+
+	a, err := newApp(ctx, h.cfg, h.clock, h.store, h.broker, h.bank)
+	require.NoError(t, err)
+	index(ctx, a.indexers...)
+	h.app = a
+	/* batches are dispatched to verified accounts only, the default user of the tests has one */
+	h.RegisterAccount("user:1", "USD")
+	return &h
+}
+
+func harnessConfig(thresholdUSD, statementDir string) config.Config {
+	cfg := config.Config{
+		ThresholdUSD:   thresholdUSD,
+		IdempotencyTTL: time.Hour,
+		MQTransactionSubscriber: rabbitConfig.Subscriber{
+			Queue:           "Donut.Q.Transaction",
+			DeadLetterQueue: "Donut.Q.Transaction.DLQ",
+		},
+		MQTransactionPublisher: rabbitConfig.Publisher{Queue: "Donut.Q.Transaction"},
+		MQDispatchSubscriber: rabbitConfig.Subscriber{
+			Queue:           "Donut.Q.Dispatch",
+			DeadLetterQueue: "Donut.Q.Dispatch.DLQ",
+		},
+		MQDispatchPublisher: rabbitConfig.Publisher{Queue: "Donut.Q.Dispatch"},
+		Bank:                banksdk.Config{WebhookSecret: _harnessWebhookSecret},
+		Reconciliation:      reconcile.Config{Dir: statementDir},
+	}
+	return cfg
+}
+
+// Do sends the request to the router, exactly like the http server would
+func (h *harness) Do(method, path string, body interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	h.t.Helper()
+	var payload []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		payload = []byte(b)
+	default:
+		var err error
+		payload, err = json.Marshal(b)
+		require.NoError(h.t, err)
+	}
+
+	req := httptest.NewRequest(method, path, bytes.NewReader(payload)).WithContext(h.ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.app.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// PostTransaction publishes the transaction through the HTTP API and fails the test when it is not accepted
+func (h *harness) PostTransaction(t transaction.Transaction) {
+	h.t.Helper()
+	rec := h.Do(http.MethodPost, "/v1/transactions", t, nil)
+	require.Equal(h.t, http.StatusAccepted, rec.Code, rec.Body.String())
+}
+
+// RegisterAccount puts a US account of the user through the API and verifies it the way an admin does
+func (h *harness) RegisterAccount(userID, currency string) {
+	h.t.Helper()
+	body := map[string]string{"holderName": "Jane Doe", "routingNumber": "011000015", "accountNumber": "123456789"}
+	rec := h.Do(http.MethodPut, "/v1/users/"+userID+"/accounts/"+currency, body, nil)
+	require.Equal(h.t, http.StatusOK, rec.Code, rec.Body.String())
+	rec = h.Do(http.MethodPost, "/v1/admin/users/"+userID+"/accounts/"+currency+"/verify", nil, nil)
+	require.Equal(h.t, http.StatusOK, rec.Code, rec.Body.String())
+}
+
+// Notify posts the transfer event to the webhook signed the way the bank does it
+func (h *harness) Notify(event banksdk.TransferEvent) *httptest.ResponseRecorder {
+	h.t.Helper()
+	body, err := json.Marshal(event)
+	require.NoError(h.t, err)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/bank/transfers/events", bytes.NewReader(body)).WithContext(h.ctx)
+	req.Header.Set("Content-Type", "application/json")
+	banksdk.SignRequest(req, _harnessWebhookSecret, h.clock.Now(), body)
+	rec := httptest.NewRecorder()
+	h.app.handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// Settle delivers messages to the subscribers until every queue is empty and returns the number of deliveries
+func (h *harness) Settle() int {
+	h.t.Helper()
+	steps := 0
+	for {
+		processed := false
+		for _, sub := range h.app.subscriptions {
+			if h.broker.Process(h.ctx, sub.cfg, sub.handler) {
+				processed = true
+				steps++
+			}
+		}
+		if !processed {
+			return steps
+		}
+		require.Less(h.t, steps, _maxSteps, "messages did not settle")
+	}
+}
+
+// Batches returns all batches of the user, the newest first
+func (h *harness) Batches(userID string) []batch.Batch {
+	h.t.Helper()
+	sort, err := batch.SortFrom("", "")
+	require.NoError(h.t, err)
+
+	batches := make([]batch.Batch, 0)
+	cursor := ""
+	for {
+		page, err := h.app.batchSvc.Browse(h.ctx, 100, sort, cursor, batch.Filter{UserID: userID})
+		require.NoError(h.t, err)
+		batches = append(batches, page.Items...)
+		if page.Next == "" {
+			return batches
+		}
+		cursor = page.Next
+	}
+}
+
+// DeadLetters returns the messages dropped by the subscriber
+func (h *harness) DeadLetters(sub rabbitConfig.Subscriber) []transport.DeadLetter {
+	h.t.Helper()
+	letters, err := h.broker.DeadLetters(h.ctx, sub.DeadLetterQueue, _maxSteps)
+	require.NoError(h.t, err)
+	return letters
+}
@@ -8,14 +8,14 @@ import (
 	"github.com/mazxaxz/donut-batcher/pkg/rest"
 )
 
-func setupRouting(handlers ...rest.SetupRouterer) http.Handler {
+func setupRouting(middlewares []gin.HandlerFunc, handlers ...rest.SetupRouterer) http.Handler {
 	router := gin.Default()
 	router.Use(gin.Logger())
 	router.Use(gin.Recovery())
 
 	/* no need for CORS right now */
 
-	v1 := router.Group("v1")
+	v1 := router.Group("v1", middlewares...)
 	for _, handler := range handlers {
 		handler.SetupRouter(v1)
 	}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/config"
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/loadgen"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/logger"
+	"github.com/mazxaxz/donut-batcher/pkg/shutdown"
+)
+
+// runLoadgen publishes generated transactions to the running instances and prints the report as JSON,
+// it uses the same environment configuration as the daemon
+func runLoadgen(args []string) {
+	d := loadgen.DefaultConfig()
+	var cfg loadgen.Config
+	var currencies string
+	fs := flag.NewFlagSet("loadgen", flag.ExitOnError)
+	fs.IntVar(&cfg.Users, "users", d.Users, "number of distinct users")
+	fs.StringVar(&currencies, "currencies", strings.Join(d.Currencies, ","), "comma separated currencies")
+	fs.IntVar(&cfg.Rate, "rate", d.Rate, "transactions published per second")
+	fs.DurationVar(&cfg.Duration, "duration", d.Duration, "how long transactions are published for")
+	fs.StringVar(&cfg.AmountMin, "amount-min", d.AmountMin, "smallest transaction amount")
+	fs.StringVar(&cfg.AmountMax, "amount-max", d.AmountMax, "largest transaction amount")
+	fs.StringVar(&cfg.Distribution, "distribution", d.Distribution, "amount distribution: uniform, normal or exponential")
+	fs.Float64Var(&cfg.DuplicateRatio, "duplicate-ratio", 0, "share of republished transactions")
+	fs.Float64Var(&cfg.InvalidRatio, "invalid-ratio", 0, "share of transactions rejected by validation")
+	fs.IntVar(&cfg.Workers, "workers", d.Workers, "concurrent publishers")
+	fs.DurationVar(&cfg.CompletionTimeout, "completion-timeout", d.CompletionTimeout, "how long to wait for transactions to get batched")
+	fs.DurationVar(&cfg.PollInterval, "poll-interval", d.PollInterval, "how often batched transactions are checked")
+	fs.Int64Var(&cfg.Seed, "seed", 0, "random seed, current time is used when not set")
+	_ = fs.Parse(args)
+	cfg.Currencies = strings.Split(currencies, ",")
+
+	appCfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := logger.Configure(log, appCfg.Logger); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go shutdown.Wait(cancel, log)
+
+	rabbitClient, err := rabbitmq.NewClient(ctx, appCfg.MQClient, clock.New(), log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	mongoClient, err := mongodb.New(ctx, appCfg.MongoClient, log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transactionPublisher, err := rabbitmq.NewPublisher(ctx, rabbitClient, appCfg.MQTransactionPublisher)
+	if err != nil {
+		log.Fatal(err)
+	}
+	/* batches are only looked up, nothing is dispatched from here so no accounts are needed */
+	thresholds := map[string]string{"USD": appCfg.ThresholdUSD}
+	batchService, err := batch.New(mongoClient, banksdk.New(), nil, nil, clock.New(), log, thresholds)
+	if err != nil {
+		log.Fatal(err)
+	}
+	loadgenService, err := loadgen.New(ctx, transactionPublisher, batchService, clock.New(), log)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report, err := loadgenService.Run(ctx, cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,486 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/goal"
+	"github.com/mazxaxz/donut-batcher/internal/idempotency"
+	"github.com/mazxaxz/donut-batcher/internal/reconcile"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Run("should dispatch batch to the bank once threshold is reached", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+
+		// act
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		h.PostTransaction(transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		h.PostTransaction(transaction.Transaction{ID: "3", UserID: "user:1", Amount: "4.75", Currency: "USD"})
+		steps := h.Settle()
+
+		// assert
+		/* three transactions and one dispatch */
+		assert.Equal(t, 4, steps)
+		batches := h.Batches("user:1")
+		assert.Len(t, batches, 2)
+		assert.Equal(t, batch.Status(batch.StatusUndispatched), batches[0].Status)
+		assert.Equal(t, "0.25", batches[0].Amount.String())
+		assert.Equal(t, batch.Status(batch.StatusDispatched), batches[1].Status)
+		assert.Equal(t, "1.4", batches[1].Amount.String())
+		assert.Equal(t, 2, batches[1].TransactionCount)
+
+		transfers := h.bank.Transfers()
+		assert.Len(t, transfers, 1)
+		assert.Equal(t, "user:1", transfers[0].UserID)
+		assert.Equal(t, "1.4", transfers[0].Amount)
+		assert.Equal(t, "USD", transfers[0].Currency)
+		assert.Equal(t, harnessStart, transfers[0].Date)
+		assert.Equal(t, transfers[0].Reference, batches[1].DispatchReference)
+		assert.Empty(t, h.DeadLetters(h.cfg.MQTransactionSubscriber))
+		assert.Empty(t, h.DeadLetters(h.cfg.MQDispatchSubscriber))
+	})
+
+	t.Run("should keep batch ready while the bank fails and dispatch it once requeued", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		h.bank.Fail(errors.New("bank is down"))
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.01", Currency: "USD"})
+		h.PostTransaction(transaction.Transaction{ID: "2", UserID: "user:1", Amount: "1.01", Currency: "USD"})
+		h.Settle()
+
+		// act
+		failed := h.Batches("user:1")
+		letters := h.DeadLetters(h.cfg.MQDispatchSubscriber)
+		h.bank.Fail(nil)
+		h.clock.Advance(90 * time.Minute)
+		_, err := h.broker.Requeue(h.ctx, h.cfg.MQDispatchSubscriber.DeadLetterQueue, 10)
+		h.Settle()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, failed, 1)
+		assert.Equal(t, batch.Status(batch.StatusReadyToDispatch), failed[0].Status)
+		assert.Len(t, letters, 1)
+		assert.Equal(t, "message was nacked too many times: bank is down", letters[0].Error)
+
+		dispatched := h.Batches("user:1")
+		assert.Len(t, dispatched, 1)
+		assert.Equal(t, batch.Status(batch.StatusDispatched), dispatched[0].Status)
+		assert.Equal(t, "1.98", dispatched[0].Amount.String())
+		assert.Equal(t, harnessStart, dispatched[0].CreatedDate)
+		assert.Equal(t, harnessStart.Add(90*time.Minute), dispatched[0].DispatchedDate)
+		transfers := h.bank.Transfers()
+		assert.Len(t, transfers, 1)
+		assert.Equal(t, harnessStart.Add(90*time.Minute), transfers[0].Date)
+		assert.Empty(t, h.DeadLetters(h.cfg.MQDispatchSubscriber))
+	})
+
+	t.Run("should flag batch of the user without verified account and dispatch it once verified", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:2", Amount: "1.10", Currency: "USD"})
+		h.PostTransaction(transaction.Transaction{ID: "2", UserID: "user:2", Amount: "2.50", Currency: "USD"})
+		h.Settle()
+
+		// act
+		flagged := h.Batches("user:2")
+		letters := h.DeadLetters(h.cfg.MQDispatchSubscriber)
+		h.clock.Advance(time.Hour)
+		h.RegisterAccount("user:2", "USD")
+		_, err := h.broker.Requeue(h.ctx, h.cfg.MQDispatchSubscriber.DeadLetterQueue, 10)
+		h.Settle()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, flagged, 1)
+		assert.Equal(t, batch.Status(batch.StatusReadyToDispatch), flagged[0].Status)
+		assert.Equal(t, batch.FlagNoDestination, flagged[0].Flag)
+		assert.Equal(t, harnessStart, flagged[0].FlaggedDate)
+		assert.Len(t, letters, 1)
+		assert.Equal(t, batch.ErrNoDestination.Error(), letters[0].Error)
+
+		dispatched := h.Batches("user:2")
+		assert.Equal(t, batch.Status(batch.StatusDispatched), dispatched[0].Status)
+		assert.Empty(t, dispatched[0].Flag)
+		assert.True(t, dispatched[0].FlaggedDate.IsZero())
+		transfers := h.bank.Transfers()
+		assert.Len(t, transfers, 1)
+		assert.Equal(t, "user:2", transfers[0].UserID)
+		assert.Empty(t, h.DeadLetters(h.cfg.MQDispatchSubscriber))
+	})
+
+	t.Run("should split batch between the portfolios of the user and settle it leg by leg", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		allocations := map[string]interface{}{"rules": []map[string]string{
+			{"portfolio": "index-fund", "percent": "70"},
+			{"portfolio": "savings", "percent": "30"},
+		}}
+		rec := h.Do(http.MethodPut, "/v1/users/user:1/allocations", allocations, nil)
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		h.PostTransaction(transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		h.Settle()
+		dispatched := h.Batches("user:1")[0]
+
+		// act
+		var responses []int
+		for _, leg := range dispatched.Legs {
+			event := banksdk.TransferEvent{TransferID: leg.TransferID, Reference: leg.DispatchReference, Status: banksdk.TransferStatusSettled}
+			responses = append(responses, h.Notify(event).Code)
+		}
+
+		// assert
+		transfers := h.bank.Transfers()
+		require.Len(t, transfers, 2)
+		assert.Equal(t, "0.98", transfers[0].Amount)
+		assert.Equal(t, "index-fund", transfers[0].Portfolio)
+		assert.Equal(t, "0.42", transfers[1].Amount)
+		assert.Equal(t, "savings", transfers[1].Portfolio)
+		assert.Equal(t, batch.Status(batch.StatusDispatched), dispatched.Status)
+		assert.Equal(t, []int{http.StatusOK, http.StatusOK}, responses)
+		settled := h.Batches("user:1")[0]
+		assert.Equal(t, batch.Status(batch.StatusSettled), settled.Status)
+		assert.Equal(t, batch.Status(batch.StatusSettled), settled.Legs[1].Status)
+	})
+
+	t.Run("should re-credit funds of the transfer returned by the bank", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		h.PostTransaction(transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		h.Settle()
+		dispatched := h.Batches("user:1")[0]
+		h.clock.Advance(24 * time.Hour)
+		event := banksdk.TransferEvent{
+			TransferID: dispatched.ID.Hex(),
+			Reference:  dispatched.DispatchReference,
+			Status:     banksdk.TransferStatusSettled,
+			Date:       h.clock.Now(),
+		}
+
+		// act
+		settled := h.Notify(event)
+		event.Status = banksdk.TransferStatusReturned
+		event.Reason = "account_closed"
+		h.clock.Advance(24 * time.Hour)
+		returned := h.Notify(event)
+		replayed := h.Notify(event)
+
+		// assert
+		assert.Equal(t, http.StatusOK, settled.Code, settled.Body.String())
+		assert.Equal(t, http.StatusOK, returned.Code, returned.Body.String())
+		assert.Equal(t, http.StatusOK, replayed.Code, replayed.Body.String())
+		batches := h.Batches("user:1")
+		assert.Len(t, batches, 2)
+		assert.Equal(t, batch.Status(batch.StatusUndispatched), batches[0].Status)
+		assert.Equal(t, "1.4", batches[0].Amount.String())
+		assert.Equal(t, batch.Status(batch.StatusReturned), batches[1].Status)
+		assert.Equal(t, "account_closed", batches[1].Settlement.Reason)
+		assert.Equal(t, batches[0].ID, batches[1].Settlement.RecreditBatchID)
+		assert.Len(t, batches[1].History, 5)
+	})
+
+	t.Run("should refuse transfer event with invalid signature", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		body := `{"transferId":"5f5f5f5f5f5f5f5f5f5f5f5f","status":"settled"}`
+		headers := map[string]string{
+			banksdk.HeaderTimestamp: strconv.FormatInt(h.clock.Now().Unix(), 10),
+			banksdk.HeaderSignature: "forged",
+		}
+
+		// act
+		rec := h.Do(http.MethodPost, "/v1/bank/transfers/events", body, headers)
+
+		// assert
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("should reconcile dispatched batch against the bank statement", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		h.PostTransaction(transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		h.Settle()
+		dispatched := h.Batches("user:1")[0]
+		statement := "reference,amount,currency,booking_date\n" + dispatched.DispatchReference + ",-1.40,USD,2021-01-01\n"
+		err := ioutil.WriteFile(filepath.Join(h.cfg.Reconciliation.Dir, "statement.csv"), []byte(statement), 0o600)
+		require.NoError(t, err)
+
+		// act
+		run := h.Do(http.MethodPost, "/v1/admin/reconciliation", nil, nil)
+		latest := h.Do(http.MethodGet, "/v1/admin/reconciliation", nil, nil)
+
+		// assert
+		assert.Equal(t, http.StatusOK, run.Code, run.Body.String())
+		var report reconcile.Report
+		assert.NoError(t, json.Unmarshal(run.Body.Bytes(), &report))
+		assert.Equal(t, 1, report.Matched)
+		assert.Len(t, report.Items, 1)
+		assert.Equal(t, dispatched.ID.Hex(), report.Items[0].BatchID)
+		assert.Equal(t, http.StatusOK, latest.Code)
+		assert.JSONEq(t, run.Body.String(), latest.Body.String())
+	})
+
+	t.Run("should save round-ups of matching transactions for the goal", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		body := map[string]interface{}{
+			"name":      "Coffee",
+			"currency":  "USD",
+			"target":    "9",
+			"threshold": "0.5",
+			"rules":     []map[string]interface{}{{"merchantCategories": []string{"5812"}}},
+		}
+		created := h.Do(http.MethodPost, "/v1/users/user:1/goals", body, nil)
+		require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+		var coffee goal.Goal
+		require.NoError(t, json.Unmarshal(created.Body.Bytes(), &coffee))
+
+		// act
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD", MerchantCategory: "5812"})
+		h.PostTransaction(transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		h.Settle()
+		rec := h.Do(http.MethodGet, "/v1/users/user:1/goals", nil, nil)
+
+		// assert
+		batches := h.Batches("user:1")
+		assert.Len(t, batches, 2)
+		assert.Equal(t, batch.Status(batch.StatusUndispatched), batches[0].Status)
+		assert.Empty(t, batches[0].Goal)
+		assert.Equal(t, batch.Status(batch.StatusDispatched), batches[1].Status)
+		assert.Equal(t, coffee.ID.Hex(), batches[1].Goal)
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var progress []goal.Progress
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &progress))
+		assert.Len(t, progress, 1)
+		assert.Equal(t, "0.9", progress[0].Invested)
+		assert.Equal(t, "10", progress[0].Percent)
+		assert.False(t, progress[0].Reached)
+	})
+
+	t.Run("should dispatch round-ups of the goal once the goal is deleted", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		body := map[string]interface{}{
+			"name":      "Coffee",
+			"currency":  "USD",
+			"target":    "100",
+			"threshold": "50",
+			"rules":     []map[string]interface{}{{"merchantCategories": []string{"5812"}}},
+		}
+		created := h.Do(http.MethodPost, "/v1/users/user:1/goals", body, nil)
+		require.Equal(t, http.StatusCreated, created.Code, created.Body.String())
+		var coffee goal.Goal
+		require.NoError(t, json.Unmarshal(created.Body.Bytes(), &coffee))
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD", MerchantCategory: "5812"})
+		h.Settle()
+		require.Equal(t, batch.Status(batch.StatusUndispatched), h.Batches("user:1")[0].Status)
+
+		// act
+		rec := h.Do(http.MethodDelete, "/v1/users/user:1/goals/"+coffee.ID.Hex(), nil, nil)
+		h.Settle()
+
+		// assert
+		assert.Equal(t, http.StatusNoContent, rec.Code, rec.Body.String())
+		batches := h.Batches("user:1")
+		assert.Len(t, batches, 1)
+		assert.Equal(t, batch.Status(batch.StatusDispatched), batches[0].Status)
+		assert.Equal(t, "0.9", batches[0].Amount.String())
+	})
+
+	t.Run("should refuse batch and entry pages outside of the bounds", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "1")
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		h.Settle()
+		batchID := h.Batches("user:1")[0].ID.Hex()
+		paths := []string{
+			"/v1/transactions/batches/history?limit=0",
+			"/v1/transactions/batches/history?limit=-1",
+			"/v1/transactions/batches/history?limit=" + strconv.Itoa(batch.MaxLimit+1),
+			"/v1/users/user:1/batches?limit=0",
+			"/v1/users/user:1/batches?limit=-1",
+			"/v1/transactions/batches/" + batchID + "?limit=0",
+			"/v1/transactions/batches/" + batchID + "?limit=" + strconv.Itoa(batch.MaxLimit+1),
+			"/v1/transactions/batches/" + batchID + "?page=0",
+			"/v1/transactions/batches/" + batchID + "?page=-1",
+		}
+
+		for _, path := range paths {
+			// act
+			rec := h.Do(http.MethodGet, path, nil, nil)
+
+			// assert
+			assert.Equal(t, http.StatusBadRequest, rec.Code, path)
+			assert.Contains(t, rec.Body.String(), "invalid_parameter__", path)
+		}
+		first := h.Do(http.MethodGet, "/v1/transactions/batches/"+batchID+"?page=1", nil, nil)
+		assert.Equal(t, http.StatusOK, first.Code, first.Body.String())
+		assert.Contains(t, first.Body.String(), `"page":1`)
+		assert.Contains(t, first.Body.String(), `"transactionId":"1"`)
+	})
+
+	t.Run("should replay transaction retried with the same idempotency key and publish it once", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "100")
+		headers := map[string]string{idempotency.HeaderKey: "key-1"}
+		give := transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"}
+
+		// act
+		first := h.Do(http.MethodPost, "/v1/transactions", give, headers)
+		retried := h.Do(http.MethodPost, "/v1/transactions", give, headers)
+		give.Amount = "2.10"
+		reused := h.Do(http.MethodPost, "/v1/transactions", give, headers)
+		steps := h.Settle()
+
+		// assert
+		assert.Equal(t, http.StatusAccepted, first.Code, first.Body.String())
+		assert.Equal(t, http.StatusAccepted, retried.Code, retried.Body.String())
+		assert.Equal(t, "true", retried.Header().Get(idempotency.HeaderReplayed))
+		assert.Equal(t, http.StatusConflict, reused.Code, reused.Body.String())
+		assert.Equal(t, 1, steps)
+		assert.Equal(t, 1, h.Batches("user:1")[0].TransactionCount)
+	})
+
+	t.Run("should replay bulk retried with the same idempotency key with its per item result", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "100")
+		headers := map[string]string{idempotency.HeaderKey: "key-1"}
+		items := make([]string, 0, 1000)
+		for i := 0; i < 1000; i++ {
+			/* the note pads the bulk over a megabyte, it is still stored */
+			items = append(items, `{"id":"`+strconv.Itoa(i)+`","userId":"user:1","amount":"1.10","currency":"USD","note":"`+strings.Repeat("x", 1100)+`"}`)
+		}
+		give := "[" + strings.Join(items, ",") + "]"
+
+		// act
+		first := h.Do(http.MethodPost, "/v1/transactions/bulk", give, headers)
+		retried := h.Do(http.MethodPost, "/v1/transactions/bulk", give, headers)
+		reused := h.Do(http.MethodPost, "/v1/transactions/bulk", `[{"id":"1001","userId":"user:1","amount":"1.10","currency":"USD"}]`, headers)
+		h.Settle()
+
+		// assert
+		assert.Equal(t, http.StatusOK, first.Code, first.Body.String())
+		assert.Contains(t, first.Body.String(), `"accepted":1000`)
+		assert.Equal(t, http.StatusOK, retried.Code, retried.Body.String())
+		assert.Equal(t, "true", retried.Header().Get(idempotency.HeaderReplayed))
+		assert.Equal(t, first.Body.String(), retried.Body.String())
+		assert.Equal(t, http.StatusConflict, reused.Code, reused.Body.String())
+		count := 0
+		for _, b := range h.Batches("user:1") {
+			count += b.TransactionCount
+		}
+		assert.Equal(t, 1000, count)
+	})
+
+	t.Run("should create goal retried with the same idempotency key once", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "100")
+		headers := map[string]string{idempotency.HeaderKey: "key-1"}
+		body := map[string]interface{}{"name": "Coffee", "currency": "USD", "target": "9", "threshold": "0.5", "default": true}
+
+		// act
+		first := h.Do(http.MethodPost, "/v1/users/user:1/goals", body, headers)
+		retried := h.Do(http.MethodPost, "/v1/users/user:1/goals", body, headers)
+		rec := h.Do(http.MethodGet, "/v1/users/user:1/goals", nil, nil)
+
+		// assert
+		assert.Equal(t, http.StatusCreated, first.Code, first.Body.String())
+		assert.Equal(t, http.StatusCreated, retried.Code, retried.Body.String())
+		assert.Equal(t, "true", retried.Header().Get(idempotency.HeaderReplayed))
+		assert.Equal(t, first.Body.String(), retried.Body.String())
+		var progress []goal.Progress
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &progress))
+		assert.Len(t, progress, 1)
+	})
+
+	t.Run("should refuse too large transaction sent with an idempotency key", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "100")
+		headers := map[string]string{idempotency.HeaderKey: "key-1"}
+		give := `{"id":"1","userId":"user:1","amount":"1.10","currency":"USD","merchantCategory":"` + strings.Repeat("x", 8<<20) + `"}`
+
+		// act
+		rec := h.Do(http.MethodPost, "/v1/transactions", give, headers)
+
+		// assert
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+	})
+
+	t.Run("should refuse bulk over the limit without reading the rest of the array", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "100")
+		items := make([]string, 0, 1002)
+		for i := 0; i < 1001; i++ {
+			items = append(items, `{"id":"`+strconv.Itoa(i)+`","userId":"user:1","amount":"1.10","currency":"USD"}`)
+		}
+		/* never read, the array is refused before it gets to the broken item */
+		oversized := "[" + strings.Join(items, ",") + ",{broken"
+
+		// act
+		rec := h.Do(http.MethodPost, "/v1/transactions/bulk", oversized, nil)
+		object := h.Do(http.MethodPost, "/v1/transactions/bulk", `{"id":"1"}`, nil)
+
+		// assert
+		assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code, rec.Body.String())
+		assert.Equal(t, http.StatusBadRequest, object.Code, object.Body.String())
+		assert.Equal(t, 0, h.Settle())
+	})
+
+	t.Run("should list batches with camel case amount and currency", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "100")
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		h.Settle()
+
+		// act
+		rec := h.Do(http.MethodGet, "/v1/users/user:1/batches", nil, nil)
+
+		// assert
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var page struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+		assert.Len(t, page.Items, 1)
+		assert.Equal(t, "0.9", page.Items[0]["amount"])
+		assert.Equal(t, "USD", page.Items[0]["currency"])
+		assert.NotContains(t, page.Items[0], "Amount")
+	})
+
+	t.Run("should count redelivered transaction once", func(t *testing.T) {
+		// arrange
+		h := newHarness(t, "100")
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		h.Settle()
+
+		// act
+		h.PostTransaction(transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		steps := h.Settle()
+
+		// assert
+		assert.Equal(t, 1, steps)
+		batches := h.Batches("user:1")
+		assert.Len(t, batches, 1)
+		assert.Equal(t, "0.9", batches[0].Amount.String())
+		assert.Equal(t, 1, batches[0].TransactionCount)
+		assert.Empty(t, h.DeadLetters(h.cfg.MQTransactionSubscriber))
+	})
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/mazxaxz/donut-batcher/cmd/batcherd/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
+	"github.com/mazxaxz/donut-batcher/internal/replay"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/logger"
+	"github.com/mazxaxz/donut-batcher/pkg/shutdown"
+)
+
+// runReplay publishes transactions from an NDJSON file, "-" or no file reads the standard input
+func runReplay(args []string) {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	rate := fs.Int("rate", 0, "transactions published per second, 0 means no limit")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: batcherd replay [-rate n] [file.jsonl]")
+		fs.PrintDefaults()
+	}
+	_ = fs.Parse(args)
+
+	var input io.Reader = os.Stdin
+	if path := fs.Arg(0); path != "" && path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		input = file
+	}
+
+	appCfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := logger.Configure(log, appCfg.Logger); err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go shutdown.Wait(cancel, log)
+
+	rabbitClient, err := rabbitmq.NewClient(ctx, appCfg.MQClient, clock.New(), log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	transactionPublisher, err := rabbitmq.NewPublisher(ctx, rabbitClient, appCfg.MQTransactionPublisher)
+	if err != nil {
+		log.Fatal(err)
+	}
+	replayService, err := replay.New(transactionPublisher, log)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	summary, err := replayService.Replay(ctx, input, *rate)
+	for _, issue := range summary.Issues {
+		fmt.Println(issue)
+	}
+	fmt.Printf("lines: %d, accepted: %d, rejected: %d, duplicates: %d\n",
+		summary.Lines, summary.Accepted, summary.Rejected, summary.Duplicates)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,173 @@
+package transactionhttphandler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+const (
+	exportFormatCSV    = "csv"
+	exportFormatNDJSON = "ndjson"
+
+	// rows written between flushes of the response, every flush sends a chunk to the client
+	_exportFlushEvery = 100
+)
+
+var (
+	ErrInvalidExportFormat = errors.New("format has to be either csv or ndjson")
+)
+
+type exportWriter interface {
+	Write(b batch.Batch) error
+	Flush() error
+}
+
+func (c *handlerContext) ExportBatches(cGin *gin.Context) {
+	f, err := batch.FilterFrom(cGin.Request.URL.Query())
+	if err != nil {
+		c.abortWithParameterError(cGin, err)
+		return
+	}
+	sort, err := batch.SortFrom(cGin.Query("sort"), cGin.Query("order"))
+	if err != nil {
+		c.abortWithParameterError(cGin, err)
+		return
+	}
+
+	var w exportWriter
+	format := cGin.DefaultQuery("format", exportFormatCSV)
+	switch format {
+	case exportFormatCSV:
+		w = newCSVExportWriter(cGin.Writer)
+		cGin.Header("Content-Type", "text/csv")
+	case exportFormatNDJSON:
+		w = newNDJSONExportWriter(cGin.Writer)
+		cGin.Header("Content-Type", "application/x-ndjson")
+	default:
+		httpErr := rest.NewParameterError("format", ErrInvalidExportFormat)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+
+	var rows int
+	write := func(b batch.Batch) error {
+		if rows == 0 {
+			cGin.Header("Content-Disposition", "attachment; filename=batches."+format)
+			cGin.Status(http.StatusOK)
+		}
+		if err := w.Write(b); err != nil {
+			return err
+		}
+		rows++
+		if rows%_exportFlushEvery == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			cGin.Writer.Flush()
+		}
+		return nil
+	}
+
+	if err := c.batchSvc.Export(cGin, sort, f, write); err != nil {
+		if rows == 0 {
+			httpErr := rest.NewError("export_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+			return
+		}
+		/* response is already on its way, the only thing left is to cut the stream short */
+		c.logger.Error(err)
+		return
+	}
+	if rows == 0 {
+		cGin.Header("Content-Disposition", "attachment; filename=batches."+format)
+		cGin.Status(http.StatusOK)
+	}
+	if err := w.Flush(); err != nil {
+		c.logger.Error(err)
+	}
+	cGin.Writer.Flush()
+}
+
+type csvExportWriter struct {
+	w             *csv.Writer
+	headerWritten bool
+}
+
+func newCSVExportWriter(rw http.ResponseWriter) *csvExportWriter {
+	return &csvExportWriter{w: csv.NewWriter(rw)}
+}
+
+func (e *csvExportWriter) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	header := []string{
+		"id", "userId", "amount", "currency", "status", "transactionCount",
+		"createdDate", "updatedDate", "dispatchedDate", "dispatchReference", "dispatchFile", "endToEndId",
+	}
+	if err := e.w.Write(header); err != nil {
+		return err
+	}
+	e.headerWritten = true
+	return nil
+}
+
+func (e *csvExportWriter) Write(b batch.Batch) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	var dispatchedDate string
+	if !b.DispatchedDate.IsZero() {
+		dispatchedDate = b.DispatchedDate.Format(time.RFC3339)
+	}
+	record := []string{
+		b.ID.Hex(),
+		b.UserID,
+		b.Amount.String(),
+		b.Currency.String(),
+		string(b.Status),
+		strconv.Itoa(b.TransactionCount),
+		b.CreatedDate.Format(time.RFC3339),
+		b.UpdatedDate.Format(time.RFC3339),
+		dispatchedDate,
+		b.DispatchReference,
+		b.DispatchFile,
+		b.EndToEndID,
+	}
+	return e.w.Write(record)
+}
+
+func (e *csvExportWriter) Flush() error {
+	/* empty export still gets the header */
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
+
+type ndjsonExportWriter struct {
+	encoder *json.Encoder
+}
+
+func newNDJSONExportWriter(rw http.ResponseWriter) *ndjsonExportWriter {
+	return &ndjsonExportWriter{encoder: json.NewEncoder(rw)}
+}
+
+func (e *ndjsonExportWriter) Write(b batch.Batch) error {
+	return e.encoder.Encode(b)
+}
+
+func (e *ndjsonExportWriter) Flush() error {
+	return nil
+}
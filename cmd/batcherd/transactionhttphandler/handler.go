@@ -1,30 +1,46 @@
 package transactionhttphandler
 
 import (
-	"fmt"
-	"math/rand"
+	"errors"
 	"net/http"
 	"strconv"
-	"sync"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
 
 	"github.com/mazxaxz/donut-batcher/internal/batch"
-	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
-	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
 	"github.com/mazxaxz/donut-batcher/pkg/rest"
 )
 
+var (
+	ErrInvalidPage  = errors.New("page has to be 1 or greater")
+	ErrNegativePage = errors.New("page can not be negative")
+)
+
+type entryPage struct {
+	Items []batch.Entry `json:"items"`
+	Limit int           `json:"limit"`
+	Page  int           `json:"page"`
+}
+
+type historyPage struct {
+	batch.Page
+	Filters map[string]string `json:"filters"`
+}
+
+type batchDetails struct {
+	batch.Batch
+	Transactions entryPage `json:"transactions"`
+}
+
 type handlerContext struct {
 	batchSvc             batch.Service
-	transactionPublisher rabbitmq.Publisher
+	transactionPublisher transport.Publisher
 	logger               *logrus.Logger
 }
 
-func New(bSvc batch.Service, transactionPublisher rabbitmq.Publisher, l *logrus.Logger) rest.SetupRouterer {
+func New(bSvc batch.Service, transactionPublisher transport.Publisher, l *logrus.Logger) rest.SetupRouterer {
 	c := handlerContext{
 		batchSvc:             bSvc,
 		transactionPublisher: transactionPublisher,
@@ -35,7 +51,12 @@ func New(bSvc batch.Service, transactionPublisher rabbitmq.Publisher, l *logrus.
 
 func (c *handlerContext) SetupRouter(r *gin.RouterGroup) {
 	r.GET("/transactions/batches/history", c.GetBatchHistory)
-	r.POST("/transactions/stress", c.Stress)
+	r.GET("/transactions/batches/export", c.ExportBatches)
+	r.GET("/transactions/batches/:id", c.GetBatch)
+	r.POST("/transactions", c.PostTransaction)
+	r.POST("/transactions/bulk", c.PostTransactions)
+	/* the wildcard next to the static /transactions/batches routes needs gin 1.7, older versions panic on the conflict */
+	r.GET("/transactions/:id", c.GetTransaction)
 }
 
 func (c *handlerContext) GetBatchHistory(cGin *gin.Context) {
@@ -45,61 +66,141 @@ func (c *handlerContext) GetBatchHistory(cGin *gin.Context) {
 		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
 		return
 	}
-	page, err := strconv.Atoi(cGin.DefaultQuery("page", "0"))
+	f, err := batch.FilterFrom(cGin.Request.URL.Query())
 	if err != nil {
-		httpErr := rest.NewError("invalid_parameter__page", err)
-		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		c.abortWithParameterError(cGin, err)
 		return
 	}
-	order := cGin.DefaultQuery("order", "-1")
-	status := cGin.Query("status")
-
-	var asc bool
-	if order == "-1" {
-		asc = false
-	} else {
-		asc = true
+	sort, err := batch.SortFrom(cGin.Query("sort"), cGin.Query("order"))
+	if err != nil {
+		c.abortWithParameterError(cGin, err)
+		return
 	}
 
-	var s *batch.Status
-	if status != "" {
-		v := batch.NewStatusFrom(status)
-		s = &v
+	/* page parameter is deprecated, offset pagination is kept only for the clients that did not move to cursors */
+	if _, deprecated := cGin.GetQuery("page"); deprecated {
+		page, err := strconv.Atoi(cGin.Query("page"))
+		if err == nil && page < 0 {
+			err = ErrNegativePage
+		}
+		if err != nil {
+			httpErr := rest.NewError("invalid_parameter__page", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+			return
+		}
+		batches, err := c.batchSvc.Paginate(cGin, limit, limit*page, sort, f)
+		if err != nil {
+			switch err {
+			case batch.ErrInvalidLimit:
+				httpErr := rest.NewParameterError("limit", err)
+				cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+			case batch.ErrInvalidOffset:
+				httpErr := rest.NewParameterError("page", err)
+				cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+			default:
+				httpErr := rest.NewError("paginate_error", err)
+				cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+			}
+			return
+		}
+		cGin.Header("Deprecation", "true")
+		cGin.JSON(http.StatusOK, batches)
+		return
 	}
-	batches, err := c.batchSvc.Paginate(cGin, limit, limit*page, asc, s)
+
+	page, err := c.batchSvc.Browse(cGin, limit, sort, cGin.Query("cursor"), f)
 	if err != nil {
-		httpErr := rest.NewError("paginate_error", err)
-		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		switch err {
+		case batch.ErrInvalidCursor:
+			httpErr := rest.NewParameterError("cursor", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		case batch.ErrInvalidLimit:
+			httpErr := rest.NewParameterError("limit", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		default:
+			httpErr := rest.NewError("paginate_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
 		return
 	}
-	cGin.JSON(http.StatusOK, batches)
+	cGin.JSON(http.StatusOK, historyPage{Page: page, Filters: f.Applied()})
 }
 
-func (c *handlerContext) Stress(cGin *gin.Context) {
-	rand.Seed(time.Now().UnixNano())
-	min := 1
-	max := 1000
-
-	var wg sync.WaitGroup
-	for i := 0; i < 10; i++ {
-		wg.Add(1)
-		go func(index int) {
-			defer wg.Done()
-			for j := 0; j < 100; j++ {
-				cents := rand.Intn(max-min+1) + min
-				msg := transaction.Transaction{
-					ID:       uuid.NewString(),
-					UserID:   fmt.Sprintf("user:%d", index),
-					Amount:   fmt.Sprintf("%.2f", float64(cents)/float64(100)),
-					Currency: "USD",
-				}
-				if err := c.transactionPublisher.Publish(cGin, msg, transaction.MessageTypeTransaction); err != nil {
-					c.logger.Error(err)
-				}
-			}
-		}(i)
+func (c *handlerContext) abortWithParameterError(cGin *gin.Context, err error) {
+	var paramErr *batch.ParameterError
+	if errors.As(err, &paramErr) {
+		httpErr := rest.NewParameterError(paramErr.Parameter, paramErr.Err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
 	}
-	wg.Wait()
+	httpErr := rest.NewError("invalid_parameters", err)
+	cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+}
 
-	cGin.JSON(http.StatusNoContent, gin.H{})
+func (c *handlerContext) GetBatch(cGin *gin.Context) {
+	limit, err := strconv.Atoi(cGin.DefaultQuery("limit", "10"))
+	if err != nil {
+		httpErr := rest.NewError("invalid_parameter__limit", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	page, err := strconv.Atoi(cGin.DefaultQuery("page", "1"))
+	if err == nil && page < 1 {
+		err = ErrInvalidPage
+	}
+	if err != nil {
+		httpErr := rest.NewError("invalid_parameter__page", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+
+	b, err := c.batchSvc.Get(cGin, cGin.Param("id"))
+	if err != nil {
+		switch err {
+		case batch.ErrInvalidBatchID:
+			httpErr := rest.NewError("invalid_parameter__id", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		case batch.ErrBatchNotFound:
+			httpErr := rest.NewError("batch_not_found", err)
+			cGin.AbortWithStatusJSON(http.StatusNotFound, httpErr)
+		default:
+			httpErr := rest.NewError("get_batch_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	entries, err := c.batchSvc.Entries(cGin, b.ID, limit, limit*(page-1))
+	if err != nil {
+		switch err {
+		case batch.ErrInvalidLimit:
+			httpErr := rest.NewParameterError("limit", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		default:
+			httpErr := rest.NewError("entries_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+
+	details := batchDetails{
+		Batch:        b,
+		Transactions: entryPage{Items: entries, Limit: limit, Page: page},
+	}
+	cGin.JSON(http.StatusOK, details)
+}
+
+func (c *handlerContext) GetTransaction(cGin *gin.Context) {
+	details, err := c.batchSvc.FindTransaction(cGin, cGin.Param("id"))
+	if err != nil {
+		switch err {
+		case batch.ErrTransactionNotFound:
+			httpErr := rest.NewError("transaction_not_found", err)
+			cGin.AbortWithStatusJSON(http.StatusNotFound, httpErr)
+		default:
+			httpErr := rest.NewError("find_transaction_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, details)
 }
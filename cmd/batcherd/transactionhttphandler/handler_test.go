@@ -1,3 +1,432 @@
 package transactionhttphandler
 
-/* NO TESTS IN HERE BECAUSE IT IS JUST AN INDICATOR */
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	mockBatch "github.com/mazxaxz/donut-batcher/internal/batch/mock"
+	mockTransport "github.com/mazxaxz/donut-batcher/internal/platform/transport/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+type mocks struct {
+	batchSvc             *mockBatch.MockService
+	transactionPublisher *mockTransport.MockPublisher
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	batchID := primitive.NewObjectID()
+	amount, _ := primitive.ParseDecimal128("0.9")
+	exported := batch.Batch{
+		ID:               batchID,
+		UserID:           "user:1",
+		Amount:           amount,
+		Currency:         "USD",
+		Status:           batch.StatusUndispatched,
+		TransactionCount: 1,
+		CreatedDate:      time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC),
+		UpdatedDate:      time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC),
+	}
+	valid := `{"id":"1","userId":"user:1","amount":"1.10","currency":"USD"}`
+	t1 := transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"}
+	overLimit := "[" + strings.TrimSuffix(strings.Repeat(valid+",", _bulkLimit+1), ",") + "]"
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		expect      func(m mocks)
+		wantStatus  int
+		wantCode    string
+		check       func(t *testing.T, rec *httptest.ResponseRecorder)
+	}{
+		{
+			name:   "should return not found, transaction was not batched",
+			method: http.MethodGet,
+			path:   "/v1/transactions/1",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().FindTransaction(gomock.Any(), "1").Return(batch.TransactionDetails{}, batch.ErrTransactionNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "transaction_not_found",
+		},
+		{
+			name:   "should return internal server error, transaction lookup failed",
+			method: http.MethodGet,
+			path:   "/v1/transactions/1",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().FindTransaction(gomock.Any(), "1").Return(batch.TransactionDetails{}, errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "find_transaction_error",
+		},
+		{
+			name:   "should return batch the transaction went into",
+			method: http.MethodGet,
+			path:   "/v1/transactions/1",
+			expect: func(m mocks) {
+				details := batch.TransactionDetails{TransactionID: "1", BatchID: batchID, BatchStatus: batch.StatusUndispatched}
+				m.batchSvc.EXPECT().FindTransaction(gomock.Any(), "1").Return(details, nil)
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var details batch.TransactionDetails
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+				assert.Equal(t, batchID, details.BatchID)
+			},
+		},
+		{
+			name:       "should return bad request, entries page is below 1",
+			method:     http.MethodGet,
+			path:       "/v1/transactions/batches/" + batchID.Hex() + "?page=0",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__page",
+		},
+		{
+			name:   "should return bad request, batch id is malformed",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/invalid",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Get(gomock.Any(), "invalid").Return(batch.Batch{}, batch.ErrInvalidBatchID)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__id",
+		},
+		{
+			name:   "should return not found, unknown batch",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/" + batchID.Hex(),
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Get(gomock.Any(), batchID.Hex()).Return(batch.Batch{}, batch.ErrBatchNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "batch_not_found",
+		},
+		{
+			name:   "should return bad request, entries limit is out of bounds",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/" + batchID.Hex() + "?limit=500",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Get(gomock.Any(), batchID.Hex()).Return(exported, nil)
+				m.batchSvc.EXPECT().Entries(gomock.Any(), batchID, 500, 0).Return(nil, batch.ErrInvalidLimit)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__limit",
+		},
+		{
+			name:   "should return batch with the requested page of its entries",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/" + batchID.Hex() + "?limit=5&page=2",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Get(gomock.Any(), batchID.Hex()).Return(exported, nil)
+				m.batchSvc.EXPECT().Entries(gomock.Any(), batchID, 5, 5).Return([]batch.Entry{{BatchID: batchID, TransactionID: "1"}}, nil)
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var details struct {
+					Transactions entryPage `json:"transactions"`
+				}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &details))
+				assert.Equal(t, 2, details.Transactions.Page)
+				assert.Len(t, details.Transactions.Items, 1)
+			},
+		},
+		{
+			name:       "should return bad request, unknown status",
+			method:     http.MethodGet,
+			path:       "/v1/transactions/batches/history?status=sent",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__status",
+		},
+		{
+			name:       "should return bad request, unknown sort field",
+			method:     http.MethodGet,
+			path:       "/v1/transactions/batches/history?sort=userId",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__sort",
+		},
+		{
+			name:   "should return history filtered by the user",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/history?userId=user:1&limit=5",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Browse(gomock.Any(), 5, gomock.Any(), "", batch.Filter{UserID: "user:1"}).Return(batch.Page{Items: []batch.Batch{exported}}, nil)
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var page struct {
+					Filters map[string]string `json:"filters"`
+				}
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+				assert.Equal(t, "user:1", page.Filters["userId"])
+			},
+		},
+		{
+			name:   "should return bad request, malformed cursor",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/history?cursor=invalid",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Browse(gomock.Any(), 10, gomock.Any(), "invalid", gomock.Any()).Return(batch.Page{}, batch.ErrInvalidCursor)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__cursor",
+		},
+		{
+			name:       "should return bad request, deprecated page is negative",
+			method:     http.MethodGet,
+			path:       "/v1/transactions/batches/history?page=-1",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__page",
+		},
+		{
+			name:   "should return bad request, limit of the deprecated page is out of bounds",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/history?page=0&limit=500",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Paginate(gomock.Any(), 500, 0, gomock.Any(), gomock.Any()).Return(nil, batch.ErrInvalidLimit)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__limit",
+		},
+		{
+			name:   "should return deprecated page",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/history?page=2&limit=5",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Paginate(gomock.Any(), 5, 10, gomock.Any(), gomock.Any()).Return([]batch.Batch{exported}, nil)
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "true", rec.Header().Get("Deprecation"))
+			},
+		},
+		{
+			name:       "should return bad request, unknown export format",
+			method:     http.MethodGet,
+			path:       "/v1/transactions/batches/export?format=xml",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__format",
+		},
+		{
+			name:   "should return internal server error, export failed before the first row",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/export",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "export_error",
+		},
+		{
+			name:   "should export batches as csv",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/export?userId=user:1",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Export(gomock.Any(), gomock.Any(), batch.Filter{UserID: "user:1"}, gomock.Any()).DoAndReturn(func(_ context.Context, _ batch.Sort, _ batch.Filter, fn func(b batch.Batch) error) error {
+					return fn(exported)
+				})
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+				lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+				assert.Len(t, lines, 2)
+				assert.True(t, strings.HasPrefix(lines[0], "id,userId,amount,currency"))
+				assert.True(t, strings.HasPrefix(lines[1], batchID.Hex()+",user:1,0.9,USD,undispatched,1,"))
+			},
+		},
+		{
+			name:   "should export only the csv header, no batch matches",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/export",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "attachment; filename=batches.csv", rec.Header().Get("Content-Disposition"))
+				assert.Len(t, strings.Split(strings.TrimSpace(rec.Body.String()), "\n"), 1)
+			},
+		},
+		{
+			name:   "should export batches as ndjson",
+			method: http.MethodGet,
+			path:   "/v1/transactions/batches/export?format=ndjson",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Export(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, _ batch.Sort, _ batch.Filter, fn func(b batch.Batch) error) error {
+					return fn(exported)
+				})
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				assert.Equal(t, "application/x-ndjson", rec.Header().Get("Content-Type"))
+				var b batch.Batch
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &b))
+				assert.Equal(t, batchID, b.ID)
+			},
+		},
+		{
+			name:       "should return bad request, transaction is not json",
+			method:     http.MethodPost,
+			path:       "/v1/transactions",
+			body:       "}invalid{",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+		{
+			name:       "should return bad request, transaction has no user",
+			method:     http.MethodPost,
+			path:       "/v1/transactions",
+			body:       `{"id":"1","amount":"1.10","currency":"USD"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_transaction",
+		},
+		{
+			name:   "should return internal server error, transaction could not be published",
+			method: http.MethodPost,
+			path:   "/v1/transactions",
+			body:   valid,
+			expect: func(m mocks) {
+				m.transactionPublisher.EXPECT().Publish(gomock.Any(), t1, transaction.MessageTypeTransaction).Return(errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "publish_error",
+		},
+		{
+			name:   "should return accepted transaction",
+			method: http.MethodPost,
+			path:   "/v1/transactions",
+			body:   valid,
+			expect: func(m mocks) {
+				m.transactionPublisher.EXPECT().Publish(gomock.Any(), t1, transaction.MessageTypeTransaction).Return(nil)
+			},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "should return bad request, bulk is not an array",
+			method:     http.MethodPost,
+			path:       "/v1/transactions/bulk",
+			body:       valid,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+		{
+			name:       "should return bad request, bulk is empty",
+			method:     http.MethodPost,
+			path:       "/v1/transactions/bulk",
+			body:       "[]",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+		{
+			name:       "should return request entity too large, bulk is over the limit",
+			method:     http.MethodPost,
+			path:       "/v1/transactions/bulk",
+			body:       overLimit,
+			wantStatus: http.StatusRequestEntityTooLarge,
+			wantCode:   "bulk_limit_exceeded",
+		},
+		{
+			name:   "should return result of every item of the bulk",
+			method: http.MethodPost,
+			path:   "/v1/transactions/bulk",
+			body:   "[" + valid + `,{"id":"2","amount":"1.10","currency":"USD"}]`,
+			expect: func(m mocks) {
+				m.transactionPublisher.EXPECT().Publish(gomock.Any(), t1, transaction.MessageTypeTransaction).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var result bulkResult
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+				assert.Equal(t, 1, result.Accepted)
+				assert.Equal(t, 1, result.Rejected)
+				assert.Equal(t, itemResult{Index: 1, ID: "2", Status: itemStatusRejected, Reason: transaction.ErrNoUserID.Error()}, result.Items[1])
+			},
+		},
+		{
+			name:        "should reject malformed ndjson line and accept the others",
+			method:      http.MethodPost,
+			path:        "/v1/transactions/bulk",
+			contentType: "application/x-ndjson; charset=utf-8",
+			body:        valid + "\n\n}invalid{\n",
+			expect: func(m mocks) {
+				m.transactionPublisher.EXPECT().Publish(gomock.Any(), t1, transaction.MessageTypeTransaction).Return(nil)
+			},
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, rec *httptest.ResponseRecorder) {
+				var result bulkResult
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &result))
+				assert.Equal(t, 1, result.Accepted)
+				assert.Equal(t, 1, result.Rejected)
+				/* blank line is skipped, so the malformed one is the second item */
+				assert.Equal(t, 1, result.Items[1].Index)
+				assert.Equal(t, itemStatusRejected, result.Items[1].Status)
+			},
+		},
+		{
+			name:       "should return bad request, ndjson is sent as json",
+			method:     http.MethodPost,
+			path:       "/v1/transactions/bulk",
+			body:       valid + "\n" + valid + "\n",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			m := mocks{
+				batchSvc:             mockBatch.NewMockService(mockCtrl),
+				transactionPublisher: mockTransport.NewMockPublisher(mockCtrl),
+			}
+			router := gin.New()
+			New(m.batchSvc, m.transactionPublisher, logrus.New()).SetupRouter(router.Group("v1"))
+
+			// expected calls
+			if tt.expect != nil {
+				tt.expect(m)
+			}
+
+			// act
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			contentType := tt.contentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			req.Header.Set("Content-Type", contentType)
+			router.ServeHTTP(rec, req)
+
+			// assert
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCode != "" {
+				var httpErr rest.Error
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &httpErr))
+				assert.Equal(t, tt.wantCode, httpErr.Code)
+			}
+			if tt.check != nil {
+				tt.check(t, rec)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package transactionhttphandler
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+const (
+	itemStatusAccepted = "accepted"
+	itemStatusRejected = "rejected"
+
+	_bulkLimit = 1000
+	// a single NDJSON line longer than that is rejected by the scanner
+	_maxLineSize = 64 * 1024
+)
+
+var (
+	ErrNotArray          = errors.New("bulk request has to be a json array")
+	ErrBulkLimitExceeded = fmt.Errorf("bulk request can not contain more than %d transactions", _bulkLimit)
+	ErrEmptyBulk         = errors.New("bulk request does not contain any transaction")
+)
+
+type itemResult struct {
+	// Index of the item in the request, blank NDJSON lines are not counted
+	Index  int    `json:"index"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason,omitempty"`
+}
+
+type bulkResult struct {
+	Accepted int          `json:"accepted"`
+	Rejected int          `json:"rejected"`
+	Items    []itemResult `json:"items"`
+}
+
+func (c *handlerContext) PostTransaction(cGin *gin.Context) {
+	var t transaction.Transaction
+	if err := cGin.ShouldBindJSON(&t); err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	if err := t.Validate(); err != nil {
+		httpErr := rest.NewError("invalid_transaction", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	if err := c.transactionPublisher.Publish(cGin, t, transaction.MessageTypeTransaction); err != nil {
+		httpErr := rest.NewError("publish_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		return
+	}
+	cGin.JSON(http.StatusAccepted, itemResult{ID: t.ID, Status: itemStatusAccepted})
+}
+
+// PostTransactions accepts either JSON array or NDJSON body, depending on the Content-Type.
+// Every item is validated and published on its own, one rejected item does not fail the others.
+func (c *handlerContext) PostTransactions(cGin *gin.Context) {
+	var items []json.RawMessage
+	var err error
+	mediaType, _, _ := mime.ParseMediaType(cGin.ContentType())
+	switch mediaType {
+	case "application/x-ndjson":
+		items, err = readNDJSON(cGin.Request.Body)
+	default:
+		items, err = readJSONArray(cGin.Request.Body)
+	}
+	if err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	if len(items) == 0 {
+		httpErr := rest.NewError("invalid_body", ErrEmptyBulk)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	if len(items) > _bulkLimit {
+		httpErr := rest.NewError("bulk_limit_exceeded", ErrBulkLimitExceeded)
+		cGin.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, httpErr)
+		return
+	}
+
+	result := bulkResult{Items: make([]itemResult, 0, len(items))}
+	for i, raw := range items {
+		item := c.publish(cGin, raw)
+		item.Index = i
+		if item.Status == itemStatusAccepted {
+			result.Accepted++
+		} else {
+			result.Rejected++
+		}
+		result.Items = append(result.Items, item)
+	}
+	cGin.JSON(http.StatusOK, result)
+}
+
+func (c *handlerContext) publish(cGin *gin.Context, raw json.RawMessage) itemResult {
+	var t transaction.Transaction
+	if err := json.Unmarshal(raw, &t); err != nil {
+		return itemResult{Status: itemStatusRejected, Reason: err.Error()}
+	}
+	if err := t.Validate(); err != nil {
+		return itemResult{ID: t.ID, Status: itemStatusRejected, Reason: err.Error()}
+	}
+	if err := c.transactionPublisher.Publish(cGin, t, transaction.MessageTypeTransaction); err != nil {
+		c.logger.Error(err)
+		return itemResult{ID: t.ID, Status: itemStatusRejected, Reason: err.Error()}
+	}
+	return itemResult{ID: t.ID, Status: itemStatusAccepted}
+}
+
+// readNDJSON splits the body into lines, blank lines are skipped so they do not count as items
+func readNDJSON(r io.Reader) ([]json.RawMessage, error) {
+	items := make([]json.RawMessage, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), _maxLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		item := make(json.RawMessage, len(line))
+		copy(item, line)
+		items = append(items, item)
+		if len(items) > _bulkLimit {
+			break
+		}
+	}
+	return items, scanner.Err()
+}
+
+// readJSONArray decodes the items of the array one by one, it stops reading once there is more of them than the limit
+func readJSONArray(r io.Reader) ([]json.RawMessage, error) {
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return nil, ErrNotArray
+	}
+
+	items := make([]json.RawMessage, 0)
+	for decoder.More() {
+		var item json.RawMessage
+		if err := decoder.Decode(&item); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if len(items) > _bulkLimit {
+			return items, nil
+		}
+	}
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
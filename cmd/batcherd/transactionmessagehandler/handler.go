@@ -7,10 +7,9 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 
 	"github.com/mazxaxz/donut-batcher/internal/batch"
-	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
 	"github.com/mazxaxz/donut-batcher/pkg/message/dispatch"
 	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
 	"github.com/mazxaxz/donut-batcher/pkg/money"
@@ -18,11 +17,11 @@ import (
 
 type handlerContext struct {
 	batchSvc          batch.Service
-	dispatchPublisher rabbitmq.Publisher
+	dispatchPublisher transport.Publisher
 	logger            *logrus.Logger
 }
 
-func New(bSvc batch.Service, dispatchPublisher rabbitmq.Publisher, l *logrus.Logger) *handlerContext {
+func New(bSvc batch.Service, dispatchPublisher transport.Publisher, l *logrus.Logger) *handlerContext {
 	c := handlerContext{
 		batchSvc:          bSvc,
 		dispatchPublisher: dispatchPublisher,
@@ -31,7 +30,7 @@ func New(bSvc batch.Service, dispatchPublisher rabbitmq.Publisher, l *logrus.Log
 	return &c
 }
 
-func (c *handlerContext) Handle(ctx context.Context, delivery amqp.Delivery) (bool, error) {
+func (c *handlerContext) Handle(ctx context.Context, delivery transport.Message) (bool, error) {
 	switch delivery.Type {
 	case transaction.MessageTypeTransaction:
 		var msg transaction.Transaction
@@ -46,6 +45,9 @@ func (c *handlerContext) Handle(ctx context.Context, delivery amqp.Delivery) (bo
 				return true, err
 			case batch.ErrNoUserID, batch.ErrNoTransactionID:
 				return true, err
+			case batch.ErrTransactionBatched:
+				c.logger.Infof("transaction %s was batched already, redelivery is skipped", msg.ID)
+				return true, nil
 			default:
 				return false, err
 			}
@@ -60,6 +62,6 @@ func (c *handlerContext) Handle(ctx context.Context, delivery amqp.Delivery) (bo
 		return true, nil
 	default:
 		c.logger.Warnf("unknown type: '%s'", delivery.Type)
-		return true, rabbitmq.ErrUnknownMessageType
+		return true, transport.ErrUnknownMessageType
 	}
 }
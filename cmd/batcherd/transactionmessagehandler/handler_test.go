@@ -8,14 +8,13 @@ import (
 	"github.com/golang/mock/gomock"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
-	"github.com/streadway/amqp"
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/mazxaxz/donut-batcher/internal/batch"
 	mockBatch "github.com/mazxaxz/donut-batcher/internal/batch/mock"
-	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq"
-	mockRabbitmq "github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/mock"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	mockTransport "github.com/mazxaxz/donut-batcher/internal/platform/transport/mock"
 	"github.com/mazxaxz/donut-batcher/pkg/message/dispatch"
 	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
 	"github.com/mazxaxz/donut-batcher/pkg/money"
@@ -24,11 +23,11 @@ import (
 func TestHandle(t *testing.T) {
 	t.Run("should return error, invalid message type", func(t *testing.T) {
 		// arrange
-		d := amqp.Delivery{Type: "invalid", Body: nil}
+		d := transport.Message{Type: "invalid", Body: nil}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
-		mockPublisher := mockRabbitmq.NewMockPublisher(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
 		handler := New(mockBatchSvc, mockPublisher, logrus.New())
 
 		// expected calls
@@ -38,16 +37,16 @@ func TestHandle(t *testing.T) {
 
 		// assert
 		assert.True(t, ack)
-		assert.Error(t, err, rabbitmq.ErrUnknownMessageType)
+		assert.Error(t, err, transport.ErrUnknownMessageType)
 	})
 
 	t.Run("should return error, invalid payload", func(t *testing.T) {
 		// arrange
-		d := amqp.Delivery{Type: transaction.MessageTypeTransaction, Body: []byte("}invalid{{{")}
+		d := transport.Message{Type: transaction.MessageTypeTransaction, Body: []byte("}invalid{{{")}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
-		mockPublisher := mockRabbitmq.NewMockPublisher(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
 		handler := New(mockBatchSvc, mockPublisher, logrus.New())
 
 		// expected calls
@@ -69,11 +68,11 @@ func TestHandle(t *testing.T) {
 		}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: transaction.MessageTypeTransaction, Body: body}
+		d := transport.Message{Type: transaction.MessageTypeTransaction, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
-		mockPublisher := mockRabbitmq.NewMockPublisher(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
 		handler := New(mockBatchSvc, mockPublisher, logrus.New())
 
 		// expected calls
@@ -87,6 +86,34 @@ func TestHandle(t *testing.T) {
 		assert.Error(t, err, batch.ErrNoTransactionID)
 	})
 
+	t.Run("should ack redelivered transaction without an error", func(t *testing.T) {
+		// arrange
+		msg := transaction.Transaction{
+			ID:       "1",
+			UserID:   "user:1",
+			Amount:   "1.11",
+			Currency: "USD",
+		}
+		body, err := json.Marshal(msg)
+		assert.NoError(t, err)
+		d := transport.Message{Type: transaction.MessageTypeTransaction, Body: body}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		handler := New(mockBatchSvc, mockPublisher, logrus.New())
+
+		// expected calls
+		mockBatchSvc.EXPECT().Batch(gomock.Any(), msg).Return(batch.BatchResult{}, batch.ErrTransactionBatched)
+
+		// act
+		ack, err := handler.Handle(context.Background(), d)
+
+		// assert
+		assert.True(t, ack)
+		assert.NoError(t, err)
+	})
+
 	t.Run("should return no user id error", func(t *testing.T) {
 		// arrange
 		msg := transaction.Transaction{
@@ -96,11 +123,11 @@ func TestHandle(t *testing.T) {
 		}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: transaction.MessageTypeTransaction, Body: body}
+		d := transport.Message{Type: transaction.MessageTypeTransaction, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
-		mockPublisher := mockRabbitmq.NewMockPublisher(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
 		handler := New(mockBatchSvc, mockPublisher, logrus.New())
 
 		// expected calls
@@ -124,11 +151,11 @@ func TestHandle(t *testing.T) {
 		}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: transaction.MessageTypeTransaction, Body: body}
+		d := transport.Message{Type: transaction.MessageTypeTransaction, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
-		mockPublisher := mockRabbitmq.NewMockPublisher(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
 		handler := New(mockBatchSvc, mockPublisher, logrus.New())
 
 		// expected calls
@@ -152,11 +179,11 @@ func TestHandle(t *testing.T) {
 		}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: transaction.MessageTypeTransaction, Body: body}
+		d := transport.Message{Type: transaction.MessageTypeTransaction, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
-		mockPublisher := mockRabbitmq.NewMockPublisher(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
 		handler := New(mockBatchSvc, mockPublisher, logrus.New())
 
 		// expected calls
@@ -183,11 +210,11 @@ func TestHandle(t *testing.T) {
 		}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: transaction.MessageTypeTransaction, Body: body}
+		d := transport.Message{Type: transaction.MessageTypeTransaction, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
-		mockPublisher := mockRabbitmq.NewMockPublisher(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
 		handler := New(mockBatchSvc, mockPublisher, logrus.New())
 
 		// expected calls
@@ -216,11 +243,11 @@ func TestHandle(t *testing.T) {
 		}
 		body, err := json.Marshal(msg)
 		assert.NoError(t, err)
-		d := amqp.Delivery{Type: transaction.MessageTypeTransaction, Body: body}
+		d := transport.Message{Type: transaction.MessageTypeTransaction, Body: body}
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockBatchSvc := mockBatch.NewMockService(mockCtrl)
-		mockPublisher := mockRabbitmq.NewMockPublisher(mockCtrl)
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
 		handler := New(mockBatchSvc, mockPublisher, logrus.New())
 
 		// expected calls
@@ -0,0 +1,411 @@
+package userhttphandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/goal"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/message/dispatch"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+// accountRequest is the destination account, the user and the currency are taken from the path
+type accountRequest struct {
+	HolderName    string `json:"holderName"`
+	IBAN          string `json:"iban"`
+	BIC           string `json:"bic"`
+	RoutingNumber string `json:"routingNumber"`
+	AccountNumber string `json:"accountNumber"`
+	Savings       bool   `json:"savings"`
+}
+
+// allocationsRequest replaces the allocations of the user, an empty list sends the batches as single transfers again
+type allocationsRequest struct {
+	Rules []account.Allocation `json:"rules"`
+}
+
+// goalRequest is the savings goal, the user and the id are taken from the path
+type goalRequest struct {
+	Name      string      `json:"name"`
+	Currency  string      `json:"currency"`
+	Target    string      `json:"target"`
+	Threshold string      `json:"threshold"`
+	Default   bool        `json:"default"`
+	Priority  int         `json:"priority"`
+	Rules     []goal.Rule `json:"rules"`
+}
+
+func (r goalRequest) goal(userID string) goal.Goal {
+	return goal.Goal{
+		UserID:    userID,
+		Name:      r.Name,
+		Currency:  money.Currency(r.Currency),
+		Target:    r.Target,
+		Threshold: r.Threshold,
+		Default:   r.Default,
+		Priority:  r.Priority,
+		Rules:     r.Rules,
+	}
+}
+
+type handlerContext struct {
+	batchSvc          batch.Service
+	accountSvc        account.Service
+	goalSvc           goal.Service
+	dispatchPublisher transport.Publisher
+	logger            *logrus.Logger
+}
+
+func New(bSvc batch.Service, aSvc account.Service, gSvc goal.Service, dispatchPublisher transport.Publisher, l *logrus.Logger) rest.SetupRouterer {
+	c := handlerContext{
+		batchSvc:          bSvc,
+		accountSvc:        aSvc,
+		goalSvc:           gSvc,
+		dispatchPublisher: dispatchPublisher,
+		logger:            l,
+	}
+	return &c
+}
+
+func (c *handlerContext) SetupRouter(r *gin.RouterGroup) {
+	r.GET("/users/:userId/batches", c.GetBatches)
+	r.GET("/users/:userId/summary", c.GetSummary)
+	r.GET("/users/:userId/accounts", c.GetAccounts)
+	r.PUT("/users/:userId/accounts/:currency", c.PutAccount)
+	r.GET("/users/:userId/allocations", c.GetAllocations)
+	r.PUT("/users/:userId/allocations", c.PutAllocations)
+	r.GET("/users/:userId/goals", c.GetGoals)
+	r.POST("/users/:userId/goals", c.PostGoal)
+	r.GET("/users/:userId/goals/:goalId", c.GetGoal)
+	r.PUT("/users/:userId/goals/:goalId", c.PutGoal)
+	r.DELETE("/users/:userId/goals/:goalId", c.DeleteGoal)
+}
+
+func (c *handlerContext) GetBatches(cGin *gin.Context) {
+	limit, err := strconv.Atoi(cGin.DefaultQuery("limit", "10"))
+	if err != nil {
+		httpErr := rest.NewError("invalid_parameter__limit", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	f, err := batch.FilterFrom(cGin.Request.URL.Query())
+	if err != nil {
+		c.abortWithParameterError(cGin, err)
+		return
+	}
+	f.UserID = cGin.Param("userId")
+	sort, err := batch.SortFrom(cGin.Query("sort"), cGin.Query("order"))
+	if err != nil {
+		c.abortWithParameterError(cGin, err)
+		return
+	}
+
+	page, err := c.batchSvc.Browse(cGin, limit, sort, cGin.Query("cursor"), f)
+	if err != nil {
+		switch err {
+		case batch.ErrInvalidCursor:
+			httpErr := rest.NewParameterError("cursor", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		case batch.ErrInvalidLimit:
+			httpErr := rest.NewParameterError("limit", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		default:
+			httpErr := rest.NewError("paginate_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, page)
+}
+
+func (c *handlerContext) GetSummary(cGin *gin.Context) {
+	summaries, err := c.batchSvc.Summary(cGin, cGin.Param("userId"))
+	if err != nil {
+		httpErr := rest.NewError("summary_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		return
+	}
+	cGin.JSON(http.StatusOK, summaries)
+}
+
+func (c *handlerContext) GetAccounts(cGin *gin.Context) {
+	accounts, err := c.accountSvc.List(cGin, cGin.Param("userId"))
+	if err != nil {
+		httpErr := rest.NewError("accounts_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		return
+	}
+	cGin.JSON(http.StatusOK, accounts)
+}
+
+// PutAccount registers the account the batches in the currency are dispatched to, it is used once verified
+func (c *handlerContext) PutAccount(cGin *gin.Context) {
+	var req accountRequest
+	if err := cGin.ShouldBindJSON(&req); err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	a := account.Account{
+		UserID:        cGin.Param("userId"),
+		Currency:      money.Currency(cGin.Param("currency")),
+		HolderName:    req.HolderName,
+		IBAN:          req.IBAN,
+		BIC:           req.BIC,
+		RoutingNumber: req.RoutingNumber,
+		AccountNumber: req.AccountNumber,
+		Savings:       req.Savings,
+	}
+
+	stored, err := c.accountSvc.Put(cGin, a)
+	if err != nil {
+		switch {
+		case errors.Is(err, money.ErrInvalidCurrencyCode):
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewParameterError("currency", err))
+		case account.IsInvalid(err):
+			httpErr := rest.NewError("invalid_account", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		default:
+			httpErr := rest.NewError("account_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, stored)
+}
+
+func (c *handlerContext) GetAllocations(cGin *gin.Context) {
+	a, err := c.accountSvc.Allocations(cGin, cGin.Param("userId"))
+	if err != nil {
+		switch err {
+		case account.ErrAllocationsNotFound:
+			httpErr := rest.NewError("allocations_not_found", err)
+			cGin.AbortWithStatusJSON(http.StatusNotFound, httpErr)
+		default:
+			httpErr := rest.NewError("allocations_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, a)
+}
+
+// PutAllocations sets the percentages the next dispatched batches are split into portfolios by
+func (c *handlerContext) PutAllocations(cGin *gin.Context) {
+	var req allocationsRequest
+	if err := cGin.ShouldBindJSON(&req); err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+
+	a, err := c.accountSvc.PutAllocations(cGin, cGin.Param("userId"), req.Rules)
+	if err != nil {
+		switch {
+		case account.IsInvalid(err):
+			httpErr := rest.NewError("invalid_allocations", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		default:
+			httpErr := rest.NewError("allocations_error", err)
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		}
+		return
+	}
+	cGin.JSON(http.StatusOK, a)
+}
+
+// GetGoals lists the goals of the user with their progress, in the order the rules are checked in
+func (c *handlerContext) GetGoals(cGin *gin.Context) {
+	goals, err := c.goalSvc.List(cGin, cGin.Param("userId"))
+	if err != nil {
+		httpErr := rest.NewError("goals_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		return
+	}
+	progress, err := c.progress(cGin, cGin.Param("userId"), goals...)
+	if err != nil {
+		httpErr := rest.NewError("goals_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		return
+	}
+	cGin.JSON(http.StatusOK, progress)
+}
+
+func (c *handlerContext) GetGoal(cGin *gin.Context) {
+	g, err := c.goalSvc.Get(cGin, cGin.Param("userId"), cGin.Param("goalId"))
+	if err != nil {
+		c.abortWithGoalError(cGin, err)
+		return
+	}
+	progress, err := c.progress(cGin, g.UserID, g)
+	if err != nil {
+		httpErr := rest.NewError("goals_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		return
+	}
+	cGin.JSON(http.StatusOK, progress[0])
+}
+
+// PostGoal creates the goal, the next matching round-ups of the user are batched for it
+func (c *handlerContext) PostGoal(cGin *gin.Context) {
+	var req goalRequest
+	if err := cGin.ShouldBindJSON(&req); err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+
+	g, err := c.goalSvc.Create(cGin, req.goal(cGin.Param("userId")))
+	if err != nil {
+		c.abortWithGoalError(cGin, err)
+		return
+	}
+	cGin.JSON(http.StatusCreated, g)
+}
+
+func (c *handlerContext) PutGoal(cGin *gin.Context) {
+	var req goalRequest
+	if err := cGin.ShouldBindJSON(&req); err != nil {
+		httpErr := rest.NewError("invalid_body", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	id, err := primitive.ObjectIDFromHex(cGin.Param("goalId"))
+	if err != nil {
+		c.abortWithGoalError(cGin, goal.ErrInvalidGoalID)
+		return
+	}
+	g := req.goal(cGin.Param("userId"))
+	g.ID = id
+	if err := c.checkCurrency(cGin, g); err != nil {
+		c.abortWithGoalError(cGin, err)
+		return
+	}
+
+	g, err = c.goalSvc.Update(cGin, g)
+	if err != nil {
+		c.abortWithGoalError(cGin, err)
+		return
+	}
+	cGin.JSON(http.StatusOK, g)
+}
+
+// checkCurrency keeps the currency of a goal with batches, their round-ups could not count towards a target in another one
+func (c *handlerContext) checkCurrency(ctx context.Context, g goal.Goal) error {
+	existing, err := c.goalSvc.Get(ctx, g.UserID, g.ID.Hex())
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(existing.Currency.String(), g.Currency.String()) {
+		return nil
+	}
+	f := batch.Filter{UserID: g.UserID, Goal: g.ID.Hex()}
+	page, err := c.batchSvc.Browse(ctx, 1, batch.Sort{Field: "createdDate"}, "", f)
+	if err != nil {
+		return err
+	}
+	if len(page.Items) > 0 {
+		return goal.ErrCurrencyLocked
+	}
+	return nil
+}
+
+// DeleteGoal takes the goal away and dispatches the round-ups it has collected so far, nothing would fill its batch up anymore
+func (c *handlerContext) DeleteGoal(cGin *gin.Context) {
+	userID, goalID := cGin.Param("userId"), cGin.Param("goalId")
+	if _, err := c.goalSvc.Get(cGin, userID, goalID); err != nil {
+		c.abortWithGoalError(cGin, err)
+		return
+	}
+
+	/* the goal is deleted last, so a failed release or dispatch can be retried with the same request */
+	released, err := c.batchSvc.ReleaseGoal(cGin, userID, goalID)
+	if err != nil {
+		httpErr := rest.NewError("release_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+		return
+	}
+	for _, b := range released {
+		payload := dispatch.Dispatch{BatchID: b.ID.Hex()}
+		if err := c.dispatchPublisher.Publish(cGin, payload, dispatch.MessageTypeDispatch); err != nil {
+			/* the batch is ready already, it is sent with batcherctl dispatch -all-ready */
+			httpErr := rest.NewError("publish_error", fmt.Errorf("could not send dispatch event, BatchID: %s: %w", b.ID.Hex(), err))
+			cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+			return
+		}
+	}
+	if err := c.goalSvc.Delete(cGin, userID, goalID); err != nil {
+		c.abortWithGoalError(cGin, err)
+		return
+	}
+	cGin.Status(http.StatusNoContent)
+}
+
+// progress puts the goals against the round-ups batched for them, a goal without batches has saved nothing yet
+func (c *handlerContext) progress(ctx context.Context, userID string, goals ...goal.Goal) ([]goal.Progress, error) {
+	summaries, err := c.batchSvc.GoalSummary(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	saved := make(map[string]batch.GoalSummary, len(summaries))
+	for _, s := range summaries {
+		saved[s.Goal] = s
+	}
+
+	progress := make([]goal.Progress, 0, len(goals))
+	for _, g := range goals {
+		s, exists := saved[g.ID.Hex()]
+		if !exists {
+			s = batch.GoalSummary{Pending: "0", Invested: "0"}
+		}
+		p, err := goal.NewProgress(g, s.Pending, s.Invested)
+		if err != nil {
+			return nil, err
+		}
+		progress = append(progress, p)
+	}
+	return progress, nil
+}
+
+func (c *handlerContext) abortWithGoalError(cGin *gin.Context, err error) {
+	switch {
+	case errors.Is(err, goal.ErrInvalidGoalID):
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewParameterError("goalId", err))
+	case errors.Is(err, goal.ErrGoalNotFound):
+		httpErr := rest.NewError("goal_not_found", err)
+		cGin.AbortWithStatusJSON(http.StatusNotFound, httpErr)
+	case errors.Is(err, goal.ErrDuplicateName), errors.Is(err, goal.ErrDuplicateDefault), errors.Is(err, goal.ErrTooManyGoals),
+		errors.Is(err, goal.ErrCurrencyLocked):
+		httpErr := rest.NewError("goal_conflict", err)
+		cGin.AbortWithStatusJSON(http.StatusConflict, httpErr)
+	case goal.IsInvalid(err):
+		httpErr := rest.NewError("invalid_goal", err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+	default:
+		httpErr := rest.NewError("goals_error", err)
+		cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+	}
+}
+
+func (c *handlerContext) abortWithParameterError(cGin *gin.Context, err error) {
+	var paramErr *batch.ParameterError
+	if errors.As(err, &paramErr) {
+		httpErr := rest.NewParameterError(paramErr.Parameter, paramErr.Err)
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+		return
+	}
+	httpErr := rest.NewError("invalid_parameters", err)
+	cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+}
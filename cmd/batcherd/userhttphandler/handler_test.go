@@ -0,0 +1,359 @@
+package userhttphandler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	mockAccount "github.com/mazxaxz/donut-batcher/internal/account/mock"
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	mockBatch "github.com/mazxaxz/donut-batcher/internal/batch/mock"
+	"github.com/mazxaxz/donut-batcher/internal/goal"
+	mockGoal "github.com/mazxaxz/donut-batcher/internal/goal/mock"
+	mockTransport "github.com/mazxaxz/donut-batcher/internal/platform/transport/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/dispatch"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+type mocks struct {
+	batchSvc          *mockBatch.MockService
+	accountSvc        *mockAccount.MockService
+	goalSvc           *mockGoal.MockService
+	dispatchPublisher *mockTransport.MockPublisher
+}
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	goalID := primitive.NewObjectID()
+	goalPath := "/v1/users/user:1/goals/" + goalID.Hex()
+	goalBody := `{"name":"Coffee","currency":"USD","target":"100","rules":[{"merchantCategories":["5812"]}]}`
+	released := batch.Batch{ID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		expect     func(m mocks)
+		wantStatus int
+		wantCode   string
+	}{
+		{
+			name:       "should return bad request, limit is not a number",
+			method:     http.MethodGet,
+			path:       "/v1/users/user:1/batches?limit=ten",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__limit",
+		},
+		{
+			name:       "should return bad request, unknown status",
+			method:     http.MethodGet,
+			path:       "/v1/users/user:1/batches?status=sent",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__status",
+		},
+		{
+			name:       "should return bad request, unknown sort field",
+			method:     http.MethodGet,
+			path:       "/v1/users/user:1/batches?sort=userId",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__sort",
+		},
+		{
+			name:   "should return bad request, limit is out of bounds",
+			method: http.MethodGet,
+			path:   "/v1/users/user:1/batches?limit=0",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Browse(gomock.Any(), 0, gomock.Any(), "", gomock.Any()).Return(batch.Page{}, batch.ErrInvalidLimit)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__limit",
+		},
+		{
+			name:   "should return bad request, malformed cursor",
+			method: http.MethodGet,
+			path:   "/v1/users/user:1/batches?cursor=invalid",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Browse(gomock.Any(), 10, gomock.Any(), "invalid", gomock.Any()).Return(batch.Page{}, batch.ErrInvalidCursor)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__cursor",
+		},
+		{
+			name:   "should return internal server error, summary failed",
+			method: http.MethodGet,
+			path:   "/v1/users/user:1/summary",
+			expect: func(m mocks) {
+				m.batchSvc.EXPECT().Summary(gomock.Any(), "user:1").Return(nil, errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "summary_error",
+		},
+		{
+			name:       "should return bad request, account is not json",
+			method:     http.MethodPut,
+			path:       "/v1/users/user:1/accounts/USD",
+			body:       "}invalid{",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+		{
+			name:   "should return bad request, unknown currency of the account",
+			method: http.MethodPut,
+			path:   "/v1/users/user:1/accounts/XXX",
+			body:   `{"holderName":"Jane Doe","iban":"DE89370400440532013000"}`,
+			expect: func(m mocks) {
+				m.accountSvc.EXPECT().Put(gomock.Any(), gomock.Any()).Return(account.Account{}, money.ErrInvalidCurrencyCode)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__currency",
+		},
+		{
+			name:   "should return bad request, invalid account",
+			method: http.MethodPut,
+			path:   "/v1/users/user:1/accounts/EUR",
+			body:   `{"holderName":"Jane Doe","iban":"DE00370400440532013000"}`,
+			expect: func(m mocks) {
+				m.accountSvc.EXPECT().Put(gomock.Any(), gomock.Any()).Return(account.Account{}, account.ErrInvalidIBAN)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_account",
+		},
+		{
+			name:   "should return not found, user has no allocations",
+			method: http.MethodGet,
+			path:   "/v1/users/user:1/allocations",
+			expect: func(m mocks) {
+				m.accountSvc.EXPECT().Allocations(gomock.Any(), "user:1").Return(account.Allocations{}, account.ErrAllocationsNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "allocations_not_found",
+		},
+		{
+			name:       "should return bad request, allocations are not json",
+			method:     http.MethodPut,
+			path:       "/v1/users/user:1/allocations",
+			body:       `{"rules":"all"}`,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+		{
+			name:   "should return bad request, percents do not add up",
+			method: http.MethodPut,
+			path:   "/v1/users/user:1/allocations",
+			body:   `{"rules":[{"portfolio":"index-fund","percent":"70"}]}`,
+			expect: func(m mocks) {
+				m.accountSvc.EXPECT().PutAllocations(gomock.Any(), "user:1", gomock.Any()).Return(account.Allocations{}, account.ErrPercentSum)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_allocations",
+		},
+		{
+			name:   "should return ok, allocations are replaced",
+			method: http.MethodPut,
+			path:   "/v1/users/user:1/allocations",
+			body:   `{"rules":[{"portfolio":"index-fund","percent":"100"}]}`,
+			expect: func(m mocks) {
+				rules := []account.Allocation{{Portfolio: "index-fund", Percent: "100"}}
+				m.accountSvc.EXPECT().PutAllocations(gomock.Any(), "user:1", rules).Return(account.Allocations{UserID: "user:1", Rules: rules}, nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "should return bad request, goal is not json",
+			method:     http.MethodPost,
+			path:       "/v1/users/user:1/goals",
+			body:       "}invalid{",
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_body",
+		},
+		{
+			name:   "should return bad request, invalid goal",
+			method: http.MethodPost,
+			path:   "/v1/users/user:1/goals",
+			body:   `{"currency":"USD","target":"100"}`,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(goal.Goal{}, goal.ErrNoName)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_goal",
+		},
+		{
+			name:   "should return conflict, user has a goal with the name",
+			method: http.MethodPost,
+			path:   "/v1/users/user:1/goals",
+			body:   goalBody,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Create(gomock.Any(), gomock.Any()).Return(goal.Goal{}, goal.ErrDuplicateName)
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   "goal_conflict",
+		},
+		{
+			name:   "should return created goal",
+			method: http.MethodPost,
+			path:   "/v1/users/user:1/goals",
+			body:   goalBody,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Create(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, g goal.Goal) (goal.Goal, error) {
+					assert.Equal(t, "user:1", g.UserID)
+					g.ID = goalID
+					return g, nil
+				})
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:   "should return not found, unknown goal",
+			method: http.MethodGet,
+			path:   goalPath,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Get(gomock.Any(), "user:1", goalID.Hex()).Return(goal.Goal{}, goal.ErrGoalNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "goal_not_found",
+		},
+		{
+			name:       "should return bad request, goal id is malformed",
+			method:     http.MethodPut,
+			path:       "/v1/users/user:1/goals/coffee",
+			body:       goalBody,
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_parameter__goalId",
+		},
+		{
+			name:   "should return conflict, currency of the goal with batches is changed",
+			method: http.MethodPut,
+			path:   goalPath,
+			body:   goalBody,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Get(gomock.Any(), "user:1", goalID.Hex()).Return(goal.Goal{ID: goalID, Currency: "EUR"}, nil)
+				f := batch.Filter{UserID: "user:1", Goal: goalID.Hex()}
+				m.batchSvc.EXPECT().Browse(gomock.Any(), 1, gomock.Any(), "", f).Return(batch.Page{Items: []batch.Batch{released}}, nil)
+			},
+			wantStatus: http.StatusConflict,
+			wantCode:   "goal_conflict",
+		},
+		{
+			name:   "should return updated goal, currency of the goal without batches is changed",
+			method: http.MethodPut,
+			path:   goalPath,
+			body:   goalBody,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Get(gomock.Any(), "user:1", goalID.Hex()).Return(goal.Goal{ID: goalID, Currency: "EUR"}, nil)
+				f := batch.Filter{UserID: "user:1", Goal: goalID.Hex()}
+				m.batchSvc.EXPECT().Browse(gomock.Any(), 1, gomock.Any(), "", f).Return(batch.Page{Items: []batch.Batch{}}, nil)
+				m.goalSvc.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, g goal.Goal) (goal.Goal, error) {
+					return g, nil
+				})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "should return updated goal, currency is kept",
+			method: http.MethodPut,
+			path:   goalPath,
+			body:   goalBody,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Get(gomock.Any(), "user:1", goalID.Hex()).Return(goal.Goal{ID: goalID, Currency: "USD"}, nil)
+				m.goalSvc.EXPECT().Update(gomock.Any(), gomock.Any()).DoAndReturn(func(_ interface{}, g goal.Goal) (goal.Goal, error) {
+					return g, nil
+				})
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:   "should return not found, deleted goal is unknown",
+			method: http.MethodDelete,
+			path:   goalPath,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Get(gomock.Any(), "user:1", goalID.Hex()).Return(goal.Goal{}, goal.ErrGoalNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantCode:   "goal_not_found",
+		},
+		{
+			name:   "should return internal server error and keep the goal, round-ups of the goal could not be released",
+			method: http.MethodDelete,
+			path:   goalPath,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Get(gomock.Any(), "user:1", goalID.Hex()).Return(goal.Goal{ID: goalID}, nil)
+				m.batchSvc.EXPECT().ReleaseGoal(gomock.Any(), "user:1", goalID.Hex()).Return(nil, errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "release_error",
+		},
+		{
+			name:   "should return internal server error and keep the goal, dispatch of the released batch could not be sent",
+			method: http.MethodDelete,
+			path:   goalPath,
+			expect: func(m mocks) {
+				m.goalSvc.EXPECT().Get(gomock.Any(), "user:1", goalID.Hex()).Return(goal.Goal{ID: goalID}, nil)
+				m.batchSvc.EXPECT().ReleaseGoal(gomock.Any(), "user:1", goalID.Hex()).Return([]batch.Batch{released}, nil)
+				m.dispatchPublisher.EXPECT().Publish(gomock.Any(), dispatch.Dispatch{BatchID: released.ID.Hex()}, dispatch.MessageTypeDispatch).Return(errors.New("random error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantCode:   "publish_error",
+		},
+		{
+			name:   "should return no content, dispatch released batches and delete the goal",
+			method: http.MethodDelete,
+			path:   goalPath,
+			expect: func(m mocks) {
+				gomock.InOrder(
+					m.goalSvc.EXPECT().Get(gomock.Any(), "user:1", goalID.Hex()).Return(goal.Goal{ID: goalID}, nil),
+					m.batchSvc.EXPECT().ReleaseGoal(gomock.Any(), "user:1", goalID.Hex()).Return([]batch.Batch{released}, nil),
+					m.dispatchPublisher.EXPECT().Publish(gomock.Any(), dispatch.Dispatch{BatchID: released.ID.Hex()}, dispatch.MessageTypeDispatch).Return(nil),
+					m.goalSvc.EXPECT().Delete(gomock.Any(), "user:1", goalID.Hex()).Return(nil),
+				)
+			},
+			wantStatus: http.StatusNoContent,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			m := mocks{
+				batchSvc:          mockBatch.NewMockService(mockCtrl),
+				accountSvc:        mockAccount.NewMockService(mockCtrl),
+				goalSvc:           mockGoal.NewMockService(mockCtrl),
+				dispatchPublisher: mockTransport.NewMockPublisher(mockCtrl),
+			}
+			router := gin.New()
+			New(m.batchSvc, m.accountSvc, m.goalSvc, m.dispatchPublisher, logrus.New()).SetupRouter(router.Group("v1"))
+
+			// expected calls
+			if tt.expect != nil {
+				tt.expect(m)
+			}
+
+			// act
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(rec, req)
+
+			// assert
+			assert.Equal(t, tt.wantStatus, rec.Code, rec.Body.String())
+			if tt.wantCode != "" {
+				var httpErr rest.Error
+				assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &httpErr))
+				assert.Equal(t, tt.wantCode, httpErr.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/cmd/fakebank/config"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+const (
+	transferStatusAccepted = "accepted"
+)
+
+var (
+	ErrNoIdempotencyKey = errors.New("idempotency key header is required")
+	ErrInvalidAPIKey    = errors.New("api key is missing or invalid")
+	ErrKeyReused        = errors.New("idempotency key was already used with a different transfer")
+	ErrUnavailable      = errors.New("bank is temporarily unavailable")
+	ErrRejected         = errors.New("transfer was rejected")
+	ErrInvalidAmount    = errors.New("amount has to be positive")
+	ErrTransferNotFound = errors.New("transfer not found")
+	ErrNoUserID         = errors.New("no user id was provided")
+)
+
+type transferRecord struct {
+	banksdk.TransferReceipt
+	Transfer       banksdk.Transfer `json:"transfer"`
+	IdempotencyKey string           `json:"idempotencyKey"`
+	Reason         string           `json:"reason,omitempty"`
+	CreatedDate    time.Time        `json:"createdDate"`
+	bodyHash       [sha256.Size]byte
+}
+
+// bankContext keeps the transfers in memory, it behaves according to the configured latency, rates and idempotency
+type bankContext struct {
+	cfg    config.Config
+	clock  clock.Clock
+	client *http.Client
+	// schedule runs the function after the duration, it must not run it synchronously
+	schedule func(d time.Duration, fn func())
+
+	mu          sync.Mutex
+	random      *rand.Rand
+	transfers   []transferRecord
+	byKey       map[string]int
+	byReference map[string]int
+}
+
+func newBank(cfg config.Config, clk clock.Clock, random *rand.Rand) *bankContext {
+	c := bankContext{
+		cfg:         cfg,
+		clock:       clk,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		schedule:    afterFunc,
+		random:      random,
+		byKey:       make(map[string]int),
+		byReference: make(map[string]int),
+	}
+	return &c
+}
+
+func (c *bankContext) SetupRouter(group *gin.RouterGroup) {
+	group.POST("/transfers", c.PostTransfer)
+	group.GET("/transfers", c.GetTransfers)
+	group.GET("/transfers/:reference", c.GetTransfer)
+}
+
+func (c *bankContext) PostTransfer(cGin *gin.Context) {
+	body, err := ioutil.ReadAll(cGin.Request.Body)
+	if err != nil {
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewError("invalid_body", err))
+		return
+	}
+	if err := c.authenticate(cGin.Request, body); err != nil {
+		cGin.AbortWithStatusJSON(http.StatusUnauthorized, rest.NewError("unauthorized", err))
+		return
+	}
+	c.delay(cGin.Request)
+
+	var t banksdk.Transfer
+	if err := json.Unmarshal(body, &t); err != nil {
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewError("invalid_body", err))
+		return
+	}
+	if err := validate(t); err != nil {
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewError("invalid_transfer", err))
+		return
+	}
+	key := cGin.GetHeader(banksdk.HeaderIdempotencyKey)
+	if key == "" {
+		cGin.AbortWithStatusJSON(http.StatusBadRequest, rest.NewError("invalid_transfer", ErrNoIdempotencyKey))
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.chance(c.cfg.FailureRate) {
+		cGin.AbortWithStatusJSON(http.StatusServiceUnavailable, rest.NewError("unavailable", ErrUnavailable))
+		return
+	}
+
+	hash := sha256.Sum256(body)
+	if i, exists := c.byKey[key]; exists && c.cfg.Idempotency == config.IdempotencyHonor {
+		existing := c.transfers[i]
+		if !bytes.Equal(existing.bodyHash[:], hash[:]) {
+			cGin.AbortWithStatusJSON(http.StatusConflict, rest.NewError("idempotency_key_reused", ErrKeyReused))
+			return
+		}
+		cGin.JSON(http.StatusOK, existing.TransferReceipt)
+		return
+	}
+
+	if c.chance(c.cfg.RejectRate) {
+		cGin.AbortWithStatusJSON(http.StatusUnprocessableEntity, rest.NewError("rejected", ErrRejected))
+		return
+	}
+
+	r := transferRecord{
+		TransferReceipt: banksdk.TransferReceipt{Reference: uuid.NewString(), Status: transferStatusAccepted},
+		Transfer:        t,
+		IdempotencyKey:  key,
+		CreatedDate:     c.clock.Now(),
+		bodyHash:        hash,
+	}
+	c.transfers = append(c.transfers, r)
+	c.byKey[key] = len(c.transfers) - 1
+	c.byReference[r.Reference] = len(c.transfers) - 1
+	c.scheduleOutcome(r.Reference)
+
+	/* transfer is booked, but the client never learns about it */
+	if c.chance(c.cfg.LostResponseRate) {
+		cGin.AbortWithStatusJSON(http.StatusServiceUnavailable, rest.NewError("unavailable", ErrUnavailable))
+		return
+	}
+	cGin.JSON(http.StatusCreated, r.TransferReceipt)
+}
+
+func (c *bankContext) GetTransfers(cGin *gin.Context) {
+	c.mu.Lock()
+	transfers := make([]transferRecord, len(c.transfers))
+	copy(transfers, c.transfers)
+	c.mu.Unlock()
+	cGin.JSON(http.StatusOK, transfers)
+}
+
+func (c *bankContext) GetTransfer(cGin *gin.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	i, exists := c.byReference[cGin.Param("reference")]
+	if !exists {
+		cGin.AbortWithStatusJSON(http.StatusNotFound, rest.NewError("not_found", ErrTransferNotFound))
+		return
+	}
+	cGin.JSON(http.StatusOK, c.transfers[i])
+}
+
+func (c *bankContext) authenticate(r *http.Request, body []byte) error {
+	if c.cfg.APIKey != "" && r.Header.Get(banksdk.HeaderAPIKey) != c.cfg.APIKey {
+		return ErrInvalidAPIKey
+	}
+	if c.cfg.Secret == "" {
+		return nil
+	}
+	return banksdk.VerifyRequest(r, c.cfg.Secret, c.clock.Now(), body)
+}
+
+// delay waits for the configured latency, it gives up when the client does
+func (c *bankContext) delay(r *http.Request) {
+	d := c.cfg.Latency
+	if c.cfg.Jitter > 0 {
+		c.mu.Lock()
+		d += time.Duration(c.random.Int63n(int64(c.cfg.Jitter)))
+		c.mu.Unlock()
+	}
+	if d <= 0 {
+		return
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.Context().Done():
+	}
+}
+
+// chance has to be called with the lock held, random is not safe for concurrent use
+func (c *bankContext) chance(rate float64) bool {
+	return rate > 0 && c.random.Float64() < rate
+}
+
+func validate(t banksdk.Transfer) error {
+	if t.UserID == "" {
+		return ErrNoUserID
+	}
+	if _, err := money.CurrencyFrom(t.Currency); err != nil {
+		return err
+	}
+	notPositive, err := money.GreaterThanOrEqual("0", t.Amount)
+	if err != nil {
+		return err
+	}
+	if notPositive {
+		return ErrInvalidAmount
+	}
+	return nil
+}
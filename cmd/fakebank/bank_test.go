@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/cmd/fakebank/config"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+var (
+	testNow      = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	testTransfer = banksdk.Transfer{ID: "batch-1", UserID: "user:1", Amount: "1.4", Currency: "USD"}
+)
+
+func newTestBank(t *testing.T, cfg config.Config, clientCfg banksdk.Config) (*bankContext, banksdk.Clienter, *httptest.Server) {
+	gin.SetMode(gin.TestMode)
+	if cfg.Idempotency == "" {
+		cfg.Idempotency = config.IdempotencyHonor
+	}
+	c := clock.NewFake(testNow)
+	bank := newBank(cfg, c, rand.New(rand.NewSource(1)))
+	srv := httptest.NewServer(setupRouting(bank))
+	t.Cleanup(srv.Close)
+
+	clientCfg.BaseURL = srv.URL
+	client, err := banksdk.NewHTTP(clientCfg, c)
+	assert.NoError(t, err)
+	return bank, client, srv
+}
+
+func TestPostTransfer(t *testing.T) {
+	t.Run("should accept signed transfer and list it", func(t *testing.T) {
+		// arrange
+		cfg := config.Config{APIKey: "key", Secret: "secret"}
+		_, client, srv := newTestBank(t, cfg, banksdk.Config{APIKey: "key", Secret: "secret"})
+
+		// act
+		receipt, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.NoError(t, err)
+		assert.NotEmpty(t, receipt.Reference)
+		resp, err := http.Get(srv.URL + "/v1/transfers/" + receipt.Reference)
+		assert.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		var record transferRecord
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&record))
+		assert.Equal(t, testTransfer, record.Transfer)
+		assert.Equal(t, "batch-1", record.IdempotencyKey)
+		assert.Equal(t, testNow, record.CreatedDate)
+	})
+
+	t.Run("should return permanent error, request is not signed", func(t *testing.T) {
+		// arrange
+		_, client, _ := newTestBank(t, config.Config{Secret: "secret"}, banksdk.Config{})
+
+		// act
+		_, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.True(t, banksdk.IsPermanent(err))
+	})
+
+	t.Run("should return the same reference, transfer was retried", func(t *testing.T) {
+		// arrange
+		bank, client, _ := newTestBank(t, config.Config{}, banksdk.Config{})
+
+		// act
+		first, err := client.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+		second, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+		assert.Len(t, bank.transfers, 1)
+	})
+
+	t.Run("should create another transfer, idempotency is ignored", func(t *testing.T) {
+		// arrange
+		bank, client, _ := newTestBank(t, config.Config{Idempotency: config.IdempotencyIgnore}, banksdk.Config{})
+
+		// act
+		first, err := client.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+		second, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.NoError(t, err)
+		assert.NotEqual(t, first, second)
+		assert.Len(t, bank.transfers, 2)
+	})
+
+	t.Run("should return permanent error, idempotency key reused for another transfer", func(t *testing.T) {
+		// arrange
+		_, client, _ := newTestBank(t, config.Config{}, banksdk.Config{})
+		changed := testTransfer
+		changed.Amount = "2"
+
+		// act
+		_, err := client.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+		_, err = client.Send(context.Background(), changed)
+
+		// assert
+		assert.True(t, banksdk.IsPermanent(err))
+	})
+
+	t.Run("should return retryable error, bank is failing", func(t *testing.T) {
+		// arrange
+		bank, client, _ := newTestBank(t, config.Config{FailureRate: 1}, banksdk.Config{})
+
+		// act
+		_, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.True(t, banksdk.IsRetryable(err))
+		assert.Len(t, bank.transfers, 0)
+	})
+
+	t.Run("should book the transfer once, response was lost", func(t *testing.T) {
+		// arrange
+		bank, client, _ := newTestBank(t, config.Config{LostResponseRate: 1}, banksdk.Config{})
+
+		// act
+		_, lost := client.Send(context.Background(), testTransfer)
+		bank.cfg.LostResponseRate = 0
+		receipt, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.True(t, banksdk.IsRetryable(lost))
+		assert.NoError(t, err)
+		assert.Len(t, bank.transfers, 1)
+		assert.Equal(t, bank.transfers[0].Reference, receipt.Reference)
+	})
+
+	t.Run("should return permanent error, transfer was rejected", func(t *testing.T) {
+		// arrange
+		_, client, _ := newTestBank(t, config.Config{RejectRate: 1}, banksdk.Config{})
+
+		// act
+		_, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.True(t, banksdk.IsPermanent(err))
+	})
+
+	t.Run("should return permanent error, amount is not positive", func(t *testing.T) {
+		// arrange
+		_, client, _ := newTestBank(t, config.Config{}, banksdk.Config{})
+		invalid := testTransfer
+		invalid.Amount = "0"
+
+		// act
+		_, err := client.Send(context.Background(), invalid)
+
+		// assert
+		assert.True(t, banksdk.IsPermanent(err))
+	})
+
+	t.Run("should return retryable error, bank is slower than the client", func(t *testing.T) {
+		// arrange
+		_, client, _ := newTestBank(t, config.Config{Latency: 2 * time.Second}, banksdk.Config{Timeout: 1})
+
+		// act
+		_, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.True(t, banksdk.IsRetryable(err))
+	})
+}
+
+// scheduled collects the functions the bank schedules, so the test decides when they run
+type scheduled struct {
+	delays []time.Duration
+	fns    []func()
+}
+
+func (s *scheduled) schedule(d time.Duration, fn func()) {
+	s.delays = append(s.delays, d)
+	s.fns = append(s.fns, fn)
+}
+
+// runAll runs the scheduled functions including the ones scheduled while running
+func (s *scheduled) runAll() {
+	for i := 0; i < len(s.fns); i++ {
+		s.fns[i]()
+	}
+}
+
+func TestTransferOutcome(t *testing.T) {
+	newWebhook := func(t *testing.T, statuses ...int) (*httptest.Server, *[]banksdk.TransferEvent) {
+		events := make([]banksdk.TransferEvent, 0)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if err := banksdk.VerifyRequest(r, "webhook", testNow, body); err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var event banksdk.TransferEvent
+			_ = json.Unmarshal(body, &event)
+			events = append(events, event)
+			status := http.StatusOK
+			if len(statuses) >= len(events) {
+				status = statuses[len(events)-1]
+			}
+			w.WriteHeader(status)
+		}))
+		t.Cleanup(srv.Close)
+		return srv, &events
+	}
+
+	t.Run("should settle booked transfer and post signed event", func(t *testing.T) {
+		// arrange
+		webhook, events := newWebhook(t)
+		cfg := config.Config{WebhookURL: webhook.URL, WebhookSecret: "webhook", SettleDelay: time.Minute}
+		bank, client, _ := newTestBank(t, cfg, banksdk.Config{})
+		s := scheduled{}
+		bank.schedule = s.schedule
+
+		// act
+		receipt, err := client.Send(context.Background(), testTransfer)
+		s.runAll()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []time.Duration{time.Minute}, s.delays)
+		assert.Equal(t, []banksdk.TransferEvent{{
+			TransferID: "batch-1",
+			Reference:  receipt.Reference,
+			Status:     banksdk.TransferStatusSettled,
+			Date:       testNow,
+		}}, *events)
+		assert.Equal(t, banksdk.TransferStatusSettled, bank.transfers[0].Status)
+	})
+
+	t.Run("should return booked transfer with reason", func(t *testing.T) {
+		// arrange
+		webhook, events := newWebhook(t)
+		cfg := config.Config{WebhookURL: webhook.URL, WebhookSecret: "webhook", ReturnRate: 1}
+		bank, client, _ := newTestBank(t, cfg, banksdk.Config{})
+		s := scheduled{}
+		bank.schedule = s.schedule
+
+		// act
+		_, err := client.Send(context.Background(), testTransfer)
+		s.runAll()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, *events, 1)
+		assert.Equal(t, banksdk.TransferStatusReturned, (*events)[0].Status)
+		assert.Equal(t, _returnReason, (*events)[0].Reason)
+	})
+
+	t.Run("should retry event, webhook did not accept it", func(t *testing.T) {
+		// arrange
+		webhook, events := newWebhook(t, http.StatusConflict, http.StatusInternalServerError)
+		cfg := config.Config{WebhookURL: webhook.URL, WebhookSecret: "webhook"}
+		bank, client, _ := newTestBank(t, cfg, banksdk.Config{})
+		s := scheduled{}
+		bank.schedule = s.schedule
+
+		// act
+		_, err := client.Send(context.Background(), testTransfer)
+		s.runAll()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, *events, 3)
+		assert.Equal(t, []time.Duration{0, _webhookBackoff, 2 * _webhookBackoff}, s.delays)
+	})
+
+	t.Run("should not schedule outcome, no webhook configured", func(t *testing.T) {
+		// arrange
+		bank, client, _ := newTestBank(t, config.Config{}, banksdk.Config{})
+		s := scheduled{}
+		bank.schedule = s.schedule
+
+		// act
+		_, err := client.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Empty(t, s.fns)
+		assert.Equal(t, transferStatusAccepted, bank.transfers[0].Status)
+	})
+}
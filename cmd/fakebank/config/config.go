@@ -0,0 +1,72 @@
+package config
+
+import (
+	"time"
+
+	"github.com/Netflix/go-env"
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/pkg/logger"
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+const (
+	// IdempotencyHonor answers a retried transfer with the transfer created the first time
+	IdempotencyHonor = "honor"
+	// IdempotencyIgnore creates a new transfer on every retry, it reproduces double payouts
+	IdempotencyIgnore = "ignore"
+)
+
+var (
+	ErrInvalidRate        = errors.New("rates have to be between 0 and 1")
+	ErrInvalidIdempotency = errors.New("idempotency has to be either honor or ignore")
+)
+
+type Config struct {
+	HTTP   rest.Config `env:"HTTP,required=true"`
+	APIKey string      `env:"BANK_API_KEY"`
+	// Secret verifies request signatures, requests are not verified without it
+	Secret  string        `env:"BANK_SECRET"`
+	Latency time.Duration `env:"LATENCY,default=0s"`
+	// Jitter is a random delay added on top of the latency
+	Jitter time.Duration `env:"JITTER,default=0s"`
+	// FailureRate of transfers answered with 503 before they are recorded
+	FailureRate float64 `env:"FAILURE_RATE,default=0"`
+	// LostResponseRate of transfers which are recorded but answered with 503, so the client has to retry them
+	LostResponseRate float64 `env:"LOST_RESPONSE_RATE,default=0"`
+	// RejectRate of transfers refused with 422
+	RejectRate  float64 `env:"REJECT_RATE,default=0"`
+	Idempotency string  `env:"IDEMPOTENCY,default=honor"`
+	// WebhookURL receives the outcome of every booked transfer, outcomes are not sent without it
+	WebhookURL    string `env:"WEBHOOK_URL"`
+	WebhookSecret string `env:"WEBHOOK_SECRET"`
+	// SettleDelay is how long a booked transfer waits for its outcome
+	SettleDelay time.Duration `env:"SETTLE_DELAY,default=5s"`
+	// ReturnRate of booked transfers which are returned instead of settled
+	ReturnRate float64       `env:"RETURN_RATE,default=0"`
+	Seed       int64         `env:"SEED"`
+	Logger     logger.Config `env:"LOGGER"`
+}
+
+func Load() (Config, error) {
+	var cfg Config
+	_, err := env.UnmarshalFromEnviron(&cfg)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.Validate()
+}
+
+func (c Config) Validate() error {
+	for _, rate := range []float64{c.FailureRate, c.LostResponseRate, c.RejectRate, c.ReturnRate} {
+		if rate < 0 || rate > 1 {
+			return ErrInvalidRate
+		}
+	}
+	switch c.Idempotency {
+	case IdempotencyHonor, IdempotencyIgnore:
+		return nil
+	default:
+		return ErrInvalidIdempotency
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("should assign all values", func(t *testing.T) {
+		// arrange
+		os.Clearenv()
+		os.Setenv("HTTP", "{\"port\":8086}")
+		os.Setenv("BANK_API_KEY", "key")
+		os.Setenv("BANK_SECRET", "secret")
+		os.Setenv("LATENCY", "50ms")
+		os.Setenv("JITTER", "10ms")
+		os.Setenv("FAILURE_RATE", "0.1")
+		os.Setenv("LOST_RESPONSE_RATE", "0.05")
+		os.Setenv("REJECT_RATE", "0.01")
+		os.Setenv("IDEMPOTENCY", "ignore")
+		os.Setenv("WEBHOOK_URL", "http://batcherd:8085/v1/bank/transfers/events")
+		os.Setenv("WEBHOOK_SECRET", "webhook")
+		os.Setenv("SETTLE_DELAY", "1m")
+		os.Setenv("RETURN_RATE", "0.02")
+		os.Setenv("SEED", "42")
+
+		// act
+		result, err := Load()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 8086, result.HTTP.Port)
+		assert.Equal(t, "key", result.APIKey)
+		assert.Equal(t, "secret", result.Secret)
+		assert.Equal(t, 50*time.Millisecond, result.Latency)
+		assert.Equal(t, 10*time.Millisecond, result.Jitter)
+		assert.Equal(t, 0.1, result.FailureRate)
+		assert.Equal(t, 0.05, result.LostResponseRate)
+		assert.Equal(t, 0.01, result.RejectRate)
+		assert.Equal(t, IdempotencyIgnore, result.Idempotency)
+		assert.Equal(t, "http://batcherd:8085/v1/bank/transfers/events", result.WebhookURL)
+		assert.Equal(t, "webhook", result.WebhookSecret)
+		assert.Equal(t, time.Minute, result.SettleDelay)
+		assert.Equal(t, 0.02, result.ReturnRate)
+		assert.Equal(t, int64(42), result.Seed)
+	})
+
+	t.Run("should assign default not required values", func(t *testing.T) {
+		// arrange
+		os.Clearenv()
+		os.Setenv("HTTP", "{\"port\":8086}")
+
+		// act
+		result, err := Load()
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), result.Latency)
+		assert.Equal(t, 0.0, result.FailureRate)
+		assert.Equal(t, IdempotencyHonor, result.Idempotency)
+		assert.Equal(t, 5*time.Second, result.SettleDelay)
+	})
+
+	t.Run("should return error, rate is out of range", func(t *testing.T) {
+		// arrange
+		os.Clearenv()
+		os.Setenv("HTTP", "{\"port\":8086}")
+		os.Setenv("REJECT_RATE", "1.5")
+
+		// act
+		_, err := Load()
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidRate))
+	})
+
+	t.Run("should return error, unknown idempotency behaviour", func(t *testing.T) {
+		// arrange
+		os.Clearenv()
+		os.Setenv("HTTP", "{\"port\":8086}")
+		os.Setenv("IDEMPOTENCY", "sometimes")
+
+		// act
+		_, err := Load()
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidIdempotency))
+	})
+}
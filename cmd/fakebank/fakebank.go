@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/cmd/fakebank/config"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/logger"
+	"github.com/mazxaxz/donut-batcher/pkg/shutdown"
+)
+
+var log = logrus.New()
+
+// fakebank stands in for the bank API during local development, see banksdk.NewHTTP for the client
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := logger.Configure(log, cfg.Logger); err != nil {
+		log.Fatal(err)
+	}
+
+	_, cancel := context.WithCancel(context.Background())
+
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	bank := newBank(cfg, clock.New(), rand.New(rand.NewSource(seed)))
+
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%d", cfg.HTTP.Port),
+		Handler:      setupRouting(bank),
+		ReadTimeout:  30 * time.Second,
+		WriteTimeout: cfg.HTTP.WriteTimeoutOrDefault(30 * time.Second),
+		IdleTimeout:  5 * time.Second,
+	}
+	go func() {
+		log.Info(fmt.Sprintf("Starting fake bank on port: %s", srv.Addr))
+		if err := srv.ListenAndServe(); err != nil {
+			log.Info("Closing server...")
+		}
+	}()
+
+	shutdown.Wait(cancel, log)
+	_ = srv.Close()
+}
+
+func setupRouting(bank *bankContext) http.Handler {
+	router := gin.New()
+	router.Use(gin.Logger())
+	router.Use(gin.Recovery())
+
+	bank.SetupRouter(router.Group("v1"))
+	return router
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+)
+
+const (
+	// _webhookAttempts before the outcome is given up on, the webhook answers 409 while the dispatch is still being saved
+	_webhookAttempts = 5
+	_webhookBackoff  = time.Second
+	_returnReason    = "account_closed"
+)
+
+// scheduleOutcome settles or returns the booked transfer once the settle delay passes
+func (c *bankContext) scheduleOutcome(reference string) {
+	if c.cfg.WebhookURL == "" {
+		return
+	}
+	c.schedule(c.cfg.SettleDelay, func() { c.settle(reference) })
+}
+
+func (c *bankContext) settle(reference string) {
+	c.mu.Lock()
+	r := &c.transfers[c.byReference[reference]]
+	r.Status = banksdk.TransferStatusSettled
+	if c.chance(c.cfg.ReturnRate) {
+		r.Status = banksdk.TransferStatusReturned
+		r.Reason = _returnReason
+	}
+	event := banksdk.TransferEvent{
+		TransferID: r.Transfer.ID,
+		Reference:  r.Reference,
+		Status:     r.Status,
+		Reason:     r.Reason,
+		Date:       c.clock.Now(),
+	}
+	c.mu.Unlock()
+	c.notify(event, 1)
+}
+
+// notify posts the event to the webhook, failed attempts are retried with a growing backoff
+func (c *bankContext) notify(event banksdk.TransferEvent, attempt int) {
+	err := c.post(event)
+	if err == nil {
+		return
+	}
+	if attempt >= _webhookAttempts {
+		log.Errorf("transfer event '%s' was given up on: %s", event.Reference, err)
+		return
+	}
+	log.Warnf("transfer event '%s' was not delivered: %s", event.Reference, err)
+	c.schedule(time.Duration(attempt)*_webhookBackoff, func() { c.notify(event, attempt+1) })
+}
+
+func (c *bankContext) post(event banksdk.TransferEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, c.cfg.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.WebhookSecret != "" {
+		banksdk.SignRequest(req, c.cfg.WebhookSecret, c.clock.Now(), body)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("webhook responded with %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func afterFunc(d time.Duration, fn func()) {
+	time.AfterFunc(d, fn)
+}
@@ -4,9 +4,8 @@ go 1.16
 
 require (
 	github.com/Netflix/go-env v0.0.0-20210215222557-e437a7e7f9fb
-	github.com/gin-gonic/gin v1.6.3 // indirect
-	github.com/go-playground/validator/v10 v10.4.1 // indirect
-	github.com/golang/mock v1.5.0 // indirect
+	github.com/gin-gonic/gin v1.7.7
+	github.com/golang/mock v1.5.0
 	github.com/golang/protobuf v1.5.1 // indirect
 	github.com/google/uuid v1.2.0
 	github.com/json-iterator/go v1.1.10 // indirect
@@ -17,12 +16,8 @@ require (
 	github.com/shopspring/decimal v1.2.0
 	github.com/sirupsen/logrus v1.8.1
 	github.com/streadway/amqp v1.0.0
-	github.com/stretchr/testify v1.6.1 // indirect
+	github.com/stretchr/testify v1.6.1
 	github.com/ugorji/go v1.2.4 // indirect
-	go.mongodb.org/mongo-driver v1.5.0
-	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2 // indirect
-	golang.org/x/sys v0.0.0-20210324051608-47abb6519492 // indirect
-	gopkg.in/go-playground/assert.v1 v1.2.1 // indirect
-	gopkg.in/go-playground/validator.v9 v9.29.1 // indirect
+	go.mongodb.org/mongo-driver v1.10.1
 	gopkg.in/yaml.v2 v2.4.0 // indirect
 )
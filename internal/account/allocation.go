@@ -0,0 +1,137 @@
+package account
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	_allocationCollectionName = "allocations"
+	// _maxAllocations keeps a batch from being spread over more transfers than the bank charges reasonably for
+	_maxAllocations = 10
+)
+
+var (
+	ErrNoPortfolio        = errors.New("allocation has to name the portfolio")
+	ErrDuplicatePortfolio = errors.New("portfolio can be allocated only once")
+	ErrInvalidPercent     = errors.New("allocation percent has to be positive with at most 2 decimal places")
+	ErrPercentSum         = errors.New("allocation percents have to add up to 100")
+	ErrTooManyAllocations = errors.New("at most 10 portfolios can be allocated")
+	_hundred              = decimal.NewFromInt(100)
+)
+
+// Allocation is the share of every dispatched batch of the user invested into the portfolio
+type Allocation struct {
+	Portfolio string `bson:"portfolio" json:"portfolio"`
+	Percent   string `bson:"percent" json:"percent"`
+}
+
+// Allocations of the user apply to batches in all currencies, without them a batch goes out as a single transfer
+type Allocations struct {
+	UserID      string       `bson:"_id" json:"userId"`
+	Rules       []Allocation `bson:"rules" json:"rules"`
+	UpdatedDate time.Time    `bson:"updatedDate" json:"updatedDate"`
+}
+
+func validateAllocations(rules []Allocation) error {
+	if len(rules) > _maxAllocations {
+		return ErrTooManyAllocations
+	}
+	seen := make(map[string]bool, len(rules))
+	sum := decimal.Zero
+	for _, r := range rules {
+		if r.Portfolio == "" {
+			return ErrNoPortfolio
+		}
+		if seen[r.Portfolio] {
+			return errors.Wrap(ErrDuplicatePortfolio, r.Portfolio)
+		}
+		seen[r.Portfolio] = true
+		percent, err := decimal.NewFromString(r.Percent)
+		if err != nil || !percent.IsPositive() || percent.Exponent() < -2 {
+			return errors.Wrap(ErrInvalidPercent, r.Portfolio)
+		}
+		sum = sum.Add(percent)
+	}
+	if len(rules) > 0 && !sum.Equal(_hundred) {
+		return ErrPercentSum
+	}
+	return nil
+}
+
+// PutAllocations replaces the allocations of the user, no rules take them away
+func (c *serviceContext) PutAllocations(ctx context.Context, userID string, rules []Allocation) (Allocations, error) {
+	if userID == "" {
+		return Allocations{}, ErrNoUserID
+	}
+	for i := range rules {
+		rules[i].Portfolio = strings.TrimSpace(rules[i].Portfolio)
+	}
+	if err := validateAllocations(rules); err != nil {
+		return Allocations{}, err
+	}
+	a := Allocations{UserID: userID, Rules: rules, UpdatedDate: c.clock.Now()}
+	if a.Rules == nil {
+		a.Rules = make([]Allocation, 0)
+	}
+
+	filter := bson.D{{"_id", userID}}
+	if len(rules) == 0 {
+		if err := c.mongo.DeleteOne(ctx, _allocationCollectionName, filter); err != nil {
+			return Allocations{}, err
+		}
+		return a, nil
+	}
+	_, err := c.Allocations(ctx, userID)
+	if errors.Is(err, ErrAllocationsNotFound) {
+		if _, err := c.mongo.InsertOne(ctx, _allocationCollectionName, a); err != nil {
+			return Allocations{}, err
+		}
+		return a, nil
+	}
+	if err != nil {
+		return Allocations{}, err
+	}
+	update := bson.D{
+		{"$set", bson.D{
+			{"rules", a.Rules},
+			{"updatedDate", a.UpdatedDate},
+		}},
+	}
+	if err := c.mongo.UpdateOne(ctx, _allocationCollectionName, filter, update); err != nil {
+		return Allocations{}, err
+	}
+	return a, nil
+}
+
+func (c *serviceContext) Allocations(ctx context.Context, userID string) (Allocations, error) {
+	if userID == "" {
+		return Allocations{}, ErrNoUserID
+	}
+	var a Allocations
+	if err := c.mongo.FindOne(ctx, _allocationCollectionName, bson.D{{"_id", userID}}).Decode(&a); err != nil {
+		if errors.Is(err, mongoOrg.ErrNoDocuments) {
+			return Allocations{}, ErrAllocationsNotFound
+		}
+		return Allocations{}, err
+	}
+	return a, nil
+}
+
+// AllocationRules are the allocations dispatch splits the batches of the user by, none when the user has not set any
+func (c *serviceContext) AllocationRules(ctx context.Context, userID string) ([]Allocation, error) {
+	a, err := c.Allocations(ctx, userID)
+	if errors.Is(err, ErrAllocationsNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return a.Rules, nil
+}
@@ -0,0 +1,101 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAllocations(t *testing.T) {
+	tests := []struct {
+		name string
+		give []Allocation
+		want error
+	}{
+		{
+			name: "split",
+			give: []Allocation{{Portfolio: "index-fund", Percent: "66.67"}, {Portfolio: "savings", Percent: "33.33"}},
+			want: nil,
+		},
+		{
+			name: "no allocations",
+			give: nil,
+			want: nil,
+		},
+		{
+			name: "no portfolio",
+			give: []Allocation{{Percent: "100"}},
+			want: ErrNoPortfolio,
+		},
+		{
+			name: "duplicate portfolio",
+			give: []Allocation{{Portfolio: "savings", Percent: "50"}, {Portfolio: "savings", Percent: "50"}},
+			want: ErrDuplicatePortfolio,
+		},
+		{
+			name: "too precise percent",
+			give: []Allocation{{Portfolio: "index-fund", Percent: "66.666"}, {Portfolio: "savings", Percent: "33.334"}},
+			want: ErrInvalidPercent,
+		},
+		{
+			name: "zero percent",
+			give: []Allocation{{Portfolio: "index-fund", Percent: "100"}, {Portfolio: "savings", Percent: "0"}},
+			want: ErrInvalidPercent,
+		},
+		{
+			name: "does not add up",
+			give: []Allocation{{Portfolio: "index-fund", Percent: "70"}, {Portfolio: "savings", Percent: "20"}},
+			want: ErrPercentSum,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAllocations(tt.give)
+			assert.True(t, errors.Is(err, tt.want), err)
+		})
+	}
+}
+
+func TestPutAllocations(t *testing.T) {
+	t.Run("should replace allocations of the user", func(t *testing.T) {
+		// arrange
+		svc, c := newTestService(t)
+		_, err := svc.PutAllocations(context.Background(), "user:1", []Allocation{{Portfolio: "savings", Percent: "100"}})
+		require.NoError(t, err)
+		c.Advance(time.Hour)
+
+		// act
+		a, err := svc.PutAllocations(context.Background(), "user:1", []Allocation{{Portfolio: " index-fund ", Percent: "70"}, {Portfolio: "savings", Percent: "30"}})
+
+		// assert
+		assert.NoError(t, err)
+		stored, err := svc.Allocations(context.Background(), "user:1")
+		assert.NoError(t, err)
+		assert.Equal(t, a, stored)
+		assert.Equal(t, []Allocation{{Portfolio: "index-fund", Percent: "70"}, {Portfolio: "savings", Percent: "30"}}, stored.Rules)
+		assert.Equal(t, testNow.Add(time.Hour), stored.UpdatedDate)
+	})
+
+	t.Run("should take allocations away, no rules were given", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		_, err := svc.PutAllocations(context.Background(), "user:1", []Allocation{{Portfolio: "savings", Percent: "100"}})
+		require.NoError(t, err)
+
+		// act
+		_, err = svc.PutAllocations(context.Background(), "user:1", nil)
+
+		// assert
+		assert.NoError(t, err)
+		_, err = svc.Allocations(context.Background(), "user:1")
+		assert.Equal(t, ErrAllocationsNotFound, err)
+		rules, err := svc.AllocationRules(context.Background(), "user:1")
+		assert.NoError(t, err)
+		assert.Nil(t, rules)
+	})
+}
@@ -0,0 +1,170 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/mazxaxz/donut-batcher/internal/account (interfaces: Service)
+
+// Package mock_account is a generated GoMock package.
+package mock_account
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	account "github.com/mazxaxz/donut-batcher/internal/account"
+	banksdk "github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	money "github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// AllocationRules mocks base method.
+func (m *MockService) AllocationRules(arg0 context.Context, arg1 string) ([]account.Allocation, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AllocationRules", arg0, arg1)
+	ret0, _ := ret[0].([]account.Allocation)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AllocationRules indicates an expected call of AllocationRules.
+func (mr *MockServiceMockRecorder) AllocationRules(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AllocationRules", reflect.TypeOf((*MockService)(nil).AllocationRules), arg0, arg1)
+}
+
+// Allocations mocks base method.
+func (m *MockService) Allocations(arg0 context.Context, arg1 string) (account.Allocations, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allocations", arg0, arg1)
+	ret0, _ := ret[0].(account.Allocations)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Allocations indicates an expected call of Allocations.
+func (mr *MockServiceMockRecorder) Allocations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allocations", reflect.TypeOf((*MockService)(nil).Allocations), arg0, arg1)
+}
+
+// Destination mocks base method.
+func (m *MockService) Destination(arg0 context.Context, arg1 string, arg2 money.Currency) (banksdk.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Destination", arg0, arg1, arg2)
+	ret0, _ := ret[0].(banksdk.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Destination indicates an expected call of Destination.
+func (mr *MockServiceMockRecorder) Destination(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Destination", reflect.TypeOf((*MockService)(nil).Destination), arg0, arg1, arg2)
+}
+
+// Get mocks base method.
+func (m *MockService) Get(arg0 context.Context, arg1 string, arg2 money.Currency) (account.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1, arg2)
+	ret0, _ := ret[0].(account.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockServiceMockRecorder) Get(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockService)(nil).Get), arg0, arg1, arg2)
+}
+
+// Index mocks base method.
+func (m *MockService) Index(arg0 context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Index", arg0)
+}
+
+// Index indicates an expected call of Index.
+func (mr *MockServiceMockRecorder) Index(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Index", reflect.TypeOf((*MockService)(nil).Index), arg0)
+}
+
+// List mocks base method.
+func (m *MockService) List(arg0 context.Context, arg1 string) ([]account.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List", arg0, arg1)
+	ret0, _ := ret[0].([]account.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockServiceMockRecorder) List(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockService)(nil).List), arg0, arg1)
+}
+
+// Put mocks base method.
+func (m *MockService) Put(arg0 context.Context, arg1 account.Account) (account.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Put", arg0, arg1)
+	ret0, _ := ret[0].(account.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Put indicates an expected call of Put.
+func (mr *MockServiceMockRecorder) Put(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Put", reflect.TypeOf((*MockService)(nil).Put), arg0, arg1)
+}
+
+// PutAllocations mocks base method.
+func (m *MockService) PutAllocations(arg0 context.Context, arg1 string, arg2 []account.Allocation) (account.Allocations, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PutAllocations", arg0, arg1, arg2)
+	ret0, _ := ret[0].(account.Allocations)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PutAllocations indicates an expected call of PutAllocations.
+func (mr *MockServiceMockRecorder) PutAllocations(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PutAllocations", reflect.TypeOf((*MockService)(nil).PutAllocations), arg0, arg1, arg2)
+}
+
+// Verify mocks base method.
+func (m *MockService) Verify(arg0 context.Context, arg1 string, arg2 money.Currency) (account.Account, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", arg0, arg1, arg2)
+	ret0, _ := ret[0].(account.Account)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockServiceMockRecorder) Verify(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockService)(nil).Verify), arg0, arg1, arg2)
+}
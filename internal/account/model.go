@@ -0,0 +1,50 @@
+package account
+
+import (
+	"time"
+
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+// Account is where the round-ups of the user in the currency are invested, either an IBAN or a US routing
+// and account number. Only verified accounts are paid to, changing the account takes the verification away.
+type Account struct {
+	ID            string         `bson:"_id" json:"-"`
+	UserID        string         `bson:"userId" json:"userId"`
+	Currency      money.Currency `bson:"currency" json:"currency"`
+	HolderName    string         `bson:"holderName" json:"holderName"`
+	IBAN          string         `bson:"iban,omitempty" json:"iban,omitempty"`
+	BIC           string         `bson:"bic,omitempty" json:"bic,omitempty"`
+	RoutingNumber string         `bson:"routingNumber,omitempty" json:"routingNumber,omitempty"`
+	AccountNumber string         `bson:"accountNumber,omitempty" json:"accountNumber,omitempty"`
+	Savings       bool           `bson:"savings" json:"savings"`
+	Verified      bool           `bson:"verified" json:"verified"`
+	CreatedDate   time.Time      `bson:"createdDate" json:"createdDate"`
+	UpdatedDate   time.Time      `bson:"updatedDate" json:"updatedDate"`
+	VerifiedDate  time.Time      `bson:"verifiedDate,omitempty" json:"verifiedDate,omitempty"`
+}
+
+func (a Account) sameDestination(other Account) bool {
+	return a.HolderName == other.HolderName &&
+		a.IBAN == other.IBAN &&
+		a.BIC == other.BIC &&
+		a.RoutingNumber == other.RoutingNumber &&
+		a.AccountNumber == other.AccountNumber &&
+		a.Savings == other.Savings
+}
+
+func (a Account) destination() banksdk.Account {
+	return banksdk.Account{
+		Name:          a.HolderName,
+		IBAN:          a.IBAN,
+		BIC:           a.BIC,
+		RoutingNumber: a.RoutingNumber,
+		AccountNumber: a.AccountNumber,
+		Savings:       a.Savings,
+	}
+}
+
+func accountID(userID string, currency money.Currency) string {
+	return userID + "/" + currency.String()
+}
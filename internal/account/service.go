@@ -0,0 +1,193 @@
+package account
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+const (
+	_collectionName = "accounts"
+)
+
+var (
+	ErrAccountNotFound = errors.New("user has no account in the currency")
+	ErrNoDestination   = errors.New("user has no verified account in the currency")
+	// ErrAllocationsNotFound means the batches of the user are not split
+	ErrAllocationsNotFound = errors.New("user has no allocations")
+)
+
+type Service interface {
+	mongodb.Indexer
+
+	// Put stores the account of the user in its currency, a changed account has to be verified again
+	Put(ctx context.Context, a Account) (Account, error)
+	Get(ctx context.Context, userID string, currency money.Currency) (Account, error)
+	List(ctx context.Context, userID string) ([]Account, error)
+	Verify(ctx context.Context, userID string, currency money.Currency) (Account, error)
+	// Destination is the verified account the transfers of the user in the currency are paid to
+	Destination(ctx context.Context, userID string, currency money.Currency) (banksdk.Account, error)
+	// PutAllocations replaces the percentages the batches of the user are split into portfolios by
+	PutAllocations(ctx context.Context, userID string, rules []Allocation) (Allocations, error)
+	Allocations(ctx context.Context, userID string) (Allocations, error)
+	AllocationRules(ctx context.Context, userID string) ([]Allocation, error)
+}
+
+type serviceContext struct {
+	mongo  mongodb.Clienter
+	clock  clock.Clock
+	logger *logrus.Logger
+}
+
+func New(mc mongodb.Clienter, clk clock.Clock, l *logrus.Logger) (Service, error) {
+	c := serviceContext{
+		mongo:  mc,
+		clock:  clk,
+		logger: l,
+	}
+	return &c, nil
+}
+
+func (c *serviceContext) Index(ctx context.Context) {
+	timeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	idx := mongoOrg.IndexModel{Keys: bson.D{{"userId", 1}}}
+	if err := c.mongo.CreateIndex(timeout, _collectionName, idx); err != nil {
+		c.logger.Error(err)
+	}
+}
+
+func (c *serviceContext) Put(ctx context.Context, a Account) (Account, error) {
+	a = normalize(a)
+	if err := validate(a); err != nil {
+		return Account{}, err
+	}
+	a.ID = accountID(a.UserID, a.Currency)
+	a.Verified = false
+	a.VerifiedDate = time.Time{}
+	a.UpdatedDate = c.clock.Now()
+
+	existing, err := c.Get(ctx, a.UserID, a.Currency)
+	switch {
+	case errors.Is(err, ErrAccountNotFound):
+		a.CreatedDate = a.UpdatedDate
+		if _, err := c.mongo.InsertOne(ctx, _collectionName, a); err != nil {
+			return Account{}, err
+		}
+		return a, nil
+	case err != nil:
+		return Account{}, err
+	}
+
+	/* the same account put again keeps its verification */
+	if existing.sameDestination(a) {
+		return existing, nil
+	}
+	a.CreatedDate = existing.CreatedDate
+	filter := bson.D{{"_id", a.ID}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"holderName", a.HolderName},
+			{"iban", a.IBAN},
+			{"bic", a.BIC},
+			{"routingNumber", a.RoutingNumber},
+			{"accountNumber", a.AccountNumber},
+			{"savings", a.Savings},
+			{"verified", false},
+			{"updatedDate", a.UpdatedDate},
+		}},
+		{"$unset", bson.D{{"verifiedDate", ""}}},
+	}
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+func (c *serviceContext) Get(ctx context.Context, userID string, currency money.Currency) (Account, error) {
+	if userID == "" {
+		return Account{}, ErrNoUserID
+	}
+	var a Account
+	filter := bson.D{{"_id", accountID(userID, money.Currency(strings.ToUpper(currency.String())))}}
+	if err := c.mongo.FindOne(ctx, _collectionName, filter).Decode(&a); err != nil {
+		if errors.Is(err, mongoOrg.ErrNoDocuments) {
+			return Account{}, ErrAccountNotFound
+		}
+		return Account{}, err
+	}
+	return a, nil
+}
+
+func (c *serviceContext) List(ctx context.Context, userID string) ([]Account, error) {
+	if userID == "" {
+		return nil, ErrNoUserID
+	}
+	opt := options.Find().SetSort(bson.D{{"currency", 1}})
+	cursor, err := c.mongo.Find(ctx, _collectionName, bson.D{{"userId", userID}}, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var accounts []Account
+	if err := cursor.All(ctx, &accounts); err != nil {
+		return nil, err
+	}
+	if accounts == nil {
+		accounts = make([]Account, 0)
+	}
+	return accounts, nil
+}
+
+func (c *serviceContext) Verify(ctx context.Context, userID string, currency money.Currency) (Account, error) {
+	a, err := c.Get(ctx, userID, currency)
+	if err != nil {
+		return Account{}, err
+	}
+	if a.Verified {
+		return a, nil
+	}
+	a.Verified = true
+	a.VerifiedDate = c.clock.Now()
+	a.UpdatedDate = a.VerifiedDate
+
+	filter := bson.D{{"_id", a.ID}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"verified", true},
+			{"verifiedDate", a.VerifiedDate},
+			{"updatedDate", a.UpdatedDate},
+		}},
+	}
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return Account{}, err
+	}
+	return a, nil
+}
+
+func (c *serviceContext) Destination(ctx context.Context, userID string, currency money.Currency) (banksdk.Account, error) {
+	a, err := c.Get(ctx, userID, currency)
+	if errors.Is(err, ErrAccountNotFound) {
+		return banksdk.Account{}, errors.Wrap(ErrNoDestination, err.Error())
+	}
+	if err != nil {
+		return banksdk.Account{}, err
+	}
+	if !a.Verified {
+		return banksdk.Account{}, errors.Wrap(ErrNoDestination, "account is not verified yet")
+	}
+	return a.destination(), nil
+}
@@ -0,0 +1,167 @@
+package account
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+var (
+	testNow     = time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	testAccount = Account{UserID: "user:1", Currency: "usd", HolderName: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123 456 789"}
+)
+
+func newTestService(t *testing.T) (Service, *clock.Fake) {
+	c := clock.NewFake(testNow)
+	svc, err := New(memory.New(), c, logrus.New())
+	require.NoError(t, err)
+	return svc, c
+}
+
+func TestPut(t *testing.T) {
+	t.Run("should store normalized account waiting for verification", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+
+		// act
+		a, err := svc.Put(context.Background(), testAccount)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "USD", a.Currency.String())
+		assert.Equal(t, "123456789", a.AccountNumber)
+		assert.False(t, a.Verified)
+		stored, err := svc.Get(context.Background(), "user:1", "USD")
+		assert.NoError(t, err)
+		assert.Equal(t, a, stored)
+	})
+
+	t.Run("should return error, routing number has invalid check digit", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		a := testAccount
+		a.RoutingNumber = "011000016"
+
+		// act
+		_, err := svc.Put(context.Background(), a)
+
+		// assert
+		assert.Equal(t, ErrInvalidRoutingNumber, err)
+	})
+
+	t.Run("should keep verification, the same account was put again", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		_, err := svc.Put(context.Background(), testAccount)
+		require.NoError(t, err)
+		_, err = svc.Verify(context.Background(), "user:1", "USD")
+		require.NoError(t, err)
+
+		// act
+		a, err := svc.Put(context.Background(), testAccount)
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, a.Verified)
+	})
+
+	t.Run("should take verification away, account was changed", func(t *testing.T) {
+		// arrange
+		svc, c := newTestService(t)
+		_, err := svc.Put(context.Background(), testAccount)
+		require.NoError(t, err)
+		_, err = svc.Verify(context.Background(), "user:1", "USD")
+		require.NoError(t, err)
+		c.Advance(time.Hour)
+		changed := testAccount
+		changed.AccountNumber = "987654321"
+
+		// act
+		a, err := svc.Put(context.Background(), changed)
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, a.Verified)
+		stored, err := svc.Get(context.Background(), "user:1", "USD")
+		assert.NoError(t, err)
+		assert.False(t, stored.Verified)
+		assert.True(t, stored.VerifiedDate.IsZero())
+		assert.Equal(t, "987654321", stored.AccountNumber)
+		assert.Equal(t, testNow, stored.CreatedDate)
+		assert.Equal(t, testNow.Add(time.Hour), stored.UpdatedDate)
+	})
+}
+
+func TestList(t *testing.T) {
+	t.Run("should list accounts of the user by currency", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		_, err := svc.Put(context.Background(), testAccount)
+		require.NoError(t, err)
+		_, err = svc.Put(context.Background(), Account{UserID: "user:1", Currency: "EUR", HolderName: "Jane Doe", IBAN: "DE89 3704 0044 0532 0130 00"})
+		require.NoError(t, err)
+		_, err = svc.Put(context.Background(), Account{UserID: "user:2", Currency: "EUR", HolderName: "John Doe", IBAN: "DE89370400440532013000"})
+		require.NoError(t, err)
+
+		// act
+		accounts, err := svc.List(context.Background(), "user:1")
+
+		// assert
+		assert.NoError(t, err)
+		require.Len(t, accounts, 2)
+		assert.Equal(t, "EUR", accounts[0].Currency.String())
+		assert.Equal(t, "DE89370400440532013000", accounts[0].IBAN)
+		assert.Equal(t, "USD", accounts[1].Currency.String())
+	})
+}
+
+func TestDestination(t *testing.T) {
+	t.Run("should return error, user has no account", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+
+		// act
+		_, err := svc.Destination(context.Background(), "user:1", "USD")
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoDestination))
+	})
+
+	t.Run("should return error, account is not verified", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		_, err := svc.Put(context.Background(), testAccount)
+		require.NoError(t, err)
+
+		// act
+		_, err = svc.Destination(context.Background(), "user:1", "USD")
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoDestination))
+	})
+
+	t.Run("should return verified account", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		_, err := svc.Put(context.Background(), testAccount)
+		require.NoError(t, err)
+		_, err = svc.Verify(context.Background(), "user:1", "USD")
+		require.NoError(t, err)
+
+		// act
+		a, err := svc.Destination(context.Background(), "user:1", "USD")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}, a)
+	})
+}
@@ -0,0 +1,101 @@
+package account
+
+import (
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+	"github.com/mazxaxz/donut-batcher/pkg/nacha"
+)
+
+var (
+	ErrNoUserID             = errors.New("no user id was provided")
+	ErrNoHolderName         = errors.New("account holder name is required")
+	ErrNoAccount            = errors.New("either iban or routing and account number are required")
+	ErrInvalidIBAN          = errors.New("iban is malformed or its check digits do not match")
+	ErrInvalidBIC           = errors.New("bic has to be 8 or 11 characters")
+	ErrInvalidRoutingNumber = errors.New("routing number has to be 9 digits with a valid aba check digit")
+	ErrInvalidAccountNumber = errors.New("account number has to be at most 17 digits")
+)
+
+var (
+	_ibanPattern          = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+	_bicPattern           = regexp.MustCompile(`^[A-Z]{6}[A-Z0-9]{2}([A-Z0-9]{3})?$`)
+	_accountNumberPattern = regexp.MustCompile(`^[0-9]{1,17}$`)
+)
+
+// IsInvalid tells whether the account or the allocations were refused because of their content
+func IsInvalid(err error) bool {
+	invalid := []error{
+		ErrNoUserID, ErrNoHolderName, ErrNoAccount, ErrInvalidIBAN, ErrInvalidBIC, ErrInvalidRoutingNumber, ErrInvalidAccountNumber,
+		ErrNoPortfolio, ErrDuplicatePortfolio, ErrInvalidPercent, ErrPercentSum, ErrTooManyAllocations,
+	}
+	for _, e := range invalid {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize drops the spaces the numbers are usually written with
+func normalize(a Account) Account {
+	a.Currency = money.Currency(strings.ToUpper(a.Currency.String()))
+	a.HolderName = strings.TrimSpace(a.HolderName)
+	a.IBAN = strings.ToUpper(strings.ReplaceAll(a.IBAN, " ", ""))
+	a.BIC = strings.ToUpper(strings.TrimSpace(a.BIC))
+	a.RoutingNumber = strings.TrimSpace(a.RoutingNumber)
+	a.AccountNumber = strings.ReplaceAll(a.AccountNumber, " ", "")
+	return a
+}
+
+func validate(a Account) error {
+	if a.UserID == "" {
+		return ErrNoUserID
+	}
+	if _, err := money.CurrencyFrom(a.Currency.String()); err != nil {
+		return err
+	}
+	if a.HolderName == "" {
+		return ErrNoHolderName
+	}
+	if a.IBAN == "" && a.RoutingNumber == "" {
+		return ErrNoAccount
+	}
+	if a.IBAN != "" && !ValidIBAN(a.IBAN) {
+		return ErrInvalidIBAN
+	}
+	if a.BIC != "" && !_bicPattern.MatchString(a.BIC) {
+		return ErrInvalidBIC
+	}
+	if a.RoutingNumber != "" {
+		if !nacha.ValidRoutingNumber(a.RoutingNumber) {
+			return ErrInvalidRoutingNumber
+		}
+		if !_accountNumberPattern.MatchString(a.AccountNumber) {
+			return ErrInvalidAccountNumber
+		}
+	}
+	return nil
+}
+
+// ValidIBAN checks the ISO 13616 check digits, the IBAN moved by 4 characters and read as a number gives 1 mod 97
+func ValidIBAN(iban string) bool {
+	if !_ibanPattern.MatchString(iban) {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(r - 'A' + 10)))
+			continue
+		}
+		digits.WriteRune(r)
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
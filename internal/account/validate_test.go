@@ -0,0 +1,78 @@
+package account
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+func TestValidIBAN(t *testing.T) {
+	tests := []struct {
+		give string
+		want bool
+	}{
+		{give: "DE89370400440532013000", want: true},
+		{give: "GB33BUKB20201555555555", want: true},
+		{give: "GB34BUKB20201555555555", want: false},
+		{give: "DE8937040044", want: false},
+		{give: "de89370400440532013000", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			assert.Equal(t, tt.want, ValidIBAN(tt.give))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		give Account
+		want error
+	}{
+		{
+			name: "iban account",
+			give: Account{UserID: "user:1", Currency: "EUR", HolderName: "Jane Doe", IBAN: "DE89370400440532013000", BIC: "COBADEFFXXX"},
+			want: nil,
+		},
+		{
+			name: "us account",
+			give: Account{UserID: "user:1", Currency: "USD", HolderName: "Jane Doe", RoutingNumber: "021000021", AccountNumber: "123456789"},
+			want: nil,
+		},
+		{
+			name: "no holder",
+			give: Account{UserID: "user:1", Currency: "EUR", IBAN: "DE89370400440532013000"},
+			want: ErrNoHolderName,
+		},
+		{
+			name: "no account",
+			give: Account{UserID: "user:1", Currency: "EUR", HolderName: "Jane Doe"},
+			want: ErrNoAccount,
+		},
+		{
+			name: "invalid currency",
+			give: Account{UserID: "user:1", Currency: "EURO", HolderName: "Jane Doe", IBAN: "DE89370400440532013000"},
+			want: money.ErrInvalidCurrencyCode,
+		},
+		{
+			name: "invalid bic",
+			give: Account{UserID: "user:1", Currency: "EUR", HolderName: "Jane Doe", IBAN: "DE89370400440532013000", BIC: "COBADE"},
+			want: ErrInvalidBIC,
+		},
+		{
+			name: "no account number",
+			give: Account{UserID: "user:1", Currency: "USD", HolderName: "Jane Doe", RoutingNumber: "021000021"},
+			want: ErrInvalidAccountNumber,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, validate(normalize(tt.give)))
+		})
+	}
+}
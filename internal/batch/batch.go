@@ -2,21 +2,22 @@ package batch
 
 import (
 	"context"
-	"time"
 
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongoOrg "go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/mazxaxz/donut-batcher/internal/goal"
 	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
 	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
 	"github.com/mazxaxz/donut-batcher/pkg/money"
 )
 
 var (
-	ErrNoTransactionID = errors.New("no transaction id was provided")
-	ErrNoUserID        = errors.New("no user id was provided")
+	ErrNoTransactionID    = errors.New("no transaction id was provided")
+	ErrNoUserID           = errors.New("no user id was provided")
+	ErrTransactionBatched = errors.New("transaction was batched already")
 )
 
 type BatchResult struct {
@@ -25,7 +26,7 @@ type BatchResult struct {
 }
 
 func (c *serviceContext) Batch(ctx context.Context, t transaction.Transaction) (BatchResult, error) {
-	result, err := c.mongo.WithinTransaction(ctx, c.callback(ctx, t))
+	result, err := c.mongo.WithinTransaction(ctx, c.callback(t))
 	if err != nil {
 		return BatchResult{}, err
 	}
@@ -35,7 +36,8 @@ func (c *serviceContext) Batch(ctx context.Context, t transaction.Transaction) (
 	return BatchResult{}, nil
 }
 
-func (c *serviceContext) callback(ctx context.Context, t transaction.Transaction) mongodb.TransactionCallback {
+// callback batches the transaction, every read and write goes through the session so they are all part of the transaction
+func (c *serviceContext) callback(t transaction.Transaction) mongodb.TransactionCallback {
 	return func(sessCtx mongoOrg.SessionContext) (interface{}, error) {
 		// that could be extracted to message, but I did not wanted to add complexity with validation library
 		if t.ID == "" {
@@ -49,24 +51,13 @@ func (c *serviceContext) callback(ctx context.Context, t transaction.Transaction
 		if err != nil {
 			return BatchResult{}, err
 		}
-		filter := bson.D{{"userId", t.UserID}, {"status", StatusUndispatched}, {"currency", currency}}
-		result := c.mongo.FindOne(ctx, _collectionName, filter)
-		if err := result.Err(); err != nil && !errors.Is(err, mongoOrg.ErrNoDocuments) {
+		route, err := c.route(sessCtx, t)
+		if err != nil {
 			return BatchResult{}, err
 		}
-
-		var b Batch
-		if err := result.Decode(&b); err != nil {
-			if !errors.Is(err, mongoOrg.ErrNoDocuments) {
-				return BatchResult{}, err
-			} else {
-				b = NewBatch(t.UserID, currency)
-				insertResult, err := c.mongo.InsertOne(ctx, _collectionName, b)
-				if err != nil {
-					return BatchResult{}, err
-				}
-				b.ID = insertResult.InsertedID.(primitive.ObjectID)
-			}
+		b, err := c.undispatched(sessCtx, t.UserID, currency, route.GoalID)
+		if err != nil {
+			return BatchResult{}, err
 		}
 		investment, err := money.CalculateInvestment(t.Amount)
 		if err != nil {
@@ -77,7 +68,11 @@ func (c *serviceContext) callback(ctx context.Context, t transaction.Transaction
 			return BatchResult{}, err
 		}
 
-		if threshold, exists := c.threshold[b.Currency]; exists {
+		threshold, exists := c.threshold[b.Currency]
+		if route.Threshold != "" {
+			threshold, exists = route.Threshold, true
+		}
+		if exists {
 			exceeded, err := money.GreaterThanOrEqual(amount, threshold)
 			if err != nil {
 				return BatchResult{}, err
@@ -92,25 +87,81 @@ func (c *serviceContext) callback(ctx context.Context, t transaction.Transaction
 			}
 		}
 
-		b.UpdatedDate = time.Now().UTC()
-		b.TransactionIDs = append(b.TransactionIDs, t.ID)
+		b.UpdatedDate = c.clock.Now()
 		b.Amount, err = primitive.ParseDecimal128(amount)
 		if err != nil {
 			return BatchResult{}, err
 		}
 
-		filter = bson.D{{"_id", b.ID}}
+		original, err := primitive.ParseDecimal128(t.Amount)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		roundUp, err := primitive.ParseDecimal128(investment)
+		if err != nil {
+			return BatchResult{}, err
+		}
+		entry := NewEntry(b.ID, t.ID, original, roundUp, c.clock.Now())
+		if _, err := c.mongo.InsertOne(sessCtx, _entryCollectionName, entry); err != nil {
+			/* unique transactionId index rolls the whole transaction back, so the amount is not added twice */
+			if mongoOrg.IsDuplicateKeyError(err) {
+				return BatchResult{}, ErrTransactionBatched
+			}
+			return BatchResult{}, err
+		}
+
+		filter := bson.D{{"_id", b.ID}}
 		update := bson.D{
 			{"$set", bson.D{
 				{"amount", b.Amount},
 				{"status", b.Status},
-				{"transactionIds", b.TransactionIDs},
 				{"updatedDate", b.UpdatedDate},
 			}},
+			{"$inc", bson.D{{"transactionCount", 1}}},
 		}
-		if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		if b.Status == StatusReadyToDispatch {
+			change := StatusChange{Status: b.Status, Date: b.UpdatedDate}
+			update = append(update, bson.E{"$push", bson.D{{"history", change}}})
+		}
+		if err := c.mongo.UpdateOne(sessCtx, _collectionName, filter, update); err != nil {
 			return BatchResult{}, err
 		}
 		return BatchResult{ID: b.ID, Status: b.Status}, nil
 	}
 }
+
+// route tells which goal the round-up is saved for, the batches of the currency take it without goals
+func (c *serviceContext) route(ctx context.Context, t transaction.Transaction) (goal.Route, error) {
+	if c.goals == nil {
+		return goal.Route{}, nil
+	}
+	return c.goals.Route(ctx, t)
+}
+
+// undispatched returns the batch collecting the funds of the user for the goal, a new one is created when there is none
+func (c *serviceContext) undispatched(ctx context.Context, userID string, currency money.Currency, goalID string) (Batch, error) {
+	filter := bson.D{{"userId", userID}, {"status", StatusUndispatched}, {"currency", currency}}
+	if goalID == "" {
+		filter = append(filter, bson.E{"goal", bson.D{{"$exists", false}}})
+	} else {
+		filter = append(filter, bson.E{"goal", goalID})
+	}
+	result := c.mongo.FindOne(ctx, _collectionName, filter)
+	if err := result.Err(); err != nil && !errors.Is(err, mongoOrg.ErrNoDocuments) {
+		return Batch{}, err
+	}
+
+	var b Batch
+	if err := result.Decode(&b); err != nil {
+		if !errors.Is(err, mongoOrg.ErrNoDocuments) {
+			return Batch{}, err
+		}
+		b = NewBatch(userID, currency, goalID, c.clock.Now())
+		insertResult, err := c.mongo.InsertOne(ctx, _collectionName, b)
+		if err != nil {
+			return Batch{}, err
+		}
+		b.ID = insertResult.InsertedID.(primitive.ObjectID)
+	}
+	return b, nil
+}
@@ -14,6 +14,7 @@ import (
 
 	mockMongodb "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/mock"
 	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
 	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
 	"github.com/mazxaxz/donut-batcher/pkg/money"
 )
@@ -29,6 +30,7 @@ func TestBatch(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -55,6 +57,7 @@ func TestBatch(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -82,6 +85,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -89,8 +93,7 @@ func TestCallback(t *testing.T) {
 		// expected calls
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
@@ -107,6 +110,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -114,8 +118,7 @@ func TestCallback(t *testing.T) {
 		// expected calls
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
@@ -132,6 +135,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -139,8 +143,7 @@ func TestCallback(t *testing.T) {
 		// expected calls
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
@@ -157,6 +160,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -164,12 +168,11 @@ func TestCallback(t *testing.T) {
 		// expected calls
 		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
 		singleResult.EXPECT().Err().Return(mongoOrg.ErrClientDisconnected)
-		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}}
+		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}, {"goal", bson.D{{"$exists", false}}}}
 		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
@@ -186,6 +189,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -193,12 +197,11 @@ func TestCallback(t *testing.T) {
 		// expected calls
 		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
 		singleResult.EXPECT().Err().Return(mongoOrg.ErrClientDisconnected)
-		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}}
+		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}, {"goal", bson.D{{"$exists", false}}}}
 		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
@@ -215,6 +218,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -223,12 +227,11 @@ func TestCallback(t *testing.T) {
 		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
 		singleResult.EXPECT().Err().Return(nil)
 		singleResult.EXPECT().Decode(gomock.Any()).Return(errors.New("random error"))
-		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}}
+		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}, {"goal", bson.D{{"$exists", false}}}}
 		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
@@ -246,6 +249,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -260,21 +264,67 @@ func TestCallback(t *testing.T) {
 			b.Currency = money.Currency(give.Currency)
 			b.Status = StatusUndispatched
 		}).Return(nil)
-		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}}
+		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}, {"goal", bson.D{{"$exists", false}}}}
 		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
 
+		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _entryCollectionName, gomock.Any()).Return(&mongoOrg.InsertOneResult{}, nil)
+
 		filter = bson.D{{"_id", batchID}}
 		mockMongoClient.EXPECT().UpdateOne(gomock.Any(), _collectionName, filter, gomock.Any()).Return(nil)
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
 		assert.NoError(t, err)
 	})
 
+	t.Run("should return entry insert error", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		give := transaction.Transaction{ID: "1", UserID: "11", Amount: "11.11", Currency: "USD"}
+		want := BatchResult{}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svcCtx := serviceContext{
+			mongo:     mockMongoClient,
+			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
+			logger:    logrus.New(),
+			threshold: map[money.Currency]string{"USD": "100"},
+		}
+
+		// expected calls
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Err().Return(nil)
+		singleResult.EXPECT().Decode(gomock.Any()).Do(func(b *Batch) {
+			b.ID = batchID
+			b.UserID = give.UserID
+			b.Amount, _ = primitive.ParseDecimal128("0")
+			b.Currency = money.Currency(give.Currency)
+			b.Status = StatusUndispatched
+		}).Return(nil)
+		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}, {"goal", bson.D{{"$exists", false}}}}
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
+
+		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _entryCollectionName, gomock.Any()).Do(func(_ context.Context, _ string, e Entry) {
+			assert.Equal(t, batchID, e.BatchID)
+			assert.Equal(t, give.ID, e.TransactionID)
+			assert.Equal(t, "11.11", e.Amount.String())
+			assert.Equal(t, "0.89", e.RoundUp.String())
+			assert.Equal(t, StrategyCeil, e.Strategy)
+		}).Return(nil, mongoOrg.ErrClientDisconnected)
+
+		// act
+		result, err := svcCtx.callback(give)(nil)
+
+		// assert
+		assert.Equal(t, want, result)
+		assert.Error(t, err, mongoOrg.ErrClientDisconnected)
+	})
+
 	t.Run("should add transaction to the newly created batch", func(t *testing.T) {
 		// arrange
 		batchID := primitive.NewObjectID()
@@ -286,6 +336,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "100"},
 		}
@@ -294,17 +345,18 @@ func TestCallback(t *testing.T) {
 		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
 		singleResult.EXPECT().Err().Return(mongoOrg.ErrNoDocuments)
 		singleResult.EXPECT().Decode(gomock.Any()).Return(mongoOrg.ErrNoDocuments)
-		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}}
+		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}, {"goal", bson.D{{"$exists", false}}}}
 		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
 
 		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _collectionName, gomock.Any()).Return(&mongoOrg.InsertOneResult{InsertedID: batchID}, nil)
 
+		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _entryCollectionName, gomock.Any()).Return(&mongoOrg.InsertOneResult{}, nil)
+
 		filter = bson.D{{"_id", batchID}}
 		mockMongoClient.EXPECT().UpdateOne(gomock.Any(), _collectionName, filter, gomock.Any()).Return(nil)
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
@@ -322,6 +374,7 @@ func TestCallback(t *testing.T) {
 		svcCtx := serviceContext{
 			mongo:     mockMongoClient,
 			bankSDK:   banksdk.New(),
+			clock:     clock.New(),
 			logger:    logrus.New(),
 			threshold: map[money.Currency]string{"USD": "0.5"},
 		}
@@ -336,15 +389,16 @@ func TestCallback(t *testing.T) {
 			b.Currency = money.Currency(give.Currency)
 			b.Status = StatusUndispatched
 		}).Return(nil)
-		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}}
+		filter := bson.D{{"userId", give.UserID}, {"status", StatusUndispatched}, {"currency", money.Currency(give.Currency)}, {"goal", bson.D{{"$exists", false}}}}
 		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
 
+		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _entryCollectionName, gomock.Any()).Return(&mongoOrg.InsertOneResult{}, nil)
+
 		filter = bson.D{{"_id", batchID}}
 		mockMongoClient.EXPECT().UpdateOne(gomock.Any(), _collectionName, filter, gomock.Any()).Return(nil)
 
 		// act
-		ctx := context.Background()
-		result, err := svcCtx.callback(ctx, give)(nil)
+		result, err := svcCtx.callback(give)(nil)
 
 		// assert
 		assert.Equal(t, want, result)
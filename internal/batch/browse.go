@@ -0,0 +1,149 @@
+package batch
+
+import (
+	"context"
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidCursor = errors.New("pagination cursor is malformed")
+	ErrInvalidLimit  = errors.New("page limit has to be between 1 and 100")
+)
+
+// MaxLimit is the largest page Browse serves
+const MaxLimit = 100
+
+// Page of batches, Next and Prev are opaque cursors to the neighbouring pages, empty when there is no such page
+type Page struct {
+	Items []Batch `json:"items"`
+	Next  string  `json:"next,omitempty"`
+	Prev  string  `json:"prev,omitempty"`
+	// Total number of batches matching the filter, regardless of the cursor
+	Total int64 `json:"total"`
+	// Sort the page was read with, prefixed with "-" when descending
+	Sort string `json:"sort"`
+}
+
+// cursorToken points at the batch next to which the page starts, it carries the sort as well
+// so following the cursor keeps the order it was created with
+type cursorToken struct {
+	Field    string             `bson:"f"`
+	Value    interface{}        `bson:"v"`
+	ID       primitive.ObjectID `bson:"i"`
+	Asc      bool               `bson:"a"`
+	Backward bool               `bson:"b"`
+}
+
+func (t cursorToken) encode() string {
+	raw, _ := bson.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(cursor string) (cursorToken, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return cursorToken{}, ErrInvalidCursor
+	}
+	var t cursorToken
+	if err := bson.Unmarshal(raw, &t); err != nil {
+		return cursorToken{}, ErrInvalidCursor
+	}
+	if _, exists := sortFields[t.Field]; !exists || t.ID.IsZero() {
+		return cursorToken{}, ErrInvalidCursor
+	}
+	return t, nil
+}
+
+// Browse is a keyset pagination over (sort field, _id), cursor decides on the sort once it is given
+func (c *serviceContext) Browse(ctx context.Context, limit int, sort Sort, cursor string, f Filter) (Page, error) {
+	if limit < 1 || limit > MaxLimit {
+		return Page{}, ErrInvalidLimit
+	}
+	token := cursorToken{Field: sort.Field, Asc: sort.Asc}
+	if cursor != "" {
+		var err error
+		if token, err = decodeCursor(cursor); err != nil {
+			return Page{}, err
+		}
+	}
+
+	filter := f.sortedQuery(token.Field)
+	total, err := c.mongo.CountDocuments(ctx, _collectionName, filter)
+	if err != nil {
+		return Page{}, err
+	}
+
+	/* walking backwards means reading in the opposite order and reversing the result */
+	forward := token.Asc != token.Backward
+	direction, operator := -1, "$lt"
+	if forward {
+		direction, operator = 1, "$gt"
+	}
+	if cursor != "" {
+		filter = append(filter, bson.E{"$or", bson.A{
+			bson.D{{token.Field, bson.D{{operator, token.Value}}}},
+			bson.D{{token.Field, token.Value}, {"_id", bson.D{{operator, token.ID}}}},
+		}})
+	}
+	opt := options.Find().
+		SetSort(bson.D{{token.Field, direction}, {"_id", direction}}).
+		SetLimit(int64(limit + 1))
+
+	result, err := c.mongo.Find(ctx, _collectionName, filter, opt)
+	if err != nil {
+		return Page{}, err
+	}
+	defer func() { _ = result.Close(ctx) }()
+
+	var batches []Batch
+	if err := result.All(ctx, &batches); err != nil {
+		return Page{}, err
+	}
+	p := newPage(batches, limit, cursor != "", token)
+	p.Total = total
+	p.Sort = Sort{Field: token.Field, Asc: token.Asc}.String()
+	return p, nil
+}
+
+func newPage(batches []Batch, limit int, fromCursor bool, token cursorToken) Page {
+	more := len(batches) > limit
+	if more {
+		batches = batches[:limit]
+	}
+	if token.Backward {
+		for i, j := 0, len(batches)-1; i < j; i, j = i+1, j-1 {
+			batches[i], batches[j] = batches[j], batches[i]
+		}
+	}
+	p := Page{Items: batches}
+	if len(batches) == 0 {
+		p.Items = make([]Batch, 0)
+		return p
+	}
+
+	first, last := batches[0], batches[len(batches)-1]
+	value := sortFields[token.Field]
+	next := cursorToken{Field: token.Field, Value: value(last), ID: last.ID, Asc: token.Asc}
+	prev := cursorToken{Field: token.Field, Value: value(first), ID: first.ID, Asc: token.Asc, Backward: true}
+
+	/* the page we came from always exists, the other side only when more documents were found */
+	if token.Backward {
+		p.Next = next.encode()
+		if more {
+			p.Prev = prev.encode()
+		}
+	} else {
+		if more {
+			p.Next = next.encode()
+		}
+		if fromCursor {
+			p.Prev = prev.encode()
+		}
+	}
+	return p
+}
@@ -0,0 +1,125 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestDecodeCursor(t *testing.T) {
+	t.Run("should return invalid cursor error, not base64", func(t *testing.T) {
+		_, err := decodeCursor("!!!")
+		assert.Equal(t, ErrInvalidCursor, err)
+	})
+
+	t.Run("should return invalid cursor error, not bson", func(t *testing.T) {
+		_, err := decodeCursor("aW52YWxpZA")
+		assert.Equal(t, ErrInvalidCursor, err)
+	})
+
+	t.Run("should decode encoded cursor", func(t *testing.T) {
+		// arrange
+		created := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		give := cursorToken{Field: "createdDate", Value: created, ID: primitive.NewObjectID(), Asc: true, Backward: true}
+
+		// act
+		result, err := decodeCursor(give.encode())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, give.ID, result.ID)
+		assert.Equal(t, primitive.NewDateTimeFromTime(created), result.Value)
+		assert.True(t, result.Asc)
+		assert.True(t, result.Backward)
+	})
+}
+
+func TestBrowse(t *testing.T) {
+	t.Run("should return invalid cursor error", func(t *testing.T) {
+		// arrange
+		svcCtx := serviceContext{}
+
+		// act
+		_, err := svcCtx.Browse(context.Background(), 10, Sort{Field: "createdDate"}, "invalid", Filter{})
+
+		// assert
+		assert.Equal(t, ErrInvalidCursor, err)
+	})
+
+	for _, limit := range []int{-1, 0, MaxLimit + 1} {
+		t.Run(fmt.Sprintf("should return invalid limit error, limit %d", limit), func(t *testing.T) {
+			// arrange
+			svcCtx := serviceContext{}
+
+			// act
+			_, err := svcCtx.Browse(context.Background(), limit, Sort{Field: "createdDate"}, "", Filter{})
+
+			// assert
+			assert.Equal(t, ErrInvalidLimit, err)
+		})
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	batches := func(n int) []Batch {
+		result := make([]Batch, n)
+		for i := range result {
+			result[i] = Batch{ID: primitive.NewObjectID(), CreatedDate: time.Date(2021, 1, i+1, 0, 0, 0, 0, time.UTC)}
+		}
+		return result
+	}
+
+	t.Run("should return empty page", func(t *testing.T) {
+		result := newPage(nil, 2, false, cursorToken{Field: "createdDate"})
+
+		assert.Empty(t, result.Items)
+		assert.NotNil(t, result.Items)
+		assert.Empty(t, result.Next)
+		assert.Empty(t, result.Prev)
+	})
+
+	t.Run("should return first page with next cursor only", func(t *testing.T) {
+		give := batches(3)
+
+		result := newPage(give, 2, false, cursorToken{Field: "createdDate"})
+
+		assert.Equal(t, give[:2], result.Items)
+		assert.Empty(t, result.Prev)
+		next, err := decodeCursor(result.Next)
+		assert.NoError(t, err)
+		assert.Equal(t, give[1].ID, next.ID)
+		assert.False(t, next.Backward)
+	})
+
+	t.Run("should return last page with prev cursor only", func(t *testing.T) {
+		give := batches(2)
+
+		result := newPage(give, 2, true, cursorToken{Field: "createdDate"})
+
+		assert.Equal(t, give, result.Items)
+		assert.Empty(t, result.Next)
+		prev, err := decodeCursor(result.Prev)
+		assert.NoError(t, err)
+		assert.Equal(t, give[0].ID, prev.ID)
+		assert.True(t, prev.Backward)
+	})
+
+	t.Run("should reverse items read backwards", func(t *testing.T) {
+		give := batches(3)
+		want := []Batch{give[1], give[0]}
+
+		result := newPage(give, 2, true, cursorToken{Field: "createdDate", Backward: true})
+
+		assert.Equal(t, want, result.Items)
+		next, err := decodeCursor(result.Next)
+		assert.NoError(t, err)
+		assert.Equal(t, want[1].ID, next.ID)
+		prev, err := decodeCursor(result.Prev)
+		assert.NoError(t, err)
+		assert.Equal(t, want[0].ID, prev.ID)
+	})
+}
@@ -0,0 +1,229 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+var (
+	ErrInvalidOutcome    = errors.New("confirmation status has to be one of: settled, returned")
+	ErrNotDispatched     = errors.New("only dispatched batch can be confirmed")
+	ErrAlreadyReturned   = errors.New("batch was already returned")
+	ErrReferenceMismatch = errors.New("confirmation reference does not match the dispatched transfer")
+	ErrLegNotFound       = errors.New("batch has no such leg")
+	ErrSplitBatch        = errors.New("batch was split, its legs are confirmed one by one")
+)
+
+// Confirmation is the bank's outcome of a dispatched transfer
+type Confirmation struct {
+	// BatchID is the id the transfer was sent with, LegTransferID for a leg of a split batch
+	BatchID   string
+	Reference string
+	Status    Status
+	Reason    string
+	Date      time.Time
+}
+
+// Confirm moves the dispatched batch to settled or returned, confirming the batch with its current status again is a no-op.
+// A settled transfer can still be returned later, the funds of a returned one are re-credited to an undispatched batch.
+func (c *serviceContext) Confirm(ctx context.Context, cf Confirmation) (Batch, error) {
+	if cf.Status != StatusSettled && cf.Status != StatusReturned {
+		return Batch{}, ErrInvalidOutcome
+	}
+	result, err := c.mongo.WithinTransaction(ctx, func(sessCtx mongoOrg.SessionContext) (interface{}, error) {
+		return c.confirm(sessCtx, cf)
+	})
+	if err != nil {
+		return Batch{}, err
+	}
+	if b, ok := result.(Batch); ok {
+		return b, nil
+	}
+	return Batch{}, nil
+}
+
+func (c *serviceContext) confirm(ctx context.Context, cf Confirmation) (Batch, error) {
+	batchID, leg := ParseTransferID(cf.BatchID)
+	b, err := c.Get(ctx, batchID)
+	if err != nil {
+		return Batch{}, err
+	}
+	switch {
+	case leg > 0:
+		return c.confirmLeg(ctx, b, leg, cf)
+	case len(b.Legs) > 0:
+		return Batch{}, ErrSplitBatch
+	}
+	if cf.Reference != "" && b.DispatchReference != "" && cf.Reference != b.DispatchReference {
+		return Batch{}, ErrReferenceMismatch
+	}
+	switch {
+	case b.Status == cf.Status:
+		/* banks deliver callbacks at least once */
+		return b, nil
+	case b.Status == StatusReturned:
+		return Batch{}, ErrAlreadyReturned
+	case b.Status == StatusDispatched:
+	case b.Status == StatusSettled && cf.Status == StatusReturned:
+	default:
+		return Batch{}, ErrNotDispatched
+	}
+
+	previous := b.Status
+	b.Status = cf.Status
+	b.UpdatedDate = c.clock.Now()
+	b.Settlement = &Settlement{Reference: cf.Reference, Reason: cf.Reason, Date: cf.Date}
+	if b.Settlement.Reference == "" {
+		b.Settlement.Reference = b.DispatchReference
+	}
+	if b.Settlement.Date.IsZero() {
+		b.Settlement.Date = b.UpdatedDate
+	}
+	if b.Status == StatusReturned {
+		b.Settlement.RecreditBatchID, err = c.recredit(ctx, b)
+		if err != nil {
+			return Batch{}, err
+		}
+	}
+	change := StatusChange{Status: b.Status, Date: b.UpdatedDate}
+	b.History = append(b.History, change)
+
+	filter := bson.D{{"_id", b.ID}, {"status", previous}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"status", b.Status},
+			{"updatedDate", b.UpdatedDate},
+			{"settlement", b.Settlement},
+		}},
+		{"$push", bson.D{{"history", change}}},
+	}
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return Batch{}, err
+	}
+	return b, nil
+}
+
+// confirmLeg settles or returns a single leg, the batch follows once its legs are all settled or one is returned
+func (c *serviceContext) confirmLeg(ctx context.Context, b Batch, number int, cf Confirmation) (Batch, error) {
+	i := number - 1
+	if i >= len(b.Legs) {
+		return Batch{}, ErrLegNotFound
+	}
+	l := b.Legs[i]
+	if cf.Reference != "" && l.DispatchReference != "" && cf.Reference != l.DispatchReference {
+		return Batch{}, ErrReferenceMismatch
+	}
+	switch {
+	case l.Status == cf.Status:
+		return b, nil
+	case l.Status == StatusReturned:
+		return Batch{}, ErrAlreadyReturned
+	case l.Status == StatusDispatched:
+	case l.Status == StatusSettled && cf.Status == StatusReturned:
+	default:
+		return Batch{}, ErrNotDispatched
+	}
+
+	var err error
+	previous := l.Status
+	l.Status = cf.Status
+	b.UpdatedDate = c.clock.Now()
+	l.Settlement = &Settlement{Reference: cf.Reference, Reason: cf.Reason, Date: cf.Date}
+	if l.Settlement.Reference == "" {
+		l.Settlement.Reference = l.DispatchReference
+	}
+	if l.Settlement.Date.IsZero() {
+		l.Settlement.Date = b.UpdatedDate
+	}
+	if l.Status == StatusReturned {
+		part := b
+		part.Amount = l.Amount
+		l.Settlement.RecreditBatchID, err = c.recredit(ctx, part)
+		if err != nil {
+			return Batch{}, err
+		}
+	}
+	b.Legs[i] = l
+
+	field := fmt.Sprintf("legs.%d.", i)
+	filter := bson.D{{"_id", b.ID}, {field + "status", previous}}
+	set := bson.D{
+		{field + "status", l.Status},
+		{field + "settlement", l.Settlement},
+		{"updatedDate", b.UpdatedDate},
+	}
+	update := bson.D{}
+	/* a batch with legs still to be sent stays ready, dispatching it takes the outcomes of the others into account */
+	if status := legsStatus(b.Legs, b.Status); b.Status != StatusReadyToDispatch && status != b.Status {
+		b.Status = status
+		change := StatusChange{Status: b.Status, Date: b.UpdatedDate}
+		b.History = append(b.History, change)
+		set = append(set, bson.E{"status", b.Status})
+		update = append(update, bson.E{"$push", bson.D{{"history", change}}})
+	}
+	update = append(bson.D{{"$set", set}}, update...)
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return Batch{}, err
+	}
+	return b, nil
+}
+
+// legsStatus is the status of the split batch following from the outcomes of its legs, the current one without any
+func legsStatus(legs []Leg, current Status) Status {
+	settled := 0
+	for _, l := range legs {
+		switch l.Status {
+		case StatusReturned:
+			return StatusReturned
+		case StatusSettled:
+			settled++
+		}
+	}
+	if settled == len(legs) {
+		return StatusSettled
+	}
+	return current
+}
+
+// recredit adds the amount of the returned batch to the undispatched batch of the user and records it in the ledger.
+// The batch is not marked as ready even when it reaches the threshold, whatever made the bank return it has to be fixed first.
+func (c *serviceContext) recredit(ctx context.Context, returned Batch) (primitive.ObjectID, error) {
+	b, err := c.undispatched(ctx, returned.UserID, returned.Currency, returned.Goal)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	amount, err := money.Add(b.Amount.String(), returned.Amount.String())
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	b.Amount, err = primitive.ParseDecimal128(amount)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	b.UpdatedDate = c.clock.Now()
+
+	entry := NewRecreditEntry(b.ID, returned.ID, returned.Amount, b.UpdatedDate)
+	if _, err := c.mongo.InsertOne(ctx, _entryCollectionName, entry); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	filter := bson.D{{"_id", b.ID}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"amount", b.Amount},
+			{"updatedDate", b.UpdatedDate},
+		}},
+	}
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return primitive.NilObjectID, err
+	}
+	return b.ID, nil
+}
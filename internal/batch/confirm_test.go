@@ -0,0 +1,235 @@
+package batch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+// dispatched batches the transactions of user:1 over the threshold and dispatches them
+func dispatched(t *testing.T, svc Service, amounts ...string) Batch {
+	ctx := context.Background()
+	var result BatchResult
+	for i, amount := range amounts {
+		var err error
+		result, err = svc.Batch(ctx, transaction.Transaction{ID: string(rune('a' + i)), UserID: "user:1", Amount: amount, Currency: "USD"})
+		require.NoError(t, err)
+	}
+	require.Equal(t, Status(StatusReadyToDispatch), result.Status)
+	require.NoError(t, svc.Dispatch(ctx, result.ID.Hex()))
+	b, err := svc.Get(ctx, result.ID.Hex())
+	require.NoError(t, err)
+	return b
+}
+
+func TestConfirm(t *testing.T) {
+	now := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	newService := func(t *testing.T) (Service, *clock.Fake) {
+		c := clock.NewFake(now)
+		svc, err := New(memory.New(), banksdk.New(), nil, nil, c, logrus.New(), map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		return svc, c
+	}
+
+	t.Run("should return error, status is not an outcome", func(t *testing.T) {
+		// arrange
+		svc, _ := newService(t)
+
+		// act
+		_, err := svc.Confirm(context.Background(), Confirmation{BatchID: "1", Status: StatusDispatched})
+
+		// assert
+		assert.Equal(t, ErrInvalidOutcome, err)
+	})
+
+	t.Run("should return error, batch was not dispatched yet", func(t *testing.T) {
+		// arrange
+		svc, _ := newService(t)
+		result, err := svc.Batch(context.Background(), transaction.Transaction{ID: "1", UserID: "user:1", Amount: "0.5", Currency: "USD"})
+		require.NoError(t, err)
+
+		// act
+		_, err = svc.Confirm(context.Background(), Confirmation{BatchID: result.ID.Hex(), Status: StatusSettled})
+
+		// assert
+		assert.Equal(t, ErrNotDispatched, err)
+	})
+
+	t.Run("should settle batch and ignore the repeated confirmation", func(t *testing.T) {
+		// arrange
+		svc, c := newService(t)
+		b := dispatched(t, svc, "1.10", "2.50")
+		c.Advance(time.Hour)
+		settledDate := now.Add(30 * time.Minute)
+		cf := Confirmation{BatchID: b.ID.Hex(), Reference: b.DispatchReference, Status: StatusSettled, Date: settledDate}
+
+		// act
+		settled, err := svc.Confirm(context.Background(), cf)
+		assert.NoError(t, err)
+		c.Advance(time.Hour)
+		again, errAgain := svc.Confirm(context.Background(), cf)
+
+		// assert
+		assert.NoError(t, errAgain)
+		assert.Equal(t, Status(StatusSettled), settled.Status)
+		stored, err := svc.Get(context.Background(), b.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusSettled), stored.Status)
+		assert.Equal(t, now.Add(time.Hour), stored.UpdatedDate)
+		assert.Equal(t, b.DispatchReference, stored.Settlement.Reference)
+		assert.Equal(t, settledDate, stored.Settlement.Date.UTC())
+		assert.Len(t, stored.History, 4)
+		assert.Equal(t, stored.UpdatedDate, again.UpdatedDate.UTC())
+	})
+
+	t.Run("should return error, reference belongs to another transfer", func(t *testing.T) {
+		// arrange
+		svc, _ := newService(t)
+		b := dispatched(t, svc, "1.10", "2.50")
+
+		// act
+		_, err := svc.Confirm(context.Background(), Confirmation{BatchID: b.ID.Hex(), Reference: "other", Status: StatusSettled})
+
+		// assert
+		assert.Equal(t, ErrReferenceMismatch, err)
+	})
+
+	t.Run("should return settled batch and re-credit funds to the undispatched batch", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		svc, _ := newService(t)
+		b := dispatched(t, svc, "1.10", "2.50")
+		open, err := svc.Batch(ctx, transaction.Transaction{ID: "z", UserID: "user:1", Amount: "4.75", Currency: "USD"})
+		require.NoError(t, err)
+		_, err = svc.Confirm(ctx, Confirmation{BatchID: b.ID.Hex(), Status: StatusSettled})
+		require.NoError(t, err)
+
+		// act
+		returned, err := svc.Confirm(ctx, Confirmation{BatchID: b.ID.Hex(), Status: StatusReturned, Reason: "account_closed"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusReturned), returned.Status)
+		assert.Equal(t, "account_closed", returned.Settlement.Reason)
+		assert.Equal(t, open.ID, returned.Settlement.RecreditBatchID)
+
+		recredited, err := svc.Get(ctx, open.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusUndispatched), recredited.Status)
+		assert.Equal(t, "1.65", recredited.Amount.String())
+		entries, err := svc.Entries(ctx, open.ID, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+		assert.Equal(t, StrategyRecredit, entries[1].Strategy)
+		assert.Equal(t, b.ID, entries[1].ReturnedBatchID)
+
+		summaries, err := svc.Summary(ctx, "user:1")
+		assert.NoError(t, err)
+		assert.Equal(t, "1.65", summaries[0].Undispatched)
+		assert.Equal(t, "0", summaries[0].Dispatched)
+		assert.Equal(t, "1.4", summaries[0].Returned)
+
+		_, err = svc.Confirm(ctx, Confirmation{BatchID: b.ID.Hex(), Status: StatusSettled})
+		assert.Equal(t, ErrAlreadyReturned, err)
+
+		/* re-credited funds are merged on purpose, the next round-up takes them over the threshold together */
+		next, err := svc.Batch(ctx, transaction.Transaction{ID: "y", UserID: "user:1", Amount: "0.99", Currency: "USD"})
+		assert.NoError(t, err)
+		assert.Equal(t, open.ID, next.ID)
+		assert.Equal(t, Status(StatusReadyToDispatch), next.Status)
+	})
+
+	t.Run("should re-credit returned funds to a new batch, user has no undispatched one", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		svc, _ := newService(t)
+		b := dispatched(t, svc, "1.10", "2.50")
+
+		// act
+		returned, err := svc.Confirm(ctx, Confirmation{BatchID: b.ID.Hex(), Status: StatusReturned})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NotEqual(t, b.ID, returned.Settlement.RecreditBatchID)
+		recredited, err := svc.Get(ctx, returned.Settlement.RecreditBatchID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusUndispatched), recredited.Status)
+		assert.Equal(t, "user:1", recredited.UserID)
+		assert.Equal(t, b.Amount.String(), recredited.Amount.String())
+	})
+
+	t.Run("should re-credit returned funds of a goal to the batch of the goal", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		goals := stubGoals{"5812": {GoalID: "goal:coffee"}}
+		svc, err := New(memory.New(), banksdk.New(), nil, goals, clock.NewFake(now), logrus.New(), map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		saved, err := svc.Batch(ctx, transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD", MerchantCategory: "5812"})
+		require.NoError(t, err)
+		_, err = svc.Batch(ctx, transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD", MerchantCategory: "5812"})
+		require.NoError(t, err)
+		open, err := svc.Batch(ctx, transaction.Transaction{ID: "3", UserID: "user:1", Amount: "4.75", Currency: "USD"})
+		require.NoError(t, err)
+		require.NoError(t, svc.Dispatch(ctx, saved.ID.Hex()))
+
+		// act
+		returned, err := svc.Confirm(ctx, Confirmation{BatchID: saved.ID.Hex(), Status: StatusReturned})
+
+		// assert
+		assert.NoError(t, err)
+		assert.NotEqual(t, open.ID, returned.Settlement.RecreditBatchID)
+		recredited, err := svc.Get(ctx, returned.Settlement.RecreditBatchID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, "goal:coffee", recredited.Goal)
+		assert.Equal(t, "1.4", recredited.Amount.String())
+	})
+
+	t.Run("should settle split batch once all its legs are and re-credit the returned leg", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		accounts := newStubAccounts()
+		accounts.destinations["user:1"] = banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}
+		accounts.allocations["user:1"] = []account.Allocation{{Portfolio: "index-fund", Percent: "70"}, {Portfolio: "savings", Percent: "30"}}
+		svc, err := New(memory.New(), &recordingBank{}, accounts, nil, clock.NewFake(now), logrus.New(), map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		b := dispatched(t, svc, "1.10", "2.50")
+
+		// act
+		_, errBatch := svc.Confirm(ctx, Confirmation{BatchID: b.ID.Hex(), Status: StatusSettled})
+		_, errLeg := svc.Confirm(ctx, Confirmation{BatchID: LegTransferID(b.ID, 3), Status: StatusSettled})
+		first, err := svc.Confirm(ctx, Confirmation{BatchID: b.Legs[0].TransferID, Reference: b.Legs[0].DispatchReference, Status: StatusSettled})
+		require.NoError(t, err)
+		second, err := svc.Confirm(ctx, Confirmation{BatchID: b.Legs[1].TransferID, Status: StatusSettled})
+		require.NoError(t, err)
+		returned, err := svc.Confirm(ctx, Confirmation{BatchID: b.Legs[1].TransferID, Status: StatusReturned, Reason: "account_closed"})
+
+		// assert
+		assert.Equal(t, ErrSplitBatch, errBatch)
+		assert.Equal(t, ErrLegNotFound, errLeg)
+		assert.Equal(t, Status(StatusDispatched), first.Status)
+		assert.Equal(t, Status(StatusSettled), first.Legs[0].Status)
+		assert.Equal(t, Status(StatusSettled), second.Status)
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusReturned), returned.Status)
+		assert.Equal(t, Status(StatusSettled), returned.Legs[0].Status)
+		assert.Equal(t, "account_closed", returned.Legs[1].Settlement.Reason)
+
+		stored, err := svc.Get(ctx, b.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, returned.Legs, stored.Legs)
+		assert.Len(t, stored.History, 5)
+		recredited, err := svc.Get(ctx, returned.Legs[1].Settlement.RecreditBatchID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, "0.42", recredited.Amount.String())
+	})
+}
@@ -2,16 +2,26 @@ package batch
 
 import (
 	"context"
-	"time"
+	"fmt"
 
 	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+const (
+	// _legPlaces are the cents the batch is split in, a more precise batch is split in its own smallest unit
+	_legPlaces = 2
 )
 
 var (
-	ErrNoBatchID = errors.New("no batch id was provided")
+	ErrNoBatchID     = errors.New("no batch id was provided")
+	ErrNoDestination = errors.New("batch is flagged, the user has no verified account to dispatch it to")
 )
 
 func (c *serviceContext) Dispatch(ctx context.Context, batchID string) error {
@@ -37,13 +47,49 @@ func (c *serviceContext) Dispatch(ctx context.Context, batchID string) error {
 			return err
 		}
 	}
-	if err := c.bankSDK.Send(ctx, b.UserID, b.Amount.String(), b.Currency.String()); err != nil {
+	var creditor *banksdk.Account
+	if c.accounts != nil {
+		destination, err := c.accounts.Destination(ctx, b.UserID, b.Currency)
+		if errors.Is(err, account.ErrNoDestination) {
+			c.logger.Warnf("batch %s is not dispatched: %s", b.ID.Hex(), err)
+			if err := c.flag(ctx, b, FlagNoDestination); err != nil {
+				return err
+			}
+			return ErrNoDestination
+		}
+		if err != nil {
+			return err
+		}
+		creditor = &destination
+		if b.Legs == nil {
+			if b, err = c.split(ctx, b); err != nil {
+				return err
+			}
+		}
+	}
+	if len(b.Legs) > 0 {
+		return c.dispatchLegs(ctx, b, creditor)
+	}
+
+	/* batch id is stable across redeliveries, so the bank pays out a retried dispatch only once */
+	t := banksdk.Transfer{
+		ID:       b.ID.Hex(),
+		UserID:   b.UserID,
+		Amount:   b.Amount.String(),
+		Currency: b.Currency.String(),
+		Creditor: creditor,
+	}
+	receipt, err := c.bankSDK.Send(ctx, t)
+	if err != nil {
 		return err
 	}
 
 	b.Status = StatusDispatched
-	b.UpdatedDate = time.Now().UTC()
-	b.DispatchedDate = time.Now().UTC()
+	b.UpdatedDate = c.clock.Now()
+	b.DispatchedDate = b.UpdatedDate
+	b.DispatchReference = receipt.Reference
+	b.DispatchFile = receipt.File
+	b.EndToEndID = receipt.EndToEndID
 
 	filter = bson.D{{"_id", b.ID}}
 	update := bson.D{
@@ -51,6 +97,161 @@ func (c *serviceContext) Dispatch(ctx context.Context, batchID string) error {
 			{"status", b.Status},
 			{"updatedDate", b.UpdatedDate},
 			{"dispatchedDate", b.DispatchedDate},
+			{"dispatchReference", b.DispatchReference},
+			{"dispatchFile", b.DispatchFile},
+			{"endToEndId", b.EndToEndID},
+		}},
+		{"$push", bson.D{{"history", StatusChange{Status: b.Status, Date: b.DispatchedDate}}}},
+	}
+	if b.Flag != "" {
+		update = append(update, bson.E{"$unset", bson.D{{"flag", ""}, {"flaggedDate", ""}}})
+	}
+	return c.mongo.UpdateOne(ctx, _collectionName, filter, update)
+}
+
+// split divides the batch by the allocations of the user, the legs are stored before anything is sent so a retry
+// sends the same parts even when the user changes the allocations in the meantime. Legs which round to zero are left out.
+func (c *serviceContext) split(ctx context.Context, b Batch) (Batch, error) {
+	rules, err := c.accounts.AllocationRules(ctx, b.UserID)
+	if err != nil || len(rules) == 0 {
+		return b, err
+	}
+	weights := make([]string, 0, len(rules))
+	for _, r := range rules {
+		weights = append(weights, r.Percent)
+	}
+	amounts, err := money.Split(b.Amount.String(), _legPlaces, weights)
+	if err != nil {
+		return b, err
+	}
+	legs := make([]Leg, 0, len(rules))
+	for i, r := range rules {
+		if zero, _ := money.GreaterThanOrEqual("0", amounts[i]); zero {
+			continue
+		}
+		amount, err := primitive.ParseDecimal128(amounts[i])
+		if err != nil {
+			return b, err
+		}
+		number := len(legs) + 1
+		legs = append(legs, Leg{
+			Number:     number,
+			Portfolio:  r.Portfolio,
+			Percent:    r.Percent,
+			Amount:     amount,
+			TransferID: LegTransferID(b.ID, number),
+			Status:     StatusReadyToDispatch,
+		})
+	}
+
+	filter := bson.D{{"_id", b.ID}, {"status", StatusReadyToDispatch}, {"legs", bson.D{{"$exists", false}}}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"legs", legs},
+			{"updatedDate", c.clock.Now()},
+		}},
+	}
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return b, err
+	}
+	/* a concurrent attempt could have split it first, its legs are the ones sent */
+	return c.Get(ctx, b.ID.Hex())
+}
+
+// dispatchLegs sends the legs which have not left yet, one failed leg does not hold the others back.
+// The error of the first failed leg is returned, the batch is dispatched once the last leg is.
+func (c *serviceContext) dispatchLegs(ctx context.Context, b Batch, creditor *banksdk.Account) error {
+	var failed error
+	filter := bson.D{{"_id", b.ID}}
+	for i, leg := range b.Legs {
+		if leg.Status != StatusReadyToDispatch {
+			continue
+		}
+		t := banksdk.Transfer{
+			ID:        leg.TransferID,
+			UserID:    b.UserID,
+			Amount:    leg.Amount.String(),
+			Currency:  b.Currency.String(),
+			Creditor:  creditor,
+			Portfolio: leg.Portfolio,
+		}
+		field := fmt.Sprintf("legs.%d.", i)
+		receipt, err := c.bankSDK.Send(ctx, t)
+		if err != nil {
+			c.logger.Warnf("leg %d of batch %s is not dispatched: %s", leg.Number, b.ID.Hex(), err)
+			if failed == nil {
+				failed = err
+			}
+			update := bson.D{{"$set", bson.D{{field + "error", err.Error()}, {"updatedDate", c.clock.Now()}}}}
+			if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+				return err
+			}
+			continue
+		}
+
+		now := c.clock.Now()
+		update := bson.D{
+			{"$set", bson.D{
+				{field + "status", StatusDispatched},
+				{field + "dispatchedDate", now},
+				{field + "dispatchReference", receipt.Reference},
+				{field + "dispatchFile", receipt.File},
+				{field + "endToEndId", receipt.EndToEndID},
+				{"updatedDate", now},
+			}},
+			{"$unset", bson.D{{field + "error", ""}}},
+		}
+		if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+			return err
+		}
+		b.Legs[i].Status = StatusDispatched
+	}
+	if failed != nil {
+		return failed
+	}
+
+	b.Status = StatusDispatched
+	b.UpdatedDate = c.clock.Now()
+	b.DispatchedDate = b.UpdatedDate
+	update := bson.D{
+		{"$set", bson.D{
+			{"status", b.Status},
+			{"updatedDate", b.UpdatedDate},
+			{"dispatchedDate", b.DispatchedDate},
+		}},
+		{"$push", bson.D{{"history", StatusChange{Status: b.Status, Date: b.DispatchedDate}}}},
+	}
+	if b.Flag != "" {
+		update = append(update, bson.E{"$unset", bson.D{{"flag", ""}, {"flaggedDate", ""}}})
+	}
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return err
+	}
+
+	/* legs sent by the previous attempts could have been confirmed already */
+	status := legsStatus(b.Legs, b.Status)
+	if status == b.Status {
+		return nil
+	}
+	update = bson.D{
+		{"$set", bson.D{{"status", status}}},
+		{"$push", bson.D{{"history", StatusChange{Status: status, Date: b.UpdatedDate}}}},
+	}
+	return c.mongo.UpdateOne(ctx, _collectionName, filter, update)
+}
+
+// flag keeps the batch ready to dispatch, the date tells since when the batch has been held back
+func (c *serviceContext) flag(ctx context.Context, b Batch, flag string) error {
+	if b.Flag == flag {
+		return nil
+	}
+	now := c.clock.Now()
+	filter := bson.D{{"_id", b.ID}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"flag", flag},
+			{"flaggedDate", now},
+			{"updatedDate", now},
 		}},
 	}
 	return c.mongo.UpdateOne(ctx, _collectionName, filter, update)
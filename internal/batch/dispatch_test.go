@@ -2,17 +2,24 @@ package batch
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongoOrg "go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
 	mockMongodb "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/mock"
 	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
 	"github.com/mazxaxz/donut-batcher/pkg/money"
 )
 
@@ -22,7 +29,7 @@ func TestDispatch(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
-		svc, err := New(mockMongoClient, banksdk.New(), logrus.New(), map[string]string{})
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
 		assert.NoError(t, err)
 
 		// expected calls
@@ -39,7 +46,7 @@ func TestDispatch(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
-		svc, err := New(mockMongoClient, banksdk.New(), logrus.New(), map[string]string{})
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
 		assert.NoError(t, err)
 
 		// expected calls
@@ -57,7 +64,7 @@ func TestDispatch(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
-		svc, err := New(mockMongoClient, banksdk.New(), logrus.New(), map[string]string{})
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
 		assert.NoError(t, err)
 
 		// expected calls
@@ -79,7 +86,7 @@ func TestDispatch(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
-		svc, err := New(mockMongoClient, banksdk.New(), logrus.New(), map[string]string{})
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
 		assert.NoError(t, err)
 
 		// expected calls
@@ -102,7 +109,7 @@ func TestDispatch(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
-		svc, err := New(mockMongoClient, banksdk.New(), logrus.New(), map[string]string{})
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
 		assert.NoError(t, err)
 
 		// expected calls
@@ -120,7 +127,14 @@ func TestDispatch(t *testing.T) {
 		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
 
 		filter = bson.D{{"_id", batchID}}
-		mockMongoClient.EXPECT().UpdateOne(gomock.Any(), _collectionName, filter, gomock.Any()).Return(nil)
+		mockMongoClient.EXPECT().UpdateOne(gomock.Any(), _collectionName, filter, gomock.Any()).Do(func(_ context.Context, _ string, _ interface{}, update bson.D) {
+			set := update.Map()["$set"].(bson.D).Map()
+			assert.Equal(t, Status(StatusDispatched), set["status"])
+			assert.NotEmpty(t, set["dispatchReference"])
+			assert.Equal(t, batchID.Hex(), set["endToEndId"])
+			push := update.Map()["$push"].(bson.D).Map()
+			assert.Equal(t, Status(StatusDispatched), push["history"].(StatusChange).Status)
+		}).Return(nil)
 
 		// act
 		err = svc.Dispatch(context.Background(), batchID.Hex())
@@ -129,3 +143,169 @@ func TestDispatch(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+// stubAccounts knows the accounts and the allocations of the users put into it
+type stubAccounts struct {
+	destinations map[string]banksdk.Account
+	allocations  map[string][]account.Allocation
+}
+
+func newStubAccounts() *stubAccounts {
+	return &stubAccounts{destinations: make(map[string]banksdk.Account), allocations: make(map[string][]account.Allocation)}
+}
+
+func (s *stubAccounts) Destination(_ context.Context, userID string, _ money.Currency) (banksdk.Account, error) {
+	a, ok := s.destinations[userID]
+	if !ok {
+		return banksdk.Account{}, account.ErrNoDestination
+	}
+	return a, nil
+}
+
+func (s *stubAccounts) AllocationRules(_ context.Context, userID string) ([]account.Allocation, error) {
+	return s.allocations[userID], nil
+}
+
+// recordingBank books every transfer except the ones it is told to fail
+type recordingBank struct {
+	transfers []banksdk.Transfer
+	fail      map[string]error
+}
+
+func (b *recordingBank) Send(_ context.Context, t banksdk.Transfer) (banksdk.Receipt, error) {
+	if err := b.fail[t.ID]; err != nil {
+		return banksdk.Receipt{}, err
+	}
+	b.transfers = append(b.transfers, t)
+	return banksdk.Receipt{Reference: "ref-" + t.ID, EndToEndID: t.ID}, nil
+}
+
+func TestDispatchDestination(t *testing.T) {
+	now := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	ready := func(t *testing.T, svc Service) string {
+		var result BatchResult
+		for i, amount := range []string{"1.10", "2.50"} {
+			var err error
+			result, err = svc.Batch(context.Background(), transaction.Transaction{ID: string(rune('a' + i)), UserID: "user:1", Amount: amount, Currency: "USD"})
+			require.NoError(t, err)
+		}
+		require.Equal(t, Status(StatusReadyToDispatch), result.Status)
+		return result.ID.Hex()
+	}
+
+	t.Run("should flag batch, user has no verified account", func(t *testing.T) {
+		// arrange
+		bank := &recordingBank{}
+		svc, err := New(memory.New(), bank, newStubAccounts(), nil, clock.NewFake(now), logrus.New(), map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		batchID := ready(t, svc)
+
+		// act
+		err = svc.Dispatch(context.Background(), batchID)
+
+		// assert
+		assert.Equal(t, ErrNoDestination, err)
+		assert.Empty(t, bank.transfers)
+		b, err := svc.Get(context.Background(), batchID)
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusReadyToDispatch), b.Status)
+		assert.Equal(t, FlagNoDestination, b.Flag)
+		assert.Equal(t, now, b.FlaggedDate)
+	})
+
+	t.Run("should dispatch flagged batch to the verified account", func(t *testing.T) {
+		// arrange
+		bank := &recordingBank{}
+		accounts := newStubAccounts()
+		svc, err := New(memory.New(), bank, accounts, nil, clock.NewFake(now), logrus.New(), map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		batchID := ready(t, svc)
+		require.Equal(t, ErrNoDestination, svc.Dispatch(context.Background(), batchID))
+		accounts.destinations["user:1"] = banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}
+
+		// act
+		err = svc.Dispatch(context.Background(), batchID)
+
+		// assert
+		assert.NoError(t, err)
+		require.Len(t, bank.transfers, 1)
+		assert.Equal(t, &banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}, bank.transfers[0].Creditor)
+		b, err := svc.Get(context.Background(), batchID)
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusDispatched), b.Status)
+		assert.Empty(t, b.Flag)
+		assert.True(t, b.FlaggedDate.IsZero())
+	})
+
+	t.Run("should send one transfer per allocation", func(t *testing.T) {
+		// arrange
+		bank := &recordingBank{}
+		accounts := newStubAccounts()
+		accounts.destinations["user:1"] = banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}
+		accounts.allocations["user:1"] = []account.Allocation{{Portfolio: "index-fund", Percent: "70"}, {Portfolio: "savings", Percent: "30"}}
+		svc, err := New(memory.New(), bank, accounts, nil, clock.NewFake(now), logrus.New(), map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		batchID := ready(t, svc)
+
+		// act
+		err = svc.Dispatch(context.Background(), batchID)
+
+		// assert
+		assert.NoError(t, err)
+		require.Len(t, bank.transfers, 2)
+		assert.Equal(t, batchID+"-1", bank.transfers[0].ID)
+		assert.Equal(t, "0.98", bank.transfers[0].Amount)
+		assert.Equal(t, "index-fund", bank.transfers[0].Portfolio)
+		assert.Equal(t, batchID+"-2", bank.transfers[1].ID)
+		assert.Equal(t, "0.42", bank.transfers[1].Amount)
+		assert.Equal(t, "savings", bank.transfers[1].Portfolio)
+		b, err := svc.Get(context.Background(), batchID)
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusDispatched), b.Status)
+		assert.Equal(t, now, b.DispatchedDate)
+		assert.Empty(t, b.DispatchReference)
+		require.Len(t, b.Legs, 2)
+		assert.Equal(t, Status(StatusDispatched), b.Legs[0].Status)
+		assert.Equal(t, "ref-"+batchID+"-1", b.Legs[0].DispatchReference)
+		assert.Equal(t, "70", b.Legs[0].Percent)
+		assert.Equal(t, "0.42", b.Legs[1].Amount.String())
+	})
+
+	t.Run("should send again only the leg which failed", func(t *testing.T) {
+		// arrange
+		bank := &recordingBank{}
+		accounts := newStubAccounts()
+		accounts.destinations["user:1"] = banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}
+		accounts.allocations["user:1"] = []account.Allocation{{Portfolio: "index-fund", Percent: "70"}, {Portfolio: "savings", Percent: "30"}}
+		svc, err := New(memory.New(), bank, accounts, nil, clock.NewFake(now), logrus.New(), map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		batchID := ready(t, svc)
+		bankErr := errors.New("bank is down")
+		bank.fail = map[string]error{batchID + "-1": bankErr}
+
+		// act
+		errFirst := svc.Dispatch(context.Background(), batchID)
+		failed, err := svc.Get(context.Background(), batchID)
+		require.NoError(t, err)
+		bank.fail = nil
+		/* allocations changed after the split do not apply to it anymore */
+		accounts.allocations["user:1"] = []account.Allocation{{Portfolio: "savings", Percent: "100"}}
+		errSecond := svc.Dispatch(context.Background(), batchID)
+
+		// assert
+		assert.Equal(t, bankErr, errFirst)
+		assert.Equal(t, Status(StatusReadyToDispatch), failed.Status)
+		assert.Equal(t, Status(StatusReadyToDispatch), failed.Legs[0].Status)
+		assert.Equal(t, "bank is down", failed.Legs[0].Error)
+		assert.Equal(t, Status(StatusDispatched), failed.Legs[1].Status)
+		assert.NoError(t, errSecond)
+		require.Len(t, bank.transfers, 2)
+		assert.Equal(t, batchID+"-2", bank.transfers[0].ID)
+		assert.Equal(t, batchID+"-1", bank.transfers[1].ID)
+		assert.Equal(t, "0.98", bank.transfers[1].Amount)
+		b, err := svc.Get(context.Background(), batchID)
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusDispatched), b.Status)
+		assert.Empty(t, b.Legs[0].Error)
+	})
+}
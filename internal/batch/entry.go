@@ -0,0 +1,59 @@
+package batch
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	_entryCollectionName = "batch_entries"
+)
+
+const (
+	// StrategyCeil rounds the transaction amount up to the next whole unit
+	StrategyCeil = "ceil"
+	// StrategyLegacy marks entries migrated from Batch.TransactionIDs, amounts are unknown for those
+	StrategyLegacy = "legacy"
+	// StrategyRecredit marks funds of a returned batch, the entry has no transaction
+	StrategyRecredit = "recredit"
+)
+
+// Entry is a single transaction contribution to a batch, stored in the ledger collection
+type Entry struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	BatchID       primitive.ObjectID   `bson:"batchId" json:"batchId"`
+	TransactionID string               `bson:"transactionId" json:"transactionId"`
+	Amount        primitive.Decimal128 `bson:"amount,omitempty" json:"amount"`
+	RoundUp       primitive.Decimal128 `bson:"roundUp,omitempty" json:"roundUp"`
+	Strategy      string               `bson:"strategy" json:"strategy"`
+	// ReturnedBatchID is set on re-credited entries only
+	ReturnedBatchID primitive.ObjectID `bson:"returnedBatchId,omitempty" json:"returnedBatchId,omitempty"`
+	CreatedDate     time.Time          `bson:"createdDate" json:"createdDate"`
+	UpdatedDate     time.Time          `bson:"updatedDate" json:"updatedDate"`
+}
+
+func NewEntry(batchID primitive.ObjectID, transactionID string, amount, roundUp primitive.Decimal128, now time.Time) Entry {
+	e := Entry{
+		BatchID:       batchID,
+		TransactionID: transactionID,
+		Amount:        amount,
+		RoundUp:       roundUp,
+		Strategy:      StrategyCeil,
+		CreatedDate:   now,
+		UpdatedDate:   now,
+	}
+	return e
+}
+
+func NewRecreditEntry(batchID, returnedBatchID primitive.ObjectID, amount primitive.Decimal128, now time.Time) Entry {
+	e := Entry{
+		BatchID:         batchID,
+		ReturnedBatchID: returnedBatchID,
+		Amount:          amount,
+		Strategy:        StrategyRecredit,
+		CreatedDate:     now,
+		UpdatedDate:     now,
+	}
+	return e
+}
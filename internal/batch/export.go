@@ -0,0 +1,41 @@
+package batch
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	_exportBatchSize = 500
+)
+
+// Export walks over all batches matching the filter without loading them into memory,
+// iteration stops at the first error returned by fn
+func (c *serviceContext) Export(ctx context.Context, sort Sort, f Filter, fn func(b Batch) error) error {
+	direction := -1
+	if sort.Asc {
+		direction = 1
+	}
+	opt := options.Find().
+		SetSort(bson.D{{sort.Field, direction}, {"_id", direction}}).
+		SetBatchSize(_exportBatchSize)
+
+	cursor, err := c.mongo.Find(ctx, _collectionName, f.sortedQuery(sort.Field), opt)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	for cursor.Next(ctx) {
+		var b Batch
+		if err := cursor.Decode(&b); err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return cursor.Err()
+}
@@ -0,0 +1,263 @@
+package batch
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+var (
+	ErrInvalidSortField = errors.New("batches can be sorted by createdDate, updatedDate, amount or dispatchedDate only")
+	ErrInvalidOrder     = errors.New("order has to be either 1 or -1")
+	ErrInvalidAmount    = errors.New("amount has to be a decimal number")
+)
+
+// ParameterError points at the query parameter which could not be parsed
+type ParameterError struct {
+	Parameter string
+	Err       error
+}
+
+func (e *ParameterError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Parameter, e.Err.Error())
+}
+
+func (e *ParameterError) Unwrap() error {
+	return e.Err
+}
+
+// Filter narrows down listed batches, zero values are not applied
+type Filter struct {
+	UserID         string
+	Status         *Status
+	Currency       *money.Currency
+	AmountMin      string
+	AmountMax      string
+	CreatedFrom    time.Time
+	CreatedTo      time.Time
+	DispatchedFrom time.Time
+	DispatchedTo   time.Time
+	// DispatchReference is the reference the bank has accepted the transfer with
+	DispatchReference string
+	// Flag of the batches held back from dispatching
+	Flag string
+	// Goal the round-ups of the batches are saved for
+	Goal string
+}
+
+// FilterFrom parses query parameters shared by all endpoints listing batches
+func FilterFrom(query url.Values) (Filter, error) {
+	f := Filter{UserID: query.Get("userId"), DispatchReference: query.Get("dispatchReference"), Flag: query.Get("flag"), Goal: query.Get("goal")}
+	if v := query.Get("status"); v != "" {
+		status, err := NewStatusFrom(v)
+		if err != nil {
+			return Filter{}, &ParameterError{Parameter: "status", Err: err}
+		}
+		f.Status = &status
+	}
+	if v := query.Get("currency"); v != "" {
+		currency, err := money.CurrencyFrom(v)
+		if err != nil {
+			return Filter{}, &ParameterError{Parameter: "currency", Err: err}
+		}
+		f.Currency = &currency
+	}
+
+	amounts := []struct {
+		parameter string
+		dst       *string
+	}{
+		{"amountMin", &f.AmountMin},
+		{"amountMax", &f.AmountMax},
+	}
+	for _, a := range amounts {
+		v := query.Get(a.parameter)
+		if v == "" {
+			continue
+		}
+		if _, err := primitive.ParseDecimal128(v); err != nil {
+			return Filter{}, &ParameterError{Parameter: a.parameter, Err: ErrInvalidAmount}
+		}
+		*a.dst = v
+	}
+
+	dates := []struct {
+		parameter string
+		dst       *time.Time
+	}{
+		/* from and to are the names the user history was filtered by at first, createdFrom and createdTo win over them */
+		{"from", &f.CreatedFrom},
+		{"to", &f.CreatedTo},
+		{"createdFrom", &f.CreatedFrom},
+		{"createdTo", &f.CreatedTo},
+		{"dispatchedFrom", &f.DispatchedFrom},
+		{"dispatchedTo", &f.DispatchedTo},
+	}
+	for _, d := range dates {
+		v := query.Get(d.parameter)
+		if v == "" {
+			continue
+		}
+		date, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return Filter{}, &ParameterError{Parameter: d.parameter, Err: err}
+		}
+		*d.dst = date.UTC()
+	}
+	return f, nil
+}
+
+// Applied lists filters which are in use, in the same format they are parsed from
+func (f Filter) Applied() map[string]string {
+	applied := make(map[string]string)
+	if f.UserID != "" {
+		applied["userId"] = f.UserID
+	}
+	if f.Status != nil {
+		applied["status"] = string(*f.Status)
+	}
+	if f.Currency != nil {
+		applied["currency"] = f.Currency.String()
+	}
+	if f.DispatchReference != "" {
+		applied["dispatchReference"] = f.DispatchReference
+	}
+	if f.Flag != "" {
+		applied["flag"] = f.Flag
+	}
+	if f.Goal != "" {
+		applied["goal"] = f.Goal
+	}
+	if f.AmountMin != "" {
+		applied["amountMin"] = f.AmountMin
+	}
+	if f.AmountMax != "" {
+		applied["amountMax"] = f.AmountMax
+	}
+	dates := map[string]time.Time{
+		"createdFrom":    f.CreatedFrom,
+		"createdTo":      f.CreatedTo,
+		"dispatchedFrom": f.DispatchedFrom,
+		"dispatchedTo":   f.DispatchedTo,
+	}
+	for k, v := range dates {
+		if !v.IsZero() {
+			applied[k] = v.Format(time.RFC3339)
+		}
+	}
+	return applied
+}
+
+func (f Filter) query() bson.D {
+	query := bson.D{}
+	if f.UserID != "" {
+		query = append(query, bson.E{"userId", f.UserID})
+	}
+	if f.Status != nil {
+		query = append(query, bson.E{"status", *f.Status})
+	}
+	if f.Currency != nil {
+		query = append(query, bson.E{"currency", *f.Currency})
+	}
+	if f.DispatchReference != "" {
+		query = append(query, bson.E{"dispatchReference", f.DispatchReference})
+	}
+	if f.Flag != "" {
+		query = append(query, bson.E{"flag", f.Flag})
+	}
+	if f.Goal != "" {
+		query = append(query, bson.E{"goal", f.Goal})
+	}
+	if amount := amountRange(f.AmountMin, f.AmountMax); len(amount) > 0 {
+		query = append(query, bson.E{"amount", amount})
+	}
+	if created := dateRange(f.CreatedFrom, f.CreatedTo); len(created) > 0 {
+		query = append(query, bson.E{"createdDate", created})
+	}
+	if dispatched := dateRange(f.DispatchedFrom, f.DispatchedTo); len(dispatched) > 0 {
+		query = append(query, bson.E{"dispatchedDate", dispatched})
+	}
+	return query
+}
+
+// sortedQuery is a query of batches which can be placed on the keyset of given field,
+// batches which were never dispatched do not have a dispatchedDate
+func (f Filter) sortedQuery(field string) bson.D {
+	query := f.query()
+	if field == "dispatchedDate" && f.DispatchedFrom.IsZero() && f.DispatchedTo.IsZero() {
+		query = append(query, bson.E{"dispatchedDate", bson.D{{"$exists", true}}})
+	}
+	return query
+}
+
+// amountRange builds inclusive bounds condition, amounts are validated while parsing the filter
+func amountRange(min, max string) bson.D {
+	condition := bson.D{}
+	if min != "" {
+		d, _ := primitive.ParseDecimal128(min)
+		condition = append(condition, bson.E{"$gte", d})
+	}
+	if max != "" {
+		d, _ := primitive.ParseDecimal128(max)
+		condition = append(condition, bson.E{"$lte", d})
+	}
+	return condition
+}
+
+// dateRange builds inclusive lower and exclusive upper bound condition
+func dateRange(from, to time.Time) bson.D {
+	condition := bson.D{}
+	if !from.IsZero() {
+		condition = append(condition, bson.E{"$gte", from})
+	}
+	if !to.IsZero() {
+		condition = append(condition, bson.E{"$lt", to})
+	}
+	return condition
+}
+
+// Sort of listed batches, _id is always used as a tie breaker
+type Sort struct {
+	Field string
+	Asc   bool
+}
+
+var sortFields = map[string]func(b Batch) interface{}{
+	"createdDate":    func(b Batch) interface{} { return b.CreatedDate },
+	"updatedDate":    func(b Batch) interface{} { return b.UpdatedDate },
+	"amount":         func(b Batch) interface{} { return b.Amount },
+	"dispatchedDate": func(b Batch) interface{} { return b.DispatchedDate },
+}
+
+// SortFrom parses sort field and order, defaults to the newest batches first
+func SortFrom(field, order string) (Sort, error) {
+	s := Sort{Field: "createdDate"}
+	if field != "" {
+		if _, exists := sortFields[field]; !exists {
+			return Sort{}, &ParameterError{Parameter: "sort", Err: ErrInvalidSortField}
+		}
+		s.Field = field
+	}
+	switch order {
+	case "", "-1":
+		s.Asc = false
+	case "1":
+		s.Asc = true
+	default:
+		return Sort{}, &ParameterError{Parameter: "order", Err: ErrInvalidOrder}
+	}
+	return s, nil
+}
+
+func (s Sort) String() string {
+	if s.Asc {
+		return s.Field
+	}
+	return "-" + s.Field
+}
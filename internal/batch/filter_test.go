@@ -0,0 +1,187 @@
+package batch
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+func TestFilterQuery(t *testing.T) {
+	status := Status(StatusDispatched)
+	currency := money.Currency("USD")
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC)
+	decimal := func(v string) primitive.Decimal128 {
+		d, _ := primitive.ParseDecimal128(v)
+		return d
+	}
+
+	tests := []struct {
+		name string
+		give Filter
+		want bson.D
+	}{
+		{
+			name: "empty filter",
+			give: Filter{},
+			want: bson.D{},
+		},
+		{
+			name: "user with status and currency",
+			give: Filter{UserID: "11", Status: &status, Currency: &currency},
+			want: bson.D{{"userId", "11"}, {"status", status}, {"currency", currency}},
+		},
+		{
+			name: "created from only",
+			give: Filter{CreatedFrom: from},
+			want: bson.D{{"createdDate", bson.D{{"$gte", from}}}},
+		},
+		{
+			name: "amount and dispatched range",
+			give: Filter{AmountMin: "1.5", DispatchedTo: to},
+			want: bson.D{{"amount", bson.D{{"$gte", decimal("1.5")}}}, {"dispatchedDate", bson.D{{"$lt", to}}}},
+		},
+		{
+			name: "dispatch reference",
+			give: Filter{DispatchReference: "ref-1"},
+			want: bson.D{{"dispatchReference", "ref-1"}},
+		},
+		{
+			name: "flag",
+			give: Filter{Flag: FlagNoDestination},
+			want: bson.D{{"flag", FlagNoDestination}},
+		},
+		{
+			name: "goal",
+			give: Filter{Goal: "5f1b0c1e8f1b2c3d4e5f6a7b"},
+			want: bson.D{{"goal", "5f1b0c1e8f1b2c3d4e5f6a7b"}},
+		},
+		{
+			name: "created range",
+			give: Filter{UserID: "11", CreatedFrom: from, CreatedTo: to},
+			want: bson.D{{"userId", "11"}, {"createdDate", bson.D{{"$gte", from}, {"$lt", to}}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.give.query())
+		})
+	}
+}
+
+func TestFilterFrom(t *testing.T) {
+	t.Run("should return parameter error, invalid status", func(t *testing.T) {
+		// act
+		_, err := FilterFrom(url.Values{"status": {"dispatchd"}})
+
+		// assert
+		var paramErr *ParameterError
+		assert.True(t, errors.As(err, &paramErr))
+		assert.Equal(t, "status", paramErr.Parameter)
+		assert.Equal(t, ErrInvalidStatus, paramErr.Err)
+	})
+
+	t.Run("should return parameter error, invalid amount", func(t *testing.T) {
+		// act
+		_, err := FilterFrom(url.Values{"amountMax": {"ten"}})
+
+		// assert
+		var paramErr *ParameterError
+		assert.True(t, errors.As(err, &paramErr))
+		assert.Equal(t, "amountMax", paramErr.Parameter)
+		assert.Equal(t, ErrInvalidAmount, paramErr.Err)
+	})
+
+	t.Run("should return parameter error, invalid date", func(t *testing.T) {
+		// act
+		_, err := FilterFrom(url.Values{"dispatchedTo": {"yesterday"}})
+
+		// assert
+		var paramErr *ParameterError
+		assert.True(t, errors.As(err, &paramErr))
+		assert.Equal(t, "dispatchedTo", paramErr.Parameter)
+	})
+
+	t.Run("should parse from and to as the created range", func(t *testing.T) {
+		// act
+		aliased, err := FilterFrom(url.Values{"from": {"2021-01-01T00:00:00Z"}, "to": {"2021-02-01T00:00:00Z"}})
+		assert.NoError(t, err)
+		both, err := FilterFrom(url.Values{"from": {"2021-01-01T00:00:00Z"}, "createdFrom": {"2021-01-10T00:00:00Z"}})
+		assert.NoError(t, err)
+		_, invalid := FilterFrom(url.Values{"to": {"tomorrow"}})
+
+		// assert
+		assert.Equal(t, time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), aliased.CreatedFrom)
+		assert.Equal(t, time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), aliased.CreatedTo)
+		assert.Equal(t, time.Date(2021, 1, 10, 0, 0, 0, 0, time.UTC), both.CreatedFrom)
+		var paramErr *ParameterError
+		assert.True(t, errors.As(invalid, &paramErr))
+		assert.Equal(t, "to", paramErr.Parameter)
+	})
+
+	t.Run("should parse all filters", func(t *testing.T) {
+		// arrange
+		give := url.Values{
+			"userId":            {"11"},
+			"status":            {"dispatched"},
+			"currency":          {"usd"},
+			"amountMin":         {"1.5"},
+			"amountMax":         {"200"},
+			"createdFrom":       {"2021-01-01T00:00:00Z"},
+			"createdTo":         {"2021-02-01T00:00:00Z"},
+			"dispatchedFrom":    {"2021-01-15T00:00:00+01:00"},
+			"dispatchedTo":      {"2021-02-15T00:00:00Z"},
+			"dispatchReference": {"ref-1"},
+			"flag":              {"no-destination"},
+		}
+
+		// act
+		result, err := FilterFrom(give)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "11", result.UserID)
+		assert.Equal(t, Status(StatusDispatched), *result.Status)
+		assert.Equal(t, money.Currency("USD"), *result.Currency)
+		assert.Equal(t, "1.5", result.AmountMin)
+		assert.Equal(t, "200", result.AmountMax)
+		assert.Equal(t, time.Date(2021, 1, 14, 23, 0, 0, 0, time.UTC), result.DispatchedFrom)
+		applied := result.Applied()
+		assert.Equal(t, "ref-1", result.DispatchReference)
+		assert.Equal(t, FlagNoDestination, result.Flag)
+		assert.Len(t, applied, 11)
+		assert.Equal(t, "USD", applied["currency"])
+		assert.Equal(t, "2021-01-14T23:00:00Z", applied["dispatchedFrom"])
+	})
+}
+
+func TestSortFrom(t *testing.T) {
+	tests := []struct {
+		giveField string
+		giveOrder string
+		want      Sort
+		wantError error
+	}{
+		{giveField: "", giveOrder: "", want: Sort{Field: "createdDate"}},
+		{giveField: "amount", giveOrder: "1", want: Sort{Field: "amount", Asc: true}},
+		{giveField: "dispatchedDate", giveOrder: "-1", want: Sort{Field: "dispatchedDate"}},
+		{giveField: "userId", giveOrder: "1", wantError: ErrInvalidSortField},
+		{giveField: "updatedDate", giveOrder: "asc", wantError: ErrInvalidOrder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.giveField+" "+tt.giveOrder, func(t *testing.T) {
+			result, err := SortFrom(tt.giveField, tt.giveOrder)
+			assert.Equal(t, tt.want, result)
+			assert.True(t, errors.Is(err, tt.wantError))
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package batch
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrNotUndispatched = errors.New("only undispatched batch can be forced to ready")
+)
+
+// ForceReady marks the batch as ready to dispatch even though it did not reach the threshold
+func (c *serviceContext) ForceReady(ctx context.Context, batchID string) (Batch, error) {
+	b, err := c.Get(ctx, batchID)
+	if err != nil {
+		return Batch{}, err
+	}
+	if b.Status != StatusUndispatched {
+		return Batch{}, ErrNotUndispatched
+	}
+
+	b.Status = StatusReadyToDispatch
+	b.UpdatedDate = c.clock.Now()
+	b.History = append(b.History, StatusChange{Status: b.Status, Date: b.UpdatedDate})
+
+	/* status is a part of the filter, a transaction could have filled the batch up in the meantime */
+	filter := bson.D{{"_id", b.ID}, {"status", StatusUndispatched}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"status", b.Status},
+			{"updatedDate", b.UpdatedDate},
+		}},
+		{"$push", bson.D{{"history", StatusChange{Status: b.Status, Date: b.UpdatedDate}}}},
+	}
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return Batch{}, err
+	}
+	return b, nil
+}
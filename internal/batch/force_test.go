@@ -0,0 +1,69 @@
+package batch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	mockMongodb "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+func TestForceReady(t *testing.T) {
+	t.Run("should return not undispatched error", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Decode(gomock.Any()).Do(func(b *Batch) {
+			b.ID = batchID
+			b.Status = StatusDispatched
+		}).Return(nil)
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, bson.D{{"_id", batchID}}).Return(singleResult)
+
+		// act
+		_, err = svc.ForceReady(context.Background(), batchID.Hex())
+
+		// assert
+		assert.Equal(t, ErrNotUndispatched, err)
+	})
+
+	t.Run("should mark batch as ready to dispatch", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Decode(gomock.Any()).Do(func(b *Batch) {
+			b.ID = batchID
+			b.Status = StatusUndispatched
+		}).Return(nil)
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, bson.D{{"_id", batchID}}).Return(singleResult)
+		mockMongoClient.EXPECT().UpdateOne(gomock.Any(), _collectionName, bson.D{{"_id", batchID}, {"status", StatusUndispatched}}, gomock.Any()).Return(nil)
+
+		// act
+		result, err := svc.ForceReady(context.Background(), batchID.Hex())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusReadyToDispatch), result.Status)
+		assert.Len(t, result.History, 1)
+	})
+}
@@ -0,0 +1,64 @@
+package batch
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var (
+	ErrInvalidBatchID = errors.New("batch id is not a valid object id")
+	ErrBatchNotFound  = errors.New("batch was not found")
+	ErrInvalidOffset  = errors.New("offset can not be negative")
+)
+
+func (c *serviceContext) Get(ctx context.Context, batchID string) (Batch, error) {
+	if batchID == "" {
+		return Batch{}, ErrNoBatchID
+	}
+	ID, err := primitive.ObjectIDFromHex(batchID)
+	if err != nil {
+		return Batch{}, ErrInvalidBatchID
+	}
+
+	var b Batch
+	filter := bson.D{{"_id", ID}}
+	if err := c.mongo.FindOne(ctx, _collectionName, filter).Decode(&b); err != nil {
+		if errors.Is(err, mongoOrg.ErrNoDocuments) {
+			return Batch{}, ErrBatchNotFound
+		}
+		return Batch{}, err
+	}
+	return b, nil
+}
+
+// Entries returns ledger entries of the batch in the order they were batched
+func (c *serviceContext) Entries(ctx context.Context, batchID primitive.ObjectID, limit, offset int) ([]Entry, error) {
+	if limit < 1 || limit > MaxLimit {
+		return nil, ErrInvalidLimit
+	}
+	if offset < 0 {
+		return nil, ErrInvalidOffset
+	}
+	filter := bson.D{{"batchId", batchID}}
+	opt := options.Find().SetSort(bson.D{{"createdDate", 1}}).SetSkip(int64(offset)).SetLimit(int64(limit))
+
+	cursor, err := c.mongo.Find(ctx, _entryCollectionName, filter, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var entries []Entry
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, err
+	}
+	if entries == nil {
+		entries = make([]Entry, 0)
+	}
+	return entries, nil
+}
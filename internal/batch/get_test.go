@@ -0,0 +1,135 @@
+package batch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+
+	mockMongodb "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+func TestGet(t *testing.T) {
+	t.Run("should return no batch id error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+
+		// act
+		_, err = svc.Get(context.Background(), "")
+
+		// assert
+		assert.Equal(t, ErrNoBatchID, err)
+	})
+
+	t.Run("should return invalid batch id error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+
+		// act
+		_, err = svc.Get(context.Background(), "invalid")
+
+		// assert
+		assert.Equal(t, ErrInvalidBatchID, err)
+	})
+
+	t.Run("should return batch not found error", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Decode(gomock.Any()).Return(mongoOrg.ErrNoDocuments)
+		filter := bson.D{{"_id", batchID}}
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
+
+		// act
+		_, err = svc.Get(context.Background(), batchID.Hex())
+
+		// assert
+		assert.Equal(t, ErrBatchNotFound, err)
+	})
+
+	t.Run("should return batch", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Decode(gomock.Any()).Do(func(b *Batch) {
+			b.ID = batchID
+			b.UserID = "11"
+			b.DispatchReference = "reference"
+		}).Return(nil)
+		filter := bson.D{{"_id", batchID}}
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(singleResult)
+
+		// act
+		result, err := svc.Get(context.Background(), batchID.Hex())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, batchID, result.ID)
+		assert.Equal(t, "reference", result.DispatchReference)
+	})
+}
+
+func TestEntries(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		offset int
+		want   error
+	}{
+		{name: "should return invalid limit error, limit is zero", limit: 0, want: ErrInvalidLimit},
+		{name: "should return invalid limit error, limit is negative", limit: -1, want: ErrInvalidLimit},
+		{name: "should return invalid limit error, limit is over the max", limit: MaxLimit + 1, want: ErrInvalidLimit},
+		{name: "should return invalid offset error", limit: 10, offset: -10, want: ErrInvalidOffset},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			mockCtrl := gomock.NewController(t)
+			defer mockCtrl.Finish()
+			mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+			svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+			assert.NoError(t, err)
+
+			// expected calls
+
+			// act
+			_, err = svc.Entries(context.Background(), primitive.NewObjectID(), tt.limit, tt.offset)
+
+			// assert
+			assert.Equal(t, tt.want, err)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package batch
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrTransactionNotFound = errors.New("transaction was not found")
+)
+
+// TransactionDetails tells where the round-up of a single transaction ended up
+type TransactionDetails struct {
+	TransactionID  string               `json:"transactionId"`
+	RoundUp        primitive.Decimal128 `json:"roundUp"`
+	BatchID        primitive.ObjectID   `json:"batchId"`
+	BatchStatus    Status               `json:"batchStatus"`
+	DispatchedDate time.Time            `json:"dispatchedDate"`
+	RecordedDate   time.Time            `json:"recordedDate"`
+}
+
+func (c *serviceContext) FindTransaction(ctx context.Context, transactionID string) (TransactionDetails, error) {
+	if transactionID == "" {
+		return TransactionDetails{}, ErrNoTransactionID
+	}
+
+	var e Entry
+	filter := bson.D{{"transactionId", transactionID}}
+	if err := c.mongo.FindOne(ctx, _entryCollectionName, filter).Decode(&e); err != nil {
+		if errors.Is(err, mongoOrg.ErrNoDocuments) {
+			return TransactionDetails{}, ErrTransactionNotFound
+		}
+		return TransactionDetails{}, err
+	}
+
+	var b Batch
+	filter = bson.D{{"_id", e.BatchID}}
+	if err := c.mongo.FindOne(ctx, _collectionName, filter).Decode(&b); err != nil {
+		/* entry without a batch means the ledger is out of sync, it should not be reported as not found */
+		return TransactionDetails{}, err
+	}
+
+	d := TransactionDetails{
+		TransactionID:  e.TransactionID,
+		RoundUp:        e.RoundUp,
+		BatchID:        b.ID,
+		BatchStatus:    b.Status,
+		DispatchedDate: b.DispatchedDate,
+		RecordedDate:   e.CreatedDate,
+	}
+	return d, nil
+}
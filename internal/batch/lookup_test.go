@@ -0,0 +1,96 @@
+package batch
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+
+	mockMongodb "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+func TestFindTransaction(t *testing.T) {
+	t.Run("should return no transaction id error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+
+		// act
+		_, err = svc.FindTransaction(context.Background(), "")
+
+		// assert
+		assert.Equal(t, ErrNoTransactionID, err)
+	})
+
+	t.Run("should return transaction not found error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Decode(gomock.Any()).Return(mongoOrg.ErrNoDocuments)
+		filter := bson.D{{"transactionId", "1"}}
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _entryCollectionName, filter).Return(singleResult)
+
+		// act
+		_, err = svc.FindTransaction(context.Background(), "1")
+
+		// assert
+		assert.Equal(t, ErrTransactionNotFound, err)
+	})
+
+	t.Run("should return transaction details", func(t *testing.T) {
+		// arrange
+		batchID := primitive.NewObjectID()
+		roundUp, _ := primitive.ParseDecimal128("0.89")
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
+		assert.NoError(t, err)
+
+		// expected calls
+		entryResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		entryResult.EXPECT().Decode(gomock.Any()).Do(func(e *Entry) {
+			e.BatchID = batchID
+			e.TransactionID = "1"
+			e.RoundUp = roundUp
+		}).Return(nil)
+		filter := bson.D{{"transactionId", "1"}}
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _entryCollectionName, filter).Return(entryResult)
+
+		batchResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		batchResult.EXPECT().Decode(gomock.Any()).Do(func(b *Batch) {
+			b.ID = batchID
+			b.Status = StatusReadyToDispatch
+		}).Return(nil)
+		filter = bson.D{{"_id", batchID}}
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, filter).Return(batchResult)
+
+		// act
+		result, err := svc.FindTransaction(context.Background(), "1")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "1", result.TransactionID)
+		assert.Equal(t, "0.89", result.RoundUp.String())
+		assert.Equal(t, batchID, result.BatchID)
+		assert.Equal(t, Status(StatusReadyToDispatch), result.BatchStatus)
+	})
+}
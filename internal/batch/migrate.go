@@ -0,0 +1,77 @@
+package batch
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+)
+
+// legacyBatch is the shape of batches stored before transaction references were moved to the ledger collection
+type legacyBatch struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	TransactionIDs []string           `bson:"transactionIds"`
+	CreatedDate    time.Time          `bson:"createdDate"`
+}
+
+// Migrate moves transactionIds of every legacy batch into batch_entries, one batch per mongo transaction
+func (c *serviceContext) Migrate(ctx context.Context) error {
+	filter := bson.D{{"transactionIds", bson.D{{"$exists", true}}}}
+	opt := options.Find().SetProjection(bson.D{{"transactionIds", 1}, {"createdDate", 1}})
+
+	cursor, err := c.mongo.Find(ctx, _collectionName, filter, opt)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	for cursor.Next(ctx) {
+		var b legacyBatch
+		if err := cursor.Decode(&b); err != nil {
+			return err
+		}
+		if _, err := c.mongo.WithinTransaction(ctx, c.migrateCallback(b)); err != nil {
+			return errors.Wrap(err, fmt.Sprintf("could not migrate batch %s", b.ID.Hex()))
+		}
+	}
+	return cursor.Err()
+}
+
+func (c *serviceContext) migrateCallback(b legacyBatch) mongodb.TransactionCallback {
+	return func(sessCtx mongoOrg.SessionContext) (interface{}, error) {
+		if len(b.TransactionIDs) > 0 {
+			now := c.clock.Now()
+			entries := make([]interface{}, 0, len(b.TransactionIDs))
+			for _, transactionID := range b.TransactionIDs {
+				e := Entry{
+					BatchID:       b.ID,
+					TransactionID: transactionID,
+					Strategy:      StrategyLegacy,
+					CreatedDate:   b.CreatedDate,
+					UpdatedDate:   now,
+				}
+				entries = append(entries, e)
+			}
+			if err := c.mongo.InsertMany(sessCtx, _entryCollectionName, entries); err != nil {
+				return nil, err
+			}
+		}
+
+		filter := bson.D{{"_id", b.ID}}
+		update := bson.D{
+			{"$set", bson.D{{"transactionCount", len(b.TransactionIDs)}}},
+			{"$unset", bson.D{{"transactionIds", ""}}},
+		}
+		if err := c.mongo.UpdateOne(sessCtx, _collectionName, filter, update); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+}
@@ -0,0 +1,110 @@
+package batch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+
+	mockMongodb "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+func TestMigrateCallback(t *testing.T) {
+	t.Run("should return insert many error", func(t *testing.T) {
+		// arrange
+		give := legacyBatch{ID: primitive.NewObjectID(), TransactionIDs: []string{"1", "2"}}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svcCtx := serviceContext{
+			mongo:   mockMongoClient,
+			bankSDK: banksdk.New(),
+			clock:   clock.New(),
+			logger:  logrus.New(),
+		}
+
+		// expected calls
+		mockMongoClient.EXPECT().InsertMany(gomock.Any(), _entryCollectionName, gomock.Any()).Return(mongoOrg.ErrClientDisconnected)
+
+		// act
+		_, err := svcCtx.migrateCallback(give)(nil)
+
+		// assert
+		assert.Error(t, err, mongoOrg.ErrClientDisconnected)
+	})
+
+	t.Run("should move transaction ids to entries and unset them", func(t *testing.T) {
+		// arrange
+		created := time.Now().UTC().Add(-time.Hour)
+		give := legacyBatch{ID: primitive.NewObjectID(), TransactionIDs: []string{"1", "2"}, CreatedDate: created}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svcCtx := serviceContext{
+			mongo:   mockMongoClient,
+			bankSDK: banksdk.New(),
+			clock:   clock.New(),
+			logger:  logrus.New(),
+		}
+
+		// expected calls
+		mockMongoClient.EXPECT().InsertMany(gomock.Any(), _entryCollectionName, gomock.Any()).Do(func(_ context.Context, _ string, docs []interface{}) {
+			assert.Len(t, docs, 2)
+			for i, doc := range docs {
+				e := doc.(Entry)
+				assert.Equal(t, give.ID, e.BatchID)
+				assert.Equal(t, give.TransactionIDs[i], e.TransactionID)
+				assert.Equal(t, StrategyLegacy, e.Strategy)
+				assert.Equal(t, created, e.CreatedDate)
+			}
+		}).Return(nil)
+		filter := bson.D{{"_id", give.ID}}
+		update := bson.D{
+			{"$set", bson.D{{"transactionCount", 2}}},
+			{"$unset", bson.D{{"transactionIds", ""}}},
+		}
+		mockMongoClient.EXPECT().UpdateOne(gomock.Any(), _collectionName, filter, update).Return(nil)
+
+		// act
+		_, err := svcCtx.migrateCallback(give)(nil)
+
+		// assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should only unset empty transaction ids", func(t *testing.T) {
+		// arrange
+		give := legacyBatch{ID: primitive.NewObjectID(), TransactionIDs: []string{}}
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svcCtx := serviceContext{
+			mongo:   mockMongoClient,
+			bankSDK: banksdk.New(),
+			clock:   clock.New(),
+			logger:  logrus.New(),
+		}
+
+		// expected calls
+		filter := bson.D{{"_id", give.ID}}
+		update := bson.D{
+			{"$set", bson.D{{"transactionCount", 0}}},
+			{"$unset", bson.D{{"transactionIds", ""}}},
+		}
+		mockMongoClient.EXPECT().UpdateOne(gomock.Any(), _collectionName, filter, update).Return(nil)
+
+		// act
+		_, err := svcCtx.migrateCallback(give)(nil)
+
+		// assert
+		assert.NoError(t, err)
+	})
+}
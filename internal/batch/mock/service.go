@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	batch "github.com/mazxaxz/donut-batcher/internal/batch"
 	transaction "github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+	primitive "go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 // MockService is a mock of Service interface.
@@ -51,6 +52,36 @@ func (mr *MockServiceMockRecorder) Batch(arg0, arg1 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Batch", reflect.TypeOf((*MockService)(nil).Batch), arg0, arg1)
 }
 
+// Browse mocks base method.
+func (m *MockService) Browse(arg0 context.Context, arg1 int, arg2 batch.Sort, arg3 string, arg4 batch.Filter) (batch.Page, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Browse", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(batch.Page)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Browse indicates an expected call of Browse.
+func (mr *MockServiceMockRecorder) Browse(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Browse", reflect.TypeOf((*MockService)(nil).Browse), arg0, arg1, arg2, arg3, arg4)
+}
+
+// Confirm mocks base method.
+func (m *MockService) Confirm(arg0 context.Context, arg1 batch.Confirmation) (batch.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Confirm", arg0, arg1)
+	ret0, _ := ret[0].(batch.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Confirm indicates an expected call of Confirm.
+func (mr *MockServiceMockRecorder) Confirm(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Confirm", reflect.TypeOf((*MockService)(nil).Confirm), arg0, arg1)
+}
+
 // Dispatch mocks base method.
 func (m *MockService) Dispatch(arg0 context.Context, arg1 string) error {
 	m.ctrl.T.Helper()
@@ -65,6 +96,95 @@ func (mr *MockServiceMockRecorder) Dispatch(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Dispatch", reflect.TypeOf((*MockService)(nil).Dispatch), arg0, arg1)
 }
 
+// Entries mocks base method.
+func (m *MockService) Entries(arg0 context.Context, arg1 primitive.ObjectID, arg2, arg3 int) ([]batch.Entry, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Entries", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].([]batch.Entry)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Entries indicates an expected call of Entries.
+func (mr *MockServiceMockRecorder) Entries(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Entries", reflect.TypeOf((*MockService)(nil).Entries), arg0, arg1, arg2, arg3)
+}
+
+// Export mocks base method.
+func (m *MockService) Export(arg0 context.Context, arg1 batch.Sort, arg2 batch.Filter, arg3 func(batch.Batch) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Export", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Export indicates an expected call of Export.
+func (mr *MockServiceMockRecorder) Export(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Export", reflect.TypeOf((*MockService)(nil).Export), arg0, arg1, arg2, arg3)
+}
+
+// FindTransaction mocks base method.
+func (m *MockService) FindTransaction(arg0 context.Context, arg1 string) (batch.TransactionDetails, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTransaction", arg0, arg1)
+	ret0, _ := ret[0].(batch.TransactionDetails)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTransaction indicates an expected call of FindTransaction.
+func (mr *MockServiceMockRecorder) FindTransaction(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTransaction", reflect.TypeOf((*MockService)(nil).FindTransaction), arg0, arg1)
+}
+
+// ForceReady mocks base method.
+func (m *MockService) ForceReady(arg0 context.Context, arg1 string) (batch.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ForceReady", arg0, arg1)
+	ret0, _ := ret[0].(batch.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ForceReady indicates an expected call of ForceReady.
+func (mr *MockServiceMockRecorder) ForceReady(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ForceReady", reflect.TypeOf((*MockService)(nil).ForceReady), arg0, arg1)
+}
+
+// Get mocks base method.
+func (m *MockService) Get(arg0 context.Context, arg1 string) (batch.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", arg0, arg1)
+	ret0, _ := ret[0].(batch.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockServiceMockRecorder) Get(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockService)(nil).Get), arg0, arg1)
+}
+
+// GoalSummary mocks base method.
+func (m *MockService) GoalSummary(arg0 context.Context, arg1 string) ([]batch.GoalSummary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GoalSummary", arg0, arg1)
+	ret0, _ := ret[0].([]batch.GoalSummary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GoalSummary indicates an expected call of GoalSummary.
+func (mr *MockServiceMockRecorder) GoalSummary(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GoalSummary", reflect.TypeOf((*MockService)(nil).GoalSummary), arg0, arg1)
+}
+
 // Index mocks base method.
 func (m *MockService) Index(arg0 context.Context) {
 	m.ctrl.T.Helper()
@@ -77,8 +197,22 @@ func (mr *MockServiceMockRecorder) Index(arg0 interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Index", reflect.TypeOf((*MockService)(nil).Index), arg0)
 }
 
+// Migrate mocks base method.
+func (m *MockService) Migrate(arg0 context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Migrate", arg0)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Migrate indicates an expected call of Migrate.
+func (mr *MockServiceMockRecorder) Migrate(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Migrate", reflect.TypeOf((*MockService)(nil).Migrate), arg0)
+}
+
 // Paginate mocks base method.
-func (m *MockService) Paginate(arg0 context.Context, arg1, arg2 int, arg3 bool, arg4 *batch.Status) ([]batch.Batch, error) {
+func (m *MockService) Paginate(arg0 context.Context, arg1, arg2 int, arg3 batch.Sort, arg4 batch.Filter) ([]batch.Batch, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "Paginate", arg0, arg1, arg2, arg3, arg4)
 	ret0, _ := ret[0].([]batch.Batch)
@@ -91,3 +225,33 @@ func (mr *MockServiceMockRecorder) Paginate(arg0, arg1, arg2, arg3, arg4 interfa
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Paginate", reflect.TypeOf((*MockService)(nil).Paginate), arg0, arg1, arg2, arg3, arg4)
 }
+
+// ReleaseGoal mocks base method.
+func (m *MockService) ReleaseGoal(arg0 context.Context, arg1, arg2 string) ([]batch.Batch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseGoal", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]batch.Batch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ReleaseGoal indicates an expected call of ReleaseGoal.
+func (mr *MockServiceMockRecorder) ReleaseGoal(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseGoal", reflect.TypeOf((*MockService)(nil).ReleaseGoal), arg0, arg1, arg2)
+}
+
+// Summary mocks base method.
+func (m *MockService) Summary(arg0 context.Context, arg1 string) ([]batch.Summary, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Summary", arg0, arg1)
+	ret0, _ := ret[0].([]batch.Summary)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Summary indicates an expected call of Summary.
+func (mr *MockServiceMockRecorder) Summary(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Summary", reflect.TypeOf((*MockService)(nil).Summary), arg0, arg1)
+}
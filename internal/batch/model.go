@@ -1,8 +1,12 @@
 package batch
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pkg/errors"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	"github.com/mazxaxz/donut-batcher/pkg/money"
@@ -10,46 +14,127 @@ import (
 
 type Status string
 
+var (
+	ErrInvalidStatus = errors.New("status has to be one of: undispatched, ready-to-dispatch, dispatched, settled, returned")
+)
+
 const (
 	StatusUndispatched    = "undispatched"
 	StatusReadyToDispatch = "ready-to-dispatch"
 	StatusDispatched      = "dispatched"
+	// StatusSettled means the bank has confirmed the funds reached the user
+	StatusSettled = "settled"
+	// StatusReturned means the bank has sent the funds back, they are re-credited to an undispatched batch
+	StatusReturned = "returned"
+)
+
+const (
+	// FlagNoDestination holds the batch back from dispatching until the user has a verified account
+	FlagNoDestination = "no-destination"
 )
 
 type Batch struct {
-	ID             primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
-	UserID         string               `bson:"userId" json:"userId"`
-	Amount         primitive.Decimal128 `bson:"amount", json:"amount"`
-	Currency       money.Currency       `bson:"currency", json:"currency"`
-	TransactionIDs []string             `bson:"transactionIds" json:"transactionIds"`
-	Status         Status               `bson:"status" json:"status"`
-	CreatedDate    time.Time            `bson:"createdDate" json:"createdDate"`
-	UpdatedDate    time.Time            `bson:"updatedDate" json:"updatedDate"`
-	DispatchedDate time.Time            `bson:"dispatchedDate,omitempty" json:"dispatchedDate"`
-}
-
-func NewBatch(userID string, currency money.Currency) Batch {
+	ID                primitive.ObjectID   `bson:"_id,omitempty" json:"id"`
+	UserID            string               `bson:"userId" json:"userId"`
+	Amount            primitive.Decimal128 `bson:"amount" json:"amount"`
+	Currency          money.Currency       `bson:"currency" json:"currency"`
+	Status            Status               `bson:"status" json:"status"`
+	CreatedDate       time.Time            `bson:"createdDate" json:"createdDate"`
+	UpdatedDate       time.Time            `bson:"updatedDate" json:"updatedDate"`
+	DispatchedDate    time.Time            `bson:"dispatchedDate,omitempty" json:"dispatchedDate"`
+	TransactionCount  int                  `bson:"transactionCount" json:"transactionCount"`
+	DispatchReference string               `bson:"dispatchReference,omitempty" json:"dispatchReference,omitempty"`
+	DispatchFile      string               `bson:"dispatchFile,omitempty" json:"dispatchFile,omitempty"`
+	EndToEndID        string               `bson:"endToEndId,omitempty" json:"endToEndId,omitempty"`
+	History           []StatusChange       `bson:"history" json:"history"`
+	Settlement        *Settlement          `bson:"settlement,omitempty" json:"settlement,omitempty"`
+	// Flag tells why the batch could not be dispatched, it is cleared once it is
+	Flag        string    `bson:"flag,omitempty" json:"flag,omitempty"`
+	FlaggedDate time.Time `bson:"flaggedDate,omitempty" json:"flaggedDate,omitempty"`
+	// Legs split the batch between the portfolios of the user, they are fixed by the first dispatch attempt.
+	// The batch is dispatched once all of them are, settled once all of them are and returned once any of them is.
+	Legs []Leg `bson:"legs,omitempty" json:"legs,omitempty"`
+	// Goal is the id of the savings goal the batch collects the round-ups for, none for the batch of the currency
+	Goal string `bson:"goal,omitempty" json:"goal,omitempty"`
+}
+
+// Leg is the part of the batch invested into one portfolio, it is sent and confirmed as a transfer of its own
+type Leg struct {
+	Number            int                  `bson:"number" json:"number"`
+	Portfolio         string               `bson:"portfolio" json:"portfolio"`
+	Percent           string               `bson:"percent" json:"percent"`
+	Amount            primitive.Decimal128 `bson:"amount" json:"amount"`
+	TransferID        string               `bson:"transferId" json:"transferId"`
+	Status            Status               `bson:"status" json:"status"`
+	DispatchedDate    time.Time            `bson:"dispatchedDate,omitempty" json:"dispatchedDate,omitempty"`
+	DispatchReference string               `bson:"dispatchReference,omitempty" json:"dispatchReference,omitempty"`
+	DispatchFile      string               `bson:"dispatchFile,omitempty" json:"dispatchFile,omitempty"`
+	EndToEndID        string               `bson:"endToEndId,omitempty" json:"endToEndId,omitempty"`
+	// Error of the last failed attempt, the leg is sent again with the next one
+	Error      string      `bson:"error,omitempty" json:"error,omitempty"`
+	Settlement *Settlement `bson:"settlement,omitempty" json:"settlement,omitempty"`
+}
+
+// LegTransferID is the id the leg is sent to the bank with, the batch id on its own is the id of an unsplit batch
+func LegTransferID(batchID primitive.ObjectID, number int) string {
+	return fmt.Sprintf("%s-%d", batchID.Hex(), number)
+}
+
+// ParseTransferID returns the batch id and the leg number of the transfer, zero for an unsplit batch
+func ParseTransferID(transferID string) (string, int) {
+	i := strings.LastIndexByte(transferID, '-')
+	if i < 0 {
+		return transferID, 0
+	}
+	number, err := strconv.Atoi(transferID[i+1:])
+	if err != nil || number < 1 {
+		return transferID, 0
+	}
+	return transferID[:i], number
+}
+
+// StatusChange records the moment a batch has entered the status
+type StatusChange struct {
+	Status Status    `bson:"status" json:"status"`
+	Date   time.Time `bson:"date" json:"date"`
+}
+
+// Settlement is the bank's final word on the dispatched transfer
+type Settlement struct {
+	Reference string    `bson:"reference" json:"reference"`
+	Reason    string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	Date      time.Time `bson:"date" json:"date"`
+	// RecreditBatchID is the undispatched batch the funds of a returned transfer were moved to
+	RecreditBatchID primitive.ObjectID `bson:"recreditBatchId,omitempty" json:"recreditBatchId,omitempty"`
+}
+
+func NewBatch(userID string, currency money.Currency, goal string, now time.Time) Batch {
 	defaultAmount, _ := primitive.ParseDecimal128("0")
 	b := Batch{
-		UserID:         userID,
-		Amount:         defaultAmount,
-		Currency:       currency,
-		TransactionIDs: make([]string, 0),
-		Status:         StatusUndispatched,
-		CreatedDate:    time.Now().UTC(),
+		UserID:      userID,
+		Amount:      defaultAmount,
+		Currency:    currency,
+		Goal:        goal,
+		Status:      StatusUndispatched,
+		CreatedDate: now,
+		History:     []StatusChange{{Status: StatusUndispatched, Date: now}},
 	}
 	return b
 }
 
-func NewStatusFrom(input string) Status {
+func NewStatusFrom(input string) (Status, error) {
 	switch input {
 	case StatusUndispatched:
-		return StatusUndispatched
+		return StatusUndispatched, nil
 	case StatusReadyToDispatch:
-		return StatusReadyToDispatch
+		return StatusReadyToDispatch, nil
 	case StatusDispatched:
-		return StatusDispatched
+		return StatusDispatched, nil
+	case StatusSettled:
+		return StatusSettled, nil
+	case StatusReturned:
+		return StatusReturned, nil
 	default:
-		return StatusUndispatched
+		return "", ErrInvalidStatus
 	}
 }
@@ -7,19 +7,20 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
-// Disclaimer pointer to status parameter, is because it is faster that way
-// USE POINTERS ONLY WHEN YOU NEED TO
-func (c *serviceContext) Paginate(ctx context.Context, limit, offset int, asc bool, status *Status) ([]Batch, error) {
-	filter := bson.M{}
-	if status != nil {
-		filter["status"] = *status
+func (c *serviceContext) Paginate(ctx context.Context, limit, offset int, sort Sort, f Filter) ([]Batch, error) {
+	if limit < 1 || limit > MaxLimit {
+		return nil, ErrInvalidLimit
 	}
+	if offset < 0 {
+		return nil, ErrInvalidOffset
+	}
+	filter := f.query()
 
-	sort := -1
-	if asc {
-		sort = 1
+	direction := -1
+	if sort.Asc {
+		direction = 1
 	}
-	opt := options.Find().SetSort(bson.M{"createdDate": sort}).SetSkip(int64(offset)).SetLimit(int64(limit))
+	opt := options.Find().SetSort(bson.D{{sort.Field, direction}}).SetSkip(int64(offset)).SetLimit(int64(limit))
 
 	cursor, err := c.mongo.Find(ctx, _collectionName, filter, opt)
 	if err != nil {
@@ -0,0 +1,161 @@
+package batch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/internal/goal"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+func TestPipeline(t *testing.T) {
+	t.Run("should batch transactions until threshold and dispatch the batch", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		svc, err := New(memory.New(), banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{"USD": "1"})
+		assert.NoError(t, err)
+		svc.Index(ctx)
+
+		// act
+		first, err := svc.Batch(ctx, transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		assert.NoError(t, err)
+		second, err := svc.Batch(ctx, transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		assert.NoError(t, err)
+		third, err := svc.Batch(ctx, transaction.Transaction{ID: "3", UserID: "user:1", Amount: "4.75", Currency: "USD"})
+		assert.NoError(t, err)
+		err = svc.Dispatch(ctx, second.ID.Hex())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+		assert.Equal(t, Status(StatusReadyToDispatch), second.Status)
+		assert.NotEqual(t, second.ID, third.ID)
+		assert.Equal(t, Status(StatusUndispatched), third.Status)
+
+		dispatched, err := svc.Get(ctx, second.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusDispatched), dispatched.Status)
+		assert.Equal(t, "1.4", dispatched.Amount.String())
+		assert.Equal(t, 2, dispatched.TransactionCount)
+		assert.NotEmpty(t, dispatched.DispatchReference)
+		assert.Len(t, dispatched.History, 3)
+
+		details, err := svc.FindTransaction(ctx, "2")
+		assert.NoError(t, err)
+		assert.Equal(t, second.ID, details.BatchID)
+		assert.Equal(t, "0.5", details.RoundUp.String())
+
+		entries, err := svc.Entries(ctx, second.ID, 10, 0)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 2)
+
+		summaries, err := svc.Summary(ctx, "user:1")
+		assert.NoError(t, err)
+		assert.Len(t, summaries, 1)
+		assert.Equal(t, "1.4", summaries[0].Dispatched)
+		assert.Equal(t, "0.25", summaries[0].Undispatched)
+
+		sort, _ := SortFrom("", "")
+		page, err := svc.Browse(ctx, 1, sort, "", Filter{UserID: "user:1"})
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), page.Total)
+		assert.Equal(t, third.ID, page.Items[0].ID)
+		next, err := svc.Browse(ctx, 1, sort, page.Next, Filter{UserID: "user:1"})
+		assert.NoError(t, err)
+		assert.Equal(t, second.ID, next.Items[0].ID)
+	})
+
+	t.Run("should roll back batch when transaction is invalid", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		store := memory.New()
+		svc, err := New(store, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{"USD": "100"})
+		assert.NoError(t, err)
+
+		// act
+		_, err = svc.Batch(ctx, transaction.Transaction{ID: "1", UserID: "user:1", Amount: "-1", Currency: "USD"})
+
+		// assert
+		assert.Error(t, err)
+		count, err := store.CountDocuments(ctx, _collectionName, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("should stamp batch and entries with the time of the clock", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		start := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+		c := clock.NewFake(start)
+		svc, err := New(memory.New(), banksdk.New(), nil, nil, c, logrus.New(), map[string]string{"USD": "1"})
+		assert.NoError(t, err)
+		svc.Index(ctx)
+
+		// act
+		_, err = svc.Batch(ctx, transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD"})
+		assert.NoError(t, err)
+		c.Advance(time.Hour)
+		result, err := svc.Batch(ctx, transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		assert.NoError(t, err)
+		c.Advance(time.Hour)
+		err = svc.Dispatch(ctx, result.ID.Hex())
+
+		// assert
+		assert.NoError(t, err)
+		b, err := svc.Get(ctx, result.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, start, b.CreatedDate)
+		assert.Equal(t, start.Add(2*time.Hour), b.DispatchedDate)
+		assert.Equal(t, start.Add(2*time.Hour), b.UpdatedDate)
+		assert.Len(t, b.History, 3)
+		assert.Equal(t, start.Add(time.Hour), b.History[1].Date)
+
+		details, err := svc.FindTransaction(ctx, "2")
+		assert.NoError(t, err)
+		assert.Equal(t, start.Add(time.Hour), details.RecordedDate)
+	})
+
+	t.Run("should batch round-ups of a goal apart with the threshold of the goal", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		goals := stubGoals{"5812": {GoalID: "goal:coffee", Threshold: "0.5"}}
+		svc, err := New(memory.New(), banksdk.New(), nil, goals, clock.New(), logrus.New(), map[string]string{"USD": "1"})
+		assert.NoError(t, err)
+		svc.Index(ctx)
+
+		// act
+		saved, err := svc.Batch(ctx, transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD", MerchantCategory: "5812"})
+		assert.NoError(t, err)
+		other, err := svc.Batch(ctx, transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		assert.NoError(t, err)
+
+		// assert
+		assert.NotEqual(t, saved.ID, other.ID)
+		assert.Equal(t, Status(StatusReadyToDispatch), saved.Status)
+		assert.Equal(t, Status(StatusUndispatched), other.Status)
+		b, err := svc.Get(ctx, saved.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, "goal:coffee", b.Goal)
+
+		assert.NoError(t, svc.Dispatch(ctx, saved.ID.Hex()))
+		_, err = svc.Batch(ctx, transaction.Transaction{ID: "3", UserID: "user:1", Amount: "4.90", Currency: "USD", MerchantCategory: "5812"})
+		assert.NoError(t, err)
+		summaries, err := svc.GoalSummary(ctx, "user:1")
+		assert.NoError(t, err)
+		assert.Equal(t, []GoalSummary{{Goal: "goal:coffee", Currency: "USD", Pending: "0.1", Invested: "0.9"}}, summaries)
+	})
+}
+
+// stubGoals routes the transactions by their merchant category only
+type stubGoals map[string]goal.Route
+
+func (s stubGoals) Route(ctx context.Context, t transaction.Transaction) (goal.Route, error) {
+	return s[t.MerchantCategory], nil
+}
@@ -0,0 +1,43 @@
+package batch
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ReleaseGoal marks the undispatched batches of the deleted goal as ready, no transaction is routed to them anymore
+// so they would never reach a threshold. Batches with nothing in them are left as they are.
+func (c *serviceContext) ReleaseGoal(ctx context.Context, userID, goalID string) ([]Batch, error) {
+	if userID == "" {
+		return nil, ErrNoUserID
+	}
+	zero, _ := primitive.ParseDecimal128("0")
+	filter := bson.D{
+		{"userId", userID},
+		{"status", StatusUndispatched},
+		{"goal", goalID},
+		{"amount", bson.D{{"$gt", zero}}},
+	}
+	cursor, err := c.mongo.Find(ctx, _collectionName, filter, options.Find().SetSort(bson.D{{"createdDate", 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var batches []Batch
+	if err := cursor.All(ctx, &batches); err != nil {
+		return nil, err
+	}
+	released := make([]Batch, 0, len(batches))
+	for _, b := range batches {
+		ready, err := c.ForceReady(ctx, b.ID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		released = append(released, ready)
+	}
+	return released, nil
+}
@@ -0,0 +1,61 @@
+package batch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+func TestReleaseGoal(t *testing.T) {
+	now := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	t.Run("should mark undispatched batch of the goal as ready and leave the other batches alone", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		goals := stubGoals{"5812": {GoalID: "goal:coffee"}}
+		svc, err := New(memory.New(), banksdk.New(), nil, goals, clock.NewFake(now), logrus.New(), map[string]string{"USD": "100"})
+		require.NoError(t, err)
+		saved, err := svc.Batch(ctx, transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.10", Currency: "USD", MerchantCategory: "5812"})
+		require.NoError(t, err)
+		other, err := svc.Batch(ctx, transaction.Transaction{ID: "2", UserID: "user:1", Amount: "2.50", Currency: "USD"})
+		require.NoError(t, err)
+
+		// act
+		released, err := svc.ReleaseGoal(ctx, "user:1", "goal:coffee")
+
+		// assert
+		assert.NoError(t, err)
+		require.Len(t, released, 1)
+		assert.Equal(t, saved.ID, released[0].ID)
+		assert.Equal(t, Status(StatusReadyToDispatch), released[0].Status)
+		b, err := svc.Get(ctx, other.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, Status(StatusUndispatched), b.Status)
+	})
+
+	t.Run("should release nothing, goal has no round-ups", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		goals := stubGoals{"5812": {GoalID: "goal:coffee"}}
+		svc, err := New(memory.New(), banksdk.New(), nil, goals, clock.NewFake(now), logrus.New(), map[string]string{"USD": "100"})
+		require.NoError(t, err)
+		_, err = svc.Batch(ctx, transaction.Transaction{ID: "1", UserID: "user:1", Amount: "2", Currency: "USD", MerchantCategory: "5812"})
+		require.NoError(t, err)
+
+		// act
+		released, err := svc.ReleaseGoal(ctx, "user:1", "goal:coffee")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Empty(t, released)
+	})
+}
@@ -7,10 +7,15 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/goal"
 	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
 	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
 	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
 	"github.com/mazxaxz/donut-batcher/pkg/money"
 )
@@ -21,23 +26,55 @@ const (
 
 type Service interface {
 	mongodb.Indexer
+	mongodb.Migrator
 
-	Paginate(ctx context.Context, limit, offset int, asc bool, status *Status) ([]Batch, error)
+	// Deprecated: Paginate skips over documents, use Browse instead
+	Paginate(ctx context.Context, limit, offset int, sort Sort, f Filter) ([]Batch, error)
+	Browse(ctx context.Context, limit int, sort Sort, cursor string, f Filter) (Page, error)
+	Export(ctx context.Context, sort Sort, f Filter, fn func(b Batch) error) error
+	Get(ctx context.Context, batchID string) (Batch, error)
+	Entries(ctx context.Context, batchID primitive.ObjectID, limit, offset int) ([]Entry, error)
 	Batch(ctx context.Context, t transaction.Transaction) (BatchResult, error)
 	Dispatch(ctx context.Context, batchID string) error
+	Confirm(ctx context.Context, cf Confirmation) (Batch, error)
+	ForceReady(ctx context.Context, batchID string) (Batch, error)
+	FindTransaction(ctx context.Context, transactionID string) (TransactionDetails, error)
+	Summary(ctx context.Context, userID string) ([]Summary, error)
+	GoalSummary(ctx context.Context, userID string) ([]GoalSummary, error)
+	ReleaseGoal(ctx context.Context, userID, goalID string) ([]Batch, error)
+}
+
+// Accounts tells which account the money of the user goes to, account.ErrNoDestination when there is none,
+// and which portfolios it is split into, no allocations when the batch goes out as a single transfer
+type Accounts interface {
+	Destination(ctx context.Context, userID string, currency money.Currency) (banksdk.Account, error)
+	AllocationRules(ctx context.Context, userID string) ([]account.Allocation, error)
+}
+
+// Goals picks the savings goal the round-up of the transaction is batched for
+type Goals interface {
+	Route(ctx context.Context, t transaction.Transaction) (goal.Route, error)
 }
 
 type serviceContext struct {
 	mongo     mongodb.Clienter
 	bankSDK   banksdk.Clienter
+	accounts  Accounts
+	goals     Goals
+	clock     clock.Clock
 	logger    *logrus.Logger
 	threshold map[money.Currency]string
 }
 
-func New(mc mongodb.Clienter, bc banksdk.Clienter, l *logrus.Logger, threshold map[string]string) (Service, error) {
+// New without accounts leaves the transfers without the creditor, the bank knows the users by their ids.
+// Without goals all round-ups of the user in a currency are batched together.
+func New(mc mongodb.Clienter, bc banksdk.Clienter, a Accounts, g Goals, clk clock.Clock, l *logrus.Logger, threshold map[string]string) (Service, error) {
 	c := serviceContext{
 		mongo:     mc,
 		bankSDK:   bc,
+		accounts:  a,
+		goals:     g,
+		clock:     clk,
 		logger:    l,
 		threshold: make(map[money.Currency]string),
 	}
@@ -57,22 +94,38 @@ func New(mc mongodb.Clienter, bc banksdk.Clienter, l *logrus.Logger, threshold m
 func (c *serviceContext) Index(ctx context.Context) {
 	timeout, _ := context.WithTimeout(ctx, 30*time.Second)
 
-	indexes := []mongoOrg.IndexModel{
-		{Keys: bson.D{{"status", 1}}},
-		{Keys: bson.D{{"createdDate", -1}}},
-		{Keys: bson.D{{"_id", 1}, {"status", 1}}},
-		{Keys: bson.D{{"userId", 1}, {"status", 1}, {"currency", 1}}},
+	indexes := map[string][]mongoOrg.IndexModel{
+		_collectionName: {
+			{Keys: bson.D{{"status", 1}}},
+			{Keys: bson.D{{"createdDate", -1}}},
+			{Keys: bson.D{{"createdDate", -1}, {"_id", -1}}},
+			{Keys: bson.D{{"_id", 1}, {"status", 1}}},
+			{Keys: bson.D{{"userId", 1}, {"status", 1}, {"currency", 1}, {"goal", 1}}},
+			{Keys: bson.D{{"userId", 1}, {"createdDate", -1}}},
+			{Keys: bson.D{{"dispatchReference", 1}}},
+			{Keys: bson.D{{"flag", 1}}},
+		},
+		_entryCollectionName: {
+			{Keys: bson.D{{"batchId", 1}, {"createdDate", 1}}},
+			/* re-credited entries have no transaction, they are left out of the unique index */
+			{
+				Keys:    bson.D{{"transactionId", 1}},
+				Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{{"transactionId", bson.D{{"$gt", ""}}}}),
+			},
+		},
 	}
 
 	var wg sync.WaitGroup
-	for _, idx := range indexes {
-		wg.Add(1)
-		go func(idx mongoOrg.IndexModel) {
-			defer wg.Done()
-			if err := c.mongo.CreateIndex(timeout, _collectionName, idx); err != nil {
-				c.logger.Error(err)
-			}
-		}(idx)
+	for coll, models := range indexes {
+		for _, idx := range models {
+			wg.Add(1)
+			go func(coll string, idx mongoOrg.IndexModel) {
+				defer wg.Done()
+				if err := c.mongo.CreateIndex(timeout, coll, idx); err != nil {
+					c.logger.Error(err)
+				}
+			}(coll, idx)
+		}
 	}
 	wg.Wait()
 }
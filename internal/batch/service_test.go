@@ -3,6 +3,7 @@ package batch
 import (
 	"context"
 	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -10,6 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	mockMongodb "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/mock"
 )
@@ -20,7 +22,7 @@ func TestIndex(t *testing.T) {
 		mockCtrl := gomock.NewController(t)
 		defer mockCtrl.Finish()
 		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
-		svc, err := New(mockMongoClient, banksdk.New(), logrus.New(), map[string]string{})
+		svc, err := New(mockMongoClient, banksdk.New(), nil, nil, clock.New(), logrus.New(), map[string]string{})
 		assert.NoError(t, err)
 
 		// expected calls
@@ -28,10 +30,23 @@ func TestIndex(t *testing.T) {
 		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _collectionName, idx).Return(nil)
 		idx = mongo.IndexModel{Keys: bson.D{{"createdDate", -1}}}
 		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _collectionName, idx).Return(nil)
+		idx = mongo.IndexModel{Keys: bson.D{{"createdDate", -1}, {"_id", -1}}}
+		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _collectionName, idx).Return(nil)
 		idx = mongo.IndexModel{Keys: bson.D{{"_id", 1}, {"status", 1}}}
 		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _collectionName, idx).Return(nil)
-		idx = mongo.IndexModel{Keys: bson.D{{"userId", 1}, {"status", 1}, {"currency", 1}}}
+		idx = mongo.IndexModel{Keys: bson.D{{"userId", 1}, {"status", 1}, {"currency", 1}, {"goal", 1}}}
+		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _collectionName, idx).Return(nil)
+		idx = mongo.IndexModel{Keys: bson.D{{"userId", 1}, {"createdDate", -1}}}
+		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _collectionName, idx).Return(nil)
+		idx = mongo.IndexModel{Keys: bson.D{{"dispatchReference", 1}}}
+		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _collectionName, idx).Return(nil)
+		idx = mongo.IndexModel{Keys: bson.D{{"flag", 1}}}
 		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _collectionName, idx).Return(nil)
+		idx = mongo.IndexModel{Keys: bson.D{{"batchId", 1}, {"createdDate", 1}}}
+		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _entryCollectionName, idx).Return(nil)
+		opt := options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{{"transactionId", bson.D{{"$gt", ""}}}})
+		idx = mongo.IndexModel{Keys: bson.D{{"transactionId", 1}}, Options: opt}
+		mockMongoClient.EXPECT().CreateIndex(gomock.Any(), _entryCollectionName, idx).Return(nil)
 
 		// act
 		svc.Index(context.Background())
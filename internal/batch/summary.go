@@ -0,0 +1,177 @@
+package batch
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+// Summary is a running balance of a single user in one currency
+type Summary struct {
+	Currency            money.Currency `json:"currency"`
+	Undispatched        string         `json:"undispatched"`
+	Threshold           string         `json:"threshold,omitempty"`
+	DistanceToThreshold string         `json:"distanceToThreshold,omitempty"`
+	Dispatched          string         `json:"dispatched"`
+	Returned            string         `json:"returned"`
+	LastDispatchedDate  time.Time      `json:"lastDispatchedDate"`
+}
+
+type summaryResult struct {
+	Currency           money.Currency       `bson:"_id"`
+	Undispatched       primitive.Decimal128 `bson:"undispatched"`
+	Dispatched         primitive.Decimal128 `bson:"dispatched"`
+	Returned           primitive.Decimal128 `bson:"returned"`
+	LastDispatchedDate time.Time            `bson:"lastDispatchedDate"`
+}
+
+// _unwindLegs turns a split batch into a document per leg, an unsplit batch is kept as it is
+var _unwindLegs = bson.D{{"$unwind", bson.D{{"path", "$legs"}, {"preserveNullAndEmptyArrays", true}}}}
+
+// perLeg are the status and amount expressions of the leg after _unwindLegs, those of the batch when it was not split.
+// A split batch is returned once any of its legs is, its settled legs are still invested.
+func perLeg() (status, amount bson.D) {
+	status = bson.D{{"$ifNull", bson.A{"$legs.status", "$status"}}}
+	amount = bson.D{{"$ifNull", bson.A{"$legs.amount", "$amount"}}}
+	return status, amount
+}
+
+func (c *serviceContext) Summary(ctx context.Context, userID string) ([]Summary, error) {
+	if userID == "" {
+		return nil, ErrNoUserID
+	}
+
+	/* zero has to be a decimal as well, otherwise sum of no matching batches would not decode into Decimal128 */
+	zero, _ := primitive.ParseDecimal128("0")
+	status, amount := perLeg()
+	/* funds of a returned batch are re-credited to an undispatched one, they would be counted twice otherwise */
+	undispatched := bson.D{{"$in", bson.A{status, bson.A{StatusUndispatched, StatusReadyToDispatch}}}}
+	dispatched := bson.D{{"$in", bson.A{status, bson.A{StatusDispatched, StatusSettled}}}}
+	returned := bson.D{{"$eq", bson.A{status, StatusReturned}}}
+	pipeline := bson.A{
+		bson.D{{"$match", bson.D{{"userId", userID}}}},
+		_unwindLegs,
+		bson.D{{"$group", bson.D{
+			{"_id", "$currency"},
+			{"undispatched", bson.D{{"$sum", bson.D{{"$cond", bson.A{undispatched, amount, zero}}}}}},
+			{"dispatched", bson.D{{"$sum", bson.D{{"$cond", bson.A{dispatched, amount, zero}}}}}},
+			{"returned", bson.D{{"$sum", bson.D{{"$cond", bson.A{returned, amount, zero}}}}}},
+			{"lastDispatchedDate", bson.D{{"$max", "$dispatchedDate"}}},
+		}}},
+		bson.D{{"$sort", bson.D{{"_id", 1}}}},
+	}
+
+	cursor, err := c.mongo.Aggregate(ctx, _collectionName, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var results []summaryResult
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	summaries := make([]Summary, 0, len(results))
+	for _, r := range results {
+		s, err := c.newSummary(r)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, nil
+}
+
+// GoalSummary is how much of the round-ups saved for the goal is still batched and how much is invested already
+type GoalSummary struct {
+	Goal     string         `json:"goal"`
+	Currency money.Currency `json:"currency"`
+	Pending  string         `json:"pending"`
+	Invested string         `json:"invested"`
+}
+
+type goalSummaryResult struct {
+	Goal     string               `bson:"_id"`
+	Currency money.Currency       `bson:"currency"`
+	Pending  primitive.Decimal128 `bson:"pending"`
+	Invested primitive.Decimal128 `bson:"invested"`
+}
+
+func (c *serviceContext) GoalSummary(ctx context.Context, userID string) ([]GoalSummary, error) {
+	if userID == "" {
+		return nil, ErrNoUserID
+	}
+
+	zero, _ := primitive.ParseDecimal128("0")
+	status, amount := perLeg()
+	/* the same as in Summary, returned batches and legs are left out since their funds are pending again */
+	pending := bson.D{{"$in", bson.A{status, bson.A{StatusUndispatched, StatusReadyToDispatch}}}}
+	invested := bson.D{{"$in", bson.A{status, bson.A{StatusDispatched, StatusSettled}}}}
+	pipeline := bson.A{
+		bson.D{{"$match", bson.D{{"userId", userID}, {"goal", bson.D{{"$exists", true}}}}}},
+		_unwindLegs,
+		bson.D{{"$group", bson.D{
+			{"_id", "$goal"},
+			/* currency of a goal is locked once it has batches, all of them are in the same one */
+			{"currency", bson.D{{"$first", "$currency"}}},
+			{"pending", bson.D{{"$sum", bson.D{{"$cond", bson.A{pending, amount, zero}}}}}},
+			{"invested", bson.D{{"$sum", bson.D{{"$cond", bson.A{invested, amount, zero}}}}}},
+		}}},
+		bson.D{{"$sort", bson.D{{"_id", 1}}}},
+	}
+
+	cursor, err := c.mongo.Aggregate(ctx, _collectionName, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var results []goalSummaryResult
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+	summaries := make([]GoalSummary, 0, len(results))
+	for _, r := range results {
+		summaries = append(summaries, GoalSummary{
+			Goal:     r.Goal,
+			Currency: r.Currency,
+			Pending:  r.Pending.String(),
+			Invested: r.Invested.String(),
+		})
+	}
+	return summaries, nil
+}
+
+func (c *serviceContext) newSummary(r summaryResult) (Summary, error) {
+	s := Summary{
+		Currency:           r.Currency,
+		Undispatched:       r.Undispatched.String(),
+		Dispatched:         r.Dispatched.String(),
+		Returned:           r.Returned.String(),
+		LastDispatchedDate: r.LastDispatchedDate,
+	}
+	threshold, exists := c.threshold[r.Currency]
+	if !exists {
+		return s, nil
+	}
+	s.Threshold = threshold
+
+	reached, err := money.GreaterThanOrEqual(s.Undispatched, threshold)
+	if err != nil {
+		return Summary{}, err
+	}
+	if reached {
+		s.DistanceToThreshold = "0"
+		return s, nil
+	}
+	s.DistanceToThreshold, err = money.Sub(threshold, s.Undispatched)
+	if err != nil {
+		return Summary{}, err
+	}
+	return s, nil
+}
@@ -0,0 +1,95 @@
+package batch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+func TestSummary(t *testing.T) {
+	t.Run("should return no user id error", func(t *testing.T) {
+		// arrange
+		svcCtx := serviceContext{}
+
+		// act
+		_, err := svcCtx.Summary(context.Background(), "")
+
+		// assert
+		assert.Equal(t, ErrNoUserID, err)
+	})
+
+	t.Run("should keep settled legs of the batch with a returned leg as dispatched", func(t *testing.T) {
+		// arrange
+		ctx := context.Background()
+		accounts := newStubAccounts()
+		accounts.destinations["user:1"] = banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}
+		accounts.allocations["user:1"] = []account.Allocation{{Portfolio: "index-fund", Percent: "70"}, {Portfolio: "savings", Percent: "30"}}
+		svc, err := New(memory.New(), &recordingBank{}, accounts, nil, clock.NewFake(time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)), logrus.New(), map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		b := dispatched(t, svc, "1.10", "2.50")
+		_, err = svc.Confirm(ctx, Confirmation{BatchID: b.Legs[0].TransferID, Status: StatusSettled})
+		require.NoError(t, err)
+		_, err = svc.Confirm(ctx, Confirmation{BatchID: b.Legs[1].TransferID, Status: StatusReturned, Reason: "account_closed"})
+		require.NoError(t, err)
+
+		// act
+		result, err := svc.Summary(ctx, "user:1")
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, result, 1)
+		assert.Equal(t, "0.98", result[0].Dispatched)
+		assert.Equal(t, "0.42", result[0].Returned)
+		assert.Equal(t, "0.42", result[0].Undispatched)
+	})
+}
+
+func TestNewSummary(t *testing.T) {
+	decimal := func(v string) primitive.Decimal128 {
+		d, _ := primitive.ParseDecimal128(v)
+		return d
+	}
+	lastDispatch := time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		give summaryResult
+		want Summary
+	}{
+		{
+			name: "below threshold",
+			give: summaryResult{Currency: "USD", Undispatched: decimal("11.11"), Dispatched: decimal("200.5"), Returned: decimal("3"), LastDispatchedDate: lastDispatch},
+			want: Summary{Currency: "USD", Undispatched: "11.11", Threshold: "100", DistanceToThreshold: "88.89", Dispatched: "200.5", Returned: "3", LastDispatchedDate: lastDispatch},
+		},
+		{
+			name: "threshold reached",
+			give: summaryResult{Currency: "USD", Undispatched: decimal("100.01"), Dispatched: decimal("0"), Returned: decimal("0")},
+			want: Summary{Currency: "USD", Undispatched: "100.01", Threshold: "100", DistanceToThreshold: "0", Dispatched: "0", Returned: "0"},
+		},
+		{
+			name: "no threshold for currency",
+			give: summaryResult{Currency: "EUR", Undispatched: decimal("1.5"), Dispatched: decimal("0"), Returned: decimal("0")},
+			want: Summary{Currency: "EUR", Undispatched: "1.5", Dispatched: "0", Returned: "0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svcCtx := serviceContext{threshold: map[money.Currency]string{"USD": "100"}}
+			result, err := svcCtx.newSummary(tt.give)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, result)
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package goal
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+// Goal is what the user saves the round-ups for, it collects them in batches of its own.
+// A transaction goes to the first goal with a matching rule, to the default goal of its currency otherwise.
+type Goal struct {
+	ID       primitive.ObjectID `bson:"_id" json:"id"`
+	UserID   string             `bson:"userId" json:"userId"`
+	Name     string             `bson:"name" json:"name"`
+	Currency money.Currency     `bson:"currency" json:"currency"`
+	Target   string             `bson:"target" json:"target"`
+	// Threshold replaces the threshold of the currency for the batches of the goal
+	Threshold string `bson:"threshold,omitempty" json:"threshold,omitempty"`
+	Default   bool   `bson:"default" json:"default"`
+	// Priority orders the goals the rules are checked in, the lower the earlier
+	Priority    int       `bson:"priority" json:"priority"`
+	Rules       []Rule    `bson:"rules" json:"rules"`
+	CreatedDate time.Time `bson:"createdDate" json:"createdDate"`
+	UpdatedDate time.Time `bson:"updatedDate" json:"updatedDate"`
+}
+
+// Rule matches a transaction made at one of the merchant categories with the amount in [AmountMin, AmountMax),
+// the parts which are left empty match any transaction
+type Rule struct {
+	MerchantCategories []string `bson:"merchantCategories,omitempty" json:"merchantCategories,omitempty"`
+	AmountMin          string   `bson:"amountMin,omitempty" json:"amountMin,omitempty"`
+	AmountMax          string   `bson:"amountMax,omitempty" json:"amountMax,omitempty"`
+}
+
+// Route is the goal the round-up of the transaction is saved for, none keeps it in the batch of the currency
+type Route struct {
+	GoalID    string
+	Threshold string
+}
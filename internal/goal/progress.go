@@ -0,0 +1,51 @@
+package goal
+
+import (
+	"github.com/shopspring/decimal"
+)
+
+var _hundred = decimal.NewFromInt(100)
+
+// Progress of the goal counts both the round-ups waiting in its batches and the ones already invested
+type Progress struct {
+	Goal
+	Saved    string `json:"saved"`
+	Pending  string `json:"pending"`
+	Invested string `json:"invested"`
+	// Percent of the target saved so far, it does not go over 100
+	Percent   string `json:"percent"`
+	Remaining string `json:"remaining"`
+	Reached   bool   `json:"reached"`
+}
+
+// NewProgress puts the amounts batched for the goal against its target, returned funds are in pending again
+func NewProgress(g Goal, pending, invested string) (Progress, error) {
+	p, err := decimal.NewFromString(pending)
+	if err != nil {
+		return Progress{}, err
+	}
+	i, err := decimal.NewFromString(invested)
+	if err != nil {
+		return Progress{}, err
+	}
+	target, err := decimal.NewFromString(g.Target)
+	if err != nil {
+		return Progress{}, err
+	}
+	saved := p.Add(i)
+	percent := _hundred
+	remaining := decimal.Zero
+	if saved.LessThan(target) {
+		percent = saved.Mul(_hundred).Div(target).Truncate(2)
+		remaining = target.Sub(saved)
+	}
+	return Progress{
+		Goal:      g,
+		Saved:     saved.String(),
+		Pending:   p.String(),
+		Invested:  i.String(),
+		Percent:   percent.String(),
+		Remaining: remaining.String(),
+		Reached:   !saved.LessThan(target),
+	}, nil
+}
@@ -0,0 +1,73 @@
+package goal
+
+import (
+	"context"
+
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+func (c *serviceContext) Route(ctx context.Context, t transaction.Transaction) (Route, error) {
+	currency, err := money.CurrencyFrom(t.Currency)
+	if err != nil {
+		return Route{}, err
+	}
+	goals, err := c.List(ctx, t.UserID)
+	if err != nil {
+		return Route{}, err
+	}
+	if g, ok := route(goals, currency, t); ok {
+		return Route{GoalID: g.ID.Hex(), Threshold: g.Threshold}, nil
+	}
+	return Route{}, nil
+}
+
+// route returns the first goal in the currency with a rule matching the transaction, the default goal otherwise
+func route(goals []Goal, currency money.Currency, t transaction.Transaction) (Goal, bool) {
+	var fallback *Goal
+	for i, g := range goals {
+		if g.Currency != currency {
+			continue
+		}
+		for _, r := range g.Rules {
+			if r.matches(t) {
+				return g, true
+			}
+		}
+		if g.Default && fallback == nil {
+			fallback = &goals[i]
+		}
+	}
+	if fallback != nil {
+		return *fallback, true
+	}
+	return Goal{}, false
+}
+
+func (r Rule) matches(t transaction.Transaction) bool {
+	if len(r.MerchantCategories) > 0 && !contains(r.MerchantCategories, t.MerchantCategory) {
+		return false
+	}
+	if r.AmountMin != "" {
+		above, err := money.GreaterThanOrEqual(t.Amount, r.AmountMin)
+		if err != nil || !above {
+			return false
+		}
+	}
+	if r.AmountMax != "" {
+		reached, err := money.GreaterThanOrEqual(t.Amount, r.AmountMax)
+		if err != nil || reached {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(categories []string, category string) bool {
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package goal
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+const (
+	_collectionName = "goals"
+	// _maxGoals keeps routing of every transaction of the user cheap
+	_maxGoals = 20
+)
+
+var (
+	ErrGoalNotFound   = errors.New("goal could not be found")
+	ErrInvalidGoalID  = errors.New("goal id is malformed")
+	ErrTooManyGoals   = errors.New("user can have at most 20 goals")
+	ErrCurrencyLocked = errors.New("goal currency can not be changed once round-ups are batched for it")
+)
+
+type Service interface {
+	mongodb.Indexer
+
+	Create(ctx context.Context, g Goal) (Goal, error)
+	// Update replaces the goal, the round-ups already batched for it stay there
+	Update(ctx context.Context, g Goal) (Goal, error)
+	Get(ctx context.Context, userID, goalID string) (Goal, error)
+	// List returns the goals of the user in the order their rules are checked in
+	List(ctx context.Context, userID string) ([]Goal, error)
+	Delete(ctx context.Context, userID, goalID string) error
+	// Route picks the goal the round-up of the transaction is saved for
+	Route(ctx context.Context, t transaction.Transaction) (Route, error)
+}
+
+type serviceContext struct {
+	mongo  mongodb.Clienter
+	clock  clock.Clock
+	logger *logrus.Logger
+}
+
+func New(mc mongodb.Clienter, clk clock.Clock, l *logrus.Logger) (Service, error) {
+	c := serviceContext{
+		mongo:  mc,
+		clock:  clk,
+		logger: l,
+	}
+	return &c, nil
+}
+
+func (c *serviceContext) Index(ctx context.Context) {
+	timeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	idx := mongoOrg.IndexModel{Keys: bson.D{{"userId", 1}, {"priority", 1}, {"createdDate", 1}}}
+	if err := c.mongo.CreateIndex(timeout, _collectionName, idx); err != nil {
+		c.logger.Error(err)
+	}
+}
+
+func (c *serviceContext) Create(ctx context.Context, g Goal) (Goal, error) {
+	g = normalize(g)
+	if err := validate(g); err != nil {
+		return Goal{}, err
+	}
+	goals, err := c.List(ctx, g.UserID)
+	if err != nil {
+		return Goal{}, err
+	}
+	if len(goals) >= _maxGoals {
+		return Goal{}, ErrTooManyGoals
+	}
+	g.ID = primitive.NewObjectID()
+	if err := conflicts(g, goals); err != nil {
+		return Goal{}, err
+	}
+	g.CreatedDate = c.clock.Now()
+	g.UpdatedDate = g.CreatedDate
+	if _, err := c.mongo.InsertOne(ctx, _collectionName, g); err != nil {
+		return Goal{}, err
+	}
+	return g, nil
+}
+
+func (c *serviceContext) Update(ctx context.Context, g Goal) (Goal, error) {
+	g = normalize(g)
+	if err := validate(g); err != nil {
+		return Goal{}, err
+	}
+	existing, err := c.Get(ctx, g.UserID, g.ID.Hex())
+	if err != nil {
+		return Goal{}, err
+	}
+	goals, err := c.List(ctx, g.UserID)
+	if err != nil {
+		return Goal{}, err
+	}
+	if err := conflicts(g, goals); err != nil {
+		return Goal{}, err
+	}
+	g.CreatedDate = existing.CreatedDate
+	g.UpdatedDate = c.clock.Now()
+
+	filter := bson.D{{"_id", g.ID}, {"userId", g.UserID}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"name", g.Name},
+			{"currency", g.Currency},
+			{"target", g.Target},
+			{"threshold", g.Threshold},
+			{"default", g.Default},
+			{"priority", g.Priority},
+			{"rules", g.Rules},
+			{"updatedDate", g.UpdatedDate},
+		}},
+	}
+	if err := c.mongo.UpdateOne(ctx, _collectionName, filter, update); err != nil {
+		return Goal{}, err
+	}
+	return g, nil
+}
+
+func (c *serviceContext) Get(ctx context.Context, userID, goalID string) (Goal, error) {
+	if userID == "" {
+		return Goal{}, ErrNoUserID
+	}
+	id, err := primitive.ObjectIDFromHex(goalID)
+	if err != nil {
+		return Goal{}, ErrInvalidGoalID
+	}
+	var g Goal
+	filter := bson.D{{"_id", id}, {"userId", userID}}
+	if err := c.mongo.FindOne(ctx, _collectionName, filter).Decode(&g); err != nil {
+		if errors.Is(err, mongoOrg.ErrNoDocuments) {
+			return Goal{}, ErrGoalNotFound
+		}
+		return Goal{}, err
+	}
+	return g, nil
+}
+
+func (c *serviceContext) List(ctx context.Context, userID string) ([]Goal, error) {
+	if userID == "" {
+		return nil, ErrNoUserID
+	}
+	opt := options.Find().SetSort(bson.D{{"priority", 1}, {"createdDate", 1}, {"_id", 1}})
+	cursor, err := c.mongo.Find(ctx, _collectionName, bson.D{{"userId", userID}}, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = cursor.Close(ctx) }()
+
+	var goals []Goal
+	if err := cursor.All(ctx, &goals); err != nil {
+		return nil, err
+	}
+	if goals == nil {
+		goals = make([]Goal, 0)
+	}
+	return goals, nil
+}
+
+// Delete takes the goal away, the next round-ups go to the other goals. Its undispatched batch is not touched here,
+// batch.Service.ReleaseGoal has to send it out before the goal is deleted.
+func (c *serviceContext) Delete(ctx context.Context, userID, goalID string) error {
+	g, err := c.Get(ctx, userID, goalID)
+	if err != nil {
+		return err
+	}
+	return c.mongo.DeleteOne(ctx, _collectionName, bson.D{{"_id", g.ID}})
+}
+
+// conflicts checks the goal against the other goals of the user, names are unique regardless of their case
+func conflicts(g Goal, goals []Goal) error {
+	for _, other := range goals {
+		if other.ID == g.ID {
+			continue
+		}
+		if strings.EqualFold(other.Name, g.Name) {
+			return errors.Wrap(ErrDuplicateName, g.Name)
+		}
+		if g.Default && other.Default && other.Currency == g.Currency {
+			return errors.Wrap(ErrDuplicateDefault, g.Currency.String())
+		}
+	}
+	return nil
+}
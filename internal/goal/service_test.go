@@ -0,0 +1,201 @@
+package goal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+var (
+	testNow      = time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+	testHolidays = Goal{UserID: "user:1", Name: " Holidays ", Currency: "usd", Target: "500", Default: true}
+	testCoffee   = Goal{UserID: "user:1", Name: "Coffee", Currency: "USD", Target: "50", Threshold: "5", Rules: []Rule{{MerchantCategories: []string{"5812"}}}}
+)
+
+func newTestService(t *testing.T) (Service, *clock.Fake) {
+	c := clock.NewFake(testNow)
+	svc, err := New(memory.New(), c, logrus.New())
+	require.NoError(t, err)
+	return svc, c
+}
+
+func TestCreate(t *testing.T) {
+	t.Run("should store normalized goal", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+
+		// act
+		g, err := svc.Create(context.Background(), testHolidays)
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, g.ID.IsZero())
+		assert.Equal(t, "Holidays", g.Name)
+		assert.Equal(t, "USD", g.Currency.String())
+		assert.Equal(t, testNow, g.CreatedDate)
+		stored, err := svc.Get(context.Background(), "user:1", g.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, g, stored)
+	})
+
+	t.Run("should return duplicate name error, names differ in case only", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		_, err := svc.Create(context.Background(), testHolidays)
+		require.NoError(t, err)
+		give := testCoffee
+		give.Name = "HOLIDAYS"
+
+		// act
+		_, err = svc.Create(context.Background(), give)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrDuplicateName), err)
+	})
+
+	t.Run("should return duplicate default error, user has a default goal in the currency", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		_, err := svc.Create(context.Background(), testHolidays)
+		require.NoError(t, err)
+		give := testCoffee
+		give.Default = true
+
+		// act
+		_, err = svc.Create(context.Background(), give)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrDuplicateDefault), err)
+	})
+}
+
+func TestUpdate(t *testing.T) {
+	t.Run("should replace the goal and keep its creation date", func(t *testing.T) {
+		// arrange
+		svc, c := newTestService(t)
+		g, err := svc.Create(context.Background(), testCoffee)
+		require.NoError(t, err)
+		c.Advance(time.Hour)
+		g.Target = "80"
+		g.Rules = append(g.Rules, Rule{AmountMax: "5"})
+
+		// act
+		updated, err := svc.Update(context.Background(), g)
+
+		// assert
+		assert.NoError(t, err)
+		stored, err := svc.Get(context.Background(), "user:1", g.ID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, updated, stored)
+		assert.Equal(t, "80", stored.Target)
+		assert.Len(t, stored.Rules, 2)
+		assert.Equal(t, testNow, stored.CreatedDate)
+		assert.Equal(t, testNow.Add(time.Hour), stored.UpdatedDate)
+	})
+
+	t.Run("should return goal not found error, goal belongs to another user", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		g, err := svc.Create(context.Background(), testCoffee)
+		require.NoError(t, err)
+		g.UserID = "user:2"
+
+		// act
+		_, err = svc.Update(context.Background(), g)
+
+		// assert
+		assert.Equal(t, ErrGoalNotFound, err)
+	})
+}
+
+func TestDelete(t *testing.T) {
+	t.Run("should take the goal away", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		g, err := svc.Create(context.Background(), testCoffee)
+		require.NoError(t, err)
+
+		// act
+		err = svc.Delete(context.Background(), "user:1", g.ID.Hex())
+
+		// assert
+		assert.NoError(t, err)
+		_, err = svc.Get(context.Background(), "user:1", g.ID.Hex())
+		assert.Equal(t, ErrGoalNotFound, err)
+	})
+
+	t.Run("should return invalid goal id error", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+
+		// act
+		err := svc.Delete(context.Background(), "user:1", "holidays")
+
+		// assert
+		assert.Equal(t, ErrInvalidGoalID, err)
+	})
+}
+
+func TestServiceRoute(t *testing.T) {
+	t.Run("should route by the goals ordered by priority", func(t *testing.T) {
+		// arrange
+		svc, _ := newTestService(t)
+		holidays, err := svc.Create(context.Background(), testHolidays)
+		require.NoError(t, err)
+		coffee, err := svc.Create(context.Background(), testCoffee)
+		require.NoError(t, err)
+		lunch := Goal{UserID: "user:1", Name: "Lunch", Currency: "USD", Target: "30", Priority: -1, Rules: []Rule{{MerchantCategories: []string{"5812"}, AmountMin: "10"}}}
+		lunch, err = svc.Create(context.Background(), lunch)
+		require.NoError(t, err)
+
+		// act
+		cheap, err := svc.Route(context.Background(), transaction.Transaction{ID: "1", UserID: "user:1", Amount: "3.20", Currency: "USD", MerchantCategory: "5812"})
+		assert.NoError(t, err)
+		pricey, err := svc.Route(context.Background(), transaction.Transaction{ID: "2", UserID: "user:1", Amount: "14.20", Currency: "USD", MerchantCategory: "5812"})
+		assert.NoError(t, err)
+		other, err := svc.Route(context.Background(), transaction.Transaction{ID: "3", UserID: "user:1", Amount: "14.20", Currency: "USD"})
+		assert.NoError(t, err)
+		none, err := svc.Route(context.Background(), transaction.Transaction{ID: "4", UserID: "user:2", Amount: "14.20", Currency: "USD"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, Route{GoalID: coffee.ID.Hex(), Threshold: "5"}, cheap)
+		assert.Equal(t, Route{GoalID: lunch.ID.Hex()}, pricey)
+		assert.Equal(t, Route{GoalID: holidays.ID.Hex()}, other)
+		assert.Equal(t, Route{}, none)
+	})
+}
+
+func TestNewProgress(t *testing.T) {
+	tests := []struct {
+		name          string
+		pending       string
+		invested      string
+		wantPercent   string
+		wantRemaining string
+		wantReached   bool
+	}{
+		{name: "nothing saved", pending: "0", invested: "0", wantPercent: "0", wantRemaining: "50"},
+		{name: "partly saved", pending: "1.25", invested: "10", wantPercent: "22.5", wantRemaining: "38.75"},
+		{name: "target reached", pending: "0.4", invested: "50", wantPercent: "100", wantRemaining: "0", wantReached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewProgress(testCoffee, tt.pending, tt.invested)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantPercent, p.Percent)
+			assert.Equal(t, tt.wantRemaining, p.Remaining)
+			assert.Equal(t, tt.wantReached, p.Reached)
+		})
+	}
+}
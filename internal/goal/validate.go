@@ -0,0 +1,127 @@
+package goal
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+const (
+	_maxNameLength = 64
+	_maxRules      = 20
+)
+
+var (
+	ErrNoUserID           = errors.New("no user id was provided")
+	ErrNoName             = errors.New("goal has to be named")
+	ErrNameTooLong        = errors.New("goal name has to be at most 64 characters")
+	ErrInvalidTarget      = errors.New("goal target has to be a positive amount")
+	ErrInvalidThreshold   = errors.New("goal threshold has to be a positive amount")
+	ErrNoRules            = errors.New("goal has to be the default one or have rules")
+	ErrTooManyRules       = errors.New("goal can have at most 20 rules")
+	ErrEmptyRule          = errors.New("rule has to name merchant categories or an amount range")
+	ErrInvalidCategory    = errors.New("merchant category code has to be 4 digits")
+	ErrInvalidAmountRange = errors.New("rule amounts have to be positive and the minimum below the maximum")
+	ErrDuplicateName      = errors.New("user already has a goal with the name")
+	ErrDuplicateDefault   = errors.New("user already has a default goal in the currency")
+)
+
+var _merchantCategoryPattern = regexp.MustCompile(`^[0-9]{4}$`)
+
+// IsInvalid tells whether the goal was refused because of its content
+func IsInvalid(err error) bool {
+	invalid := []error{
+		ErrNoUserID, ErrNoName, ErrNameTooLong, ErrInvalidTarget, ErrInvalidThreshold, ErrNoRules, ErrTooManyRules,
+		ErrEmptyRule, ErrInvalidCategory, ErrInvalidAmountRange, money.ErrInvalidCurrencyCode,
+	}
+	for _, e := range invalid {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
+
+func normalize(g Goal) Goal {
+	g.Name = strings.TrimSpace(g.Name)
+	g.Currency = money.Currency(strings.ToUpper(g.Currency.String()))
+	g.Target = strings.TrimSpace(g.Target)
+	g.Threshold = strings.TrimSpace(g.Threshold)
+	if g.Rules == nil {
+		g.Rules = make([]Rule, 0)
+	}
+	for i := range g.Rules {
+		for j := range g.Rules[i].MerchantCategories {
+			g.Rules[i].MerchantCategories[j] = strings.TrimSpace(g.Rules[i].MerchantCategories[j])
+		}
+		g.Rules[i].AmountMin = strings.TrimSpace(g.Rules[i].AmountMin)
+		g.Rules[i].AmountMax = strings.TrimSpace(g.Rules[i].AmountMax)
+	}
+	return g
+}
+
+func validate(g Goal) error {
+	if g.UserID == "" {
+		return ErrNoUserID
+	}
+	if g.Name == "" {
+		return ErrNoName
+	}
+	if len([]rune(g.Name)) > _maxNameLength {
+		return ErrNameTooLong
+	}
+	if _, err := money.CurrencyFrom(g.Currency.String()); err != nil {
+		return err
+	}
+	if !positive(g.Target) {
+		return ErrInvalidTarget
+	}
+	if g.Threshold != "" && !positive(g.Threshold) {
+		return ErrInvalidThreshold
+	}
+	if !g.Default && len(g.Rules) == 0 {
+		return ErrNoRules
+	}
+	if len(g.Rules) > _maxRules {
+		return ErrTooManyRules
+	}
+	for _, r := range g.Rules {
+		if err := validateRule(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateRule(r Rule) error {
+	if len(r.MerchantCategories) == 0 && r.AmountMin == "" && r.AmountMax == "" {
+		return ErrEmptyRule
+	}
+	for _, mcc := range r.MerchantCategories {
+		if !_merchantCategoryPattern.MatchString(mcc) {
+			return errors.Wrap(ErrInvalidCategory, mcc)
+		}
+	}
+	if r.AmountMin != "" && !positive(r.AmountMin) {
+		return ErrInvalidAmountRange
+	}
+	if r.AmountMax != "" && !positive(r.AmountMax) {
+		return ErrInvalidAmountRange
+	}
+	if r.AmountMin != "" && r.AmountMax != "" {
+		inverted, err := money.GreaterThanOrEqual(r.AmountMin, r.AmountMax)
+		if err != nil || inverted {
+			return ErrInvalidAmountRange
+		}
+	}
+	return nil
+}
+
+func positive(amount string) bool {
+	d, err := decimal.NewFromString(amount)
+	return err == nil && d.IsPositive()
+}
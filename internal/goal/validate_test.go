@@ -0,0 +1,114 @@
+package goal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+func TestValidate(t *testing.T) {
+	valid := Goal{UserID: "user:1", Name: "Holidays", Currency: "USD", Target: "500", Default: true}
+
+	tests := []struct {
+		name string
+		give func(g Goal) Goal
+		want error
+	}{
+		{
+			name: "default goal",
+			give: func(g Goal) Goal { return g },
+			want: nil,
+		},
+		{
+			name: "goal with rules",
+			give: func(g Goal) Goal {
+				g.Default = false
+				g.Rules = []Rule{{MerchantCategories: []string{"5812", "5814"}}, {AmountMin: "50", AmountMax: "100"}}
+				return g
+			},
+			want: nil,
+		},
+		{
+			name: "no name",
+			give: func(g Goal) Goal { g.Name = ""; return g },
+			want: ErrNoName,
+		},
+		{
+			name: "zero target",
+			give: func(g Goal) Goal { g.Target = "0"; return g },
+			want: ErrInvalidTarget,
+		},
+		{
+			name: "negative threshold",
+			give: func(g Goal) Goal { g.Threshold = "-5"; return g },
+			want: ErrInvalidThreshold,
+		},
+		{
+			name: "neither default nor rules",
+			give: func(g Goal) Goal { g.Default = false; return g },
+			want: ErrNoRules,
+		},
+		{
+			name: "empty rule",
+			give: func(g Goal) Goal { g.Rules = []Rule{{}}; return g },
+			want: ErrEmptyRule,
+		},
+		{
+			name: "invalid merchant category",
+			give: func(g Goal) Goal { g.Rules = []Rule{{MerchantCategories: []string{"58"}}}; return g },
+			want: ErrInvalidCategory,
+		},
+		{
+			name: "inverted amount range",
+			give: func(g Goal) Goal { g.Rules = []Rule{{AmountMin: "100", AmountMax: "50"}}; return g },
+			want: ErrInvalidAmountRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate(normalize(tt.give(valid)))
+			assert.True(t, errors.Is(err, tt.want), err)
+			if tt.want != nil {
+				assert.True(t, IsInvalid(err))
+			}
+		})
+	}
+}
+
+func TestRoute(t *testing.T) {
+	coffee := Goal{Name: "Coffee", Currency: "USD", Rules: []Rule{{MerchantCategories: []string{"5812"}, AmountMax: "20"}}}
+	big := Goal{Name: "Big spender", Currency: "USD", Rules: []Rule{{AmountMin: "100"}}}
+	holidays := Goal{Name: "Holidays", Currency: "USD", Default: true}
+	euro := Goal{Name: "Euro trip", Currency: "EUR", Default: true}
+	goals := []Goal{coffee, big, holidays, euro}
+
+	tests := []struct {
+		name       string
+		category   string
+		amount     string
+		currency   string
+		want       string
+		wantRouted bool
+	}{
+		{name: "merchant category", category: "5812", amount: "4.50", currency: "USD", want: "Coffee", wantRouted: true},
+		{name: "amount over the maximum", category: "5812", amount: "20", currency: "USD", want: "Holidays", wantRouted: true},
+		{name: "amount range", amount: "120.30", currency: "USD", want: "Big spender", wantRouted: true},
+		{name: "default goal", amount: "7.20", currency: "USD", want: "Holidays", wantRouted: true},
+		{name: "default goal of the currency", amount: "7.20", currency: "EUR", want: "Euro trip", wantRouted: true},
+		{name: "no goal", amount: "7.20", currency: "GBP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			give := transaction.Transaction{ID: "1", UserID: "user:1", Amount: tt.amount, Currency: tt.currency, MerchantCategory: tt.category}
+			g, routed := route(goals, money.Currency(tt.currency), give)
+			assert.Equal(t, tt.wantRouted, routed)
+			assert.Equal(t, tt.want, g.Name)
+		})
+	}
+}
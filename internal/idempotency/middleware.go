@@ -0,0 +1,133 @@
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/pkg/rest"
+)
+
+const (
+	HeaderKey      = "Idempotency-Key"
+	HeaderReplayed = "Idempotent-Replayed"
+	// _maxBodySize of the request the middleware reads, the body is stored as a hash only but it is read whole,
+	// a bulk ingest of 1000 transactions fits in with plenty to spare
+	_maxBodySize = 8 << 20
+)
+
+// Middleware replays stored responses of write requests retried with the same Idempotency-Key,
+// requests without the header are passed through untouched. The response is stored in a single
+// document, so a bulk request replays its per-item result.
+func Middleware(svc Service, l *logrus.Logger) gin.HandlerFunc {
+	return func(cGin *gin.Context) {
+		key := cGin.GetHeader(HeaderKey)
+		if key == "" || !isWrite(cGin.Request.Method) {
+			cGin.Next()
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(cGin.Writer, cGin.Request.Body, _maxBodySize))
+		if err != nil {
+			if len(body) >= _maxBodySize {
+				httpErr := rest.NewError("body_too_large", err)
+				cGin.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, httpErr)
+				return
+			}
+			httpErr := rest.NewError("invalid_body", err)
+			cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+			return
+		}
+		cGin.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, replay, err := svc.Begin(cGin, key, hash(cGin.Request, body))
+		if err != nil {
+			switch err {
+			case ErrKeyReused:
+				httpErr := rest.NewError("idempotency_key_reused", err)
+				cGin.AbortWithStatusJSON(http.StatusConflict, httpErr)
+			case ErrInProgress:
+				httpErr := rest.NewError("idempotency_key_in_progress", err)
+				cGin.AbortWithStatusJSON(http.StatusConflict, httpErr)
+			case ErrKeyTooLong:
+				httpErr := rest.NewError("invalid_header__idempotency_key", err)
+				cGin.AbortWithStatusJSON(http.StatusBadRequest, httpErr)
+			default:
+				httpErr := rest.NewError("idempotency_error", err)
+				cGin.AbortWithStatusJSON(http.StatusInternalServerError, httpErr)
+			}
+			return
+		}
+		if replay {
+			cGin.Header(HeaderReplayed, "true")
+			cGin.Data(record.StatusCode, record.ContentType, record.Body)
+			cGin.Abort()
+			return
+		}
+
+		/* a panicking handler would leave the key in progress until it expires, the recovery further up answers 500 */
+		defer func() {
+			if r := recover(); r != nil {
+				if err := svc.Release(cGin, key); err != nil {
+					l.Error(err)
+				}
+				panic(r)
+			}
+		}()
+
+		recorder := &responseRecorder{ResponseWriter: cGin.Writer}
+		cGin.Writer = recorder
+		cGin.Next()
+
+		/* server errors are not stored, the retry should be processed from scratch */
+		if recorder.Status() >= http.StatusInternalServerError {
+			if err := svc.Release(cGin, key); err != nil {
+				l.Error(err)
+			}
+			return
+		}
+		if err := svc.Complete(cGin, key, recorder.Status(), recorder.Header().Get("Content-Type"), recorder.body.Bytes()); err != nil {
+			l.Error(err)
+		}
+	}
+}
+
+func isWrite(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// hash identifies the request, the same key sent to a different endpoint is a different request
+func hash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method))
+	h.Write([]byte{'\n'})
+	h.Write([]byte(r.URL.RequestURI()))
+	h.Write([]byte{'\n'})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *responseRecorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,55 @@
+package idempotency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("should release key of the request which panicked", func(t *testing.T) {
+		// arrange
+		svc, err := New(memory.New(), clock.New(), logrus.New(), time.Hour)
+		assert.NoError(t, err)
+		calls := 0
+		router := gin.New()
+		router.Use(gin.Recovery(), Middleware(svc, logrus.New()))
+		router.POST("/goals", func(cGin *gin.Context) {
+			calls++
+			if calls == 1 {
+				panic("boom")
+			}
+			cGin.String(http.StatusCreated, "created")
+		})
+		do := func() *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/goals", strings.NewReader(`{"name":"Coffee"}`))
+			req.Header.Set(HeaderKey, "key-1")
+			router.ServeHTTP(rec, req)
+			return rec
+		}
+
+		// act
+		panicked := do()
+		retried := do()
+		replayed := do()
+
+		// assert
+		assert.Equal(t, http.StatusInternalServerError, panicked.Code)
+		assert.Equal(t, http.StatusCreated, retried.Code, retried.Body.String())
+		assert.Equal(t, http.StatusCreated, replayed.Code)
+		assert.Equal(t, "true", replayed.Header().Get(HeaderReplayed))
+		assert.Equal(t, 2, calls)
+	})
+}
@@ -0,0 +1,14 @@
+package idempotency
+
+import "time"
+
+// Record of a request made with an Idempotency-Key, the response is stored once the request is completed
+type Record struct {
+	Key         string    `bson:"_id"`
+	RequestHash string    `bson:"requestHash"`
+	Completed   bool      `bson:"completed"`
+	StatusCode  int       `bson:"statusCode,omitempty"`
+	ContentType string    `bson:"contentType,omitempty"`
+	Body        []byte    `bson:"body,omitempty"`
+	CreatedDate time.Time `bson:"createdDate"`
+}
@@ -0,0 +1,127 @@
+package idempotency
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+const (
+	_collectionName = "idempotency_keys"
+	_maxKeyLength   = 255
+)
+
+var (
+	ErrNoTTL      = errors.New("idempotency key ttl has to be positive")
+	ErrKeyReused  = errors.New("idempotency key was already used with a different request")
+	ErrInProgress = errors.New("request with the same idempotency key is still in progress")
+	ErrNoKey      = errors.New("no idempotency key was provided")
+	ErrKeyTooLong = errors.New("idempotency key can not be longer than 255 characters")
+)
+
+type Service interface {
+	mongodb.Indexer
+
+	// Begin reserves the key for the request, if the key was used before its record is returned instead
+	Begin(ctx context.Context, key, requestHash string) (Record, bool, error)
+	// Complete stores the response, so retries of the request can be answered with it
+	Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error
+	// Release forgets the key, so the request can be retried from scratch
+	Release(ctx context.Context, key string) error
+}
+
+type serviceContext struct {
+	mongo  mongodb.Clienter
+	clock  clock.Clock
+	logger *logrus.Logger
+	ttl    time.Duration
+}
+
+func New(mc mongodb.Clienter, clk clock.Clock, l *logrus.Logger, ttl time.Duration) (Service, error) {
+	if ttl <= 0 {
+		return nil, ErrNoTTL
+	}
+	c := serviceContext{
+		mongo:  mc,
+		clock:  clk,
+		logger: l,
+		ttl:    ttl,
+	}
+	return &c, nil
+}
+
+func (c *serviceContext) Index(ctx context.Context) {
+	timeout, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	idx := mongoOrg.IndexModel{
+		Keys:    bson.D{{"createdDate", 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(c.ttl.Seconds())),
+	}
+	if err := c.mongo.CreateIndex(timeout, _collectionName, idx); err != nil {
+		c.logger.Error(err)
+	}
+}
+
+func (c *serviceContext) Begin(ctx context.Context, key, requestHash string) (Record, bool, error) {
+	if key == "" {
+		return Record{}, false, ErrNoKey
+	}
+	if len(key) > _maxKeyLength {
+		return Record{}, false, ErrKeyTooLong
+	}
+
+	r := Record{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedDate: c.clock.Now().UTC(),
+	}
+	_, err := c.mongo.InsertOne(ctx, _collectionName, r)
+	if err == nil {
+		return r, false, nil
+	}
+	if !mongoOrg.IsDuplicateKeyError(err) {
+		return Record{}, false, err
+	}
+
+	var existing Record
+	if err := c.mongo.FindOne(ctx, _collectionName, bson.D{{"_id", key}}).Decode(&existing); err != nil {
+		/* record could have expired or got released in the meantime, retry will settle it */
+		if errors.Is(err, mongoOrg.ErrNoDocuments) {
+			return Record{}, false, ErrInProgress
+		}
+		return Record{}, false, err
+	}
+	if existing.RequestHash != requestHash {
+		return Record{}, false, ErrKeyReused
+	}
+	if !existing.Completed {
+		return Record{}, false, ErrInProgress
+	}
+	return existing, true, nil
+}
+
+func (c *serviceContext) Complete(ctx context.Context, key string, statusCode int, contentType string, body []byte) error {
+	filter := bson.D{{"_id", key}}
+	update := bson.D{
+		{"$set", bson.D{
+			{"completed", true},
+			{"statusCode", statusCode},
+			{"contentType", contentType},
+			{"body", body},
+		}},
+	}
+	return c.mongo.UpdateOne(ctx, _collectionName, filter, update)
+}
+
+func (c *serviceContext) Release(ctx context.Context, key string) error {
+	return c.mongo.DeleteOne(ctx, _collectionName, bson.D{{"_id", key}})
+}
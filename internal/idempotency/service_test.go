@@ -0,0 +1,148 @@
+package idempotency
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	mongoOrg "go.mongodb.org/mongo-driver/mongo"
+
+	mockMongodb "github.com/mazxaxz/donut-batcher/internal/platform/mongodb/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+var _duplicateKeyError = mongoOrg.WriteException{WriteErrors: []mongoOrg.WriteError{{Code: 11000}}}
+
+func TestNew(t *testing.T) {
+	t.Run("should return no ttl error", func(t *testing.T) {
+		// act
+		_, err := New(nil, clock.New(), logrus.New(), 0)
+
+		// assert
+		assert.Equal(t, ErrNoTTL, err)
+	})
+}
+
+func TestBegin(t *testing.T) {
+	t.Run("should return key too long error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, clock.New(), logrus.New(), time.Hour)
+		assert.NoError(t, err)
+
+		// expected calls
+
+		// act
+		_, _, err = svc.Begin(context.Background(), strings.Repeat("k", _maxKeyLength+1), "hash")
+
+		// assert
+		assert.Equal(t, ErrKeyTooLong, err)
+	})
+
+	t.Run("should reserve new key", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		now := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+		svc, err := New(mockMongoClient, clock.NewFake(now), logrus.New(), time.Hour)
+		assert.NoError(t, err)
+
+		// expected calls
+		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _collectionName, gomock.Any()).Return(nil, nil)
+
+		// act
+		record, replay, err := svc.Begin(context.Background(), "key", "hash")
+
+		// assert
+		assert.NoError(t, err)
+		assert.False(t, replay)
+		assert.Equal(t, now, record.CreatedDate)
+	})
+
+	t.Run("should return key reused error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, clock.New(), logrus.New(), time.Hour)
+		assert.NoError(t, err)
+
+		// expected calls
+		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _collectionName, gomock.Any()).Return(nil, _duplicateKeyError)
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Decode(gomock.Any()).Do(func(r *Record) {
+			r.Key = "key"
+			r.RequestHash = "other"
+			r.Completed = true
+		}).Return(nil)
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, bson.D{{"_id", "key"}}).Return(singleResult)
+
+		// act
+		_, _, err = svc.Begin(context.Background(), "key", "hash")
+
+		// assert
+		assert.Equal(t, ErrKeyReused, err)
+	})
+
+	t.Run("should return in progress error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, clock.New(), logrus.New(), time.Hour)
+		assert.NoError(t, err)
+
+		// expected calls
+		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _collectionName, gomock.Any()).Return(nil, _duplicateKeyError)
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Decode(gomock.Any()).Do(func(r *Record) {
+			r.Key = "key"
+			r.RequestHash = "hash"
+		}).Return(nil)
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, bson.D{{"_id", "key"}}).Return(singleResult)
+
+		// act
+		_, _, err = svc.Begin(context.Background(), "key", "hash")
+
+		// assert
+		assert.Equal(t, ErrInProgress, err)
+	})
+
+	t.Run("should return stored response", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockMongoClient := mockMongodb.NewMockClienter(mockCtrl)
+		svc, err := New(mockMongoClient, clock.New(), logrus.New(), time.Hour)
+		assert.NoError(t, err)
+
+		// expected calls
+		mockMongoClient.EXPECT().InsertOne(gomock.Any(), _collectionName, gomock.Any()).Return(nil, _duplicateKeyError)
+		singleResult := mockMongodb.NewMockSingleResulter(mockCtrl)
+		singleResult.EXPECT().Decode(gomock.Any()).Do(func(r *Record) {
+			r.Key = "key"
+			r.RequestHash = "hash"
+			r.Completed = true
+			r.StatusCode = 202
+			r.Body = []byte(`{}`)
+		}).Return(nil)
+		mockMongoClient.EXPECT().FindOne(gomock.Any(), _collectionName, bson.D{{"_id", "key"}}).Return(singleResult)
+
+		// act
+		result, replay, err := svc.Begin(context.Background(), "key", "hash")
+
+		// assert
+		assert.NoError(t, err)
+		assert.True(t, replay)
+		assert.Equal(t, 202, result.StatusCode)
+		assert.Equal(t, []byte(`{}`), result.Body)
+	})
+}
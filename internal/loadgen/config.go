@@ -0,0 +1,142 @@
+package loadgen
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/shopspring/decimal"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+const (
+	DistributionUniform     = "uniform"
+	DistributionNormal      = "normal"
+	DistributionExponential = "exponential"
+)
+
+var (
+	ErrInvalidUsers        = errors.New("number of users has to be positive")
+	ErrInvalidRate         = errors.New("rate has to be positive")
+	ErrInvalidDuration     = errors.New("duration has to be positive")
+	ErrInvalidAmountRange  = errors.New("amounts have to be positive decimals and min can not exceed max")
+	ErrInvalidDistribution = errors.New("distribution has to be uniform, normal or exponential")
+	ErrInvalidRatio        = errors.New("duplicate and invalid ratios have to be within 0 and 1 and can not exceed 1 together")
+	ErrInvalidWorkers      = errors.New("number of workers has to be positive")
+)
+
+// Config of a single load generation run, zero values are replaced by the defaults
+type Config struct {
+	Users             int
+	Currencies        []string
+	Rate              int
+	Duration          time.Duration
+	AmountMin         string
+	AmountMax         string
+	Distribution      string
+	DuplicateRatio    float64
+	InvalidRatio      float64
+	Workers           int
+	CompletionTimeout time.Duration
+	PollInterval      time.Duration
+	Seed              int64
+}
+
+// DefaultConfig reproduces the profile of the former stress endpoint, 10 users sending 1000 transactions
+func DefaultConfig() Config {
+	return Config{
+		Users:             10,
+		Currencies:        []string{"USD"},
+		Rate:              100,
+		Duration:          10 * time.Second,
+		AmountMin:         "0.01",
+		AmountMax:         "10.00",
+		Distribution:      DistributionUniform,
+		Workers:           10,
+		CompletionTimeout: time.Minute,
+		PollInterval:      time.Second,
+	}
+}
+
+func (c Config) withDefaults() Config {
+	d := DefaultConfig()
+	if c.Users == 0 {
+		c.Users = d.Users
+	}
+	if len(c.Currencies) == 0 {
+		c.Currencies = d.Currencies
+	}
+	if c.Rate == 0 {
+		c.Rate = d.Rate
+	}
+	if c.Duration == 0 {
+		c.Duration = d.Duration
+	}
+	if c.AmountMin == "" {
+		c.AmountMin = d.AmountMin
+	}
+	if c.AmountMax == "" {
+		c.AmountMax = d.AmountMax
+	}
+	if c.Distribution == "" {
+		c.Distribution = d.Distribution
+	}
+	if c.Workers == 0 {
+		c.Workers = d.Workers
+	}
+	if c.CompletionTimeout == 0 {
+		c.CompletionTimeout = d.CompletionTimeout
+	}
+	if c.PollInterval == 0 {
+		c.PollInterval = d.PollInterval
+	}
+	if c.Seed == 0 {
+		c.Seed = time.Now().UnixNano()
+	}
+	return c
+}
+
+func (c Config) validate() error {
+	if c.Users < 0 {
+		return ErrInvalidUsers
+	}
+	if c.Rate < 0 {
+		return ErrInvalidRate
+	}
+	if c.Duration < 0 || c.CompletionTimeout < 0 || c.PollInterval < 0 {
+		return ErrInvalidDuration
+	}
+	if c.Workers < 0 {
+		return ErrInvalidWorkers
+	}
+	for _, currency := range c.Currencies {
+		if _, err := money.CurrencyFrom(currency); err != nil {
+			return err
+		}
+	}
+	min, err := decimal.NewFromString(c.AmountMin)
+	if err != nil {
+		return ErrInvalidAmountRange
+	}
+	max, err := decimal.NewFromString(c.AmountMax)
+	if err != nil {
+		return ErrInvalidAmountRange
+	}
+	if !min.IsPositive() || min.GreaterThan(max) {
+		return ErrInvalidAmountRange
+	}
+	switch c.Distribution {
+	case DistributionUniform, DistributionNormal, DistributionExponential:
+	default:
+		return ErrInvalidDistribution
+	}
+	if c.DuplicateRatio < 0 || c.InvalidRatio < 0 || c.DuplicateRatio+c.InvalidRatio > 1 {
+		return ErrInvalidRatio
+	}
+	return nil
+}
+
+// total number of messages sent during the run
+func (c Config) total() int {
+	return int(c.Duration.Seconds() * float64(c.Rate))
+}
@@ -0,0 +1,107 @@
+package loadgen
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+const (
+	kindValid = iota
+	kindDuplicate
+	kindInvalid
+)
+
+type message struct {
+	kind        int
+	transaction transaction.Transaction
+}
+
+// generator is not safe for concurrent use, messages are produced by a single goroutine
+type generator struct {
+	cfg      Config
+	rand     *rand.Rand
+	minCents int64
+	maxCents int64
+	sent     []transaction.Transaction
+}
+
+func newGenerator(cfg Config) generator {
+	min, _ := decimal.NewFromString(cfg.AmountMin)
+	max, _ := decimal.NewFromString(cfg.AmountMax)
+	g := generator{
+		cfg:      cfg,
+		rand:     rand.New(rand.NewSource(cfg.Seed)),
+		minCents: min.Shift(2).Ceil().IntPart(),
+		maxCents: max.Shift(2).Floor().IntPart(),
+	}
+	return g
+}
+
+func (g *generator) next() message {
+	roll := g.rand.Float64()
+	if roll < g.cfg.InvalidRatio {
+		return message{kind: kindInvalid, transaction: g.invalid()}
+	}
+	if roll < g.cfg.InvalidRatio+g.cfg.DuplicateRatio && len(g.sent) > 0 {
+		return message{kind: kindDuplicate, transaction: g.sent[g.rand.Intn(len(g.sent))]}
+	}
+
+	t := transaction.Transaction{
+		ID:       uuid.NewString(),
+		UserID:   fmt.Sprintf("user:%d", g.rand.Intn(g.cfg.Users)),
+		Amount:   formatCents(g.cents()),
+		Currency: g.cfg.Currencies[g.rand.Intn(len(g.cfg.Currencies))],
+	}
+	g.sent = append(g.sent, t)
+	return message{kind: kindValid, transaction: t}
+}
+
+// invalid breaks a single field of an otherwise correct transaction, all of them are rejected by the consumer
+func (g *generator) invalid() transaction.Transaction {
+	t := transaction.Transaction{
+		ID:       uuid.NewString(),
+		UserID:   fmt.Sprintf("user:%d", g.rand.Intn(g.cfg.Users)),
+		Amount:   formatCents(g.cents()),
+		Currency: g.cfg.Currencies[g.rand.Intn(len(g.cfg.Currencies))],
+	}
+	switch g.rand.Intn(3) {
+	case 0:
+		t.UserID = ""
+	case 1:
+		t.Amount = "not-a-number"
+	case 2:
+		t.Currency = "INVALID"
+	}
+	return t
+}
+
+func (g *generator) cents() int64 {
+	span := g.maxCents - g.minCents
+	if span <= 0 {
+		return g.minCents
+	}
+
+	var offset float64
+	switch g.cfg.Distribution {
+	case DistributionNormal:
+		/* 99.7% of the amounts fall within the range, the rest is clamped to its bounds */
+		offset = float64(span)/2 + g.rand.NormFloat64()*float64(span)/6
+	case DistributionExponential:
+		/* most of the amounts are small, as card payments usually are */
+		offset = g.rand.ExpFloat64() * float64(span) / 5
+	default:
+		offset = float64(g.rand.Int63n(span + 1))
+	}
+	offset = math.Max(0, math.Min(float64(span), math.Round(offset)))
+	return g.minCents + int64(offset)
+}
+
+func formatCents(cents int64) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
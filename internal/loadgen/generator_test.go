@@ -0,0 +1,80 @@
+package loadgen
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerator(t *testing.T) {
+	distributions := []string{DistributionUniform, DistributionNormal, DistributionExponential}
+	for _, distribution := range distributions {
+		t.Run("should generate amounts within range for "+distribution+" distribution", func(t *testing.T) {
+			// arrange
+			cfg := Config{AmountMin: "1.50", AmountMax: "2.50", Distribution: distribution, Seed: 1}.withDefaults()
+			g := newGenerator(cfg)
+			min := decimal.RequireFromString("1.50")
+			max := decimal.RequireFromString("2.50")
+
+			for i := 0; i < 1000; i++ {
+				// act
+				m := g.next()
+
+				// assert
+				amount := decimal.RequireFromString(m.transaction.Amount)
+				assert.False(t, amount.LessThan(min), m.transaction.Amount)
+				assert.False(t, amount.GreaterThan(max), m.transaction.Amount)
+				assert.NoError(t, m.transaction.Validate())
+			}
+		})
+	}
+
+	t.Run("should generate duplicates and invalid transactions", func(t *testing.T) {
+		// arrange
+		cfg := Config{DuplicateRatio: 0.3, InvalidRatio: 0.2, Seed: 1}.withDefaults()
+		g := newGenerator(cfg)
+		seen := make(map[string]bool)
+		kinds := make(map[int]int)
+
+		for i := 0; i < 1000; i++ {
+			// act
+			m := g.next()
+
+			// assert
+			kinds[m.kind]++
+			switch m.kind {
+			case kindValid:
+				assert.NoError(t, m.transaction.Validate())
+				assert.False(t, seen[m.transaction.ID])
+				seen[m.transaction.ID] = true
+			case kindDuplicate:
+				assert.True(t, seen[m.transaction.ID])
+			case kindInvalid:
+				assert.Error(t, m.transaction.Validate())
+			}
+		}
+		assert.InDelta(t, 500, kinds[kindValid], 75)
+		assert.InDelta(t, 300, kinds[kindDuplicate], 75)
+		assert.InDelta(t, 200, kinds[kindInvalid], 75)
+	})
+
+	t.Run("should spread transactions across users and currencies", func(t *testing.T) {
+		// arrange
+		cfg := Config{Users: 3, Currencies: []string{"USD", "EUR"}, Seed: 1}.withDefaults()
+		g := newGenerator(cfg)
+		users := make(map[string]bool)
+		currencies := make(map[string]bool)
+
+		for i := 0; i < 100; i++ {
+			// act
+			tx := g.next().transaction
+
+			// assert
+			users[tx.UserID] = true
+			currencies[tx.Currency] = true
+		}
+		assert.Len(t, users, 3)
+		assert.Len(t, currencies, 2)
+	})
+}
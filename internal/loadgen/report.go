@@ -0,0 +1,70 @@
+package loadgen
+
+import (
+	"sort"
+	"time"
+)
+
+// Report summarizes a finished run, latency is measured from publishing a transaction
+// until its entry was recorded in a batch
+type Report struct {
+	Published         int     `json:"published"`
+	PublishErrors     int     `json:"publishErrors"`
+	Duplicates        int     `json:"duplicates"`
+	Invalid           int     `json:"invalid"`
+	Tracked           int     `json:"tracked"`
+	Completed         int     `json:"completed"`
+	Missing           int     `json:"missing"`
+	PublishSeconds    float64 `json:"publishSeconds"`
+	PublishRate       float64 `json:"publishRate"`
+	CompletionSeconds float64 `json:"completionSeconds"`
+	CompletionRate    float64 `json:"completionRate"`
+	Latency           Latency `json:"latency"`
+}
+
+// Latency percentiles in milliseconds
+type Latency struct {
+	P50 float64 `json:"p50"`
+	P90 float64 `json:"p90"`
+	P95 float64 `json:"p95"`
+	P99 float64 `json:"p99"`
+	Max float64 `json:"max"`
+}
+
+func newLatency(latencies []time.Duration) Latency {
+	if len(latencies) == 0 {
+		return Latency{}
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	l := Latency{
+		P50: milliseconds(percentile(sorted, 50)),
+		P90: milliseconds(percentile(sorted, 90)),
+		P95: milliseconds(percentile(sorted, 95)),
+		P99: milliseconds(percentile(sorted, 99)),
+		Max: milliseconds(sorted[len(sorted)-1]),
+	}
+	return l
+}
+
+// percentile uses the nearest-rank method on sorted durations
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func rate(count int, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(count) / d.Seconds()
+}
@@ -0,0 +1,33 @@
+package loadgen
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewLatency(t *testing.T) {
+	t.Run("should return zero latency when nothing completed", func(t *testing.T) {
+		// act
+		result := newLatency(nil)
+
+		// assert
+		assert.Equal(t, Latency{}, result)
+	})
+
+	t.Run("should return nearest-rank percentiles", func(t *testing.T) {
+		// arrange
+		var latencies []time.Duration
+		for i := 100; i >= 1; i-- {
+			latencies = append(latencies, time.Duration(i)*time.Millisecond)
+		}
+
+		// act
+		result := newLatency(latencies)
+
+		// assert
+		assert.Equal(t, Latency{P50: 50, P90: 90, P95: 95, P99: 99, Max: 100}, result)
+		assert.Equal(t, 100*time.Millisecond, latencies[0])
+	})
+}
@@ -0,0 +1,299 @@
+package loadgen
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+)
+
+var (
+	ErrNoPublisher   = errors.New("transaction publisher was not provided")
+	ErrNoTracker     = errors.New("transaction tracker was not provided")
+	ErrRunNotFound   = errors.New("load generation run was not found")
+	ErrRunInProgress = errors.New("another load generation run is in progress")
+)
+
+// Tracker finds transactions which made it into a batch
+type Tracker interface {
+	FindTransaction(ctx context.Context, transactionID string) (batch.TransactionDetails, error)
+}
+
+type Service interface {
+	// Run generates the load and waits until it is processed
+	Run(ctx context.Context, cfg Config) (Report, error)
+	// Start runs the load generation in the background, only one run can be in progress
+	Start(cfg Config) (Run, error)
+	Get(id string) (Run, error)
+}
+
+// Run of the load generation started in the background
+type Run struct {
+	ID           string    `json:"id"`
+	Status       string    `json:"status"`
+	StartedDate  time.Time `json:"startedDate"`
+	FinishedDate time.Time `json:"finishedDate,omitempty"`
+	Report       *Report   `json:"report,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+type serviceContext struct {
+	ctx       context.Context
+	publisher transport.Publisher
+	tracker   Tracker
+	clock     clock.Clock
+	logger    *logrus.Logger
+
+	mu   sync.Mutex
+	runs map[string]Run
+}
+
+// New creates the load generator, background runs are cancelled together with given context
+func New(ctx context.Context, p transport.Publisher, t Tracker, clk clock.Clock, l *logrus.Logger) (Service, error) {
+	if p == nil {
+		return nil, ErrNoPublisher
+	}
+	if t == nil {
+		return nil, ErrNoTracker
+	}
+	c := serviceContext{
+		ctx:       ctx,
+		publisher: p,
+		tracker:   t,
+		clock:     clk,
+		logger:    l,
+		runs:      make(map[string]Run),
+	}
+	return &c, nil
+}
+
+func (c *serviceContext) Start(cfg Config) (Run, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.validate(); err != nil {
+		return Run{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, r := range c.runs {
+		if r.Status == StatusRunning {
+			return Run{}, ErrRunInProgress
+		}
+	}
+	r := Run{
+		ID:          uuid.NewString(),
+		Status:      StatusRunning,
+		StartedDate: c.clock.Now(),
+	}
+	c.runs[r.ID] = r
+
+	go func(r Run) {
+		report, err := c.Run(c.ctx, cfg)
+		r.FinishedDate = c.clock.Now()
+		if err != nil {
+			c.logger.Error(err)
+			r.Status = StatusFailed
+			r.Error = err.Error()
+		} else {
+			r.Status = StatusCompleted
+			r.Report = &report
+		}
+		c.mu.Lock()
+		c.runs[r.ID] = r
+		c.mu.Unlock()
+	}(r)
+	return r, nil
+}
+
+func (c *serviceContext) Get(id string) (Run, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	r, exists := c.runs[id]
+	if !exists {
+		return Run{}, ErrRunNotFound
+	}
+	return r, nil
+}
+
+func (c *serviceContext) Run(ctx context.Context, cfg Config) (Report, error) {
+	cfg = cfg.withDefaults()
+	if err := cfg.validate(); err != nil {
+		return Report{}, err
+	}
+
+	var (
+		report    Report
+		mu        sync.Mutex
+		published = make(map[string]time.Time)
+	)
+	messages := make(chan message, cfg.Workers)
+	start := c.clock.Now()
+
+	go c.produce(ctx, cfg, messages)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for m := range messages {
+				err := c.publisher.Publish(ctx, m.transaction, transaction.MessageTypeTransaction)
+				now := c.clock.Now()
+
+				mu.Lock()
+				if err != nil {
+					report.PublishErrors++
+					mu.Unlock()
+					c.logger.Error(err)
+					continue
+				}
+				report.Published++
+				switch m.kind {
+				case kindValid:
+					published[m.transaction.ID] = now
+				case kindDuplicate:
+					report.Duplicates++
+				case kindInvalid:
+					report.Invalid++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return Report{}, err
+	}
+	publishElapsed := c.clock.Now().Sub(start)
+
+	latencies, lastCompleted := c.track(ctx, cfg, published)
+	report.Tracked = len(published)
+	report.Completed = len(latencies)
+	report.Missing = report.Tracked - report.Completed
+	report.PublishSeconds = publishElapsed.Seconds()
+	report.PublishRate = rate(report.Published, publishElapsed)
+	if !lastCompleted.IsZero() {
+		completionElapsed := lastCompleted.Sub(start)
+		report.CompletionSeconds = completionElapsed.Seconds()
+		report.CompletionRate = rate(report.Completed, completionElapsed)
+	}
+	report.Latency = newLatency(latencies)
+	return report, nil
+}
+
+// produce paces the messages evenly over the configured duration,
+// pacing follows the wall clock as it has to actually wait between messages
+func (c *serviceContext) produce(ctx context.Context, cfg Config, messages chan<- message) {
+	defer close(messages)
+
+	start := time.Now()
+
+	g := newGenerator(cfg)
+	for i := 0; i < cfg.total(); i++ {
+		due := start.Add(time.Duration(i) * time.Second / time.Duration(cfg.Rate))
+		if wait := time.Until(due); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(wait):
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case messages <- g.next():
+		}
+	}
+}
+
+// track polls published transactions until all of them are batched or completion timeout passes
+func (c *serviceContext) track(ctx context.Context, cfg Config, published map[string]time.Time) ([]time.Duration, time.Time) {
+	pending := make(map[string]time.Time, len(published))
+	for id, date := range published {
+		pending[id] = date
+	}
+
+	var (
+		latencies     []time.Duration
+		lastCompleted time.Time
+	)
+	/* the timeout bounds how long polling really waits, so it is not taken from the clock */
+	deadline := time.Now().Add(cfg.CompletionTimeout)
+	for len(pending) > 0 {
+		for id, recorded := range c.poll(ctx, cfg.Workers, pending) {
+			latency := recorded.Sub(pending[id])
+			if latency < 0 {
+				latency = 0
+			}
+			latencies = append(latencies, latency)
+			if recorded.After(lastCompleted) {
+				lastCompleted = recorded
+			}
+			delete(pending, id)
+		}
+		if len(pending) == 0 || time.Now().After(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return latencies, lastCompleted
+		case <-time.After(cfg.PollInterval):
+		}
+	}
+	return latencies, lastCompleted
+}
+
+// poll looks the pending transactions up concurrently, returns dates they were recorded at
+func (c *serviceContext) poll(ctx context.Context, workers int, pending map[string]time.Time) map[string]time.Time {
+	ids := make(chan string)
+	go func() {
+		defer close(ids)
+		for id := range pending {
+			select {
+			case <-ctx.Done():
+				return
+			case ids <- id:
+			}
+		}
+	}()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		recorded = make(map[string]time.Time)
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range ids {
+				d, err := c.tracker.FindTransaction(ctx, id)
+				if err != nil {
+					if !errors.Is(err, batch.ErrTransactionNotFound) {
+						c.logger.Error(err)
+					}
+					continue
+				}
+				mu.Lock()
+				recorded[id] = d.RecordedDate
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return recorded
+}
@@ -0,0 +1,209 @@
+package loadgen
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	mockBatch "github.com/mazxaxz/donut-batcher/internal/batch/mock"
+	mockTransport "github.com/mazxaxz/donut-batcher/internal/platform/transport/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+func TestRun(t *testing.T) {
+	t.Run("should return invalid config error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		mockTracker := mockBatch.NewMockService(mockCtrl)
+		svc, err := New(context.Background(), mockPublisher, mockTracker, clock.New(), logrus.New())
+		assert.NoError(t, err)
+
+		// expected calls
+
+		// act
+		_, err = svc.Run(context.Background(), Config{DuplicateRatio: 0.6, InvalidRatio: 0.6})
+
+		// assert
+		assert.Equal(t, ErrInvalidRatio, err)
+	})
+
+	t.Run("should report published and completed transactions", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		mockTracker := mockBatch.NewMockService(mockCtrl)
+		svc, err := New(context.Background(), mockPublisher, mockTracker, clock.New(), logrus.New())
+		assert.NoError(t, err)
+		cfg := Config{Rate: 100, Duration: 100 * time.Millisecond, PollInterval: time.Millisecond}
+
+		// expected calls
+		mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), transaction.MessageTypeTransaction).Return(nil).Times(10)
+		mockTracker.EXPECT().FindTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, id string) (batch.TransactionDetails, error) {
+			return batch.TransactionDetails{TransactionID: id, RecordedDate: time.Now()}, nil
+		}).Times(10)
+
+		// act
+		result, err := svc.Run(context.Background(), cfg)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 10, result.Published)
+		assert.Equal(t, 10, result.Tracked)
+		assert.Equal(t, 10, result.Completed)
+		assert.Equal(t, 0, result.Missing)
+		assert.True(t, result.PublishRate > 0)
+		assert.True(t, result.CompletionRate > 0)
+	})
+
+	t.Run("should measure latency with the clock batches are recorded by", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		mockTracker := mockBatch.NewMockService(mockCtrl)
+		clk := clock.NewFake(time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC))
+		svc, err := New(context.Background(), mockPublisher, mockTracker, clk, logrus.New())
+		assert.NoError(t, err)
+		cfg := Config{Rate: 100, Duration: 20 * time.Millisecond, PollInterval: time.Millisecond}
+
+		// expected calls
+		mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), transaction.MessageTypeTransaction).Return(nil).Times(2)
+		mockTracker.EXPECT().FindTransaction(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, id string) (batch.TransactionDetails, error) {
+			return batch.TransactionDetails{TransactionID: id, RecordedDate: clk.Now().Add(2 * time.Second)}, nil
+		}).Times(2)
+
+		// act
+		result, err := svc.Run(context.Background(), cfg)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Completed)
+		assert.Equal(t, float64(2000), result.Latency.Max)
+		assert.Equal(t, float64(2), result.CompletionSeconds)
+	})
+
+	t.Run("should report missing transactions after completion timeout", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		mockTracker := mockBatch.NewMockService(mockCtrl)
+		svc, err := New(context.Background(), mockPublisher, mockTracker, clock.New(), logrus.New())
+		assert.NoError(t, err)
+		cfg := Config{Rate: 100, Duration: 20 * time.Millisecond, CompletionTimeout: 5 * time.Millisecond, PollInterval: time.Millisecond}
+
+		// expected calls
+		mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), transaction.MessageTypeTransaction).Return(nil).Times(2)
+		mockTracker.EXPECT().FindTransaction(gomock.Any(), gomock.Any()).Return(batch.TransactionDetails{}, batch.ErrTransactionNotFound).MinTimes(2)
+
+		// act
+		result, err := svc.Run(context.Background(), cfg)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 2, result.Tracked)
+		assert.Equal(t, 0, result.Completed)
+		assert.Equal(t, 2, result.Missing)
+	})
+
+	t.Run("should count publish errors", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		mockTracker := mockBatch.NewMockService(mockCtrl)
+		svc, err := New(context.Background(), mockPublisher, mockTracker, clock.New(), logrus.New())
+		assert.NoError(t, err)
+		cfg := Config{Rate: 100, Duration: 30 * time.Millisecond}
+
+		// expected calls
+		mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), transaction.MessageTypeTransaction).Return(errors.New("closed")).Times(3)
+
+		// act
+		result, err := svc.Run(context.Background(), cfg)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 0, result.Published)
+		assert.Equal(t, 3, result.PublishErrors)
+		assert.Equal(t, 0, result.Tracked)
+	})
+}
+
+func TestStart(t *testing.T) {
+	t.Run("should reject second run while first is in progress", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		mockTracker := mockBatch.NewMockService(mockCtrl)
+		ctx, cancel := context.WithCancel(context.Background())
+		svc, err := New(ctx, mockPublisher, mockTracker, clock.New(), logrus.New())
+		assert.NoError(t, err)
+
+		// expected calls
+		mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+		// act
+		run, err := svc.Start(Config{Duration: time.Hour})
+		assert.NoError(t, err)
+		_, err = svc.Start(Config{})
+
+		// assert
+		assert.Equal(t, ErrRunInProgress, err)
+		cancel()
+		assert.Eventually(t, func() bool {
+			r, err := svc.Get(run.ID)
+			return err == nil && r.Status == StatusFailed
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should date the run with the clock", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		mockTracker := mockBatch.NewMockService(mockCtrl)
+		now := time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+		ctx, cancel := context.WithCancel(context.Background())
+		svc, err := New(ctx, mockPublisher, mockTracker, clock.NewFake(now), logrus.New())
+		assert.NoError(t, err)
+
+		// expected calls
+		mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+		// act
+		run, err := svc.Start(Config{Duration: time.Hour})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, now, run.StartedDate)
+		cancel()
+		assert.Eventually(t, func() bool {
+			r, err := svc.Get(run.ID)
+			return err == nil && r.Status == StatusFailed && r.FinishedDate.Equal(now)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("should return run not found error", func(t *testing.T) {
+		// arrange
+		svc, err := New(context.Background(), mockTransport.NewMockPublisher(gomock.NewController(t)), mockBatch.NewMockService(gomock.NewController(t)), clock.New(), logrus.New())
+		assert.NoError(t, err)
+
+		// act
+		_, err = svc.Get("unknown")
+
+		// assert
+		assert.Equal(t, ErrRunNotFound, err)
+	})
+}
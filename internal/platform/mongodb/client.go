@@ -17,8 +17,12 @@ import (
 type Clienter interface {
 	Find(ctx context.Context, coll string, filter interface{}, opt *options.FindOptions) (*mongo.Cursor, error)
 	FindOne(ctx context.Context, coll string, filter interface{}) SingleResulter
+	CountDocuments(ctx context.Context, coll string, filter interface{}) (int64, error)
+	Aggregate(ctx context.Context, coll string, pipeline interface{}) (*mongo.Cursor, error)
 	UpdateOne(ctx context.Context, coll string, filter, update interface{}) error
 	InsertOne(ctx context.Context, coll string, doc interface{}) (*mongo.InsertOneResult, error)
+	InsertMany(ctx context.Context, coll string, docs []interface{}) error
+	DeleteOne(ctx context.Context, coll string, filter interface{}) error
 	WithinTransaction(ctx context.Context, cb TransactionCallback) (result interface{}, err error)
 	CreateIndex(ctx context.Context, collectionName string, spec mongo.IndexModel) error
 }
@@ -15,6 +15,14 @@ func (c *clientContext) FindOne(ctx context.Context, coll string, filter interfa
 	return c.client.Database(c.db).Collection(coll).FindOne(ctx, filter)
 }
 
+func (c *clientContext) CountDocuments(ctx context.Context, coll string, filter interface{}) (int64, error) {
+	return c.client.Database(c.db).Collection(coll).CountDocuments(ctx, filter)
+}
+
+func (c *clientContext) Aggregate(ctx context.Context, coll string, pipeline interface{}) (*mongo.Cursor, error) {
+	return c.client.Database(c.db).Collection(coll).Aggregate(ctx, pipeline)
+}
+
 func (c *clientContext) UpdateOne(ctx context.Context, coll string, filter, update interface{}) error {
 	_, err := c.client.Database(c.db).Collection(coll).UpdateOne(ctx, filter, update)
 	if err != nil {
@@ -26,3 +34,19 @@ func (c *clientContext) UpdateOne(ctx context.Context, coll string, filter, upda
 func (c *clientContext) InsertOne(ctx context.Context, coll string, doc interface{}) (*mongo.InsertOneResult, error) {
 	return c.client.Database(c.db).Collection(coll).InsertOne(ctx, doc)
 }
+
+func (c *clientContext) InsertMany(ctx context.Context, coll string, docs []interface{}) error {
+	_, err := c.client.Database(c.db).Collection(coll).InsertMany(ctx, docs)
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func (c *clientContext) DeleteOne(ctx context.Context, coll string, filter interface{}) error {
+	_, err := c.client.Database(c.db).Collection(coll).DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,405 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// aggregate runs $match, $sort, $skip, $limit, $unwind, $group and $count stages
+func aggregate(docs []bson.D, pipeline bson.A) ([]bson.D, error) {
+	for _, s := range pipeline {
+		stage := toD(s)
+		if len(stage) != 1 {
+			return nil, errors.New("pipeline stage has to contain exactly one operator")
+		}
+		var err error
+		switch stage[0].Key {
+		case "$match":
+			docs, err = matching(docs, toD(stage[0].Value))
+		case "$sort":
+			docs = sorted(docs, toD(stage[0].Value))
+		case "$skip":
+			docs = skip(docs, number(stage[0].Value).IntPart())
+		case "$limit":
+			docs = limit(docs, number(stage[0].Value).IntPart())
+		case "$unwind":
+			docs, err = unwind(docs, stage[0].Value)
+		case "$group":
+			docs, err = group(docs, toD(stage[0].Value))
+		case "$count":
+			field, _ := stage[0].Value.(string)
+			docs = []bson.D{{{Key: field, Value: int32(len(docs))}}}
+		default:
+			return nil, errors.Wrap(ErrUnsupportedOperator, stage[0].Key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return docs, nil
+}
+
+func matching(docs []bson.D, f bson.D) ([]bson.D, error) {
+	result := make([]bson.D, 0, len(docs))
+	for _, d := range docs {
+		ok, err := matches(d, f)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			result = append(result, d)
+		}
+	}
+	return result, nil
+}
+
+// sorted orders a copy of the documents, missing fields are sorted as nulls
+func sorted(docs []bson.D, by bson.D) []bson.D {
+	result := make([]bson.D, len(docs))
+	copy(result, docs)
+	sort.SliceStable(result, func(i, j int) bool {
+		for _, e := range by {
+			a, _ := lookup(result[i], e.Key)
+			b, _ := lookup(result[j], e.Key)
+			c := compare(a, b)
+			if number(e.Value).IsNegative() {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	})
+	return result
+}
+
+func skip(docs []bson.D, n int64) []bson.D {
+	if n <= 0 {
+		return docs
+	}
+	if n >= int64(len(docs)) {
+		return []bson.D{}
+	}
+	return docs[n:]
+}
+
+func limit(docs []bson.D, n int64) []bson.D {
+	if n < 0 {
+		n = -n
+	}
+	if n == 0 || n >= int64(len(docs)) {
+		return docs
+	}
+	return docs[:n]
+}
+
+// unwind outputs a document per element of the array, spec is either the path or a document with
+// path and preserveNullAndEmptyArrays
+func unwind(docs []bson.D, spec interface{}) ([]bson.D, error) {
+	path, preserve := "", false
+	switch x := spec.(type) {
+	case string:
+		path = x
+	default:
+		for _, e := range toD(x) {
+			switch e.Key {
+			case "path":
+				path, _ = e.Value.(string)
+			case "preserveNullAndEmptyArrays":
+				preserve, _ = e.Value.(bool)
+			}
+		}
+	}
+	if !strings.HasPrefix(path, "$") {
+		return nil, errors.New("$unwind path has to start with $")
+	}
+	field := path[1:]
+
+	result := make([]bson.D, 0, len(docs))
+	for _, d := range docs {
+		v, _ := lookup(d, field)
+		values, isArray := v.(bson.A)
+		if !isArray {
+			if rank(v) != rank(nil) {
+				values = bson.A{v}
+			}
+		}
+		if len(values) == 0 {
+			if preserve {
+				result = append(result, d)
+			}
+			continue
+		}
+		for _, element := range values {
+			unwound, err := set(append(bson.D{}, d...), field, element)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, unwound)
+		}
+	}
+	return result, nil
+}
+
+type accumulator struct {
+	field    string
+	operator string
+	expr     interface{}
+}
+
+func group(docs []bson.D, spec bson.D) ([]bson.D, error) {
+	var idExpr interface{}
+	accumulators := make([]accumulator, 0, len(spec))
+	for _, e := range spec {
+		if e.Key == "_id" {
+			idExpr = e.Value
+			continue
+		}
+		op := toD(e.Value)
+		if len(op) != 1 {
+			return nil, errors.Errorf("accumulator of %s has to contain exactly one operator", e.Key)
+		}
+		accumulators = append(accumulators, accumulator{field: e.Key, operator: op[0].Key, expr: op[0].Value})
+	}
+
+	type bucket struct {
+		id     interface{}
+		values [][]interface{}
+	}
+	var buckets []*bucket
+	for _, d := range docs {
+		id, err := evaluate(d, idExpr)
+		if err != nil {
+			return nil, err
+		}
+		var b *bucket
+		for _, candidate := range buckets {
+			if equal(candidate.id, id) {
+				b = candidate
+				break
+			}
+		}
+		if b == nil {
+			b = &bucket{id: id, values: make([][]interface{}, len(accumulators))}
+			buckets = append(buckets, b)
+		}
+		for i, a := range accumulators {
+			v, err := evaluate(d, a.expr)
+			if err != nil {
+				return nil, err
+			}
+			b.values[i] = append(b.values[i], v)
+		}
+	}
+
+	result := make([]bson.D, 0, len(buckets))
+	for _, b := range buckets {
+		d := bson.D{{Key: "_id", Value: b.id}}
+		for i, a := range accumulators {
+			v, err := accumulate(a.operator, b.values[i])
+			if err != nil {
+				return nil, err
+			}
+			d = append(d, bson.E{Key: a.field, Value: v})
+		}
+		result = append(result, d)
+	}
+	return result, nil
+}
+
+func accumulate(operator string, values []interface{}) (interface{}, error) {
+	switch operator {
+	case "$sum":
+		var sum interface{} = int32(0)
+		for _, v := range values {
+			if isNumber(v) {
+				sum = add(sum, v)
+			}
+		}
+		return sum, nil
+	case "$avg":
+		var sum interface{} = int32(0)
+		count := 0
+		for _, v := range values {
+			if isNumber(v) {
+				sum = add(sum, v)
+				count++
+			}
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		avg := number(sum).Div(number(int64(count)))
+		if _, ok := sum.(primitive.Decimal128); ok {
+			d, _ := primitive.ParseDecimal128(avg.String())
+			return d, nil
+		}
+		f, _ := avg.Float64()
+		return f, nil
+	case "$min", "$max":
+		var result interface{}
+		for _, v := range values {
+			if rank(v) == rank(nil) {
+				continue
+			}
+			if result == nil || (operator == "$min" && compare(v, result) < 0) || (operator == "$max" && compare(v, result) > 0) {
+				result = v
+			}
+		}
+		return result, nil
+	case "$first":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values[0], nil
+	case "$last":
+		if len(values) == 0 {
+			return nil, nil
+		}
+		return values[len(values)-1], nil
+	case "$push":
+		return bson.A(values), nil
+	case "$count":
+		return int32(len(values)), nil
+	default:
+		return nil, errors.Wrap(ErrUnsupportedOperator, operator)
+	}
+}
+
+// evaluate resolves aggregation expression, field paths start with $
+func evaluate(doc bson.D, expr interface{}) (interface{}, error) {
+	switch x := expr.(type) {
+	case string:
+		if strings.HasPrefix(x, "$") {
+			v, _ := lookup(doc, x[1:])
+			return v, nil
+		}
+		return x, nil
+	case bson.A:
+		result := make(bson.A, len(x))
+		for i := range x {
+			v, err := evaluate(doc, x[i])
+			if err != nil {
+				return nil, err
+			}
+			result[i] = v
+		}
+		return result, nil
+	case bson.D:
+		if len(x) == 1 && strings.HasPrefix(x[0].Key, "$") {
+			return evaluateOperator(doc, x[0].Key, x[0].Value)
+		}
+		result := make(bson.D, 0, len(x))
+		for _, e := range x {
+			v, err := evaluate(doc, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, bson.E{Key: e.Key, Value: v})
+		}
+		return result, nil
+	default:
+		return expr, nil
+	}
+}
+
+func evaluateOperator(doc bson.D, operator string, args interface{}) (interface{}, error) {
+	if operator == "$literal" {
+		return args, nil
+	}
+	if operator == "$cond" {
+		if d, ok := args.(bson.D); ok {
+			branches := bson.A{nil, nil, nil}
+			for _, e := range d {
+				switch e.Key {
+				case "if":
+					branches[0] = e.Value
+				case "then":
+					branches[1] = e.Value
+				case "else":
+					branches[2] = e.Value
+				}
+			}
+			args = branches
+		}
+		branches, ok := args.(bson.A)
+		if !ok || len(branches) != 3 {
+			return nil, errors.New("$cond requires if, then and else")
+		}
+		condition, err := evaluate(doc, branches[0])
+		if err != nil {
+			return nil, err
+		}
+		if truthy(condition) {
+			return evaluate(doc, branches[1])
+		}
+		return evaluate(doc, branches[2])
+	}
+
+	evaluated, err := evaluate(doc, args)
+	if err != nil {
+		return nil, err
+	}
+	operands, ok := evaluated.(bson.A)
+	if !ok {
+		operands = bson.A{evaluated}
+	}
+	switch operator {
+	case "$eq", "$ne", "$gt", "$gte", "$lt", "$lte":
+		if len(operands) != 2 {
+			return nil, errors.Errorf("%s requires exactly two operands", operator)
+		}
+		c := compare(operands[0], operands[1])
+		switch operator {
+		case "$eq":
+			return c == 0, nil
+		case "$ne":
+			return c != 0, nil
+		case "$gt":
+			return c > 0, nil
+		case "$gte":
+			return c >= 0, nil
+		case "$lt":
+			return c < 0, nil
+		default:
+			return c <= 0, nil
+		}
+	case "$in":
+		if len(operands) != 2 {
+			return nil, errors.New("$in requires exactly two operands")
+		}
+		values, ok := operands[1].(bson.A)
+		if !ok {
+			return nil, errors.New("$in requires an array as the second operand")
+		}
+		for _, v := range values {
+			if equal(operands[0], v) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$ifNull":
+		for _, o := range operands {
+			if rank(o) != rank(nil) {
+				return o, nil
+			}
+		}
+		return nil, nil
+	case "$add":
+		var sum interface{} = int32(0)
+		for _, o := range operands {
+			if !isNumber(o) {
+				return nil, errors.New("$add supports numeric operands only")
+			}
+			sum = add(sum, o)
+		}
+		return sum, nil
+	default:
+		return nil, errors.Wrap(ErrUnsupportedOperator, operator)
+	}
+}
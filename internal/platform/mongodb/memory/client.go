@@ -0,0 +1,375 @@
+// Package memory implements mongodb.Clienter without a server, for tests and local runs.
+// Documents are kept as bson, so they are decoded by the same codecs as the real driver uses.
+package memory
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb"
+)
+
+const (
+	// URI selects the in-memory store instead of a mongodb server
+	URI = "memory://"
+
+	_duplicateKeyCode = 11000
+)
+
+type collection struct {
+	docs []bson.D
+	// unique holds the unique indexes, _id is always unique
+	unique []uniqueIndex
+}
+
+// uniqueIndex only covers the documents matching the partial filter, when it is set
+type uniqueIndex struct {
+	fields  []string
+	partial bson.D
+}
+
+func (idx uniqueIndex) covers(d bson.D) bool {
+	if len(idx.partial) == 0 {
+		return true
+	}
+	ok, err := matches(d, idx.partial)
+	return err == nil && ok
+}
+
+type clientContext struct {
+	/* transactions are serialized, so a rollback restores the state from before the transaction */
+	tx sync.Mutex
+
+	mu          sync.RWMutex
+	collections map[string]*collection
+}
+
+// New creates an empty store, TTL indexes are accepted but documents never expire
+func New() mongodb.Clienter {
+	c := clientContext{
+		collections: make(map[string]*collection),
+	}
+	return &c
+}
+
+// collection has to be called with the write lock held
+func (c *clientContext) collection(name string) *collection {
+	coll, exists := c.collections[name]
+	if !exists {
+		coll = &collection{unique: []uniqueIndex{{fields: []string{"_id"}}}}
+		c.collections[name] = coll
+	}
+	return coll
+}
+
+func (c *clientContext) snapshot(name string) []bson.D {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	coll, exists := c.collections[name]
+	if !exists {
+		return nil
+	}
+	/* documents are never modified in place, copy of the slice is enough */
+	docs := make([]bson.D, len(coll.docs))
+	copy(docs, coll.docs)
+	return docs
+}
+
+func (c *clientContext) Find(ctx context.Context, coll string, filter interface{}, opt *options.FindOptions) (*mongo.Cursor, error) {
+	f, err := normalize(filter)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := matching(c.snapshot(coll), f)
+	if err != nil {
+		return nil, err
+	}
+	if opt != nil {
+		if opt.Sort != nil {
+			by, err := normalize(opt.Sort)
+			if err != nil {
+				return nil, err
+			}
+			docs = sorted(docs, by)
+		}
+		if opt.Skip != nil {
+			docs = skip(docs, *opt.Skip)
+		}
+		if opt.Limit != nil {
+			docs = limit(docs, *opt.Limit)
+		}
+	}
+	return cursor(docs)
+}
+
+func (c *clientContext) FindOne(ctx context.Context, coll string, filter interface{}) mongodb.SingleResulter {
+	f, err := normalize(filter)
+	if err != nil {
+		return &singleResult{err: err}
+	}
+	docs, err := matching(c.snapshot(coll), f)
+	if err != nil {
+		return &singleResult{err: err}
+	}
+	if len(docs) == 0 {
+		return &singleResult{err: mongo.ErrNoDocuments}
+	}
+	return newSingleResult(docs[0])
+}
+
+func (c *clientContext) CountDocuments(ctx context.Context, coll string, filter interface{}) (int64, error) {
+	f, err := normalize(filter)
+	if err != nil {
+		return 0, err
+	}
+	docs, err := matching(c.snapshot(coll), f)
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(docs)), nil
+}
+
+func (c *clientContext) Aggregate(ctx context.Context, coll string, pipeline interface{}) (*mongo.Cursor, error) {
+	stages, err := normalizeArray(pipeline)
+	if err != nil {
+		return nil, err
+	}
+	docs, err := aggregate(c.snapshot(coll), stages)
+	if err != nil {
+		return nil, err
+	}
+	return cursor(docs)
+}
+
+func (c *clientContext) UpdateOne(ctx context.Context, coll string, filter, changes interface{}) error {
+	f, err := normalize(filter)
+	if err != nil {
+		return err
+	}
+	u, err := normalize(changes)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	col := c.collection(coll)
+	for i, d := range col.docs {
+		ok, err := matches(d, f)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		updated, err := update(d, u)
+		if err != nil {
+			return err
+		}
+		if err := col.checkUnique(updated, i); err != nil {
+			return err
+		}
+		col.docs[i] = updated
+		return nil
+	}
+	return nil
+}
+
+func (c *clientContext) InsertOne(ctx context.Context, coll string, doc interface{}) (*mongo.InsertOneResult, error) {
+	d, err := normalize(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id, err := c.collection(coll).insert(d)
+	if err != nil {
+		return nil, mongo.WriteException{WriteErrors: []mongo.WriteError{toWriteError(err, 0)}}
+	}
+	return &mongo.InsertOneResult{InsertedID: id}, nil
+}
+
+// InsertMany is ordered, documents inserted before the failing one are kept
+func (c *clientContext) InsertMany(ctx context.Context, coll string, docs []interface{}) error {
+	normalized := make([]bson.D, 0, len(docs))
+	for _, doc := range docs {
+		d, err := normalize(doc)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, d)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	col := c.collection(coll)
+	for i, d := range normalized {
+		if _, err := col.insert(d); err != nil {
+			bulkErr := mongo.BulkWriteError{WriteError: toWriteError(err, i)}
+			return mongo.BulkWriteException{WriteErrors: []mongo.BulkWriteError{bulkErr}}
+		}
+	}
+	return nil
+}
+
+func (c *clientContext) DeleteOne(ctx context.Context, coll string, filter interface{}) error {
+	f, err := normalize(filter)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	col := c.collection(coll)
+	for i, d := range col.docs {
+		ok, err := matches(d, f)
+		if err != nil {
+			return err
+		}
+		if ok {
+			col.docs = append(col.docs[:i:i], col.docs[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+// WithinTransaction restores all the collections when the callback fails. Session of the passed context
+// does not support any operation, writes made outside of the transaction while it runs are rolled back as well.
+func (c *clientContext) WithinTransaction(ctx context.Context, cb mongodb.TransactionCallback) (interface{}, error) {
+	c.tx.Lock()
+	defer c.tx.Unlock()
+
+	c.mu.RLock()
+	backup := make(map[string]collection, len(c.collections))
+	for name, coll := range c.collections {
+		docs := make([]bson.D, len(coll.docs))
+		copy(docs, coll.docs)
+		backup[name] = collection{docs: docs, unique: coll.unique}
+	}
+	c.mu.RUnlock()
+
+	result, err := cb(mongo.NewSessionContext(ctx, nil))
+	if err != nil {
+		c.mu.Lock()
+		c.collections = make(map[string]*collection, len(backup))
+		for name := range backup {
+			coll := backup[name]
+			c.collections[name] = &coll
+		}
+		c.mu.Unlock()
+		return nil, err
+	}
+	return result, nil
+}
+
+// CreateIndex only enforces unique indexes, others are accepted for compatibility
+func (c *clientContext) CreateIndex(ctx context.Context, collectionName string, spec mongo.IndexModel) error {
+	if spec.Options == nil || spec.Options.Unique == nil || !*spec.Options.Unique {
+		return nil
+	}
+	keys, err := normalize(spec.Keys)
+	if err != nil {
+		return err
+	}
+	fields := make([]string, 0, len(keys))
+	for _, k := range keys {
+		fields = append(fields, k.Key)
+	}
+	var partial bson.D
+	if spec.Options.PartialFilterExpression != nil {
+		if partial, err = normalize(spec.Options.PartialFilterExpression); err != nil {
+			return err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	col := c.collection(collectionName)
+	for _, existing := range col.unique {
+		if strings.Join(existing.fields, ",") == strings.Join(fields, ",") {
+			return nil
+		}
+	}
+	col.unique = append(col.unique, uniqueIndex{fields: fields, partial: partial})
+	for i, d := range col.docs {
+		if err := col.checkUnique(d, i); err != nil {
+			col.unique = col.unique[:len(col.unique)-1]
+			return errors.Wrap(err, "could not create mongodb index")
+		}
+	}
+	return nil
+}
+
+func (col *collection) insert(d bson.D) (interface{}, error) {
+	id, exists := lookup(d, "_id")
+	if !exists {
+		id = primitive.NewObjectID()
+		d = append(bson.D{{Key: "_id", Value: id}}, d...)
+	}
+	if err := col.checkUnique(d, -1); err != nil {
+		return nil, err
+	}
+	col.docs = append(col.docs, d)
+	return id, nil
+}
+
+// checkUnique compares the document with all the others but the one at position self
+func (col *collection) checkUnique(d bson.D, self int) error {
+	for _, idx := range col.unique {
+		if !idx.covers(d) {
+			continue
+		}
+		for i, other := range col.docs {
+			if i == self || !idx.covers(other) {
+				continue
+			}
+			duplicate := true
+			for _, f := range idx.fields {
+				a, _ := lookup(d, f)
+				b, _ := lookup(other, f)
+				if !equal(a, b) {
+					duplicate = false
+					break
+				}
+			}
+			if duplicate {
+				return errDuplicateKey{fields: idx.fields}
+			}
+		}
+	}
+	return nil
+}
+
+type errDuplicateKey struct {
+	fields []string
+}
+
+func (e errDuplicateKey) Error() string {
+	return fmt.Sprintf("E11000 duplicate key error, index: %s", strings.Join(e.fields, "_1_")+"_1")
+}
+
+func toWriteError(err error, index int) mongo.WriteError {
+	we := mongo.WriteError{Index: index, Message: err.Error()}
+	if _, ok := err.(errDuplicateKey); ok {
+		we.Code = _duplicateKeyCode
+	}
+	return we
+}
+
+func cursor(docs []bson.D) (*mongo.Cursor, error) {
+	items := make([]interface{}, 0, len(docs))
+	for _, d := range docs {
+		items = append(items, d)
+	}
+	return mongo.NewCursorFromDocuments(items, nil, nil)
+}
@@ -0,0 +1,422 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type document struct {
+	ID      primitive.ObjectID   `bson:"_id,omitempty"`
+	Name    string               `bson:"name"`
+	Count   int                  `bson:"count"`
+	Amount  primitive.Decimal128 `bson:"amount"`
+	Tags    []string             `bson:"tags"`
+	Created time.Time            `bson:"created"`
+}
+
+func seed(t *testing.T, c *clientContext, docs ...document) []primitive.ObjectID {
+	ids := make([]primitive.ObjectID, 0, len(docs))
+	for _, d := range docs {
+		result, err := c.InsertOne(context.Background(), "docs", d)
+		assert.NoError(t, err)
+		ids = append(ids, result.InsertedID.(primitive.ObjectID))
+	}
+	return ids
+}
+
+func decimal128(s string) primitive.Decimal128 {
+	d, _ := primitive.ParseDecimal128(s)
+	return d
+}
+
+func TestFind(t *testing.T) {
+	t.Run("should filter, sort, skip and limit documents", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		seed(t, c,
+			document{Name: "a", Count: 1, Amount: decimal128("1.50")},
+			document{Name: "b", Count: 2, Amount: decimal128("20")},
+			document{Name: "c", Count: 3, Amount: decimal128("3.25")},
+			document{Name: "d", Count: 4, Amount: decimal128("0.10")},
+		)
+		filter := bson.D{{"amount", bson.D{{"$gte", decimal128("1")}}}}
+		opt := options.Find().SetSort(bson.D{{"count", -1}}).SetSkip(1).SetLimit(5)
+
+		// act
+		cursor, err := c.Find(context.Background(), "docs", filter, opt)
+
+		// assert
+		assert.NoError(t, err)
+		var result []document
+		assert.NoError(t, cursor.All(context.Background(), &result))
+		assert.Len(t, result, 2)
+		assert.Equal(t, "b", result[0].Name)
+		assert.Equal(t, "a", result[1].Name)
+	})
+
+	t.Run("should match $or, $in, $exists and array elements", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		seed(t, c,
+			document{Name: "a", Tags: []string{"x", "y"}},
+			document{Name: "b", Tags: []string{"z"}},
+			document{Name: "c"},
+		)
+		tests := []struct {
+			filter   bson.D
+			expected int64
+		}{
+			{bson.D{{"tags", "y"}}, 1},
+			{bson.D{{"name", bson.D{{"$in", bson.A{"a", "c"}}}}}, 2},
+			{bson.D{{"$or", bson.A{bson.D{{"name", "b"}}, bson.D{{"tags", "x"}}}}}, 2},
+			{bson.D{{"missing", bson.D{{"$exists", false}}}}, 3},
+			{bson.D{{"missing", nil}}, 3},
+			{bson.D{{"name", bson.D{{"$ne", "a"}}}}, 2},
+		}
+
+		for _, tt := range tests {
+			// act
+			count, err := c.CountDocuments(context.Background(), "docs", tt.filter)
+
+			// assert
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, count, tt.filter)
+		}
+	})
+
+	t.Run("should return unsupported operator error", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		seed(t, c, document{Name: "a"})
+
+		// act
+		_, err := c.Find(context.Background(), "docs", bson.D{{"name", bson.D{{"$regex", "a"}}}}, nil)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrUnsupportedOperator))
+	})
+}
+
+func TestFindOne(t *testing.T) {
+	t.Run("should return no documents error", func(t *testing.T) {
+		// arrange
+		c := New()
+
+		// act
+		var d document
+		err := c.FindOne(context.Background(), "docs", bson.D{{"name", "a"}}).Decode(&d)
+
+		// assert
+		assert.Equal(t, mongo.ErrNoDocuments, err)
+	})
+
+	t.Run("should decode document", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		created := time.Now().UTC().Truncate(time.Millisecond)
+		ids := seed(t, c, document{Name: "a", Amount: decimal128("1.50"), Created: created})
+
+		// act
+		var d document
+		err := c.FindOne(context.Background(), "docs", bson.D{{"_id", ids[0]}}).Decode(&d)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "a", d.Name)
+		assert.Equal(t, "1.50", d.Amount.String())
+		assert.Equal(t, created, d.Created)
+	})
+}
+
+func TestUpdateOne(t *testing.T) {
+	t.Run("should apply update operators", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		ids := seed(t, c, document{Name: "a", Count: 1, Tags: []string{"x"}})
+		changes := bson.D{
+			{"$set", bson.D{{"name", "b"}}},
+			{"$inc", bson.D{{"count", 2}}},
+			{"$push", bson.D{{"tags", "y"}}},
+		}
+
+		// act
+		err := c.UpdateOne(context.Background(), "docs", bson.D{{"_id", ids[0]}}, changes)
+
+		// assert
+		assert.NoError(t, err)
+		var d document
+		assert.NoError(t, c.FindOne(context.Background(), "docs", bson.D{{"_id", ids[0]}}).Decode(&d))
+		assert.Equal(t, "b", d.Name)
+		assert.Equal(t, 3, d.Count)
+		assert.Equal(t, []string{"x", "y"}, d.Tags)
+	})
+
+	t.Run("should set array element by its index", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		ids := seed(t, c, document{Name: "a", Tags: []string{"x", "y"}})
+
+		// act
+		err := c.UpdateOne(context.Background(), "docs", bson.D{{"_id", ids[0]}}, bson.D{{"$set", bson.D{{"tags.1", "z"}}}})
+		errOutOfRange := c.UpdateOne(context.Background(), "docs", bson.D{{"_id", ids[0]}}, bson.D{{"$set", bson.D{{"tags.2", "z"}}}})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Error(t, errOutOfRange)
+		var d document
+		assert.NoError(t, c.FindOne(context.Background(), "docs", bson.D{{"_id", ids[0]}}).Decode(&d))
+		assert.Equal(t, []string{"x", "z"}, d.Tags)
+	})
+
+	t.Run("should update fields of array element", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		legs := bson.A{bson.D{{"status", "ready"}, {"error", "timeout"}}, bson.D{{"status", "ready"}}}
+		_, err := c.InsertOne(context.Background(), "docs", bson.D{{"_id", "a"}, {"legs", legs}})
+		assert.NoError(t, err)
+		changes := bson.D{
+			{"$set", bson.D{{"legs.0.status", "sent"}}},
+			{"$unset", bson.D{{"legs.0.error", ""}}},
+		}
+
+		// act
+		err = c.UpdateOne(context.Background(), "docs", bson.D{{"_id", "a"}, {"legs.0.status", "ready"}}, changes)
+
+		// assert
+		assert.NoError(t, err)
+		var d struct {
+			Legs []struct {
+				Status string `bson:"status"`
+				Error  string `bson:"error,omitempty"`
+			} `bson:"legs"`
+		}
+		assert.NoError(t, c.FindOne(context.Background(), "docs", bson.D{{"_id", "a"}}).Decode(&d))
+		assert.Equal(t, "sent", d.Legs[0].Status)
+		assert.Empty(t, d.Legs[0].Error)
+		assert.Equal(t, "ready", d.Legs[1].Status)
+	})
+
+	t.Run("should unset field", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		ids := seed(t, c, document{Name: "a"})
+
+		// act
+		err := c.UpdateOne(context.Background(), "docs", bson.D{{"_id", ids[0]}}, bson.D{{"$unset", bson.D{{"name", ""}}}})
+
+		// assert
+		assert.NoError(t, err)
+		count, _ := c.CountDocuments(context.Background(), "docs", bson.D{{"name", bson.D{{"$exists", true}}}})
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("should not modify _id", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		ids := seed(t, c, document{Name: "a"})
+
+		// act
+		err := c.UpdateOne(context.Background(), "docs", bson.D{{"_id", ids[0]}}, bson.D{{"$set", bson.D{{"_id", primitive.NewObjectID()}}}})
+
+		// assert
+		assert.Equal(t, ErrImmutableID, err)
+	})
+}
+
+func TestInsertOne(t *testing.T) {
+	t.Run("should return duplicate key error", func(t *testing.T) {
+		// arrange
+		c := New()
+		_, err := c.InsertOne(context.Background(), "keys", bson.D{{"_id", "key"}})
+		assert.NoError(t, err)
+
+		// act
+		_, err = c.InsertOne(context.Background(), "keys", bson.D{{"_id", "key"}})
+
+		// assert
+		assert.True(t, mongo.IsDuplicateKeyError(err))
+	})
+
+	t.Run("should enforce unique index", func(t *testing.T) {
+		// arrange
+		c := New()
+		idx := mongo.IndexModel{Keys: bson.D{{"name", 1}}, Options: options.Index().SetUnique(true)}
+		assert.NoError(t, c.CreateIndex(context.Background(), "docs", idx))
+		assert.NoError(t, c.InsertMany(context.Background(), "docs", []interface{}{document{Name: "a"}, document{Name: "b"}}))
+
+		// act
+		err := c.InsertMany(context.Background(), "docs", []interface{}{document{Name: "c"}, document{Name: "a"}})
+
+		// assert
+		assert.True(t, mongo.IsDuplicateKeyError(err))
+		count, _ := c.CountDocuments(context.Background(), "docs", nil)
+		assert.Equal(t, int64(3), count)
+	})
+
+	t.Run("should enforce partial unique index only on covered documents", func(t *testing.T) {
+		// arrange
+		c := New()
+		opt := options.Index().SetUnique(true).SetPartialFilterExpression(bson.D{{"name", bson.D{{"$gt", ""}}}})
+		idx := mongo.IndexModel{Keys: bson.D{{"name", 1}}, Options: opt}
+		assert.NoError(t, c.CreateIndex(context.Background(), "docs", idx))
+		assert.NoError(t, c.InsertMany(context.Background(), "docs", []interface{}{document{Name: "a"}, document{}, document{}}))
+
+		// act
+		_, err := c.InsertOne(context.Background(), "docs", document{Name: "a"})
+
+		// assert
+		assert.True(t, mongo.IsDuplicateKeyError(err))
+	})
+}
+
+func TestDeleteOne(t *testing.T) {
+	t.Run("should delete first matching document", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		seed(t, c, document{Name: "a"}, document{Name: "a"})
+
+		// act
+		err := c.DeleteOne(context.Background(), "docs", bson.D{{"name", "a"}})
+
+		// assert
+		assert.NoError(t, err)
+		count, _ := c.CountDocuments(context.Background(), "docs", bson.D{{"name", "a"}})
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestWithinTransaction(t *testing.T) {
+	t.Run("should roll back writes of failed transaction", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		ids := seed(t, c, document{Name: "a"})
+		failure := errors.New("failure")
+
+		// act
+		_, err := c.WithinTransaction(context.Background(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+			_, _ = c.InsertOne(sessCtx, "docs", document{Name: "b"})
+			_ = c.UpdateOne(sessCtx, "docs", bson.D{{"_id", ids[0]}}, bson.D{{"$set", bson.D{{"name", "c"}}}})
+			_, _ = c.InsertOne(sessCtx, "other", document{Name: "d"})
+			return nil, failure
+		})
+
+		// assert
+		assert.Equal(t, failure, err)
+		count, _ := c.CountDocuments(context.Background(), "docs", bson.D{{"name", "a"}})
+		assert.Equal(t, int64(1), count)
+		count, _ = c.CountDocuments(context.Background(), "docs", nil)
+		assert.Equal(t, int64(1), count)
+		count, _ = c.CountDocuments(context.Background(), "other", nil)
+		assert.Equal(t, int64(0), count)
+	})
+
+	t.Run("should keep writes of committed transaction", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+
+		// act
+		result, err := c.WithinTransaction(context.Background(), func(sessCtx mongo.SessionContext) (interface{}, error) {
+			_, err := c.InsertOne(sessCtx, "docs", document{Name: "a"})
+			return "done", err
+		})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "done", result)
+		count, _ := c.CountDocuments(context.Background(), "docs", nil)
+		assert.Equal(t, int64(1), count)
+	})
+}
+
+func TestAggregate(t *testing.T) {
+	t.Run("should group documents", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		first := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+		second := first.Add(time.Hour)
+		seed(t, c,
+			document{Name: "a", Count: 1, Amount: decimal128("1.50"), Created: first},
+			document{Name: "a", Count: 2, Amount: decimal128("2.25"), Created: second},
+			document{Name: "b", Count: 3, Amount: decimal128("5")},
+		)
+		zero := decimal128("0")
+		pipeline := bson.A{
+			bson.D{{"$match", bson.D{{"count", bson.D{{"$lt", 10}}}}}},
+			bson.D{{"$group", bson.D{
+				{"_id", "$name"},
+				{"total", bson.D{{"$sum", "$amount"}}},
+				{"big", bson.D{{"$sum", bson.D{{"$cond", bson.A{bson.D{{"$gte", bson.A{"$count", 2}}}, "$amount", zero}}}}}},
+				{"picked", bson.D{{"$sum", bson.D{{"$cond", bson.A{bson.D{{"$in", bson.A{"$count", bson.A{1, 3}}}}, "$amount", zero}}}}}},
+				{"last", bson.D{{"$max", "$created"}}},
+			}}},
+			bson.D{{"$sort", bson.D{{"_id", -1}}}},
+		}
+
+		// act
+		cursor, err := c.Aggregate(context.Background(), "docs", pipeline)
+
+		// assert
+		assert.NoError(t, err)
+		var result []struct {
+			Name   string               `bson:"_id"`
+			Total  primitive.Decimal128 `bson:"total"`
+			Big    primitive.Decimal128 `bson:"big"`
+			Picked primitive.Decimal128 `bson:"picked"`
+			Last   time.Time            `bson:"last"`
+		}
+		assert.NoError(t, cursor.All(context.Background(), &result))
+		assert.Len(t, result, 2)
+		assert.Equal(t, "b", result[0].Name)
+		assert.Equal(t, "a", result[1].Name)
+		assert.Equal(t, "3.75", result[1].Total.String())
+		assert.Equal(t, "2.25", result[1].Big.String())
+		assert.Equal(t, "1.5", result[1].Picked.String())
+		assert.Equal(t, "5", result[0].Picked.String())
+		assert.Equal(t, second, result[1].Last.UTC())
+	})
+
+	t.Run("should unwind arrays, keeping documents without any when asked to", func(t *testing.T) {
+		// arrange
+		c := New().(*clientContext)
+		seed(t, c,
+			document{Name: "a", Tags: []string{"x", "y"}},
+			document{Name: "b", Tags: []string{"x"}},
+			document{Name: "c"},
+		)
+		pipeline := func(preserve bool) bson.A {
+			return bson.A{
+				bson.D{{"$unwind", bson.D{{"path", "$tags"}, {"preserveNullAndEmptyArrays", preserve}}}},
+				bson.D{{"$group", bson.D{
+					{"_id", bson.D{{"$ifNull", bson.A{"$tags", "none"}}}},
+					{"count", bson.D{{"$sum", 1}}},
+				}}},
+				bson.D{{"$sort", bson.D{{"_id", 1}}}},
+			}
+		}
+		type tagCount struct {
+			Tag   string `bson:"_id"`
+			Count int    `bson:"count"`
+		}
+
+		// act
+		preserved, err := c.Aggregate(context.Background(), "docs", pipeline(true))
+		assert.NoError(t, err)
+		dropped, err := c.Aggregate(context.Background(), "docs", pipeline(false))
+		assert.NoError(t, err)
+
+		// assert
+		var withEmpty, withoutEmpty []tagCount
+		assert.NoError(t, preserved.All(context.Background(), &withEmpty))
+		assert.NoError(t, dropped.All(context.Background(), &withoutEmpty))
+		assert.Equal(t, []tagCount{{"none", 1}, {"x", 2}, {"y", 1}}, withEmpty)
+		assert.Equal(t, []tagCount{{"x", 2}, {"y", 1}}, withoutEmpty)
+	})
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"bytes"
+	"math"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// rank orders values of different types the same way mongodb does
+func rank(v interface{}) int {
+	switch v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return 1
+	case int32, int64, float64, primitive.Decimal128:
+		return 2
+	case string, primitive.Symbol:
+		return 3
+	case bson.D, bson.M:
+		return 4
+	case bson.A:
+		return 5
+	case primitive.Binary:
+		return 6
+	case primitive.ObjectID:
+		return 7
+	case bool:
+		return 8
+	case primitive.DateTime:
+		return 9
+	case primitive.Timestamp:
+		return 10
+	case primitive.Regex:
+		return 11
+	default:
+		return 12
+	}
+}
+
+// compare returns -1, 0 or 1, values of different types are ordered by their type
+func compare(a, b interface{}) int {
+	ra, rb := rank(a), rank(b)
+	if ra != rb {
+		return sign(ra - rb)
+	}
+
+	switch x := a.(type) {
+	case int32, int64, float64, primitive.Decimal128:
+		return number(x).Cmp(number(b))
+	case string:
+		return strings.Compare(x, b.(string))
+	case primitive.Symbol:
+		return strings.Compare(string(x), string(b.(primitive.Symbol)))
+	case bson.D:
+		return compareDocuments(x, toD(b))
+	case bson.M:
+		return compareDocuments(toD(x), toD(b))
+	case bson.A:
+		y := b.(bson.A)
+		for i := 0; i < len(x) && i < len(y); i++ {
+			if c := compare(x[i], y[i]); c != 0 {
+				return c
+			}
+		}
+		return sign(len(x) - len(y))
+	case primitive.Binary:
+		y := b.(primitive.Binary)
+		if c := sign(len(x.Data) - len(y.Data)); c != 0 {
+			return c
+		}
+		return bytes.Compare(x.Data, y.Data)
+	case primitive.ObjectID:
+		y := b.(primitive.ObjectID)
+		return bytes.Compare(x[:], y[:])
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		default:
+			return 1
+		}
+	case primitive.DateTime:
+		return sign64(int64(x) - int64(b.(primitive.DateTime)))
+	case primitive.Timestamp:
+		return primitive.CompareTimestamp(x, b.(primitive.Timestamp))
+	default:
+		return 0
+	}
+}
+
+func compareDocuments(a, b bson.D) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := strings.Compare(a[i].Key, b[i].Key); c != 0 {
+			return c
+		}
+		if c := compare(a[i].Value, b[i].Value); c != 0 {
+			return c
+		}
+	}
+	return sign(len(a) - len(b))
+}
+
+func equal(a, b interface{}) bool {
+	return compare(a, b) == 0
+}
+
+// number converts any of the numeric types, so int32, int64, double and decimal are comparable with each other
+func number(v interface{}) decimal.Decimal {
+	switch x := v.(type) {
+	case int32:
+		return decimal.NewFromInt32(x)
+	case int64:
+		return decimal.NewFromInt(x)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return decimal.Zero
+		}
+		return decimal.NewFromFloat(x)
+	case primitive.Decimal128:
+		d, err := decimal.NewFromString(x.String())
+		if err != nil {
+			return decimal.Zero
+		}
+		return d
+	default:
+		return decimal.Zero
+	}
+}
+
+func isNumber(v interface{}) bool {
+	return rank(v) == 2
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func sign64(i int64) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	default:
+		return 0
+	}
+}
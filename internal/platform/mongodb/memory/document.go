@@ -0,0 +1,213 @@
+package memory
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrNotDocument = errors.New("value can not be stored as a document")
+)
+
+// normalize round trips the value through bson, so structs, maps and documents are compared the same way
+// and the stored copy is not shared with the caller
+func normalize(v interface{}) (bson.D, error) {
+	if v == nil {
+		return bson.D{}, nil
+	}
+	raw, err := bson.Marshal(v)
+	if err != nil {
+		return nil, errors.Wrap(ErrNotDocument, err.Error())
+	}
+	var d bson.D
+	if err := bson.Unmarshal(raw, &d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// normalizeArray is normalize for the values which are not documents, e.g. aggregation pipeline
+func normalizeArray(v interface{}) (bson.A, error) {
+	d, err := normalize(bson.D{{"v", v}})
+	if err != nil {
+		return nil, err
+	}
+	a, ok := d[0].Value.(bson.A)
+	if !ok {
+		return nil, ErrNotDocument
+	}
+	return a, nil
+}
+
+func toD(v interface{}) bson.D {
+	switch x := v.(type) {
+	case bson.D:
+		return x
+	case bson.M:
+		d := make(bson.D, 0, len(x))
+		for k, v := range x {
+			d = append(d, bson.E{Key: k, Value: v})
+		}
+		return d
+	default:
+		return nil
+	}
+}
+
+func clone(d bson.D) bson.D {
+	c := make(bson.D, len(d))
+	for i, e := range d {
+		c[i] = bson.E{Key: e.Key, Value: cloneValue(e.Value)}
+	}
+	return c
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case bson.D:
+		return clone(x)
+	case bson.A:
+		c := make(bson.A, len(x))
+		for i := range x {
+			c[i] = cloneValue(x[i])
+		}
+		return c
+	default:
+		return v
+	}
+}
+
+// lookup resolves dotted path, array elements can be addressed by their index
+func lookup(d bson.D, path string) (interface{}, bool) {
+	var current interface{} = d
+	for _, key := range strings.Split(path, ".") {
+		switch x := current.(type) {
+		case bson.D:
+			found := false
+			for _, e := range x {
+				if e.Key == key {
+					current, found = e.Value, true
+					break
+				}
+			}
+			if !found {
+				return nil, false
+			}
+		case bson.A:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(x) {
+				return nil, false
+			}
+			current = x[i]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// set assigns the value under dotted path, missing intermediate documents are created and
+// existing array elements can be addressed by their index
+func set(d bson.D, path string, v interface{}) (bson.D, error) {
+	key, rest := path, ""
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		key, rest = path[:i], path[i+1:]
+	}
+	for i, e := range d {
+		if e.Key != key {
+			continue
+		}
+		if rest == "" {
+			d[i].Value = v
+			return d, nil
+		}
+		nested, err := setNested(e.Value, key, rest, v)
+		if err != nil {
+			return nil, err
+		}
+		d[i].Value = nested
+		return d, nil
+	}
+	if rest == "" {
+		return append(d, bson.E{Key: key, Value: v}), nil
+	}
+	nested, err := set(bson.D{}, rest, v)
+	if err != nil {
+		return nil, err
+	}
+	return append(d, bson.E{Key: key, Value: nested}), nil
+}
+
+func setNested(current interface{}, key, rest string, v interface{}) (interface{}, error) {
+	switch x := current.(type) {
+	case bson.D:
+		return set(x, rest, v)
+	case bson.A:
+		index, next := rest, ""
+		if i := strings.IndexByte(rest, '.'); i >= 0 {
+			index, next = rest[:i], rest[i+1:]
+		}
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(x) {
+			return nil, errors.Errorf("can not create field %s in array value of %s", rest, key)
+		}
+		if next == "" {
+			x[i] = v
+			return x, nil
+		}
+		nested, err := setNested(x[i], key+"."+index, next, v)
+		if err != nil {
+			return nil, err
+		}
+		x[i] = nested
+		return x, nil
+	default:
+		return nil, errors.Errorf("can not create field %s in non-document value of %s", rest, key)
+	}
+}
+
+func unset(d bson.D, path string) bson.D {
+	key, rest := path, ""
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		key, rest = path[:i], path[i+1:]
+	}
+	for i, e := range d {
+		if e.Key != key {
+			continue
+		}
+		if rest == "" {
+			return append(d[:i:i], d[i+1:]...)
+		}
+		d[i].Value = unsetNested(e.Value, rest)
+		return d
+	}
+	return d
+}
+
+func unsetNested(current interface{}, rest string) interface{} {
+	switch x := current.(type) {
+	case bson.D:
+		return unset(x, rest)
+	case bson.A:
+		index, next := rest, ""
+		if i := strings.IndexByte(rest, '.'); i >= 0 {
+			index, next = rest[:i], rest[i+1:]
+		}
+		i, err := strconv.Atoi(index)
+		if err != nil || i < 0 || i >= len(x) {
+			return x
+		}
+		/* unsetting an array element leaves null in its place */
+		if next == "" {
+			x[i] = nil
+			return x
+		}
+		x[i] = unsetNested(x[i], next)
+		return x
+	default:
+		return current
+	}
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var (
+	ErrUnsupportedOperator = errors.New("operator is not supported by the in-memory store")
+)
+
+// matches evaluates query filter against the document, only the operators used by the application are supported
+func matches(doc bson.D, filter bson.D) (bool, error) {
+	for _, e := range filter {
+		switch e.Key {
+		case "$and", "$or", "$nor":
+			conditions, ok := e.Value.(bson.A)
+			if !ok {
+				return false, errors.Errorf("%s has to be an array", e.Key)
+			}
+			ok, err := matchLogical(doc, e.Key, conditions)
+			if err != nil || !ok {
+				return false, err
+			}
+		default:
+			if strings.HasPrefix(e.Key, "$") {
+				return false, errors.Wrap(ErrUnsupportedOperator, e.Key)
+			}
+			ok, err := matchField(doc, e.Key, e.Value)
+			if err != nil || !ok {
+				return false, err
+			}
+		}
+	}
+	return true, nil
+}
+
+func matchLogical(doc bson.D, operator string, conditions bson.A) (bool, error) {
+	for _, c := range conditions {
+		ok, err := matches(doc, toD(c))
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case operator == "$and" && !ok:
+			return false, nil
+		case operator == "$or" && ok:
+			return true, nil
+		case operator == "$nor" && ok:
+			return false, nil
+		}
+	}
+	return operator != "$or", nil
+}
+
+func matchField(doc bson.D, path string, condition interface{}) (bool, error) {
+	v, exists := lookup(doc, path)
+	operators, ok := condition.(bson.D)
+	if !ok || len(operators) == 0 || !strings.HasPrefix(operators[0].Key, "$") {
+		return matchEqual(v, exists, condition), nil
+	}
+	for _, op := range operators {
+		ok, err := matchOperator(v, exists, op.Key, op.Value)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// matchEqual follows mongodb semantics, null matches missing fields and arrays match any of their elements
+func matchEqual(v interface{}, exists bool, condition interface{}) bool {
+	if !exists {
+		return rank(condition) == rank(nil)
+	}
+	if arr, ok := v.(bson.A); ok {
+		if _, ok := condition.(bson.A); !ok {
+			for _, item := range arr {
+				if equal(item, condition) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return equal(v, condition)
+}
+
+func matchOperator(v interface{}, exists bool, operator string, condition interface{}) (bool, error) {
+	switch operator {
+	case "$eq":
+		return matchEqual(v, exists, condition), nil
+	case "$ne":
+		return !matchEqual(v, exists, condition), nil
+	case "$gt", "$gte", "$lt", "$lte":
+		if !exists {
+			return false, nil
+		}
+		values := bson.A{v}
+		if arr, ok := v.(bson.A); ok {
+			values = arr
+		}
+		for _, item := range values {
+			/* comparison operators only match values of the same type */
+			if rank(item) != rank(condition) {
+				continue
+			}
+			c := compare(item, condition)
+			switch {
+			case operator == "$gt" && c > 0,
+				operator == "$gte" && c >= 0,
+				operator == "$lt" && c < 0,
+				operator == "$lte" && c <= 0:
+				return true, nil
+			}
+		}
+		return false, nil
+	case "$in", "$nin":
+		candidates, ok := condition.(bson.A)
+		if !ok {
+			return false, errors.Errorf("%s has to be an array", operator)
+		}
+		found := false
+		for _, c := range candidates {
+			if matchEqual(v, exists, c) {
+				found = true
+				break
+			}
+		}
+		return found == (operator == "$in"), nil
+	case "$exists":
+		return exists == truthy(condition), nil
+	default:
+		return false, errors.Wrap(ErrUnsupportedOperator, operator)
+	}
+}
+
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case int32, int64, float64:
+		return !number(x).IsZero()
+	default:
+		return true
+	}
+}
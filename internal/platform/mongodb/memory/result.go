@@ -0,0 +1,31 @@
+package memory
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// singleResult mirrors mongo.SingleResult, ErrNoDocuments is returned by both Err and Decode
+type singleResult struct {
+	raw bson.Raw
+	err error
+}
+
+func newSingleResult(d bson.D) *singleResult {
+	raw, err := bson.Marshal(d)
+	return &singleResult{raw: raw, err: err}
+}
+
+func (r *singleResult) Decode(v interface{}) error {
+	if r.err != nil {
+		return r.err
+	}
+	return bson.Unmarshal(r.raw, v)
+}
+
+func (r *singleResult) DecodeBytes() (bson.Raw, error) {
+	return r.raw, r.err
+}
+
+func (r *singleResult) Err() error {
+	return r.err
+}
@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrNoUpdateOperators = errors.New("update document has to contain update operators only")
+	ErrImmutableID       = errors.New("_id field can not be modified")
+)
+
+// update applies $set, $unset, $inc and $push operators to the copy of the document
+func update(doc bson.D, changes bson.D) (bson.D, error) {
+	if len(changes) == 0 {
+		return nil, ErrNoUpdateOperators
+	}
+	result := clone(doc)
+	for _, op := range changes {
+		if !strings.HasPrefix(op.Key, "$") {
+			return nil, ErrNoUpdateOperators
+		}
+		fields := toD(op.Value)
+		for _, f := range fields {
+			if f.Key == "_id" || strings.HasPrefix(f.Key, "_id.") {
+				return nil, ErrImmutableID
+			}
+			var err error
+			switch op.Key {
+			case "$set":
+				result, err = set(result, f.Key, cloneValue(f.Value))
+			case "$unset":
+				result = unset(result, f.Key)
+			case "$inc":
+				result, err = inc(result, f.Key, f.Value)
+			case "$push":
+				result, err = push(result, f.Key, f.Value)
+			default:
+				return nil, errors.Wrap(ErrUnsupportedOperator, op.Key)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return result, nil
+}
+
+func inc(doc bson.D, path string, by interface{}) (bson.D, error) {
+	if !isNumber(by) {
+		return nil, errors.Errorf("can not increment %s by a non-numeric value", path)
+	}
+	current, exists := lookup(doc, path)
+	if !exists {
+		return set(doc, path, by)
+	}
+	if !isNumber(current) {
+		return nil, errors.Errorf("can not increment non-numeric field %s", path)
+	}
+	return set(doc, path, add(current, by))
+}
+
+// add keeps the widest of both types, as mongodb does
+func add(a, b interface{}) interface{} {
+	switch {
+	case rankNumber(a) == 3 || rankNumber(b) == 3:
+		d, _ := primitive.ParseDecimal128(number(a).Add(number(b)).String())
+		return d
+	case rankNumber(a) == 2 || rankNumber(b) == 2:
+		f, _ := number(a).Add(number(b)).Float64()
+		return f
+	case rankNumber(a) == 1 || rankNumber(b) == 1:
+		return number(a).Add(number(b)).IntPart()
+	default:
+		sum := number(a).Add(number(b)).IntPart()
+		if sum > int64(^uint32(0)>>1) || sum < -int64(^uint32(0)>>1)-1 {
+			return sum
+		}
+		return int32(sum)
+	}
+}
+
+func rankNumber(v interface{}) int {
+	switch v.(type) {
+	case int64:
+		return 1
+	case float64:
+		return 2
+	case primitive.Decimal128:
+		return 3
+	default:
+		return 0
+	}
+}
+
+func push(doc bson.D, path string, v interface{}) (bson.D, error) {
+	current, exists := lookup(doc, path)
+	if !exists {
+		return set(doc, path, bson.A{cloneValue(v)})
+	}
+	arr, ok := current.(bson.A)
+	if !ok {
+		return nil, errors.Errorf("can not push to non-array field %s", path)
+	}
+	extended := make(bson.A, len(arr), len(arr)+1)
+	copy(extended, arr)
+	return set(doc, path, append(extended, cloneValue(v)))
+}
@@ -0,0 +1,8 @@
+package mongodb
+
+import "context"
+
+// Migrator reshapes documents persisted by older versions of the application
+type Migrator interface {
+	Migrate(ctx context.Context) error
+}
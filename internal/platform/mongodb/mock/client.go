@@ -38,6 +38,36 @@ func (m *MockClienter) EXPECT() *MockClienterMockRecorder {
 	return m.recorder
 }
 
+// Aggregate mocks base method.
+func (m *MockClienter) Aggregate(arg0 context.Context, arg1 string, arg2 interface{}) (*mongo.Cursor, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Aggregate", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*mongo.Cursor)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Aggregate indicates an expected call of Aggregate.
+func (mr *MockClienterMockRecorder) Aggregate(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Aggregate", reflect.TypeOf((*MockClienter)(nil).Aggregate), arg0, arg1, arg2)
+}
+
+// CountDocuments mocks base method.
+func (m *MockClienter) CountDocuments(arg0 context.Context, arg1 string, arg2 interface{}) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountDocuments", arg0, arg1, arg2)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountDocuments indicates an expected call of CountDocuments.
+func (mr *MockClienterMockRecorder) CountDocuments(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountDocuments", reflect.TypeOf((*MockClienter)(nil).CountDocuments), arg0, arg1, arg2)
+}
+
 // CreateIndex mocks base method.
 func (m *MockClienter) CreateIndex(arg0 context.Context, arg1 string, arg2 mongo.IndexModel) error {
 	m.ctrl.T.Helper()
@@ -52,6 +82,20 @@ func (mr *MockClienterMockRecorder) CreateIndex(arg0, arg1, arg2 interface{}) *g
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIndex", reflect.TypeOf((*MockClienter)(nil).CreateIndex), arg0, arg1, arg2)
 }
 
+// DeleteOne mocks base method.
+func (m *MockClienter) DeleteOne(arg0 context.Context, arg1 string, arg2 interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteOne", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteOne indicates an expected call of DeleteOne.
+func (mr *MockClienterMockRecorder) DeleteOne(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteOne", reflect.TypeOf((*MockClienter)(nil).DeleteOne), arg0, arg1, arg2)
+}
+
 // Find mocks base method.
 func (m *MockClienter) Find(arg0 context.Context, arg1 string, arg2 interface{}, arg3 *options.FindOptions) (*mongo.Cursor, error) {
 	m.ctrl.T.Helper()
@@ -81,6 +125,20 @@ func (mr *MockClienterMockRecorder) FindOne(arg0, arg1, arg2 interface{}) *gomoc
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindOne", reflect.TypeOf((*MockClienter)(nil).FindOne), arg0, arg1, arg2)
 }
 
+// InsertMany mocks base method.
+func (m *MockClienter) InsertMany(arg0 context.Context, arg1 string, arg2 []interface{}) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "InsertMany", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// InsertMany indicates an expected call of InsertMany.
+func (mr *MockClienterMockRecorder) InsertMany(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "InsertMany", reflect.TypeOf((*MockClienter)(nil).InsertMany), arg0, arg1, arg2)
+}
+
 // InsertOne mocks base method.
 func (m *MockClienter) InsertOne(arg0 context.Context, arg1 string, arg2 interface{}) (*mongo.InsertOneResult, error) {
 	m.ctrl.T.Helper()
@@ -7,15 +7,21 @@ import (
 	"github.com/streadway/amqp"
 
 	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
 )
 
+var _ transport.Transport = (*Client)(nil)
+
 type Client struct {
 	connection *amqp.Connection
+	clock      clock.Clock
 	logger     *logrus.Logger
 }
 
-func NewClient(ctx context.Context, cfg config.Config, l *logrus.Logger) (*Client, error) {
+func NewClient(ctx context.Context, cfg config.Config, clk clock.Clock, l *logrus.Logger) (*Client, error) {
 	c := Client{
+		clock:  clk,
 		logger: l,
 	}
 	conn, err := amqp.Dial(cfg.URI)
@@ -28,6 +34,10 @@ func NewClient(ctx context.Context, cfg config.Config, l *logrus.Logger) (*Clien
 	return &c, err
 }
 
+func (c *Client) NewPublisher(ctx context.Context, cfg config.Publisher) (transport.Publisher, error) {
+	return NewPublisher(ctx, c, cfg)
+}
+
 func (c *Client) close(ctx context.Context) {
 	<-ctx.Done()
 	func() { _ = c.connection.Close() }()
@@ -14,6 +14,8 @@ type Subscriber struct {
 	Queue         string `json:"queue"`
 	Exclusive     bool   `json:"exclusive"`
 	PrefetchCount int    `json:"prefetch_count"`
+	// DeadLetterQueue receives messages dropped by the callback with an error, they are discarded when not set
+	DeadLetterQueue string `json:"dead_letter_queue"`
 }
 
 func (s *Subscriber) UnmarshalEnvironmentValue(data string) error {
@@ -0,0 +1,120 @@
+package rabbitmq
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/streadway/amqp"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+)
+
+var (
+	ErrNoOriginalQueue = errors.New("dead letter does not point at the queue it came from")
+)
+
+func deadLetter(ch *amqp.Channel, cfg config.Subscriber, d amqp.Delivery, cause error, now time.Time) error {
+	headers := amqp.Table{}
+	for k, v := range d.Headers {
+		headers[k] = v
+	}
+	headers[transport.HeaderError] = cause.Error()
+	headers[transport.HeaderOriginalQueue] = cfg.Queue
+	headers[transport.HeaderFailedDate] = now.Format(time.RFC3339)
+
+	msg := amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		CorrelationId: d.CorrelationId,
+		Type:          d.Type,
+		AppId:         d.AppId,
+		Body:          d.Body,
+		Timestamp:     d.Timestamp,
+		DeliveryMode:  amqp.Persistent,
+	}
+	/* default exchange routes by the queue name */
+	return ch.Publish("", cfg.DeadLetterQueue, false, false, msg)
+}
+
+// DeadLetters returns up to limit messages from the dead letter queue without removing them
+func (c *Client) DeadLetters(ctx context.Context, queue string, limit int) ([]transport.DeadLetter, error) {
+	ch, err := c.connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	/* closing the channel returns all the unacknowledged messages to the queue */
+	defer func() { _ = ch.Close() }()
+
+	letters := make([]transport.DeadLetter, 0)
+	for len(letters) < limit {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		d, ok, err := ch.Get(queue, false)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		letters = append(letters, transport.NewDeadLetter(queue, toMessage(d)))
+	}
+	return letters, nil
+}
+
+// Requeue moves up to limit messages from the dead letter queue back to the queues they came from
+func (c *Client) Requeue(ctx context.Context, queue string, limit int) ([]transport.DeadLetter, error) {
+	ch, err := c.connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = ch.Close() }()
+
+	requeued := make([]transport.DeadLetter, 0)
+	for len(requeued) < limit {
+		if err := ctx.Err(); err != nil {
+			return requeued, err
+		}
+		d, ok, err := ch.Get(queue, false)
+		if err != nil {
+			return requeued, err
+		}
+		if !ok {
+			break
+		}
+		letter := transport.NewDeadLetter(queue, toMessage(d))
+		if letter.OriginalQueue == "" {
+			_ = d.Nack(false, true)
+			return requeued, errors.Wrap(ErrNoOriginalQueue, letter.CorrelationID)
+		}
+
+		headers := amqp.Table{}
+		for k, v := range d.Headers {
+			headers[k] = v
+		}
+		delete(headers, transport.HeaderError)
+		delete(headers, transport.HeaderOriginalQueue)
+		delete(headers, transport.HeaderFailedDate)
+		msg := amqp.Publishing{
+			Headers:       headers,
+			ContentType:   d.ContentType,
+			CorrelationId: d.CorrelationId,
+			Type:          d.Type,
+			AppId:         d.AppId,
+			Body:          d.Body,
+			Timestamp:     d.Timestamp,
+			DeliveryMode:  amqp.Persistent,
+		}
+		if err := ch.Publish("", letter.OriginalQueue, false, false, msg); err != nil {
+			_ = d.Nack(false, true)
+			return requeued, err
+		}
+		if err := d.Ack(false); err != nil {
+			return requeued, err
+		}
+		requeued = append(requeued, letter)
+	}
+	return requeued, nil
+}
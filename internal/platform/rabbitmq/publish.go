@@ -4,29 +4,28 @@ import (
 	"context"
 	"encoding/json"
 	"os"
-	"time"
 
 	"github.com/streadway/amqp"
 
 	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
 	"github.com/mazxaxz/donut-batcher/pkg/requestid"
 )
 
-type Publisher interface {
-	Publish(ctx context.Context, data interface{}, msgType string) error
-}
-
 type publisherContext struct {
 	channel *amqp.Channel
+	clock   clock.Clock
 	cfg     config.Publisher
 }
 
-func NewPublisher(ctx context.Context, c *Client, cfg config.Publisher) (Publisher, error) {
+func NewPublisher(ctx context.Context, c *Client, cfg config.Publisher) (transport.Publisher, error) {
 	if c == nil {
 		return nil, ErrClientNotProvided
 	}
 	p := publisherContext{
-		cfg: cfg,
+		clock: c.clock,
+		cfg:   cfg,
 	}
 	ch, err := c.connection.Channel()
 	if err != nil {
@@ -82,7 +81,7 @@ func (c *publisherContext) Publish(ctx context.Context, data interface{}, msgTyp
 		Type:          msgType,
 		AppId:         hostname,
 		Body:          payload,
-		Timestamp:     time.Now().UTC(),
+		Timestamp:     c.clock.Now(),
 	}
 	if err := c.channel.Publish(c.cfg.Exchange, c.cfg.RoutingKey, false, false, msg); err != nil {
 		return err
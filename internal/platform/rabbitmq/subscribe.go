@@ -12,13 +12,12 @@ import (
 	"github.com/streadway/amqp"
 
 	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
 	"github.com/mazxaxz/donut-batcher/pkg/logger"
 	"github.com/mazxaxz/donut-batcher/pkg/requestid"
 )
 
-type Callback func(ctx context.Context, delivery amqp.Delivery) (bool, error)
-
-func (c *Client) Subscribe(ctx context.Context, cfg config.Subscriber, cb Callback) {
+func (c *Client) Subscribe(ctx context.Context, cfg config.Subscriber, h transport.Handler) {
 	hostname, _ := os.Hostname()
 	ch, err := c.connection.Channel()
 	if err != nil {
@@ -46,6 +45,19 @@ func (c *Client) Subscribe(ctx context.Context, cfg config.Subscriber, cb Callba
 		return
 	}
 
+	if cfg.DeadLetterQueue != "" {
+		if _, err := ch.QueueDeclare(cfg.DeadLetterQueue, true, false, false, false, nil); err != nil {
+			entry := logger.Log{
+				Hostname:  hostname,
+				Severity:  logrus.ErrorLevel.String(),
+				Message:   errors.Wrap(err, "could not declare dead letter queue").Error(),
+				Timestamp: time.Now().UTC(),
+			}
+			c.logger.Error(entry)
+			return
+		}
+	}
+
 	consumerID := uuid.NewString()
 	ch.Qos(cfg.PrefetchCount, 0, false)
 	msgs, err := ch.Consume(cfg.Queue, fmt.Sprintf("%s_%s", hostname, consumerID), false, cfg.Exclusive, false, false, nil)
@@ -74,7 +86,7 @@ func (c *Client) Subscribe(ctx context.Context, cfg config.Subscriber, cb Callba
 			ctx = requestid.New(ctx, d.CorrelationId)
 
 			start := time.Now()
-			ack, err := cb(ctx, d)
+			ack, err := h(ctx, toMessage(d))
 			elapsed := time.Since(start)
 			if err != nil {
 				entry := logger.Log{
@@ -86,6 +98,21 @@ func (c *Client) Subscribe(ctx context.Context, cfg config.Subscriber, cb Callba
 					Milliseconds: elapsed.Milliseconds(),
 				}
 				c.logger.Error(entry)
+
+				/* message is going to be dropped, it is kept aside so operators can inspect and requeue it */
+				if ack && cfg.DeadLetterQueue != "" {
+					if err := deadLetter(ch, cfg, d, err, c.clock.Now()); err != nil {
+						entry := logger.Log{
+							Hostname:     hostname,
+							Severity:     logrus.ErrorLevel.String(),
+							RequestID:    d.CorrelationId,
+							Message:      errors.Wrap(err, "could not dead letter message").Error(),
+							Timestamp:    time.Now().UTC(),
+							Milliseconds: elapsed.Milliseconds(),
+						}
+						c.logger.Error(entry)
+					}
+				}
 			}
 			if ack {
 				if err := d.Ack(false); err != nil {
@@ -125,3 +152,14 @@ func (c *Client) Subscribe(ctx context.Context, cfg config.Subscriber, cb Callba
 	}()
 	<-hold
 }
+
+func toMessage(d amqp.Delivery) transport.Message {
+	m := transport.Message{
+		Type:          d.Type,
+		Body:          d.Body,
+		CorrelationID: d.CorrelationId,
+		Headers:       d.Headers,
+		Redelivered:   d.Redelivered,
+	}
+	return m
+}
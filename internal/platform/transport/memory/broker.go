@@ -0,0 +1,333 @@
+// Package memory implements transport.Transport in-process, for tests and local runs.
+// Queues are unbounded and live as long as the broker, nothing is persisted.
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/logger"
+	"github.com/mazxaxz/donut-batcher/pkg/requestid"
+)
+
+const (
+	// URI selects the in-memory broker instead of a rabbitmq server
+	URI = "memory://"
+
+	// HeaderDeliveryCount is incremented every time the message is nacked
+	HeaderDeliveryCount = "x-delivery-count"
+)
+
+var (
+	ErrNoQueue              = errors.New("queue name is required")
+	ErrNoOriginalQueue      = errors.New("dead letter does not point at the queue it came from")
+	ErrRedeliveriesExceeded = errors.New("message was nacked too many times")
+)
+
+type queue struct {
+	mu       sync.Mutex
+	messages []transport.Message
+	/* buffered by one, so a push never blocks and a waiting worker is woken up */
+	signal chan struct{}
+}
+
+func (q *queue) push(m transport.Message) {
+	q.mu.Lock()
+	q.messages = append(q.messages, m)
+	q.mu.Unlock()
+	q.notify()
+}
+
+func (q *queue) notify() {
+	select {
+	case q.signal <- struct{}{}:
+	default:
+	}
+}
+
+// tryPop returns the first message without waiting for one
+func (q *queue) tryPop() (transport.Message, bool) {
+	q.mu.Lock()
+	if len(q.messages) == 0 {
+		q.mu.Unlock()
+		return transport.Message{}, false
+	}
+	m := q.messages[0]
+	q.messages = q.messages[1:]
+	left := len(q.messages)
+	q.mu.Unlock()
+	/* other workers could have missed the signal consumed by this one */
+	if left > 0 {
+		q.notify()
+	}
+	return m, true
+}
+
+// pop blocks until there is a message or the context is done
+func (q *queue) pop(ctx context.Context) (transport.Message, bool) {
+	for ctx.Err() == nil {
+		if m, ok := q.tryPop(); ok {
+			return m, true
+		}
+
+		select {
+		case <-ctx.Done():
+			return transport.Message{}, false
+		case <-q.signal:
+		}
+	}
+	return transport.Message{}, false
+}
+
+func (q *queue) peek(limit int) []transport.Message {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if limit > len(q.messages) {
+		limit = len(q.messages)
+	}
+	messages := make([]transport.Message, limit)
+	copy(messages, q.messages[:limit])
+	return messages
+}
+
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.messages)
+}
+
+var _ transport.Transport = (*Broker)(nil)
+
+type Broker struct {
+	mu     sync.Mutex
+	queues map[string]*queue
+	clock  clock.Clock
+	logger *logrus.Logger
+	// maxRedeliveries limits how many times a message can be nacked, zero means no limit
+	maxRedeliveries int
+}
+
+func New(clk clock.Clock, l *logrus.Logger, maxRedeliveries int) *Broker {
+	b := Broker{
+		queues:          make(map[string]*queue),
+		clock:           clk,
+		logger:          l,
+		maxRedeliveries: maxRedeliveries,
+	}
+	return &b
+}
+
+func (b *Broker) queue(name string) *queue {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	q, exists := b.queues[name]
+	if !exists {
+		q = &queue{signal: make(chan struct{}, 1)}
+		b.queues[name] = q
+	}
+	return q
+}
+
+// Len returns the number of messages waiting in the queue
+func (b *Broker) Len(queue string) int {
+	return b.queue(queue).len()
+}
+
+type publisherContext struct {
+	queue *queue
+}
+
+// NewPublisher routes straight to the configured queue, exchange and routing key are ignored
+func (b *Broker) NewPublisher(_ context.Context, cfg config.Publisher) (transport.Publisher, error) {
+	if cfg.Queue == "" {
+		return nil, ErrNoQueue
+	}
+	p := publisherContext{
+		queue: b.queue(cfg.Queue),
+	}
+	return &p, nil
+}
+
+func (c *publisherContext) Publish(ctx context.Context, data interface{}, msgType string) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	rid, exists := requestid.From(ctx)
+	if !exists {
+		rid = requestid.NewRequestID()
+	}
+	msg := transport.Message{
+		Type:          msgType,
+		Body:          payload,
+		CorrelationID: rid,
+		Headers:       map[string]interface{}{},
+	}
+	c.queue.push(msg)
+	return nil
+}
+
+// Subscribe consumes the queue with PrefetchCount workers, it blocks until the context is done
+func (b *Broker) Subscribe(ctx context.Context, cfg config.Subscriber, h transport.Handler) {
+	q := b.queue(cfg.Queue)
+	workers := cfg.PrefetchCount
+	if workers < 1 {
+		workers = 1
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for {
+				m, ok := q.pop(ctx)
+				if !ok {
+					return
+				}
+				b.handle(ctx, cfg, q, m, h)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Process handles a single waiting message without blocking and reports whether there was one.
+// It lets tests drive the subscriber step by step on one goroutine instead of running Subscribe.
+func (b *Broker) Process(ctx context.Context, cfg config.Subscriber, h transport.Handler) bool {
+	q := b.queue(cfg.Queue)
+	m, ok := q.tryPop()
+	if !ok {
+		return false
+	}
+	b.handle(ctx, cfg, q, m, h)
+	return true
+}
+
+func (b *Broker) handle(ctx context.Context, cfg config.Subscriber, q *queue, m transport.Message, h transport.Handler) {
+	hostname, _ := os.Hostname()
+	ctx = requestid.New(ctx, m.CorrelationID)
+
+	start := time.Now()
+	ack, err := h(ctx, m)
+	elapsed := time.Since(start)
+	if err != nil {
+		entry := logger.Log{
+			Hostname:     hostname,
+			Severity:     logrus.ErrorLevel.String(),
+			RequestID:    m.CorrelationID,
+			Message:      err.Error(),
+			Timestamp:    time.Now().UTC(),
+			Milliseconds: elapsed.Milliseconds(),
+		}
+		b.logger.Error(entry)
+	}
+
+	switch {
+	case ack && err != nil:
+		b.deadLetter(cfg, m, err)
+	case !ack:
+		count := deliveryCount(m) + 1
+		if b.maxRedeliveries > 0 && count > b.maxRedeliveries {
+			cause := ErrRedeliveriesExceeded
+			if err != nil {
+				cause = errors.Wrap(err, cause.Error())
+			}
+			b.deadLetter(cfg, m, cause)
+			break
+		}
+		redelivery := copyMessage(m)
+		redelivery.Headers[HeaderDeliveryCount] = count
+		redelivery.Redelivered = true
+		q.push(redelivery)
+	}
+
+	entry := logger.Log{
+		Hostname:     hostname,
+		Severity:     logrus.InfoLevel.String(),
+		RequestID:    m.CorrelationID,
+		Message:      "Processing finished",
+		Timestamp:    time.Now().UTC(),
+		Milliseconds: elapsed.Milliseconds(),
+	}
+	b.logger.Info(entry)
+}
+
+// deadLetter keeps the dropped message aside, when the subscriber has no dead letter queue it is lost
+func (b *Broker) deadLetter(cfg config.Subscriber, m transport.Message, cause error) {
+	if cfg.DeadLetterQueue == "" {
+		return
+	}
+	letter := copyMessage(m)
+	letter.Headers[transport.HeaderError] = cause.Error()
+	letter.Headers[transport.HeaderOriginalQueue] = cfg.Queue
+	letter.Headers[transport.HeaderFailedDate] = b.clock.Now().Format(time.RFC3339)
+	b.queue(cfg.DeadLetterQueue).push(letter)
+}
+
+// DeadLetters returns up to limit messages from the dead letter queue without removing them
+func (b *Broker) DeadLetters(ctx context.Context, queue string, limit int) ([]transport.DeadLetter, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	messages := b.queue(queue).peek(limit)
+	letters := make([]transport.DeadLetter, 0, len(messages))
+	for _, m := range messages {
+		letters = append(letters, transport.NewDeadLetter(queue, m))
+	}
+	return letters, nil
+}
+
+// Requeue moves up to limit messages from the dead letter queue back to the queues they came from
+func (b *Broker) Requeue(ctx context.Context, queue string, limit int) ([]transport.DeadLetter, error) {
+	dlq := b.queue(queue)
+	requeued := make([]transport.DeadLetter, 0)
+	for len(requeued) < limit && dlq.len() > 0 {
+		if err := ctx.Err(); err != nil {
+			return requeued, err
+		}
+		m, ok := dlq.pop(ctx)
+		if !ok {
+			break
+		}
+		letter := transport.NewDeadLetter(queue, m)
+		if letter.OriginalQueue == "" {
+			dlq.push(m)
+			return requeued, errors.Wrap(ErrNoOriginalQueue, letter.CorrelationID)
+		}
+
+		msg := copyMessage(m)
+		delete(msg.Headers, transport.HeaderError)
+		delete(msg.Headers, transport.HeaderOriginalQueue)
+		delete(msg.Headers, transport.HeaderFailedDate)
+		delete(msg.Headers, HeaderDeliveryCount)
+		msg.Redelivered = false
+		b.queue(letter.OriginalQueue).push(msg)
+		requeued = append(requeued, letter)
+	}
+	return requeued, nil
+}
+
+func deliveryCount(m transport.Message) int {
+	count, _ := m.Headers[HeaderDeliveryCount].(int)
+	return count
+}
+
+func copyMessage(m transport.Message) transport.Message {
+	headers := make(map[string]interface{}, len(m.Headers))
+	for k, v := range m.Headers {
+		headers[k] = v
+	}
+	m.Headers = headers
+	return m
+}
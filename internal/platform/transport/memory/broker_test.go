@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/requestid"
+)
+
+var (
+	brokerStart   = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	publisherCfg  = config.Publisher{Queue: "Q.Transaction"}
+	subscriberCfg = config.Subscriber{Queue: "Q.Transaction", DeadLetterQueue: "Q.Transaction.DLQ", PrefetchCount: 2}
+)
+
+func newBroker(maxRedeliveries int) *Broker {
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	return New(clock.NewFake(brokerStart), l, maxRedeliveries)
+}
+
+// consume runs the subscriber until n messages were handled
+func consume(t *testing.T, b *Broker, n int, h transport.Handler) []transport.Message {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var mu sync.Mutex
+	handled := make([]transport.Message, 0, n)
+	done := make(chan struct{})
+	go func() {
+		b.Subscribe(ctx, subscriberCfg, func(ctx context.Context, m transport.Message) (bool, error) {
+			ack, err := h(ctx, m)
+			mu.Lock()
+			handled = append(handled, m)
+			if len(handled) == n {
+				cancel()
+			}
+			mu.Unlock()
+			return ack, err
+		})
+		close(done)
+	}()
+	<-done
+	assert.True(t, errors.Is(ctx.Err(), context.Canceled), "handler was not called %d times", n)
+
+	mu.Lock()
+	defer mu.Unlock()
+	return handled
+}
+
+func TestPublish(t *testing.T) {
+	t.Run("should deliver message with correlation id of the context", func(t *testing.T) {
+		// arrange
+		b := newBroker(0)
+		p, err := b.NewPublisher(context.Background(), publisherCfg)
+		assert.NoError(t, err)
+		ctx := requestid.New(context.Background(), "rid-1")
+
+		// act
+		err = p.Publish(ctx, map[string]string{"id": "1"}, "transaction")
+
+		// assert
+		assert.NoError(t, err)
+		handled := consume(t, b, 1, func(ctx context.Context, m transport.Message) (bool, error) {
+			rid, _ := requestid.From(ctx)
+			assert.Equal(t, "rid-1", rid)
+			return true, nil
+		})
+		assert.Equal(t, "transaction", handled[0].Type)
+		assert.Equal(t, "rid-1", handled[0].CorrelationID)
+		assert.JSONEq(t, `{"id":"1"}`, string(handled[0].Body))
+		assert.False(t, handled[0].Redelivered)
+		assert.Equal(t, 0, b.Len(subscriberCfg.Queue))
+	})
+
+	t.Run("should return error, no queue configured", func(t *testing.T) {
+		// arrange
+		b := newBroker(0)
+
+		// act
+		_, err := b.NewPublisher(context.Background(), config.Publisher{Exchange: "T.Topic"})
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoQueue))
+	})
+}
+
+func TestSubscribe(t *testing.T) {
+	t.Run("should redeliver nacked message", func(t *testing.T) {
+		// arrange
+		b := newBroker(0)
+		p, _ := b.NewPublisher(context.Background(), publisherCfg)
+		_ = p.Publish(context.Background(), "payload", "transaction")
+
+		// act
+		handled := consume(t, b, 2, func(ctx context.Context, m transport.Message) (bool, error) {
+			return m.Redelivered, nil
+		})
+
+		// assert
+		assert.False(t, handled[0].Redelivered)
+		assert.True(t, handled[1].Redelivered)
+		assert.Equal(t, 1, handled[1].Headers[HeaderDeliveryCount])
+		assert.Equal(t, 0, b.Len(subscriberCfg.Queue))
+		assert.Equal(t, 0, b.Len(subscriberCfg.DeadLetterQueue))
+	})
+
+	t.Run("should dead letter message acknowledged with an error", func(t *testing.T) {
+		// arrange
+		b := newBroker(0)
+		p, _ := b.NewPublisher(context.Background(), publisherCfg)
+		_ = p.Publish(requestid.New(context.Background(), "rid-2"), "payload", "transaction")
+
+		// act
+		consume(t, b, 1, func(ctx context.Context, m transport.Message) (bool, error) {
+			return true, transport.ErrUnknownMessageType
+		})
+
+		// assert
+		letters, err := b.DeadLetters(context.Background(), subscriberCfg.DeadLetterQueue, 10)
+		assert.NoError(t, err)
+		assert.Len(t, letters, 1)
+		assert.Equal(t, subscriberCfg.Queue, letters[0].OriginalQueue)
+		assert.Equal(t, transport.ErrUnknownMessageType.Error(), letters[0].Error)
+		assert.Equal(t, "rid-2", letters[0].CorrelationID)
+		assert.Equal(t, `"payload"`, letters[0].Body)
+		assert.Equal(t, brokerStart, letters[0].FailedDate)
+		assert.Equal(t, 1, b.Len(subscriberCfg.DeadLetterQueue))
+	})
+
+	t.Run("should dead letter message nacked too many times", func(t *testing.T) {
+		// arrange
+		b := newBroker(2)
+		p, _ := b.NewPublisher(context.Background(), publisherCfg)
+		_ = p.Publish(context.Background(), "payload", "transaction")
+
+		// act
+		consume(t, b, 3, func(ctx context.Context, m transport.Message) (bool, error) {
+			return false, nil
+		})
+
+		// assert
+		letters, _ := b.DeadLetters(context.Background(), subscriberCfg.DeadLetterQueue, 10)
+		assert.Len(t, letters, 1)
+		assert.Equal(t, ErrRedeliveriesExceeded.Error(), letters[0].Error)
+		assert.Equal(t, 0, b.Len(subscriberCfg.Queue))
+	})
+
+	t.Run("should drop message acknowledged with an error, no dead letter queue configured", func(t *testing.T) {
+		// arrange
+		b := newBroker(0)
+		p, _ := b.NewPublisher(context.Background(), publisherCfg)
+		_ = p.Publish(context.Background(), "payload", "transaction")
+		cfg := subscriberCfg
+		cfg.DeadLetterQueue = ""
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+
+		// act
+		go func() {
+			b.Subscribe(ctx, cfg, func(ctx context.Context, m transport.Message) (bool, error) {
+				cancel()
+				return true, errors.New("failed")
+			})
+			close(done)
+		}()
+		<-done
+
+		// assert
+		assert.Equal(t, 0, b.Len(subscriberCfg.Queue))
+		assert.Equal(t, 0, b.Len(subscriberCfg.DeadLetterQueue))
+	})
+}
+
+func TestRequeue(t *testing.T) {
+	t.Run("should move dead letters back to the original queue", func(t *testing.T) {
+		// arrange
+		b := newBroker(0)
+		p, _ := b.NewPublisher(context.Background(), publisherCfg)
+		_ = p.Publish(context.Background(), "first", "transaction")
+		_ = p.Publish(context.Background(), "second", "transaction")
+		consume(t, b, 2, func(ctx context.Context, m transport.Message) (bool, error) {
+			return true, errors.New("failed")
+		})
+
+		// act
+		requeued, err := b.Requeue(context.Background(), subscriberCfg.DeadLetterQueue, 1)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Len(t, requeued, 1)
+		assert.Equal(t, 1, b.Len(subscriberCfg.Queue))
+		assert.Equal(t, 1, b.Len(subscriberCfg.DeadLetterQueue))
+		handled := consume(t, b, 1, func(ctx context.Context, m transport.Message) (bool, error) {
+			return true, nil
+		})
+		assert.Equal(t, requeued[0].Body, string(handled[0].Body))
+		assert.Nil(t, handled[0].Headers[transport.HeaderError])
+		assert.Nil(t, handled[0].Headers[transport.HeaderOriginalQueue])
+	})
+
+	t.Run("should return error, dead letter does not point at its queue", func(t *testing.T) {
+		// arrange
+		b := newBroker(0)
+		p, _ := b.NewPublisher(context.Background(), config.Publisher{Queue: subscriberCfg.DeadLetterQueue})
+		_ = p.Publish(context.Background(), "payload", "transaction")
+
+		// act
+		requeued, err := b.Requeue(context.Background(), subscriberCfg.DeadLetterQueue, 10)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoOriginalQueue))
+		assert.Len(t, requeued, 0)
+		assert.Equal(t, 1, b.Len(subscriberCfg.DeadLetterQueue))
+	})
+}
+
+func TestProcess(t *testing.T) {
+	t.Run("should handle waiting messages one by one", func(t *testing.T) {
+		// arrange
+		b := newBroker(0)
+		p, _ := b.NewPublisher(context.Background(), publisherCfg)
+		_ = p.Publish(context.Background(), "first", "transaction")
+		_ = p.Publish(context.Background(), "second", "transaction")
+		handled := make([]string, 0)
+		h := func(ctx context.Context, m transport.Message) (bool, error) {
+			handled = append(handled, string(m.Body))
+			return true, nil
+		}
+
+		// act
+		first := b.Process(context.Background(), subscriberCfg, h)
+		second := b.Process(context.Background(), subscriberCfg, h)
+		third := b.Process(context.Background(), subscriberCfg, h)
+
+		// assert
+		assert.True(t, first)
+		assert.True(t, second)
+		assert.False(t, third)
+		assert.Equal(t, []string{`"first"`, `"second"`}, handled)
+	})
+}
@@ -1,8 +1,8 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq (interfaces: Publisher)
+// Source: github.com/mazxaxz/donut-batcher/internal/platform/transport (interfaces: Publisher)
 
-// Package mock_rabbitmq is a generated GoMock package.
-package mock_rabbitmq
+// Package mock_transport is a generated GoMock package.
+package mock_transport
 
 import (
 	context "context"
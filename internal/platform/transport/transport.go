@@ -0,0 +1,75 @@
+// Package transport decouples message handlers from the broker they are delivered by.
+package transport
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/rabbitmq/config"
+)
+
+const (
+	HeaderError         = "x-error"
+	HeaderOriginalQueue = "x-original-queue"
+	HeaderFailedDate    = "x-failed-date"
+)
+
+var (
+	ErrUnknownMessageType = errors.New("unknown message type")
+)
+
+// Message as seen by the handlers, regardless of the broker it came from
+type Message struct {
+	Type          string
+	Body          []byte
+	CorrelationID string
+	Headers       map[string]interface{}
+	// Redelivered is set when the message was nacked before
+	Redelivered bool
+}
+
+// Handler processes the message, returned ack decides whether the message is removed from the queue.
+// Message acknowledged with an error is moved to the dead letter queue, when the subscriber has one.
+type Handler func(ctx context.Context, m Message) (bool, error)
+
+type Publisher interface {
+	Publish(ctx context.Context, data interface{}, msgType string) error
+}
+
+type Transport interface {
+	NewPublisher(ctx context.Context, cfg config.Publisher) (Publisher, error)
+	// Subscribe blocks until the context is done
+	Subscribe(ctx context.Context, cfg config.Subscriber, h Handler)
+}
+
+// DeadLetter is a message which failed processing and was dropped by the subscriber
+type DeadLetter struct {
+	Queue         string    `json:"queue"`
+	OriginalQueue string    `json:"originalQueue"`
+	Type          string    `json:"type"`
+	CorrelationID string    `json:"correlationId"`
+	Error         string    `json:"error"`
+	FailedDate    time.Time `json:"failedDate"`
+	Body          string    `json:"body"`
+}
+
+// NewDeadLetter reads the failure details from the headers set while dead lettering the message
+func NewDeadLetter(queue string, m Message) DeadLetter {
+	l := DeadLetter{
+		Queue:         queue,
+		Type:          m.Type,
+		CorrelationID: m.CorrelationID,
+		Body:          string(m.Body),
+	}
+	if v, ok := m.Headers[HeaderOriginalQueue].(string); ok {
+		l.OriginalQueue = v
+	}
+	if v, ok := m.Headers[HeaderError].(string); ok {
+		l.Error = v
+	}
+	if v, ok := m.Headers[HeaderFailedDate].(string); ok {
+		l.FailedDate, _ = time.Parse(time.RFC3339, v)
+	}
+	return l
+}
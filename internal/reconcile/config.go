@@ -0,0 +1,21 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Config struct {
+	// Dir the bank statement files are read from, .csv and .xml (camt.053) files are reconciled
+	Dir string `json:"dir"`
+	// Interval in seconds between scheduled runs, zero means the reconciliation runs only on request
+	Interval int `json:"interval"`
+}
+
+func (c *Config) UnmarshalEnvironmentValue(data string) error {
+	return json.Unmarshal([]byte(data), &c)
+}
+
+func (c Config) interval() time.Duration {
+	return time.Duration(c.Interval) * time.Second
+}
@@ -0,0 +1,78 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/mazxaxz/donut-batcher/internal/reconcile (interfaces: Service)
+
+// Package mock_reconcile is a generated GoMock package.
+package mock_reconcile
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	reconcile "github.com/mazxaxz/donut-batcher/internal/reconcile"
+)
+
+// MockService is a mock of Service interface.
+type MockService struct {
+	ctrl     *gomock.Controller
+	recorder *MockServiceMockRecorder
+}
+
+// MockServiceMockRecorder is the mock recorder for MockService.
+type MockServiceMockRecorder struct {
+	mock *MockService
+}
+
+// NewMockService creates a new mock instance.
+func NewMockService(ctrl *gomock.Controller) *MockService {
+	mock := &MockService{ctrl: ctrl}
+	mock.recorder = &MockServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockService) EXPECT() *MockServiceMockRecorder {
+	return m.recorder
+}
+
+// Latest mocks base method.
+func (m *MockService) Latest() (reconcile.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Latest")
+	ret0, _ := ret[0].(reconcile.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Latest indicates an expected call of Latest.
+func (mr *MockServiceMockRecorder) Latest() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Latest", reflect.TypeOf((*MockService)(nil).Latest))
+}
+
+// Run mocks base method.
+func (m *MockService) Run(arg0 context.Context, arg1 reconcile.Range) (reconcile.Report, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Run", arg0, arg1)
+	ret0, _ := ret[0].(reconcile.Report)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Run indicates an expected call of Run.
+func (mr *MockServiceMockRecorder) Run(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Run", reflect.TypeOf((*MockService)(nil).Run), arg0, arg1)
+}
+
+// Schedule mocks base method.
+func (m *MockService) Schedule(arg0 context.Context) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Schedule", arg0)
+}
+
+// Schedule indicates an expected call of Schedule.
+func (mr *MockServiceMockRecorder) Schedule(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Schedule", reflect.TypeOf((*MockService)(nil).Schedule), arg0)
+}
@@ -0,0 +1,470 @@
+package reconcile
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+const (
+	StatusMatched        = "matched"
+	StatusMissing        = "missing"
+	StatusUnexpected     = "unexpected"
+	StatusAmountMismatch = "amount-mismatch"
+)
+
+const (
+	// _achSettlementDays is how long after the file an ACH entry can be booked, its trace number is looked up that far back
+	_achSettlementDays = 5
+)
+
+var _tracePattern = regexp.MustCompile(`^[0-9]{15}$`)
+
+var (
+	ErrNoBatchService = errors.New("batch service was not provided")
+	ErrNoDirectory    = errors.New("statement directory is not configured")
+	ErrNoReport       = errors.New("reconciliation has not run yet")
+	ErrInvalidRange   = errors.New("range has to end after it starts")
+	ErrNoStatements   = errors.New("statements have no lines to take the range from, it has to be given")
+)
+
+// Range of dispatch dates the batches are expected in, zero bounds are taken from the booking dates of the statements
+type Range struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Item is a statement line paired with the batch it pays out, either of them is missing when they could not be paired
+type Item struct {
+	Status  string `json:"status"`
+	BatchID string `json:"batchId,omitempty"`
+	// Leg is the number of the leg of a split batch the line pays out
+	Leg            int    `json:"leg,omitempty"`
+	Reference      string `json:"reference,omitempty"`
+	ExpectedAmount string `json:"expectedAmount,omitempty"`
+	ActualAmount   string `json:"actualAmount,omitempty"`
+	Currency       string `json:"currency,omitempty"`
+	// ActualCurrency is set only when the bank booked the transfer in another currency
+	ActualCurrency string `json:"actualCurrency,omitempty"`
+	File           string `json:"file,omitempty"`
+	Line           int    `json:"line,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
+
+// Report of a single reconciliation, matched items are listed as well so the report proves what was checked
+type Report struct {
+	Range          Range     `json:"range"`
+	Files          []string  `json:"files"`
+	Matched        int       `json:"matched"`
+	Missing        int       `json:"missing"`
+	Unexpected     int       `json:"unexpected"`
+	AmountMismatch int       `json:"amountMismatch"`
+	Items          []Item    `json:"items"`
+	GeneratedDate  time.Time `json:"generatedDate"`
+}
+
+type Service interface {
+	// Run reconciles the batches dispatched within the range against all statements in the directory
+	Run(ctx context.Context, r Range) (Report, error)
+	// Latest returns the report of the last successful run
+	Latest() (Report, error)
+	// Schedule runs the reconciliation every configured interval until the context is done
+	Schedule(ctx context.Context)
+}
+
+type serviceContext struct {
+	batchSvc batch.Service
+	cfg      Config
+	clock    clock.Clock
+	logger   *logrus.Logger
+
+	mu     sync.Mutex
+	latest *Report
+}
+
+func New(bSvc batch.Service, cfg Config, clk clock.Clock, l *logrus.Logger) (Service, error) {
+	if bSvc == nil {
+		return nil, ErrNoBatchService
+	}
+	c := serviceContext{
+		batchSvc: bSvc,
+		cfg:      cfg,
+		clock:    clk,
+		logger:   l,
+	}
+	return &c, nil
+}
+
+func (c *serviceContext) Run(ctx context.Context, r Range) (Report, error) {
+	if c.cfg.Dir == "" {
+		return Report{}, ErrNoDirectory
+	}
+	files, lines, err := c.read()
+	if err != nil {
+		return Report{}, err
+	}
+	if len(lines) == 0 && (r.From.IsZero() || r.To.IsZero()) {
+		return Report{}, ErrNoStatements
+	}
+	r = r.orFrom(lines)
+	if !r.To.After(r.From) {
+		return Report{}, ErrInvalidRange
+	}
+
+	report := Report{Range: r, Files: files, Items: make([]Item, 0), GeneratedDate: c.clock.Now()}
+	expected, err := c.dispatched(ctx, r)
+	if err != nil {
+		return Report{}, err
+	}
+	idx := newIndex(expected)
+	matched := make(map[string]bool, len(expected))
+	for _, l := range lines {
+		t, found, err := c.lookup(ctx, idx, l)
+		if err != nil {
+			return Report{}, err
+		}
+		var item Item
+		switch {
+		case !found:
+			item = unexpected(l, "no dispatched batch has the reference")
+		case matched[t.id]:
+			item = unexpected(l, "batch was already paired with another line")
+		default:
+			matched[t.id] = true
+			item, err = compare(t, l)
+			if err != nil {
+				return Report{}, err
+			}
+		}
+		report.add(item)
+	}
+
+	/* transfers are kept in the order they were dispatched, so are the missing ones */
+	for _, t := range expected {
+		if matched[t.id] {
+			continue
+		}
+		report.add(Item{
+			Status:         StatusMissing,
+			BatchID:        t.batchID,
+			Leg:            t.leg,
+			Reference:      t.reference,
+			ExpectedAmount: t.amount,
+			Currency:       t.currency,
+			Reason:         "no statement line pays out the batch",
+		})
+	}
+
+	c.mu.Lock()
+	c.latest = &report
+	c.mu.Unlock()
+	return report, nil
+}
+
+func (c *serviceContext) Latest() (Report, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.latest == nil {
+		return Report{}, ErrNoReport
+	}
+	return *c.latest, nil
+}
+
+func (c *serviceContext) Schedule(ctx context.Context) {
+	if c.cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(c.cfg.interval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := c.Run(ctx, Range{})
+			if err != nil {
+				c.logger.Errorf("reconciliation failed: %s", err)
+				continue
+			}
+			if report.Missing+report.Unexpected+report.AmountMismatch > 0 {
+				c.logger.Warnf("reconciliation found %d missing, %d unexpected and %d amount-mismatched items",
+					report.Missing, report.Unexpected, report.AmountMismatch)
+			}
+		}
+	}
+}
+
+// read parses all statements in the directory, in the order of their names
+func (c *serviceContext) read() ([]string, []Line, error) {
+	entries, err := ioutil.ReadDir(c.cfg.Dir)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "could not list statements")
+	}
+	files := make([]string, 0)
+	lines := make([]Line, 0)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		var parse func(file string, f *os.File) ([]Line, error)
+		switch strings.ToLower(filepath.Ext(e.Name())) {
+		case ".csv":
+			parse = func(file string, f *os.File) ([]Line, error) { return ParseCSV(file, f) }
+		case ".xml":
+			parse = func(file string, f *os.File) ([]Line, error) { return ParseCamt053(file, f) }
+		default:
+			continue
+		}
+		parsed, err := parseFile(filepath.Join(c.cfg.Dir, e.Name()), parse)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "could not parse statement '%s'", e.Name())
+		}
+		files = append(files, e.Name())
+		lines = append(lines, parsed...)
+	}
+	return files, lines, nil
+}
+
+func parseFile(path string, parse func(file string, f *os.File) ([]Line, error)) ([]Line, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+	return parse(filepath.Base(path), f)
+}
+
+// transfer is what the bank is expected to book, a whole batch or a leg of a split one
+type transfer struct {
+	id        string
+	batchID   string
+	leg       int
+	reference string
+	amount    string
+	currency  string
+}
+
+// transfersOf returns the transfers of the batch which left for the bank
+func transfersOf(b batch.Batch) []transfer {
+	if len(b.Legs) == 0 {
+		if b.DispatchReference == "" {
+			return nil
+		}
+		t := transfer{
+			id:        b.ID.Hex(),
+			batchID:   b.ID.Hex(),
+			reference: b.DispatchReference,
+			amount:    b.Amount.String(),
+			currency:  b.Currency.String(),
+		}
+		return []transfer{t}
+	}
+	transfers := make([]transfer, 0, len(b.Legs))
+	for _, l := range b.Legs {
+		if l.DispatchReference == "" {
+			continue
+		}
+		transfers = append(transfers, transfer{
+			id:        l.TransferID,
+			batchID:   b.ID.Hex(),
+			leg:       l.Number,
+			reference: l.DispatchReference,
+			amount:    l.Amount.String(),
+			currency:  b.Currency.String(),
+		})
+	}
+	return transfers
+}
+
+// dispatched returns the transfers of the batches which left for the bank within the range, in the order they were dispatched
+func (c *serviceContext) dispatched(ctx context.Context, r Range) ([]transfer, error) {
+	transfers := make([]transfer, 0)
+	f := batch.Filter{DispatchedFrom: r.From, DispatchedTo: r.To}
+	err := c.batchSvc.Export(ctx, batch.Sort{Field: "dispatchedDate", Asc: true}, f, func(b batch.Batch) error {
+		transfers = append(transfers, transfersOf(b)...)
+		return nil
+	})
+	return transfers, err
+}
+
+// index of the expected transfers by the bank reference and by the transfer id
+type index struct {
+	byReference map[string]transfer
+	byID        map[string]transfer
+}
+
+func newIndex(transfers []transfer) index {
+	idx := index{
+		byReference: make(map[string]transfer, len(transfers)),
+		byID:        make(map[string]transfer, len(transfers)),
+	}
+	for _, t := range transfers {
+		idx.byReference[t.reference] = t
+		idx.byID[t.id] = t
+	}
+	return idx
+}
+
+// lookup pairs the line with a transfer by the bank reference first and by the transfer id sent as end to end id second,
+// batches dispatched out of the range are looked up as well, a transfer can be booked a day after it was sent
+func (c *serviceContext) lookup(ctx context.Context, idx index, l Line) (transfer, bool, error) {
+	references := referencesOf(l)
+	for _, reference := range references {
+		if t, exists := idx.byReference[reference]; exists {
+			return t, true, nil
+		}
+	}
+	if t, exists := idx.byID[l.EndToEndID]; exists && l.EndToEndID != "" {
+		return t, true, nil
+	}
+
+	var found []transfer
+	var reference string
+	for _, reference = range references {
+		err := c.batchSvc.Export(ctx, batch.Sort{Field: "createdDate"}, batch.Filter{DispatchReference: reference}, func(b batch.Batch) error {
+			found = transfersOf(b)
+			return nil
+		})
+		if err != nil {
+			return transfer{}, false, err
+		}
+		if found != nil {
+			break
+		}
+	}
+	if found == nil && l.EndToEndID != "" {
+		batchID, _ := batch.ParseTransferID(l.EndToEndID)
+		b, err := c.batchSvc.Get(ctx, batchID)
+		switch {
+		case err == nil:
+			found = transfersOf(b)
+		case errors.Is(err, batch.ErrBatchNotFound), errors.Is(err, batch.ErrInvalidBatchID):
+		default:
+			return transfer{}, false, err
+		}
+	}
+	for _, t := range found {
+		if (reference != "" && t.reference == reference) || t.id == l.EndToEndID {
+			return t, true, nil
+		}
+	}
+	return transfer{}, false, nil
+}
+
+// referencesOf lists the dispatch references the line can pay out, a bare ACH trace number is prefixed with the dates
+// of the files it could have been sent in, the latest first
+func referencesOf(l Line) []string {
+	if l.Reference == "" {
+		return nil
+	}
+	if !_tracePattern.MatchString(l.Reference) || l.BookingDate.IsZero() {
+		return []string{l.Reference}
+	}
+	references := make([]string, 0, _achSettlementDays+2)
+	references = append(references, l.Reference)
+	for days := 0; days <= _achSettlementDays; days++ {
+		references = append(references, banksdk.TraceReference(l.BookingDate.AddDate(0, 0, -days), l.Reference))
+	}
+	return references
+}
+
+func compare(t transfer, l Line) (Item, error) {
+	item := Item{
+		Status:         StatusMatched,
+		BatchID:        t.batchID,
+		Leg:            t.leg,
+		Reference:      t.reference,
+		ExpectedAmount: t.amount,
+		ActualAmount:   l.Amount,
+		Currency:       t.currency,
+		File:           l.File,
+		Line:           l.Line,
+	}
+	if l.Currency != t.currency {
+		item.Status = StatusAmountMismatch
+		item.ActualCurrency = l.Currency
+		item.Reason = "bank booked the transfer in another currency"
+		return item, nil
+	}
+	equal, err := equalAmounts(item.ExpectedAmount, item.ActualAmount)
+	if err != nil {
+		return Item{}, err
+	}
+	if !equal {
+		item.Status = StatusAmountMismatch
+		item.Reason = "bank booked another amount"
+	}
+	return item, nil
+}
+
+func unexpected(l Line, reason string) Item {
+	item := Item{
+		Status:       StatusUnexpected,
+		Reference:    l.Reference,
+		ActualAmount: l.Amount,
+		Currency:     l.Currency,
+		File:         l.File,
+		Line:         l.Line,
+		Reason:       reason,
+	}
+	return item
+}
+
+func equalAmounts(a, b string) (bool, error) {
+	aGreater, err := money.GreaterThanOrEqual(a, b)
+	if err != nil {
+		return false, err
+	}
+	bGreater, err := money.GreaterThanOrEqual(b, a)
+	if err != nil {
+		return false, err
+	}
+	return aGreater && bGreater, nil
+}
+
+func (r *Report) add(item Item) {
+	switch item.Status {
+	case StatusMatched:
+		r.Matched++
+	case StatusMissing:
+		r.Missing++
+	case StatusUnexpected:
+		r.Unexpected++
+	case StatusAmountMismatch:
+		r.AmountMismatch++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// orFrom fills the zero bounds with whole days the statement lines were booked in
+func (r Range) orFrom(lines []Line) Range {
+	if !r.From.IsZero() && !r.To.IsZero() {
+		return r
+	}
+	dates := make([]time.Time, 0, len(lines))
+	for _, l := range lines {
+		dates = append(dates, l.BookingDate)
+	}
+	sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+	if r.From.IsZero() {
+		r.From = dates[0].Truncate(24 * time.Hour)
+	}
+	if r.To.IsZero() {
+		r.To = dates[len(dates)-1].Truncate(24 * time.Hour).Add(24 * time.Hour)
+	}
+	return r
+}
@@ -0,0 +1,312 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mazxaxz/donut-batcher/internal/account"
+	"github.com/mazxaxz/donut-batcher/internal/batch"
+	"github.com/mazxaxz/donut-batcher/internal/platform/mongodb/memory"
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+var testNow = time.Date(2021, 3, 1, 8, 0, 0, 0, time.UTC)
+
+func TestNew(t *testing.T) {
+	t.Run("should return error, no batch service", func(t *testing.T) {
+		// act
+		_, err := New(nil, Config{}, clock.New(), logrus.New())
+
+		// assert
+		assert.Equal(t, ErrNoBatchService, err)
+	})
+}
+
+// dispatchFor batches 1.4 USD of the user and dispatches it at the time of the clock
+func dispatchFor(t *testing.T, svc batch.Service, userID string) batch.Batch {
+	ctx := context.Background()
+	var result batch.BatchResult
+	for i, amount := range []string{"1.10", "2.50"} {
+		var err error
+		result, err = svc.Batch(ctx, transaction.Transaction{ID: fmt.Sprintf("%s-%d", userID, i), UserID: userID, Amount: amount, Currency: "USD"})
+		require.NoError(t, err)
+	}
+	require.NoError(t, svc.Dispatch(ctx, result.ID.Hex()))
+	b, err := svc.Get(ctx, result.ID.Hex())
+	require.NoError(t, err)
+	return b
+}
+
+// splitAccounts pays every user to the same account and splits their batches 70/30
+type splitAccounts struct{}
+
+func (splitAccounts) Destination(context.Context, string, money.Currency) (banksdk.Account, error) {
+	return banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}, nil
+}
+
+func (splitAccounts) AllocationRules(context.Context, string) ([]account.Allocation, error) {
+	return []account.Allocation{{Portfolio: "index-fund", Percent: "70"}, {Portfolio: "savings", Percent: "30"}}, nil
+}
+
+func writeStatement(t *testing.T, dir, name, content string) {
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+}
+
+func TestRun(t *testing.T) {
+	newServices := func(t *testing.T) (batch.Service, Service, string, *clock.Fake) {
+		c := clock.NewFake(testNow)
+		l := logrus.New()
+		l.SetOutput(ioutil.Discard)
+		bSvc, err := batch.New(memory.New(), banksdk.New(), nil, nil, c, l, map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		dir := t.TempDir()
+		svc, err := New(bSvc, Config{Dir: dir}, c, l)
+		require.NoError(t, err)
+		return bSvc, svc, dir, c
+	}
+
+	t.Run("should report matched, mismatched, unexpected and missing items", func(t *testing.T) {
+		// arrange
+		bSvc, svc, dir, c := newServices(t)
+		matched := dispatchFor(t, bSvc, "user:1")
+		mismatched := dispatchFor(t, bSvc, "user:2")
+		missing := dispatchFor(t, bSvc, "user:3")
+		c.Advance(time.Hour)
+		writeStatement(t, dir, "1-march.csv", "reference,amount,currency,booking_date\n"+
+			matched.DispatchReference+",-1.40,USD,2021-03-01\n"+
+			"unknown,5,USD,2021-03-01\n"+
+			matched.DispatchReference+",1.4,USD,2021-03-01\n")
+		writeStatement(t, dir, "2-march.xml", `<Document><BkToCstmrStmt><Stmt><Ntry>
+			<Amt Ccy="USD">1.5</Amt><CdtDbtInd>DBIT</CdtDbtInd><BookgDt><Dt>2021-03-01</Dt></BookgDt>
+			<NtryDtls><TxDtls><Refs><EndToEndId>`+mismatched.ID.Hex()+`</EndToEndId></Refs></TxDtls></NtryDtls>
+		</Ntry></Stmt></BkToCstmrStmt></Document>`)
+		writeStatement(t, dir, "notes.txt", "not a statement")
+
+		// act
+		report, err := svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, Range{From: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), To: time.Date(2021, 3, 2, 0, 0, 0, 0, time.UTC)}, report.Range)
+		assert.Equal(t, []string{"1-march.csv", "2-march.xml"}, report.Files)
+		assert.Equal(t, 1, report.Matched)
+		assert.Equal(t, 1, report.AmountMismatch)
+		assert.Equal(t, 2, report.Unexpected)
+		assert.Equal(t, 1, report.Missing)
+		assert.Equal(t, testNow.Add(time.Hour), report.GeneratedDate)
+		assert.Equal(t, []Item{
+			{Status: StatusMatched, BatchID: matched.ID.Hex(), Reference: matched.DispatchReference, ExpectedAmount: "1.4", ActualAmount: "1.40", Currency: "USD", File: "1-march.csv", Line: 2},
+			{Status: StatusUnexpected, Reference: "unknown", ActualAmount: "5", Currency: "USD", File: "1-march.csv", Line: 3, Reason: "no dispatched batch has the reference"},
+			{Status: StatusUnexpected, Reference: matched.DispatchReference, ActualAmount: "1.4", Currency: "USD", File: "1-march.csv", Line: 4, Reason: "batch was already paired with another line"},
+			{Status: StatusAmountMismatch, BatchID: mismatched.ID.Hex(), Reference: mismatched.DispatchReference, ExpectedAmount: "1.4", ActualAmount: "1.5", Currency: "USD", File: "2-march.xml", Line: 1, Reason: "bank booked another amount"},
+			{Status: StatusMissing, BatchID: missing.ID.Hex(), Reference: missing.DispatchReference, ExpectedAmount: "1.4", Currency: "USD", Reason: "no statement line pays out the batch"},
+		}, report.Items)
+
+		latest, err := svc.Latest()
+		assert.NoError(t, err)
+		assert.Equal(t, report, latest)
+	})
+
+	t.Run("should match line booked after the range, batch was dispatched before midnight", func(t *testing.T) {
+		// arrange
+		bSvc, svc, dir, c := newServices(t)
+		c.Set(time.Date(2021, 2, 28, 23, 59, 0, 0, time.UTC))
+		late := dispatchFor(t, bSvc, "user:1")
+		writeStatement(t, dir, "march.csv", "reference,amount,currency,booking_date\n"+late.DispatchReference+",1.4,USD,2021-03-01\n")
+
+		// act
+		report, err := svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Matched)
+		assert.Len(t, report.Items, 1)
+	})
+
+	t.Run("should report mismatch, bank booked another currency", func(t *testing.T) {
+		// arrange
+		bSvc, svc, dir, _ := newServices(t)
+		b := dispatchFor(t, bSvc, "user:1")
+		writeStatement(t, dir, "march.csv", "reference,amount,currency,booking_date\n"+b.DispatchReference+",1.4,EUR,2021-03-01\n")
+
+		// act
+		report, err := svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.AmountMismatch)
+		assert.Equal(t, "EUR", report.Items[0].ActualCurrency)
+	})
+
+	t.Run("should report every batch of the range as missing, statements are empty", func(t *testing.T) {
+		// arrange
+		bSvc, svc, _, _ := newServices(t)
+		dispatchFor(t, bSvc, "user:1")
+		r := Range{From: testNow.Add(-time.Hour), To: testNow.Add(time.Hour)}
+
+		// act
+		report, err := svc.Run(context.Background(), r)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Missing)
+		assert.Empty(t, report.Files)
+	})
+
+	t.Run("should return error, statements are empty and range is not given", func(t *testing.T) {
+		// arrange
+		_, svc, _, _ := newServices(t)
+
+		// act
+		_, err := svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.Equal(t, ErrNoStatements, err)
+		_, err = svc.Latest()
+		assert.Equal(t, ErrNoReport, err)
+	})
+
+	t.Run("should return error, statement is malformed", func(t *testing.T) {
+		// arrange
+		_, svc, dir, _ := newServices(t)
+		writeStatement(t, dir, "march.csv", "reference,amount\n")
+
+		// act
+		_, err := svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "march.csv")
+	})
+
+	t.Run("should return error, no directory configured", func(t *testing.T) {
+		// arrange
+		bSvc, _, _, _ := newServices(t)
+		svc, err := New(bSvc, Config{}, clock.New(), logrus.New())
+		require.NoError(t, err)
+
+		// act
+		_, err = svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.Equal(t, ErrNoDirectory, err)
+	})
+
+	t.Run("should reconcile every leg of split batch on its own", func(t *testing.T) {
+		// arrange
+		c := clock.NewFake(testNow)
+		l := logrus.New()
+		l.SetOutput(ioutil.Discard)
+		bSvc, err := batch.New(memory.New(), banksdk.New(), splitAccounts{}, nil, c, l, map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		dir := t.TempDir()
+		svc, err := New(bSvc, Config{Dir: dir}, c, l)
+		require.NoError(t, err)
+		split := dispatchFor(t, bSvc, "user:1")
+		require.Len(t, split.Legs, 2)
+		writeStatement(t, dir, "march.csv", "reference,amount,currency,booking_date,end_to_end_id\n"+
+			split.Legs[0].DispatchReference+",0.98,USD,2021-03-01,\n"+
+			",0.40,USD,2021-03-01,"+split.Legs[1].TransferID+"\n")
+
+		// act
+		report, err := svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, report.Matched)
+		assert.Equal(t, 1, report.AmountMismatch)
+		require.Len(t, report.Items, 2)
+		assert.Equal(t, split.ID.Hex(), report.Items[0].BatchID)
+		assert.Equal(t, 1, report.Items[0].Leg)
+		assert.Equal(t, StatusMatched, report.Items[0].Status)
+		assert.Equal(t, 2, report.Items[1].Leg)
+		assert.Equal(t, "0.42", report.Items[1].ExpectedAmount)
+		assert.Equal(t, StatusAmountMismatch, report.Items[1].Status)
+	})
+
+	t.Run("should reconcile batches paid out in pain.001 files by their end to end ids", func(t *testing.T) {
+		// arrange
+		c := clock.NewFake(testNow)
+		l := logrus.New()
+		l.SetOutput(ioutil.Discard)
+		fileCfg := banksdk.FileConfig{Dir: t.TempDir(), DebtorName: "Donut", DebtorIBAN: "DE89370400440532013000"}
+		fileBank, err := banksdk.NewFile(fileCfg, c, l)
+		require.NoError(t, err)
+		bSvc, err := batch.New(memory.New(), fileBank, nil, nil, c, l, map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		dir := t.TempDir()
+		svc, err := New(bSvc, Config{Dir: dir}, c, l)
+		require.NoError(t, err)
+		paid := dispatchFor(t, bSvc, "user:1")
+		missing := dispatchFor(t, bSvc, "user:2")
+		writeStatement(t, dir, "march.csv", "reference,amount,currency,booking_date,end_to_end_id\n"+
+			"BANK-REF-1,1.4,USD,2021-03-01,"+paid.ID.Hex()+"\n")
+
+		// act
+		report, err := svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, paid.ID.Hex(), paid.DispatchReference)
+		assert.Equal(t, 1, report.Matched)
+		assert.Equal(t, 0, report.Unexpected)
+		assert.Equal(t, 1, report.Missing)
+		require.Len(t, report.Items, 2)
+		assert.Equal(t, paid.ID.Hex(), report.Items[0].BatchID)
+		assert.Equal(t, missing.ID.Hex(), report.Items[1].BatchID)
+	})
+
+	t.Run("should pair ach entries by the trace number and the day of their file", func(t *testing.T) {
+		// arrange
+		c := clock.NewFake(testNow)
+		l := logrus.New()
+		l.SetOutput(ioutil.Discard)
+		account := banksdk.Account{Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789"}
+		fileCfg := banksdk.FileConfig{
+			Dir:        t.TempDir(),
+			DebtorName: "Donut",
+			DebtorIBAN: "DE89370400440532013000",
+			ACH:        banksdk.ACHConfig{ImmediateDestination: "011000015", ODFI: "01100001"},
+			Accounts:   map[string]banksdk.Account{"user:1": account, "user:2": account},
+		}
+		fileBank, err := banksdk.NewFile(fileCfg, c, l)
+		require.NoError(t, err)
+		bSvc, err := batch.New(memory.New(), fileBank, nil, nil, c, l, map[string]string{"USD": "1"})
+		require.NoError(t, err)
+		dir := t.TempDir()
+		svc, err := New(bSvc, Config{Dir: dir}, c, l)
+		require.NoError(t, err)
+		first := dispatchFor(t, bSvc, "user:1")
+		c.Advance(24 * time.Hour)
+		second := dispatchFor(t, bSvc, "user:2")
+		trace := "011000010100001"
+		writeStatement(t, dir, "march.csv", "reference,amount,currency,booking_date\n"+
+			trace+",1.4,USD,2021-03-01\n"+
+			trace+",1.4,USD,2021-03-03\n")
+
+		// act
+		report, err := svc.Run(context.Background(), Range{})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "20210301-"+trace, first.DispatchReference)
+		assert.Equal(t, "20210302-"+trace, second.DispatchReference)
+		assert.Equal(t, 2, report.Matched)
+		assert.Equal(t, 0, report.Unexpected)
+		assert.Equal(t, 0, report.Missing)
+		require.Len(t, report.Items, 2)
+		assert.Equal(t, first.ID.Hex(), report.Items[0].BatchID)
+		assert.Equal(t, second.ID.Hex(), report.Items[1].BatchID)
+	})
+}
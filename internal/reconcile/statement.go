@@ -0,0 +1,199 @@
+package reconcile
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	_camtDebit = "DBIT"
+)
+
+var (
+	ErrMissingColumn = errors.New("statement is missing a required column")
+	ErrInvalidLine   = errors.New("statement line is invalid")
+)
+
+// Line is a single transfer the bank has booked on the account
+type Line struct {
+	File string `json:"file"`
+	// Line is the row of a CSV statement (the header is row 1) or the entry of a camt.053 statement, counted from 1
+	Line      int    `json:"line"`
+	Reference string `json:"reference"`
+	// EndToEndID is the id the transfer was sent with, the batch id
+	EndToEndID  string    `json:"endToEndId,omitempty"`
+	Amount      string    `json:"amount"`
+	Currency    string    `json:"currency"`
+	BookingDate time.Time `json:"bookingDate"`
+}
+
+// ParseCSV reads a statement with a header row, the columns reference, amount, currency and booking_date are
+// required, end_to_end_id is optional. Debits are often exported as negative amounts, the sign is dropped.
+func ParseCSV(file string, r io.Reader) ([]Line, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read statement header")
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"reference", "amount", "currency", "booking_date"} {
+		if _, exists := columns[required]; !exists {
+			return nil, errors.Wrap(ErrMissingColumn, required)
+		}
+	}
+	value := func(record []string, name string) string {
+		i, exists := columns[name]
+		if !exists || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	lines := make([]Line, 0)
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		l := Line{
+			File:       file,
+			Line:       row,
+			Reference:  value(record, "reference"),
+			EndToEndID: value(record, "end_to_end_id"),
+			Amount:     value(record, "amount"),
+			Currency:   value(record, "currency"),
+		}
+		if l.BookingDate, err = parseDate(value(record, "booking_date")); err != nil {
+			return nil, errors.Wrapf(ErrInvalidLine, "row %d: %s", row, err)
+		}
+		if err := l.normalize(); err != nil {
+			return nil, errors.Wrapf(ErrInvalidLine, "row %d: %s", row, err)
+		}
+		lines = append(lines, l)
+	}
+}
+
+type camtDocument struct {
+	Statements []camtStatement `xml:"BkToCstmrStmt>Stmt"`
+}
+
+type camtStatement struct {
+	Entries []camtEntry `xml:"Ntry"`
+}
+
+type camtAmount struct {
+	Value    string `xml:",chardata"`
+	Currency string `xml:"Ccy,attr"`
+}
+
+type camtEntry struct {
+	Amount      camtAmount `xml:"Amt"`
+	Indicator   string     `xml:"CdtDbtInd"`
+	BookingDate struct {
+		Date     string `xml:"Dt"`
+		DateTime string `xml:"DtTm"`
+	} `xml:"BookgDt"`
+	Reference    string            `xml:"AcctSvcrRef"`
+	Transactions []camtTransaction `xml:"NtryDtls>TxDtls"`
+}
+
+type camtTransaction struct {
+	Refs struct {
+		Reference  string `xml:"AcctSvcrRef"`
+		EndToEndID string `xml:"EndToEndId"`
+	} `xml:"Refs"`
+	Amount *camtAmount `xml:"Amt"`
+}
+
+// ParseCamt053 reads the debit entries of an ISO 20022 bank to customer statement, credits are skipped.
+// An entry booking several transactions at once results in a line per transaction.
+func ParseCamt053(file string, r io.Reader) ([]Line, error) {
+	var doc camtDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "could not decode camt.053 statement")
+	}
+
+	lines := make([]Line, 0)
+	entry := 0
+	for _, s := range doc.Statements {
+		for _, e := range s.Entries {
+			entry++
+			if e.Indicator != _camtDebit {
+				continue
+			}
+			booked := e.BookingDate.Date
+			if booked == "" {
+				booked = e.BookingDate.DateTime
+			}
+			date, err := parseDate(booked)
+			if err != nil {
+				return nil, errors.Wrapf(ErrInvalidLine, "entry %d: %s", entry, err)
+			}
+
+			transactions := e.Transactions
+			if len(transactions) == 0 {
+				transactions = []camtTransaction{{}}
+			}
+			for _, t := range transactions {
+				l := Line{
+					File:        file,
+					Line:        entry,
+					Reference:   t.Refs.Reference,
+					EndToEndID:  t.Refs.EndToEndID,
+					Amount:      e.Amount.Value,
+					Currency:    e.Amount.Currency,
+					BookingDate: date,
+				}
+				if l.Reference == "" {
+					l.Reference = e.Reference
+				}
+				if t.Amount != nil {
+					l.Amount, l.Currency = t.Amount.Value, t.Amount.Currency
+				}
+				if err := l.normalize(); err != nil {
+					return nil, errors.Wrapf(ErrInvalidLine, "entry %d: %s", entry, err)
+				}
+				lines = append(lines, l)
+			}
+		}
+	}
+	return lines, nil
+}
+
+// normalize trims the amount to the same form the batches are stored in
+func (l *Line) normalize() error {
+	l.Amount = strings.TrimPrefix(strings.TrimSpace(l.Amount), "-")
+	l.Currency = strings.ToUpper(strings.TrimSpace(l.Currency))
+	if l.Reference == "" && l.EndToEndID == "" {
+		return errors.New("neither reference nor end to end id is present")
+	}
+	amount, err := primitive.ParseDecimal128(l.Amount)
+	if err != nil {
+		return errors.Errorf("amount '%s' is not a decimal number", l.Amount)
+	}
+	l.Amount = amount.String()
+	return nil
+}
+
+func parseDate(v string) (time.Time, error) {
+	v = strings.TrimSpace(v)
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"} {
+		if date, err := time.Parse(layout, v); err == nil {
+			return date.UTC(), nil
+		}
+	}
+	return time.Time{}, errors.Errorf("booking date '%s' is not a date", v)
+}
@@ -0,0 +1,98 @@
+package reconcile
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCamt = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Ntry>
+        <Amt Ccy="USD">1.40</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><Dt>2021-03-01</Dt></BookgDt>
+        <AcctSvcrRef>ref-1</AcctSvcrRef>
+        <NtryDtls><TxDtls><Refs><EndToEndId>batch-1</EndToEndId></Refs></TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="USD">10</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><Dt>2021-03-01</Dt></BookgDt>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="USD">3</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><DtTm>2021-03-02T10:00:00Z</DtTm></BookgDt>
+        <NtryDtls>
+          <TxDtls><Refs><AcctSvcrRef>ref-2</AcctSvcrRef></Refs><Amt Ccy="USD">1</Amt></TxDtls>
+          <TxDtls><Refs><AcctSvcrRef>ref-3</AcctSvcrRef></Refs><Amt Ccy="USD">2</Amt></TxDtls>
+        </NtryDtls>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+func TestParseCSV(t *testing.T) {
+	t.Run("should parse lines in any column order", func(t *testing.T) {
+		// arrange
+		give := "Currency,Reference,Booking_Date,Amount,End_To_End_ID\n" +
+			"usd,ref-1,2021-03-01,-1.40,batch-1\n" +
+			"USD,ref-2,2021-03-02T10:00:00Z,2,\n"
+
+		// act
+		lines, err := ParseCSV("march.csv", strings.NewReader(give))
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []Line{
+			{File: "march.csv", Line: 2, Reference: "ref-1", EndToEndID: "batch-1", Amount: "1.40", Currency: "USD", BookingDate: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+			{File: "march.csv", Line: 3, Reference: "ref-2", Amount: "2", Currency: "USD", BookingDate: time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC)},
+		}, lines)
+	})
+
+	t.Run("should return error, required column is missing", func(t *testing.T) {
+		// act
+		_, err := ParseCSV("march.csv", strings.NewReader("reference,amount,currency\nref-1,1,USD\n"))
+
+		// assert
+		assert.True(t, errors.Is(err, ErrMissingColumn))
+	})
+
+	t.Run("should return error, amount is not a number", func(t *testing.T) {
+		// act
+		_, err := ParseCSV("march.csv", strings.NewReader("reference,amount,currency,booking_date\nref-1,one,USD,2021-03-01\n"))
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidLine))
+		assert.Contains(t, err.Error(), "row 2")
+	})
+}
+
+func TestParseCamt053(t *testing.T) {
+	t.Run("should parse debit entries and their transactions", func(t *testing.T) {
+		// act
+		lines, err := ParseCamt053("march.xml", strings.NewReader(testCamt))
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []Line{
+			{File: "march.xml", Line: 1, Reference: "ref-1", EndToEndID: "batch-1", Amount: "1.40", Currency: "USD", BookingDate: time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC)},
+			{File: "march.xml", Line: 3, Reference: "ref-2", Amount: "1", Currency: "USD", BookingDate: time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC)},
+			{File: "march.xml", Line: 3, Reference: "ref-3", Amount: "2", Currency: "USD", BookingDate: time.Date(2021, 3, 2, 10, 0, 0, 0, time.UTC)},
+		}, lines)
+	})
+
+	t.Run("should return error, document is not xml", func(t *testing.T) {
+		// act
+		_, err := ParseCamt053("march.xml", strings.NewReader("reference,amount"))
+
+		// assert
+		assert.Error(t, err)
+	})
+}
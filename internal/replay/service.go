@@ -0,0 +1,146 @@
+package replay
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/internal/platform/transport"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+const (
+	IssueRejected  = "rejected"
+	IssueDuplicate = "duplicate"
+
+	// a single line longer than that stops the replay
+	_maxLineSize = 1024 * 1024
+)
+
+var (
+	ErrNoPublisher = errors.New("transaction publisher was not provided")
+	ErrInvalidRate = errors.New("rate can not be negative")
+)
+
+// Summary of a replayed file, lines are numbered from 1 and blank lines are counted too,
+// so the numbers match the ones shown by an editor
+type Summary struct {
+	Lines      int     `json:"lines"`
+	Accepted   int     `json:"accepted"`
+	Rejected   int     `json:"rejected"`
+	Duplicates int     `json:"duplicates"`
+	Issues     []Issue `json:"issues"`
+}
+
+// Issue describes a line which was not published
+type Issue struct {
+	Line   int    `json:"line"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status"`
+	Reason string `json:"reason"`
+}
+
+func (i Issue) String() string {
+	if i.ID == "" {
+		return fmt.Sprintf("line %d: %s: %s", i.Line, i.Status, i.Reason)
+	}
+	return fmt.Sprintf("line %d: %s (%s): %s", i.Line, i.Status, i.ID, i.Reason)
+}
+
+type Service interface {
+	// Replay publishes NDJSON transactions read from r, at most rate per second, rate 0 means no limit
+	Replay(ctx context.Context, r io.Reader, rate int) (Summary, error)
+}
+
+type serviceContext struct {
+	publisher transport.Publisher
+	logger    *logrus.Logger
+}
+
+func New(p transport.Publisher, l *logrus.Logger) (Service, error) {
+	if p == nil {
+		return nil, ErrNoPublisher
+	}
+	c := serviceContext{
+		publisher: p,
+		logger:    l,
+	}
+	return &c, nil
+}
+
+func (c *serviceContext) Replay(ctx context.Context, r io.Reader, rate int) (Summary, error) {
+	if rate < 0 {
+		return Summary{}, ErrInvalidRate
+	}
+
+	summary := Summary{Issues: make([]Issue, 0)}
+	/* transaction id -> line it was first published from */
+	seen := make(map[string]int)
+	start := time.Now()
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), _maxLineSize)
+	for scanner.Scan() {
+		summary.Lines++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var t transaction.Transaction
+		if err := json.Unmarshal(line, &t); err != nil {
+			summary.reject(Issue{Line: summary.Lines, Reason: err.Error()})
+			continue
+		}
+		if err := t.Validate(); err != nil {
+			summary.reject(Issue{Line: summary.Lines, ID: t.ID, Reason: err.Error()})
+			continue
+		}
+		if first, exists := seen[t.ID]; exists {
+			summary.Duplicates++
+			issue := Issue{
+				Line:   summary.Lines,
+				ID:     t.ID,
+				Status: IssueDuplicate,
+				Reason: fmt.Sprintf("duplicate of line %d", first),
+			}
+			summary.Issues = append(summary.Issues, issue)
+			continue
+		}
+
+		if rate > 0 {
+			due := start.Add(time.Duration(summary.Accepted) * time.Second / time.Duration(rate))
+			if wait := time.Until(due); wait > 0 {
+				select {
+				case <-ctx.Done():
+					return summary, ctx.Err()
+				case <-time.After(wait):
+				}
+			}
+		}
+		if err := c.publisher.Publish(ctx, t, transaction.MessageTypeTransaction); err != nil {
+			c.logger.Error(err)
+			summary.reject(Issue{Line: summary.Lines, ID: t.ID, Reason: err.Error()})
+			continue
+		}
+		seen[t.ID] = summary.Lines
+		summary.Accepted++
+	}
+	if err := scanner.Err(); err != nil {
+		return summary, errors.Wrapf(err, "line %d", summary.Lines+1)
+	}
+	return summary, nil
+}
+
+func (s *Summary) reject(i Issue) {
+	i.Status = IssueRejected
+	s.Rejected++
+	s.Issues = append(s.Issues, i)
+}
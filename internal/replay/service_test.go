@@ -0,0 +1,126 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	mockTransport "github.com/mazxaxz/donut-batcher/internal/platform/transport/mock"
+	"github.com/mazxaxz/donut-batcher/pkg/message/transaction"
+)
+
+func TestReplay(t *testing.T) {
+	t.Run("should return invalid rate error", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		svc, err := New(mockPublisher, logrus.New())
+		assert.NoError(t, err)
+
+		// expected calls
+
+		// act
+		_, err = svc.Replay(context.Background(), strings.NewReader(""), -1)
+
+		// assert
+		assert.Equal(t, ErrInvalidRate, err)
+	})
+
+	t.Run("should report accepted, rejected and duplicate lines", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		svc, err := New(mockPublisher, logrus.New())
+		assert.NoError(t, err)
+		input := strings.Join([]string{
+			`{"id":"1","userId":"user:1","amount":"1.20","currency":"USD"}`,
+			``,
+			`{"id":"2","userId":"","amount":"1.20","currency":"USD"}`,
+			`not json`,
+			`{"id":"1","userId":"user:1","amount":"1.20","currency":"USD"}`,
+			`{"id":"3","userId":"user:2","amount":"3.50","currency":"USD"}`,
+		}, "\n")
+
+		// expected calls
+		first := transaction.Transaction{ID: "1", UserID: "user:1", Amount: "1.20", Currency: "USD"}
+		third := transaction.Transaction{ID: "3", UserID: "user:2", Amount: "3.50", Currency: "USD"}
+		gomock.InOrder(
+			mockPublisher.EXPECT().Publish(gomock.Any(), first, transaction.MessageTypeTransaction).Return(nil),
+			mockPublisher.EXPECT().Publish(gomock.Any(), third, transaction.MessageTypeTransaction).Return(nil),
+		)
+
+		// act
+		result, err := svc.Replay(context.Background(), strings.NewReader(input), 0)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 6, result.Lines)
+		assert.Equal(t, 2, result.Accepted)
+		assert.Equal(t, 2, result.Rejected)
+		assert.Equal(t, 1, result.Duplicates)
+		assert.Len(t, result.Issues, 3)
+		assert.Equal(t, Issue{Line: 3, ID: "2", Status: IssueRejected, Reason: transaction.ErrNoUserID.Error()}, result.Issues[0])
+		assert.Equal(t, 4, result.Issues[1].Line)
+		assert.Equal(t, IssueRejected, result.Issues[1].Status)
+		assert.Equal(t, Issue{Line: 5, ID: "1", Status: IssueDuplicate, Reason: "duplicate of line 1"}, result.Issues[2])
+	})
+
+	t.Run("should reject line which failed to publish and allow its duplicate", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		svc, err := New(mockPublisher, logrus.New())
+		assert.NoError(t, err)
+		line := `{"id":"1","userId":"user:1","amount":"1.20","currency":"USD"}`
+
+		// expected calls
+		gomock.InOrder(
+			mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), transaction.MessageTypeTransaction).Return(errors.New("closed")),
+			mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), transaction.MessageTypeTransaction).Return(nil),
+		)
+
+		// act
+		result, err := svc.Replay(context.Background(), strings.NewReader(line+"\n"+line), 0)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result.Accepted)
+		assert.Equal(t, 1, result.Rejected)
+		assert.Equal(t, 0, result.Duplicates)
+	})
+
+	t.Run("should limit publishing rate", func(t *testing.T) {
+		// arrange
+		mockCtrl := gomock.NewController(t)
+		defer mockCtrl.Finish()
+		mockPublisher := mockTransport.NewMockPublisher(mockCtrl)
+		svc, err := New(mockPublisher, logrus.New())
+		assert.NoError(t, err)
+		input := strings.Join([]string{
+			`{"id":"1","userId":"user:1","amount":"1.20","currency":"USD"}`,
+			`{"id":"2","userId":"user:1","amount":"1.20","currency":"USD"}`,
+			`{"id":"3","userId":"user:1","amount":"1.20","currency":"USD"}`,
+		}, "\n")
+
+		// expected calls
+		mockPublisher.EXPECT().Publish(gomock.Any(), gomock.Any(), transaction.MessageTypeTransaction).Return(nil).Times(3)
+
+		// act
+		start := time.Now()
+		result, err := svc.Replay(context.Background(), strings.NewReader(input), 20)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result.Accepted)
+		assert.True(t, time.Since(start) >= 100*time.Millisecond)
+	})
+}
@@ -0,0 +1,76 @@
+package banksdk
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+	"github.com/mazxaxz/donut-batcher/pkg/nacha"
+)
+
+// newACH puts the USD transfers of an execution date into one PPD batch of credits, the file is validated
+// before it is released to the bank
+func newACH(name string, created time.Time, cfg ACHConfig, transfers []spooledTransfer) ([]byte, error) {
+	executionDate, err := time.Parse("2006-01-02", transfers[0].ExecutionDate)
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]spooledTransfer, len(transfers))
+	copy(sorted, transfers)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Trace < sorted[j].Trace })
+
+	entries := make([]nacha.Entry, 0, len(sorted))
+	for _, t := range sorted {
+		cents, err := money.MinorUnits(t.Amount, 2)
+		if err != nil {
+			return nil, ErrInvalidAmount
+		}
+		if t.Creditor == nil {
+			return nil, ErrNoCreditorAccount
+		}
+		code := nacha.TransactionCodeCheckingCredit
+		if t.Creditor.Savings {
+			code = nacha.TransactionCodeSavingsCredit
+		}
+		individualName := t.Creditor.Name
+		if individualName == "" {
+			individualName = t.UserID
+		}
+		entries = append(entries, nacha.Entry{
+			TransactionCode: code,
+			RoutingNumber:   t.Creditor.RoutingNumber,
+			AccountNumber:   t.Creditor.AccountNumber,
+			Amount:          cents,
+			IndividualID:    t.UserID,
+			IndividualName:  individualName,
+			TraceNumber:     t.Trace,
+		})
+	}
+
+	f := nacha.File{
+		ImmediateDestination: cfg.ImmediateDestination,
+		ImmediateOrigin:      cfg.ImmediateOrigin,
+		DestinationName:      cfg.DestinationName,
+		OriginName:           cfg.OriginName,
+		CreationDate:         created,
+		Sequence:             fileSequence(name),
+		Batches: []nacha.Batch{{
+			CompanyName:      cfg.CompanyName,
+			CompanyID:        cfg.CompanyID,
+			EntryDescription: cfg.EntryDescription,
+			EffectiveDate:    executionDate,
+			ODFI:             cfg.ODFI,
+			Entries:          entries,
+		}},
+	}
+	data, err := nacha.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	if err := nacha.Validate(data); err != nil {
+		return nil, errors.Wrap(err, "generated ach file is not released")
+	}
+	return data, nil
+}
@@ -0,0 +1,76 @@
+package banksdk
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	ModeAPI  = "api"
+	ModeFile = "file"
+)
+
+type Config struct {
+	// Mode is either api or file, api is the default
+	Mode string `json:"mode"`
+	// BaseURL of the bank API, without it transfers are only printed
+	BaseURL string `json:"base_url"`
+	APIKey  string `json:"api_key"`
+	// Secret signs the requests, see Sign
+	Secret string `json:"secret"`
+	// WebhookSecret verifies the transfer events posted by the bank, without it the webhook is not served
+	WebhookSecret string `json:"webhook_secret"`
+	// Timeout of a single request in seconds
+	Timeout int `json:"timeout"`
+	// File configures the file mode, transfers are written into pain.001 files instead of being sent
+	File FileConfig `json:"file"`
+}
+
+type FileConfig struct {
+	// Dir is the outbound directory the files are dropped into
+	Dir        string `json:"dir"`
+	DebtorName string `json:"debtor_name"`
+	DebtorIBAN string `json:"debtor_iban"`
+	DebtorBIC  string `json:"debtor_bic"`
+	// FlushInterval in seconds, how often the collected transfers are written into files
+	FlushInterval int `json:"flush_interval"`
+	// ACH writes the USD transfers into NACHA files instead of pain.001
+	ACH ACHConfig `json:"ach"`
+	// Accounts of the users by their id, for the transfers which do not carry the creditor
+	Accounts map[string]Account `json:"accounts"`
+}
+
+type ACHConfig struct {
+	// ImmediateDestination is the routing number of the bank receiving the files, ACH is off without it
+	ImmediateDestination string `json:"immediate_destination"`
+	DestinationName      string `json:"destination_name"`
+	ImmediateOrigin      string `json:"immediate_origin"`
+	OriginName           string `json:"origin_name"`
+	CompanyName          string `json:"company_name"`
+	CompanyID            string `json:"company_id"`
+	// ODFI is the first 8 digits of the routing number of the originating bank
+	ODFI             string `json:"odfi"`
+	EntryDescription string `json:"entry_description"`
+}
+
+func (c ACHConfig) Enabled() bool {
+	return c.ImmediateDestination != ""
+}
+
+func (c *Config) UnmarshalEnvironmentValue(data string) error {
+	return json.Unmarshal([]byte(data), &c)
+}
+
+func (c Config) TimeoutOrDefault(d time.Duration) time.Duration {
+	if c.Timeout <= 0 {
+		return d
+	}
+	return time.Duration(c.Timeout) * time.Second
+}
+
+func (c FileConfig) FlushIntervalOrDefault(d time.Duration) time.Duration {
+	if c.FlushInterval <= 0 {
+		return d
+	}
+	return time.Duration(c.FlushInterval) * time.Second
+}
@@ -0,0 +1,51 @@
+package banksdk
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// Error is returned by the HTTP client for every failed transfer, Retryable tells whether sending
+// the same transfer again can succeed. Retries are safe, the bank deduplicates them by the transfer ID.
+type Error struct {
+	// StatusCode is zero when the bank could not be reached at all
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Retryable  bool   `json:"-"`
+	cause      error
+}
+
+func (e *Error) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("bank request failed: %s", e.Message)
+	}
+	return fmt.Sprintf("bank responded with %d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+func (e *Error) Unwrap() error {
+	return e.cause
+}
+
+// IsRetryable reports whether the transfer failed for a reason which could go away, like an outage or rate limiting
+func IsRetryable(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Retryable
+}
+
+// IsPermanent reports whether the bank refused the transfer, sending it again is going to fail the same way
+func IsPermanent(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && !e.Retryable
+}
+
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusRequestTimeout, http.StatusTooEarly, http.StatusTooManyRequests:
+		return true
+	default:
+		return code >= http.StatusInternalServerError
+	}
+}
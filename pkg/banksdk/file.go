@@ -0,0 +1,424 @@
+package banksdk
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+	"github.com/mazxaxz/donut-batcher/pkg/nacha"
+)
+
+const (
+	_spoolDir = ".spool"
+	_sentDir  = ".sent"
+
+	_formatPain001 = "pain001"
+	_formatACH     = "ach"
+
+	_defaultFlushInterval = 5 * time.Minute
+	// _maxEndToEndID is the length limit of the identifiers in pain.001
+	_maxEndToEndID = 35
+	// _maxACHEntries in a file, the rest of the 7 digits of the trace number is the sequence of the file
+	_maxACHEntries = 100000
+)
+
+var (
+	ErrNoOutboundDir     = errors.New("outbound directory of the payment files is required")
+	ErrNoDebtor          = errors.New("debtor name and iban are required to initiate the payments")
+	ErrInvalidTransferID = errors.New("transfer id has to be a plain name of at most 35 characters")
+	ErrInvalidAmount     = errors.New("transfer amount has to be a non negative decimal")
+	ErrInvalidACHConfig  = errors.New("ach needs valid routing number of the immediate destination and 8 digits of the odfi")
+	ErrNoCreditorAccount = errors.New("ach transfer needs the routing and account number of the user")
+	ErrACHFileFull       = errors.New("ach file of the day has no trace numbers left")
+)
+
+var _extensions = map[string]string{
+	_formatPain001: ".xml",
+	_formatACH:     ".ach",
+}
+
+// FileClient collects the transfers into pain.001 files, or NACHA files for USD when ACH is configured, one per
+// currency and execution date, which are dropped into the outbound directory for the bank to pick up. Until Flush the transfers wait in the spool,
+// a transfer gets into a file only once, so a retried dispatch is not paid out twice.
+// Only one process may write into the outbound directory.
+type FileClient struct {
+	mu    sync.Mutex
+	cfg   FileConfig
+	clock clock.Clock
+	log   *logrus.Logger
+}
+
+var _ Clienter = (*FileClient)(nil)
+
+// spooledTransfer waits in the spool for its file, after the flush it is kept as the proof it was sent
+type spooledTransfer struct {
+	Transfer
+	File          string `json:"file"`
+	ExecutionDate string `json:"executionDate"`
+	// Trace number of the ACH entry, the bank refers to the entry by it
+	Trace string `json:"trace,omitempty"`
+}
+
+// receipt refers to the ACH entry by its trace number, a pain.001 payment has no reference other than its end to end id
+func (s spooledTransfer) receipt() Receipt {
+	if s.Trace == "" {
+		return Receipt{Reference: s.ID, File: s.File, EndToEndID: s.ID}
+	}
+	executionDate, _ := time.Parse("2006-01-02", s.ExecutionDate)
+	return Receipt{Reference: TraceReference(executionDate, s.Trace), File: s.File, EndToEndID: s.ID}
+}
+
+// TraceReference is the reference of the ACH entry, trace numbers start over every day so the date of the file comes first
+func TraceReference(executionDate time.Time, trace string) string {
+	return executionDate.Format("20060102") + "-" + trace
+}
+
+func NewFile(cfg FileConfig, clk clock.Clock, l *logrus.Logger) (*FileClient, error) {
+	if cfg.Dir == "" {
+		return nil, ErrNoOutboundDir
+	}
+	if cfg.DebtorName == "" || cfg.DebtorIBAN == "" {
+		return nil, ErrNoDebtor
+	}
+	if cfg.ACH.Enabled() {
+		if !nacha.ValidRoutingNumber(cfg.ACH.ImmediateDestination) || len(cfg.ACH.ODFI) != 8 {
+			return nil, ErrInvalidACHConfig
+		}
+	}
+	for _, dir := range []string{_spoolDir, _sentDir} {
+		if err := os.MkdirAll(filepath.Join(cfg.Dir, dir), 0750); err != nil {
+			return nil, err
+		}
+	}
+	c := FileClient{
+		cfg:   cfg,
+		clock: clk,
+		log:   l,
+	}
+	return &c, nil
+}
+
+func (c *FileClient) Send(ctx context.Context, t Transfer) (Receipt, error) {
+	if t.ID == "" {
+		return Receipt{}, ErrNoTransferID
+	}
+	/* the id names the spooled file and is the end to end id of the payment */
+	if len(t.ID) > _maxEndToEndID || filepath.Base(t.ID) != t.ID || strings.HasPrefix(t.ID, ".") {
+		return Receipt{}, refused(ErrInvalidTransferID)
+	}
+	if ok, err := money.GreaterThanOrEqual(t.Amount, "0"); err != nil || !ok {
+		return Receipt{}, refused(ErrInvalidAmount)
+	}
+	currency, err := money.CurrencyFrom(t.Currency)
+	if err != nil {
+		return Receipt{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return Receipt{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	/* a retried dispatch gets the file the transfer was put into the first time */
+	s, ok, err := c.find(t.ID)
+	if err != nil {
+		return Receipt{}, err
+	}
+	if ok {
+		return s.receipt(), nil
+	}
+
+	if t.Creditor == nil {
+		if a, ok := c.cfg.Accounts[t.UserID]; ok {
+			t.Creditor = &a
+		}
+	}
+	format := c.format(currency.String())
+	if format == _formatACH {
+		if t.Creditor == nil || !nacha.ValidRoutingNumber(t.Creditor.RoutingNumber) || t.Creditor.AccountNumber == "" {
+			return Receipt{}, refused(ErrNoCreditorAccount)
+		}
+		if _, err := money.MinorUnits(t.Amount, 2); err != nil {
+			return Receipt{}, refused(ErrInvalidAmount)
+		}
+	}
+
+	now := c.clock.Now()
+	name, err := c.openFile(now, format, currency.String())
+	if err != nil {
+		return Receipt{}, err
+	}
+	s = spooledTransfer{
+		Transfer:      t,
+		File:          name + _extensions[format],
+		ExecutionDate: now.Format("2006-01-02"),
+	}
+	s.Currency = currency.String()
+
+	dir := filepath.Join(c.cfg.Dir, _spoolDir, name)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return Receipt{}, err
+	}
+	if format == _formatACH {
+		if s.Trace, err = c.trace(dir, name); err != nil {
+			if errors.Is(err, ErrACHFileFull) {
+				return Receipt{}, refused(err)
+			}
+			return Receipt{}, err
+		}
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return Receipt{}, err
+	}
+	if err := writeFile(filepath.Join(dir, t.ID+".json"), data); err != nil {
+		return Receipt{}, err
+	}
+	return s.receipt(), nil
+}
+
+// refused makes the transfer the file can never take a permanent failure, like a refusal of the bank
+func refused(err error) *Error {
+	return &Error{Code: "file_refused", Message: err.Error(), cause: err}
+}
+
+// Flush writes the spooled transfers into the files and returns their paths
+func (c *FileClient) Flush(ctx context.Context) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	dirs, err := filepath.Glob(filepath.Join(c.cfg.Dir, _spoolDir, "*-*"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(dirs)
+
+	var files []string
+	for _, dir := range dirs {
+		if err := ctx.Err(); err != nil {
+			return files, err
+		}
+		file, err := c.flush(dir)
+		if err != nil {
+			return files, errors.Wrapf(err, "could not write payment file %s", filepath.Base(dir))
+		}
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+// Run flushes the spool every flush interval, transfers left in the spool by the previous run are flushed right away
+func (c *FileClient) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.FlushIntervalOrDefault(_defaultFlushInterval))
+	defer ticker.Stop()
+	for {
+		c.flushAndLog(ctx)
+		select {
+		case <-ctx.Done():
+			/* the last flush is not cancelled, otherwise the spool would wait for the next start */
+			c.flushAndLog(context.Background())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *FileClient) flushAndLog(ctx context.Context) {
+	files, err := c.Flush(ctx)
+	for _, file := range files {
+		c.log.Infof("payment file %s written", file)
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		c.log.Error(err)
+	}
+}
+
+func (c *FileClient) flush(dir string) (string, error) {
+	name := filepath.Base(dir)
+	format := fileFormat(name)
+	ext, ok := _extensions[format]
+	if !ok {
+		return "", nil
+	}
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", os.RemoveAll(dir)
+	}
+	sort.Strings(paths)
+
+	transfers := make([]spooledTransfer, 0, len(paths))
+	for _, p := range paths {
+		s, err := readSpooled(p)
+		if err != nil {
+			return "", err
+		}
+		transfers = append(transfers, s)
+	}
+
+	file := filepath.Join(c.cfg.Dir, name+ext)
+	/* the file written before a crash could have been picked up already, it is not written again */
+	_, err = os.Stat(file)
+	switch {
+	case os.IsNotExist(err):
+		data, err := c.render(name, format, transfers)
+		if err != nil {
+			return "", err
+		}
+		/* the file is renamed into the outbound directory only once complete, so it is never picked up half written */
+		tmp := filepath.Join(dir, name+ext+".tmp")
+		if err := ioutil.WriteFile(tmp, data, 0640); err != nil {
+			return "", err
+		}
+		if err := os.Rename(tmp, file); err != nil {
+			return "", err
+		}
+	case err != nil:
+		return "", err
+	}
+
+	for i, p := range paths {
+		if err := os.Rename(p, filepath.Join(c.cfg.Dir, _sentDir, transfers[i].ID+".json")); err != nil {
+			return "", err
+		}
+	}
+	return file, os.RemoveAll(dir)
+}
+
+func (c *FileClient) render(name, format string, transfers []spooledTransfer) ([]byte, error) {
+	if format == _formatACH {
+		return newACH(name, c.clock.Now(), c.cfg.ACH, transfers)
+	}
+	doc, err := newPain001(name, c.clock.Now(), c.cfg, transfers)
+	if err != nil {
+		return nil, err
+	}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// format of the files the transfers in the currency are collected into
+func (c *FileClient) format(currency string) string {
+	if currency == "USD" && c.cfg.ACH.Enabled() {
+		return _formatACH
+	}
+	return _formatPain001
+}
+
+// trace numbers follow the order the transfers were spooled in, the sequence of the file keeps them unique within the day
+func (c *FileClient) trace(dir, name string) (string, error) {
+	spooled, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return "", err
+	}
+	if len(spooled)+1 >= _maxACHEntries {
+		return "", ErrACHFileFull
+	}
+	return nacha.TraceNumber(c.cfg.ACH.ODFI, fileSequence(name)*_maxACHEntries+len(spooled)+1), nil
+}
+
+func (c *FileClient) find(transferID string) (spooledTransfer, bool, error) {
+	paths := []string{filepath.Join(c.cfg.Dir, _sentDir, transferID+".json")}
+	spooled, err := filepath.Glob(filepath.Join(c.cfg.Dir, _spoolDir, "*-*", transferID+".json"))
+	if err != nil {
+		return spooledTransfer{}, false, err
+	}
+	for _, p := range append(paths, spooled...) {
+		s, err := readSpooled(p)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return spooledTransfer{}, false, err
+		}
+		return s, true, nil
+	}
+	return spooledTransfer{}, false, nil
+}
+
+// openFile returns the name of the file collecting the transfers of the day and currency,
+// a new sequence number is taken once the previous file is written
+func (c *FileClient) openFile(now time.Time, format, currency string) (string, error) {
+	ext := _extensions[format]
+	prefix := fmt.Sprintf("%s-%s-%s-", format, now.Format("20060102"), currency)
+	spooled, err := filepath.Glob(filepath.Join(c.cfg.Dir, _spoolDir, prefix+"*"))
+	if err != nil {
+		return "", err
+	}
+	written, err := filepath.Glob(filepath.Join(c.cfg.Dir, prefix+"*"+ext))
+	if err != nil {
+		return "", err
+	}
+
+	seq := 0
+	for _, p := range spooled {
+		name := filepath.Base(p)
+		if _, err := os.Stat(filepath.Join(c.cfg.Dir, name+ext)); os.IsNotExist(err) {
+			return name, nil
+		}
+	}
+	for _, p := range append(spooled, written...) {
+		if n := fileSequence(strings.TrimSuffix(filepath.Base(p), ext)); n > seq {
+			seq = n
+		}
+	}
+	if format == _formatACH && seq >= nacha.MaxFilesPerDay {
+		return "", nacha.ErrInvalidFileSequence
+	}
+	return fmt.Sprintf("%s%03d", prefix, seq+1), nil
+}
+
+// fileFormat and fileSequence read the name of the file, <format>-<date>-<currency>-<sequence>
+func fileFormat(name string) string {
+	return strings.SplitN(name, "-", 2)[0]
+}
+
+func fileSequence(name string) int {
+	n, err := strconv.Atoi(name[strings.LastIndex(name, "-")+1:])
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func readSpooled(path string) (spooledTransfer, error) {
+	var s spooledTransfer
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, errors.Wrapf(err, "could not decode spooled transfer %s", filepath.Base(path))
+	}
+	return s, nil
+}
+
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
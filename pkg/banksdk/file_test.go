@@ -0,0 +1,357 @@
+package banksdk
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+	"github.com/mazxaxz/donut-batcher/pkg/nacha"
+)
+
+var (
+	testFileConfig = FileConfig{DebtorName: "Donut", DebtorIBAN: "DE89370400440532013000", DebtorBIC: "COBADEFFXXX"}
+	testACHConfig  = ACHConfig{
+		ImmediateDestination: "021000021",
+		ImmediateOrigin:      "121000358",
+		CompanyName:          "Donut",
+		CompanyID:            "1234567890",
+		ODFI:                 "12100035",
+		EntryDescription:     "Roundups",
+	}
+	testAccounts = map[string]Account{
+		"user:1": {Name: "Jane Doe", RoutingNumber: "011000015", AccountNumber: "123456789", IBAN: "GB33BUKB20201555555555"},
+		"user:2": {Name: "John Doe", RoutingNumber: "021000021", AccountNumber: "987654321", Savings: true},
+	}
+)
+
+func newTestFileClient(t *testing.T, clk clock.Clock) (*FileClient, string) {
+	return newTestFileClientWith(t, testFileConfig, clk)
+}
+
+func newTestFileClientWith(t *testing.T, cfg FileConfig, clk clock.Clock) (*FileClient, string) {
+	cfg.Dir = t.TempDir()
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	c, err := NewFile(cfg, clk, l)
+	assert.NoError(t, err)
+	return c, cfg.Dir
+}
+
+func readPain001(t *testing.T, path string) pain001Document {
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	var doc pain001Document
+	assert.NoError(t, xml.Unmarshal(data, &doc))
+	return doc
+}
+
+func TestNewFile(t *testing.T) {
+	t.Run("should return error, no outbound directory", func(t *testing.T) {
+		// act
+		_, err := NewFile(testFileConfig, clock.New(), logrus.New())
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoOutboundDir))
+	})
+
+	t.Run("should return error, no debtor account", func(t *testing.T) {
+		// act
+		_, err := NewFile(FileConfig{Dir: t.TempDir(), DebtorName: "Donut"}, clock.New(), logrus.New())
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoDebtor))
+	})
+
+	t.Run("should return error, ach destination is not a routing number", func(t *testing.T) {
+		// arrange
+		cfg := testFileConfig
+		cfg.Dir = t.TempDir()
+		cfg.ACH = testACHConfig
+		cfg.ACH.ImmediateDestination = "021000022"
+
+		// act
+		_, err := NewFile(cfg, clock.New(), logrus.New())
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidACHConfig))
+	})
+}
+
+func TestFileSend(t *testing.T) {
+	t.Run("should spool transfer and return its file", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClient(t, clock.NewFake(testNow))
+
+		// act
+		receipt, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, Receipt{Reference: testTransfer.ID, File: "pain001-20210101-USD-001.xml", EndToEndID: testTransfer.ID}, receipt)
+	})
+
+	t.Run("should return the same file, transfer was retried", func(t *testing.T) {
+		// arrange
+		clk := clock.NewFake(testNow)
+		c, _ := newTestFileClient(t, clk)
+		first, err := c.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+		clk.Advance(24 * time.Hour)
+
+		// act
+		second, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, first, second)
+	})
+
+	t.Run("should collect transfers per currency and execution date", func(t *testing.T) {
+		// arrange
+		clk := clock.NewFake(testNow)
+		c, _ := newTestFileClient(t, clk)
+
+		// act
+		usd, _ := c.Send(context.Background(), testTransfer)
+		eur, _ := c.Send(context.Background(), Transfer{ID: "batch-2", UserID: "user:1", Amount: "2", Currency: "eur"})
+		clk.Advance(24 * time.Hour)
+		nextDay, _ := c.Send(context.Background(), Transfer{ID: "batch-3", UserID: "user:1", Amount: "3", Currency: "USD"})
+
+		// assert
+		assert.Equal(t, "pain001-20210101-USD-001.xml", usd.File)
+		assert.Equal(t, "pain001-20210101-EUR-001.xml", eur.File)
+		assert.Equal(t, "pain001-20210102-USD-001.xml", nextDay.File)
+	})
+
+	t.Run("should return error, transfer id cannot name a file", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClient(t, clock.NewFake(testNow))
+
+		// act
+		_, err := c.Send(context.Background(), Transfer{ID: "../batch-1", UserID: "user:1", Amount: "1", Currency: "USD"})
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidTransferID))
+	})
+
+	t.Run("should return error, amount is negative", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClient(t, clock.NewFake(testNow))
+
+		// act
+		_, err := c.Send(context.Background(), Transfer{ID: "batch-1", UserID: "user:1", Amount: "-1", Currency: "USD"})
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidAmount))
+	})
+}
+
+func TestFileFlush(t *testing.T) {
+	t.Run("should write pain.001 file of the spooled transfers", func(t *testing.T) {
+		// arrange
+		c, dir := newTestFileClient(t, clock.NewFake(testNow))
+		_, err := c.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+		_, err = c.Send(context.Background(), Transfer{ID: "batch-2-1", UserID: "user:2", Amount: "2.25", Currency: "USD", Portfolio: "index-fund"})
+		assert.NoError(t, err)
+
+		// act
+		files, err := c.Flush(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(dir, "pain001-20210101-USD-001.xml")}, files)
+		raw, err := ioutil.ReadFile(files[0])
+		assert.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(raw), "<RmtInf>"))
+		doc := readPain001(t, files[0])
+		assert.Equal(t, Pain001Namespace, doc.Namespace)
+		assert.Equal(t, "pain001-20210101-USD-001", doc.Initiation.GroupHeader.MessageID)
+		assert.Equal(t, "2021-01-01T12:00:00", doc.Initiation.GroupHeader.CreationDate)
+		assert.Equal(t, 2, doc.Initiation.GroupHeader.NumberOfTransactions)
+		assert.Equal(t, "3.65", doc.Initiation.GroupHeader.ControlSum)
+		assert.Len(t, doc.Initiation.PaymentInfo, 1)
+		payment := doc.Initiation.PaymentInfo[0]
+		assert.Equal(t, "2021-01-01", payment.ExecutionDate)
+		assert.Equal(t, pain001Account{IBAN: testFileConfig.DebtorIBAN, Currency: "USD"}, payment.DebtorAccount)
+		assert.Equal(t, pain001Agent{BIC: testFileConfig.DebtorBIC}, payment.DebtorAgent)
+		assert.Equal(t, []pain001Transaction{
+			{
+				InstructionID:   "batch-1",
+				EndToEndID:      "batch-1",
+				Amount:          pain001Amount{Currency: "USD", Value: "1.4"},
+				Creditor:        pain001Party{Name: "user:1"},
+				CreditorAccount: pain001Account{Other: &pain001Other{ID: "user:1"}},
+			},
+			{
+				InstructionID:   "batch-2-1",
+				EndToEndID:      "batch-2-1",
+				Amount:          pain001Amount{Currency: "USD", Value: "2.25"},
+				Creditor:        pain001Party{Name: "user:2"},
+				CreditorAccount: pain001Account{Other: &pain001Other{ID: "user:2"}},
+				Remittance:      &pain001Remittance{Unstructured: "index-fund"},
+			},
+		}, payment.Transactions)
+	})
+
+	t.Run("should pay to the iban of the user", func(t *testing.T) {
+		// arrange
+		cfg := testFileConfig
+		cfg.Accounts = map[string]Account{"user:1": {Name: "Jane Doe", IBAN: "GB33BUKB20201555555555", BIC: "BUKBGB22"}}
+		c, _ := newTestFileClientWith(t, cfg, clock.NewFake(testNow))
+		_, err := c.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+
+		// act
+		files, err := c.Flush(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		tx := readPain001(t, files[0]).Initiation.PaymentInfo[0].Transactions[0]
+		assert.Equal(t, pain001Party{Name: "Jane Doe"}, tx.Creditor)
+		assert.Equal(t, pain001Account{IBAN: "GB33BUKB20201555555555"}, tx.CreditorAccount)
+		assert.Equal(t, &pain001Agent{BIC: "BUKBGB22"}, tx.CreditorAgent)
+	})
+
+	t.Run("should start next file, previous one was written", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClient(t, clock.NewFake(testNow))
+		first, err := c.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+		_, err = c.Flush(context.Background())
+		assert.NoError(t, err)
+
+		// act
+		retried, errRetried := c.Send(context.Background(), testTransfer)
+		next, errNext := c.Send(context.Background(), Transfer{ID: "batch-2", UserID: "user:2", Amount: "2", Currency: "USD"})
+
+		// assert
+		assert.NoError(t, errRetried)
+		assert.NoError(t, errNext)
+		assert.Equal(t, first, retried)
+		assert.Equal(t, "pain001-20210101-USD-002.xml", next.File)
+	})
+
+	t.Run("should not write anything, spool is empty", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClient(t, clock.NewFake(testNow))
+
+		// act
+		files, err := c.Flush(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Empty(t, files)
+	})
+
+	t.Run("should keep the file written before a crash", func(t *testing.T) {
+		// arrange
+		c, dir := newTestFileClient(t, clock.NewFake(testNow))
+		_, err := c.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+		file := filepath.Join(dir, "pain001-20210101-USD-001.xml")
+		assert.NoError(t, ioutil.WriteFile(file, []byte("picked up"), 0640))
+
+		// act
+		files, err := c.Flush(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{file}, files)
+		data, _ := ioutil.ReadFile(file)
+		assert.Equal(t, "picked up", string(data))
+		_, err = os.Stat(filepath.Join(dir, _sentDir, testTransfer.ID+".json"))
+		assert.NoError(t, err)
+	})
+}
+
+func TestFileACH(t *testing.T) {
+	cfg := testFileConfig
+	cfg.ACH = testACHConfig
+	cfg.Accounts = testAccounts
+
+	t.Run("should spool usd transfer with its trace number", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClientWith(t, cfg, clock.NewFake(testNow))
+
+		// act
+		first, errFirst := c.Send(context.Background(), testTransfer)
+		second, errSecond := c.Send(context.Background(), Transfer{ID: "batch-2", UserID: "user:2", Amount: "2.25", Currency: "USD"})
+		retried, errRetried := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.NoError(t, errFirst)
+		assert.NoError(t, errSecond)
+		assert.NoError(t, errRetried)
+		assert.Equal(t, Receipt{Reference: "20210101-121000350100001", File: "ach-20210101-USD-001.ach", EndToEndID: "batch-1"}, first)
+		assert.Equal(t, "20210101-121000350100002", second.Reference)
+		assert.Equal(t, first, retried)
+	})
+
+	t.Run("should keep other currencies in pain.001", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClientWith(t, cfg, clock.NewFake(testNow))
+
+		// act
+		receipt, err := c.Send(context.Background(), Transfer{ID: "batch-1", UserID: "user:1", Amount: "1.4", Currency: "EUR"})
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, "pain001-20210101-EUR-001.xml", receipt.File)
+	})
+
+	t.Run("should return error, account of the user is unknown", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClientWith(t, cfg, clock.NewFake(testNow))
+
+		// act
+		_, err := c.Send(context.Background(), Transfer{ID: "batch-1", UserID: "user:3", Amount: "1", Currency: "USD"})
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoCreditorAccount))
+		assert.True(t, IsPermanent(err))
+	})
+
+	t.Run("should return error, amount has fractions of a cent", func(t *testing.T) {
+		// arrange
+		c, _ := newTestFileClientWith(t, cfg, clock.NewFake(testNow))
+
+		// act
+		_, err := c.Send(context.Background(), Transfer{ID: "batch-1", UserID: "user:1", Amount: "1.001", Currency: "USD"})
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidAmount))
+	})
+
+	t.Run("should write valid nacha file of the spooled transfers", func(t *testing.T) {
+		// arrange
+		c, dir := newTestFileClientWith(t, cfg, clock.NewFake(testNow))
+		_, err := c.Send(context.Background(), Transfer{ID: "batch-2", UserID: "user:2", Amount: "2.25", Currency: "USD"})
+		assert.NoError(t, err)
+		_, err = c.Send(context.Background(), testTransfer)
+		assert.NoError(t, err)
+
+		// act
+		files, err := c.Flush(context.Background())
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(dir, "ach-20210101-USD-001.ach")}, files)
+		data, err := ioutil.ReadFile(files[0])
+		assert.NoError(t, err)
+		assert.NoError(t, nacha.Validate(data))
+		lines := strings.Split(string(data), "\n")
+		assert.Equal(t, "632021000021987654321        0000000225USER:2         JOHN DOE                0121000350100001", lines[2])
+		assert.Equal(t, "622011000015123456789        0000000140USER:1         JANE DOE                0121000350100002", lines[3])
+	})
+}
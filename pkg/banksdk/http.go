@@ -0,0 +1,123 @@
+package banksdk
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+const (
+	TransfersPath = "/v1/transfers"
+
+	// _maxResponseSize keeps a misbehaving bank from exhausting the memory
+	_maxResponseSize = 1 << 20
+)
+
+var (
+	ErrNoBaseURL      = errors.New("bank base url is required")
+	ErrNoTransferID   = errors.New("transfer has no id, the bank would not be able to deduplicate it")
+	ErrNoReference    = errors.New("bank accepted the transfer without a reference")
+	ErrInvalidBaseURL = errors.New("bank base url has to be an absolute http(s) url")
+)
+
+// TransferReceipt is the bank's answer to an accepted transfer
+type TransferReceipt struct {
+	Reference string `json:"reference"`
+	Status    string `json:"status"`
+}
+
+type httpClientContext struct {
+	client  *http.Client
+	baseURL string
+	apiKey  string
+	secret  string
+	clock   clock.Clock
+}
+
+// NewHTTP returns a client of the bank API, requests are signed when the secret is configured
+func NewHTTP(cfg Config, clk clock.Clock) (Clienter, error) {
+	if cfg.BaseURL == "" {
+		return nil, ErrNoBaseURL
+	}
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, ErrInvalidBaseURL
+	}
+	c := httpClientContext{
+		client:  &http.Client{Timeout: cfg.TimeoutOrDefault(10 * time.Second)},
+		baseURL: strings.TrimSuffix(cfg.BaseURL, "/"),
+		apiKey:  cfg.APIKey,
+		secret:  cfg.Secret,
+		clock:   clk,
+	}
+	return &c, nil
+}
+
+func (c *httpClientContext) Send(ctx context.Context, t Transfer) (Receipt, error) {
+	if t.ID == "" {
+		return Receipt{}, ErrNoTransferID
+	}
+	body, err := json.Marshal(t)
+	if err != nil {
+		return Receipt{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+TransfersPath, bytes.NewReader(body))
+	if err != nil {
+		return Receipt{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(HeaderIdempotencyKey, t.ID)
+	if c.apiKey != "" {
+		req.Header.Set(HeaderAPIKey, c.apiKey)
+	}
+	if c.secret != "" {
+		SignRequest(req, c.secret, c.clock.Now(), body)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		/* the transfer could have reached the bank, retrying with the same id is safe */
+		return Receipt{}, &Error{Code: "transport_error", Message: err.Error(), Retryable: true, cause: err}
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	payload, err := ioutil.ReadAll(io.LimitReader(resp.Body, _maxResponseSize))
+	if err != nil {
+		return Receipt{}, &Error{StatusCode: resp.StatusCode, Code: "transport_error", Message: err.Error(), Retryable: true, cause: err}
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Receipt{}, newError(resp.StatusCode, payload)
+	}
+
+	var receipt TransferReceipt
+	if err := json.Unmarshal(payload, &receipt); err != nil {
+		return Receipt{}, errors.Wrap(err, "could not decode bank response")
+	}
+	if receipt.Reference == "" {
+		return Receipt{}, ErrNoReference
+	}
+	return Receipt{Reference: receipt.Reference, EndToEndID: t.ID}, nil
+}
+
+func newError(statusCode int, payload []byte) *Error {
+	e := Error{}
+	/* not every failure comes from the bank itself, proxies answer with plain text */
+	if err := json.Unmarshal(payload, &e); err != nil || e.Code == "" {
+		e.Code = strings.ToLower(strings.ReplaceAll(http.StatusText(statusCode), " ", "_"))
+		e.Message = strings.TrimSpace(string(payload))
+	}
+	e.StatusCode = statusCode
+	e.Retryable = retryableStatus(statusCode)
+	return &e
+}
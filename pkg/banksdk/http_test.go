@@ -0,0 +1,166 @@
+package banksdk
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+var (
+	testNow      = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	testTransfer = Transfer{ID: "batch-1", UserID: "user:1", Amount: "1.4", Currency: "USD"}
+)
+
+func newTestClient(t *testing.T, h http.HandlerFunc) Clienter {
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	c, err := NewHTTP(Config{BaseURL: srv.URL + "/", APIKey: "key", Secret: "secret", Timeout: 1}, clock.NewFake(testNow))
+	assert.NoError(t, err)
+	return c
+}
+
+func TestNewHTTP(t *testing.T) {
+	t.Run("should return error, no base url", func(t *testing.T) {
+		// act
+		_, err := NewHTTP(Config{}, clock.New())
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoBaseURL))
+	})
+
+	t.Run("should return error, base url is not absolute", func(t *testing.T) {
+		// act
+		_, err := NewHTTP(Config{BaseURL: "bank.local/api"}, clock.New())
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidBaseURL))
+	})
+}
+
+func TestSend(t *testing.T) {
+	t.Run("should send signed transfer and return its reference", func(t *testing.T) {
+		// arrange
+		var got *http.Request
+		var body []byte
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			got = r
+			body, _ = ioutil.ReadAll(r.Body)
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"reference":"ref-1","status":"accepted"}`))
+		})
+
+		// act
+		receipt, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.NoError(t, err)
+		assert.Equal(t, Receipt{Reference: "ref-1", EndToEndID: testTransfer.ID}, receipt)
+		assert.Equal(t, http.MethodPost, got.Method)
+		assert.Equal(t, TransfersPath, got.URL.Path)
+		assert.Equal(t, "batch-1", got.Header.Get(HeaderIdempotencyKey))
+		assert.Equal(t, "key", got.Header.Get(HeaderAPIKey))
+		assert.JSONEq(t, `{"id":"batch-1","userId":"user:1","amount":"1.4","currency":"USD"}`, string(body))
+		err = Verify("secret", got.Header.Get(HeaderSignature), got.Header.Get(HeaderTimestamp), testNow, got.Method, got.URL.Path, body)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should return retryable error, bank is unavailable", func(t *testing.T) {
+		// arrange
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"code":"maintenance","message":"back in 5 minutes"}`))
+		})
+
+		// act
+		_, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		var bankErr *Error
+		assert.True(t, errors.As(err, &bankErr))
+		assert.Equal(t, http.StatusServiceUnavailable, bankErr.StatusCode)
+		assert.Equal(t, "maintenance", bankErr.Code)
+		assert.Equal(t, "back in 5 minutes", bankErr.Message)
+		assert.True(t, IsRetryable(err))
+		assert.False(t, IsPermanent(err))
+	})
+
+	t.Run("should return retryable error, rate limited by a proxy", func(t *testing.T) {
+		// arrange
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "slow down", http.StatusTooManyRequests)
+		})
+
+		// act
+		_, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		var bankErr *Error
+		assert.True(t, errors.As(err, &bankErr))
+		assert.Equal(t, "too_many_requests", bankErr.Code)
+		assert.Equal(t, "slow down", bankErr.Message)
+		assert.True(t, IsRetryable(err))
+	})
+
+	t.Run("should return permanent error, transfer was refused", func(t *testing.T) {
+		// arrange
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_, _ = w.Write([]byte(`{"code":"account_closed","message":"account is closed"}`))
+		})
+
+		// act
+		_, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.True(t, IsPermanent(err))
+		assert.False(t, IsRetryable(err))
+		assert.Equal(t, "bank responded with 422 account_closed: account is closed", err.Error())
+	})
+
+	t.Run("should return retryable error, bank did not answer in time", func(t *testing.T) {
+		// arrange
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(1500 * time.Millisecond)
+		})
+
+		// act
+		_, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.True(t, IsRetryable(err))
+	})
+
+	t.Run("should return error, accepted without reference", func(t *testing.T) {
+		// arrange
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"status":"accepted"}`))
+		})
+
+		// act
+		_, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoReference))
+	})
+
+	t.Run("should return error, transfer has no id", func(t *testing.T) {
+		// arrange
+		c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("request should not be sent")
+		})
+
+		// act
+		_, err := c.Send(context.Background(), Transfer{UserID: "user:1", Amount: "1", Currency: "USD"})
+
+		// assert
+		assert.True(t, errors.Is(err, ErrNoTransferID))
+	})
+}
@@ -0,0 +1,145 @@
+package banksdk
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+const Pain001Namespace = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09"
+
+// pain001Document is the customer credit transfer initiation, only with the elements the payouts need
+type pain001Document struct {
+	XMLName    xml.Name          `xml:"Document"`
+	Namespace  string            `xml:"xmlns,attr"`
+	Initiation pain001Initiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type pain001Initiation struct {
+	GroupHeader pain001GroupHeader   `xml:"GrpHdr"`
+	PaymentInfo []pain001PaymentInfo `xml:"PmtInf"`
+}
+
+type pain001GroupHeader struct {
+	MessageID            string       `xml:"MsgId"`
+	CreationDate         string       `xml:"CreDtTm"`
+	NumberOfTransactions int          `xml:"NbOfTxs"`
+	ControlSum           string       `xml:"CtrlSum"`
+	InitiatingParty      pain001Party `xml:"InitgPty"`
+}
+
+type pain001PaymentInfo struct {
+	ID                   string               `xml:"PmtInfId"`
+	Method               string               `xml:"PmtMtd"`
+	BatchBooking         bool                 `xml:"BtchBookg"`
+	NumberOfTransactions int                  `xml:"NbOfTxs"`
+	ControlSum           string               `xml:"CtrlSum"`
+	ExecutionDate        string               `xml:"ReqdExctnDt>Dt"`
+	Debtor               pain001Party         `xml:"Dbtr"`
+	DebtorAccount        pain001Account       `xml:"DbtrAcct"`
+	DebtorAgent          pain001Agent         `xml:"DbtrAgt"`
+	Transactions         []pain001Transaction `xml:"CdtTrfTxInf"`
+}
+
+type pain001Transaction struct {
+	InstructionID   string             `xml:"PmtId>InstrId"`
+	EndToEndID      string             `xml:"PmtId>EndToEndId"`
+	Amount          pain001Amount      `xml:"Amt>InstdAmt"`
+	CreditorAgent   *pain001Agent      `xml:"CdtrAgt,omitempty"`
+	Creditor        pain001Party       `xml:"Cdtr"`
+	CreditorAccount pain001Account     `xml:"CdtrAcct"`
+	Remittance      *pain001Remittance `xml:"RmtInf,omitempty"`
+}
+
+type pain001Remittance struct {
+	Unstructured string `xml:"Ustrd"`
+}
+
+type pain001Party struct {
+	Name string `xml:"Nm"`
+}
+
+type pain001Account struct {
+	IBAN     string        `xml:"Id>IBAN,omitempty"`
+	Other    *pain001Other `xml:"Id>Othr,omitempty"`
+	Currency string        `xml:"Ccy,omitempty"`
+}
+
+type pain001Agent struct {
+	BIC   string        `xml:"FinInstnId>BICFI,omitempty"`
+	Other *pain001Other `xml:"FinInstnId>Othr,omitempty"`
+}
+
+type pain001Other struct {
+	ID string `xml:"Id"`
+}
+
+type pain001Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// newPain001 puts the transfers of a single currency and execution date into one payment
+func newPain001(messageID string, created time.Time, debtor FileConfig, transfers []spooledTransfer) (pain001Document, error) {
+	var err error
+	sum := "0"
+	txs := make([]pain001Transaction, 0, len(transfers))
+	for _, t := range transfers {
+		if sum, err = money.Add(sum, t.Amount); err != nil {
+			return pain001Document{}, ErrInvalidAmount
+		}
+		tx := pain001Transaction{
+			InstructionID:   t.ID,
+			EndToEndID:      t.ID,
+			Amount:          pain001Amount{Currency: t.Currency, Value: t.Amount},
+			Creditor:        pain001Party{Name: t.UserID},
+			CreditorAccount: pain001Account{Other: &pain001Other{ID: t.UserID}},
+		}
+		/* the portfolio the transfer is invested into travels as the remittance information */
+		if t.Portfolio != "" {
+			tx.Remittance = &pain001Remittance{Unstructured: t.Portfolio}
+		}
+		/* without the account of the user the bank knows the creditor by the user id */
+		if a := t.Creditor; a != nil && a.IBAN != "" {
+			tx.CreditorAccount = pain001Account{IBAN: a.IBAN}
+			if a.Name != "" {
+				tx.Creditor.Name = a.Name
+			}
+			if a.BIC != "" {
+				tx.CreditorAgent = &pain001Agent{BIC: a.BIC}
+			}
+		}
+		txs = append(txs, tx)
+	}
+
+	agent := pain001Agent{BIC: debtor.DebtorBIC}
+	if agent.BIC == "" {
+		agent.Other = &pain001Other{ID: "NOTPROVIDED"}
+	}
+	doc := pain001Document{
+		Namespace: Pain001Namespace,
+		Initiation: pain001Initiation{
+			GroupHeader: pain001GroupHeader{
+				MessageID:            messageID,
+				CreationDate:         created.UTC().Format("2006-01-02T15:04:05"),
+				NumberOfTransactions: len(txs),
+				ControlSum:           sum,
+				InitiatingParty:      pain001Party{Name: debtor.DebtorName},
+			},
+			PaymentInfo: []pain001PaymentInfo{{
+				ID:                   messageID,
+				Method:               "TRF",
+				BatchBooking:         true,
+				NumberOfTransactions: len(txs),
+				ControlSum:           sum,
+				ExecutionDate:        transfers[0].ExecutionDate,
+				Debtor:               pain001Party{Name: debtor.DebtorName},
+				DebtorAccount:        pain001Account{IBAN: debtor.DebtorIBAN, Currency: transfers[0].Currency},
+				DebtorAgent:          agent,
+				Transactions:         txs,
+			}},
+		},
+	}
+	return doc, nil
+}
@@ -0,0 +1,139 @@
+package resilience
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half-open"
+)
+
+var (
+	ErrCircuitOpen = errors.New("bank circuit is open")
+)
+
+// OpenError is returned instead of sending the transfer while the circuit is open
+type OpenError struct {
+	// RetryAfter is when the circuit lets the next probe through
+	RetryAfter time.Duration
+}
+
+func (e *OpenError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrCircuitOpen.Error(), e.RetryAfter)
+}
+
+func (e *OpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
+// breaker opens after FailureThreshold consecutive failures, once OpenTimeout passes a single probe is let through
+// at a time and SuccessThreshold successful probes close it again. A failed probe opens it for another OpenTimeout.
+type breaker struct {
+	cfg   Config
+	clock clock.Clock
+	// onChange is called with the lock held, it must not call the breaker
+	onChange func(from, to State)
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	successes int
+	openedAt  time.Time
+	probing   bool
+}
+
+func newBreaker(cfg Config, clk clock.Clock, onChange func(from, to State)) *breaker {
+	b := breaker{
+		cfg:      cfg,
+		clock:    clk,
+		onChange: onChange,
+		state:    StateClosed,
+	}
+	return &b
+}
+
+func (b *breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+	return b.state
+}
+
+// allow reserves the call, every allowed call has to be followed by done
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.refresh()
+
+	switch b.state {
+	case StateOpen:
+		return &OpenError{RetryAfter: b.openedAt.Add(b.cfg.openTimeout()).Sub(b.clock.Now())}
+	case StateHalfOpen:
+		if b.probing {
+			/* another probe is already on its way, the outcome is known soon */
+			return &OpenError{RetryAfter: time.Second}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+func (b *breaker) done(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.cfg.FailureThreshold {
+			b.open()
+		}
+	case StateHalfOpen:
+		b.probing = false
+		if failed {
+			b.open()
+			return
+		}
+		b.successes++
+		if b.successes >= b.cfg.SuccessThreshold {
+			b.transition(StateClosed)
+		}
+	}
+}
+
+// refresh moves the open circuit to half-open once the timeout passes
+func (b *breaker) refresh() {
+	if b.state == StateOpen && !b.clock.Now().Before(b.openedAt.Add(b.cfg.openTimeout())) {
+		b.transition(StateHalfOpen)
+	}
+}
+
+func (b *breaker) open() {
+	b.openedAt = b.clock.Now()
+	b.transition(StateOpen)
+}
+
+func (b *breaker) transition(to State) {
+	from := b.state
+	b.state = to
+	b.failures = 0
+	b.successes = 0
+	b.probing = false
+	if from != to && b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
@@ -0,0 +1,54 @@
+// Package resilience keeps a degraded bank from being hammered by the dispatch consumers.
+package resilience
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+// Client sends transfers through the circuit breaker and the rate limiter.
+// Only retryable failures count against the circuit, a refused transfer means the bank is up.
+type Client struct {
+	next    banksdk.Clienter
+	breaker *breaker
+	limiter *limiter
+}
+
+var _ banksdk.Clienter = (*Client)(nil)
+
+func New(next banksdk.Clienter, cfg Config, clk clock.Clock, l *logrus.Logger) *Client {
+	cfg = cfg.withDefaults()
+	c := Client{
+		next: next,
+		breaker: newBreaker(cfg, clk, func(from, to State) {
+			l.Warnf("bank circuit changed from %s to %s", from, to)
+		}),
+	}
+	if cfg.RatePerSecond > 0 {
+		c.limiter = newLimiter(cfg.RatePerSecond, cfg.Burst, clk)
+	}
+	return &c
+}
+
+func (c *Client) Send(ctx context.Context, t banksdk.Transfer) (banksdk.Receipt, error) {
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return banksdk.Receipt{}, err
+		}
+	}
+	if err := c.breaker.allow(); err != nil {
+		return banksdk.Receipt{}, err
+	}
+	receipt, err := c.next.Send(ctx, t)
+	c.breaker.done(banksdk.IsRetryable(err))
+	return receipt, err
+}
+
+// State of the circuit
+func (c *Client) State() State {
+	return c.breaker.State()
+}
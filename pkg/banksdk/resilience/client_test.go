@@ -0,0 +1,217 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/pkg/banksdk"
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+var (
+	testNow      = time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	testTransfer = banksdk.Transfer{ID: "batch-1", UserID: "user:1", Amount: "1.4", Currency: "USD"}
+	errDown      = &banksdk.Error{StatusCode: http.StatusServiceUnavailable, Code: "unavailable", Retryable: true}
+	errRefused   = &banksdk.Error{StatusCode: http.StatusUnprocessableEntity, Code: "account_closed"}
+)
+
+// stubBank answers with the queued errors, a nil error books the transfer
+type stubBank struct {
+	errs  []error
+	calls int
+}
+
+func (b *stubBank) Send(_ context.Context, _ banksdk.Transfer) (banksdk.Receipt, error) {
+	b.calls++
+	if len(b.errs) == 0 {
+		return banksdk.Receipt{Reference: "ref"}, nil
+	}
+	err := b.errs[0]
+	b.errs = b.errs[1:]
+	if err != nil {
+		return banksdk.Receipt{}, err
+	}
+	return banksdk.Receipt{Reference: "ref"}, nil
+}
+
+func newTestClient(bank *stubBank, cfg Config, clk clock.Clock) *Client {
+	l := logrus.New()
+	l.SetOutput(ioutil.Discard)
+	return New(bank, cfg, clk, l)
+}
+
+func send(c *Client, times int) (err error) {
+	for i := 0; i < times; i++ {
+		_, err = c.Send(context.Background(), testTransfer)
+	}
+	return err
+}
+
+func TestBreaker(t *testing.T) {
+	cfg := Config{FailureThreshold: 3, SuccessThreshold: 2, OpenTimeout: 30}
+
+	t.Run("should open circuit, consecutive failures reached threshold", func(t *testing.T) {
+		// arrange
+		bank := &stubBank{errs: []error{errDown, errDown, errDown}}
+		clk := clock.NewFake(testNow)
+		c := newTestClient(bank, cfg, clk)
+
+		// act
+		_ = send(c, 3)
+		clk.Advance(10 * time.Second)
+		_, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		var open *OpenError
+		assert.True(t, errors.As(err, &open))
+		assert.True(t, errors.Is(err, ErrCircuitOpen))
+		assert.Equal(t, 20*time.Second, open.RetryAfter)
+		assert.Equal(t, StateOpen, c.State())
+		assert.Equal(t, 3, bank.calls)
+	})
+
+	t.Run("should keep circuit closed, failures were not consecutive", func(t *testing.T) {
+		// arrange
+		bank := &stubBank{errs: []error{errDown, errDown, nil, errDown, errDown}}
+		c := newTestClient(bank, cfg, clock.NewFake(testNow))
+
+		// act
+		_ = send(c, 5)
+
+		// assert
+		assert.Equal(t, StateClosed, c.State())
+	})
+
+	t.Run("should keep circuit closed, bank refused the transfers", func(t *testing.T) {
+		// arrange
+		bank := &stubBank{errs: []error{errRefused, errRefused, errRefused, errRefused}}
+		c := newTestClient(bank, cfg, clock.NewFake(testNow))
+
+		// act
+		err := send(c, 4)
+
+		// assert
+		assert.True(t, banksdk.IsPermanent(err))
+		assert.Equal(t, StateClosed, c.State())
+		assert.Equal(t, 4, bank.calls)
+	})
+
+	t.Run("should close circuit, probes succeeded after timeout", func(t *testing.T) {
+		// arrange
+		bank := &stubBank{errs: []error{errDown, errDown, errDown}}
+		clk := clock.NewFake(testNow)
+		c := newTestClient(bank, cfg, clk)
+		_ = send(c, 3)
+
+		// act
+		clk.Advance(30 * time.Second)
+		state := c.State()
+		_, errFirst := c.Send(context.Background(), testTransfer)
+		stateFirst := c.State()
+		_, errSecond := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.Equal(t, StateHalfOpen, state)
+		assert.NoError(t, errFirst)
+		assert.Equal(t, StateHalfOpen, stateFirst)
+		assert.NoError(t, errSecond)
+		assert.Equal(t, StateClosed, c.State())
+	})
+
+	t.Run("should open circuit again, probe failed", func(t *testing.T) {
+		// arrange
+		bank := &stubBank{errs: []error{errDown, errDown, errDown, errDown}}
+		clk := clock.NewFake(testNow)
+		c := newTestClient(bank, cfg, clk)
+		_ = send(c, 3)
+		clk.Advance(30 * time.Second)
+
+		// act
+		_, errProbe := c.Send(context.Background(), testTransfer)
+		_, err := c.Send(context.Background(), testTransfer)
+
+		// assert
+		assert.Equal(t, errDown, errProbe)
+		var open *OpenError
+		assert.True(t, errors.As(err, &open))
+		assert.Equal(t, 30*time.Second, open.RetryAfter)
+		assert.Equal(t, 4, bank.calls)
+	})
+
+	t.Run("should let a single probe through at a time", func(t *testing.T) {
+		// arrange
+		b := newBreaker(cfg.withDefaults(), clock.NewFake(testNow), nil)
+		b.state = StateHalfOpen
+
+		// act
+		errFirst := b.allow()
+		errSecond := b.allow()
+
+		// assert
+		assert.NoError(t, errFirst)
+		assert.True(t, errors.Is(errSecond, ErrCircuitOpen))
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	t.Run("should wait for tokens once burst is spent", func(t *testing.T) {
+		// arrange
+		clk := clock.NewFake(testNow)
+		l := newLimiter(2, 2, clk)
+		slept := make([]time.Duration, 0)
+		l.sleep = func(_ context.Context, d time.Duration) error {
+			slept = append(slept, d)
+			clk.Advance(d)
+			return nil
+		}
+
+		// act
+		for i := 0; i < 4; i++ {
+			assert.NoError(t, l.Wait(context.Background()))
+		}
+
+		// assert
+		assert.Equal(t, []time.Duration{500 * time.Millisecond, 500 * time.Millisecond}, slept)
+		assert.Equal(t, testNow.Add(time.Second), clk.Now())
+	})
+
+	t.Run("should not save more tokens than burst", func(t *testing.T) {
+		// arrange
+		clk := clock.NewFake(testNow)
+		l := newLimiter(1, 1, clk)
+		assert.NoError(t, l.Wait(context.Background()))
+		clk.Advance(time.Hour)
+
+		// act
+		first := l.reserve()
+		second := l.reserve()
+
+		// assert
+		assert.Equal(t, time.Duration(0), first)
+		assert.Equal(t, time.Second, second)
+	})
+
+	t.Run("should return error, context was cancelled while waiting", func(t *testing.T) {
+		// arrange
+		clk := clock.NewFake(testNow)
+		bank := &stubBank{}
+		c := newTestClient(bank, Config{RatePerSecond: 1}, clk)
+		ctx, cancel := context.WithCancel(context.Background())
+		_ = send(c, 1)
+		cancel()
+
+		// act
+		_, err := c.Send(ctx, testTransfer)
+
+		// assert
+		assert.True(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, 1, bank.calls)
+	})
+}
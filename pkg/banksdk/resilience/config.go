@@ -0,0 +1,50 @@
+package resilience
+
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
+
+const (
+	_defaultFailureThreshold = 5
+	_defaultSuccessThreshold = 1
+	_defaultOpenTimeout      = 30
+)
+
+type Config struct {
+	// FailureThreshold of consecutive retryable failures which opens the circuit
+	FailureThreshold int `json:"failure_threshold"`
+	// SuccessThreshold of consecutive successful probes which close the half-open circuit
+	SuccessThreshold int `json:"success_threshold"`
+	// OpenTimeout in seconds, the open circuit lets a probe through after it
+	OpenTimeout int `json:"open_timeout"`
+	// RatePerSecond of transfers sent to the bank, zero means no limit
+	RatePerSecond float64 `json:"rate_per_second"`
+	// Burst of transfers sent at once, it defaults to the rate
+	Burst int `json:"burst"`
+}
+
+func (c *Config) UnmarshalEnvironmentValue(data string) error {
+	return json.Unmarshal([]byte(data), &c)
+}
+
+func (c Config) withDefaults() Config {
+	if c.FailureThreshold <= 0 {
+		c.FailureThreshold = _defaultFailureThreshold
+	}
+	if c.SuccessThreshold <= 0 {
+		c.SuccessThreshold = _defaultSuccessThreshold
+	}
+	if c.OpenTimeout <= 0 {
+		c.OpenTimeout = _defaultOpenTimeout
+	}
+	if c.RatePerSecond > 0 && c.Burst <= 0 {
+		c.Burst = int(math.Max(1, math.Ceil(c.RatePerSecond)))
+	}
+	return c
+}
+
+func (c Config) openTimeout() time.Duration {
+	return time.Duration(c.OpenTimeout) * time.Second
+}
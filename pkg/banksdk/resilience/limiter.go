@@ -0,0 +1,80 @@
+package resilience
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/mazxaxz/donut-batcher/pkg/clock"
+)
+
+// limiter is a token bucket, it holds up to burst tokens and refills rate tokens per second
+type limiter struct {
+	rate  float64
+	burst float64
+	clock clock.Clock
+	// sleep waits for the duration or until the context is done
+	sleep func(ctx context.Context, d time.Duration) error
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newLimiter(rate float64, burst int, clk clock.Clock) *limiter {
+	l := limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		clock:  clk,
+		sleep:  sleep,
+		tokens: float64(burst),
+		last:   clk.Now(),
+	}
+	return &l
+}
+
+// Wait blocks until a token is available and takes it
+func (l *limiter) Wait(ctx context.Context) error {
+	for {
+		d := l.reserve()
+		if d <= 0 {
+			return nil
+		}
+		if err := l.sleep(ctx, d); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve takes a token when there is one, otherwise it returns how long until the next one
+func (l *limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens += elapsed * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	missing := 1 - l.tokens
+	return time.Duration(missing / l.rate * float64(time.Second))
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
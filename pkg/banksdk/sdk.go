@@ -3,21 +3,57 @@ package banksdk
 import (
 	"context"
 	"fmt"
+
+	"github.com/google/uuid"
 )
 
 type Clienter interface {
-	Send(_ context.Context, userID, amount, currency string) error
+	// Send transfers the amount to the user and returns where the transfer went
+	Send(ctx context.Context, t Transfer) (Receipt, error)
+}
+
+// Receipt of a sent transfer, Reference stays empty when the transfer is delivered in a file
+// and the bank only gets to know it once the file is picked up
+type Receipt struct {
+	Reference  string
+	File       string
+	EndToEndID string
+}
+
+// Transfer is a single payout, ID is ours and lets the bank recognize a retried transfer
+type Transfer struct {
+	ID       string `json:"id"`
+	UserID   string `json:"userId"`
+	Amount   string `json:"amount"`
+	Currency string `json:"currency"`
+	// Creditor is the account of the user, the bank knows it by the user id when it is missing
+	Creditor *Account `json:"creditor,omitempty"`
+	// Portfolio of the user the transfer is invested into, the default one when it is missing
+	Portfolio string `json:"portfolio,omitempty"`
+}
+
+// Account the transfer is paid to, pain.001 needs the IBAN and ACH the routing and account number
+type Account struct {
+	Name          string `json:"name,omitempty"`
+	IBAN          string `json:"iban,omitempty"`
+	BIC           string `json:"bic,omitempty"`
+	RoutingNumber string `json:"routingNumber,omitempty"`
+	AccountNumber string `json:"accountNumber,omitempty"`
+	// Savings accounts are credited with their own ACH transaction code
+	Savings bool `json:"savings,omitempty"`
 }
 
 type clientContext struct{}
 
+// New returns a client which only prints the transfers, it does not talk to any bank
 func New() Clienter {
 	c := clientContext{}
 	return &c
 }
 
-func (c *clientContext) Send(_ context.Context, userID, amount, currency string) error {
+func (c *clientContext) Send(_ context.Context, t Transfer) (Receipt, error) {
+	reference := uuid.NewString()
 	fmt.Println("### sending money to the bank...")
-	fmt.Println(fmt.Sprintf("UserID: %s, Amount: %s, Currency: %s sent!", userID, amount, currency))
-	return nil
+	fmt.Println(fmt.Sprintf("UserID: %s, Amount: %s, Currency: %s sent! Reference: %s", t.UserID, t.Amount, t.Currency, reference))
+	return Receipt{Reference: reference, EndToEndID: t.ID}, nil
 }
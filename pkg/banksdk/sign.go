@@ -0,0 +1,52 @@
+package banksdk
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	HeaderAPIKey         = "X-Api-Key"
+	HeaderTimestamp      = "X-Timestamp"
+	HeaderSignature      = "X-Signature"
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// MaxClockSkew is how far the timestamp of a signed request can be from the receiver's clock
+	MaxClockSkew = 5 * time.Minute
+)
+
+var (
+	ErrInvalidSignature = errors.New("request signature does not match")
+	ErrStaleSignature   = errors.New("request timestamp is too far from the current time")
+)
+
+// Sign returns hex encoded HMAC-SHA256 of the timestamp, method, path and body, separated by new lines
+func Sign(secret string, timestamp time.Time, method, path string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("\n" + method + "\n" + path + "\n"))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature made by Sign, timestamp is the unix time sent along with it
+func Verify(secret, signature, timestamp string, now time.Time, method, path string, body []byte) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleSignature
+	}
+	signed := time.Unix(seconds, 0)
+	if signed.Before(now.Add(-MaxClockSkew)) || signed.After(now.Add(MaxClockSkew)) {
+		return ErrStaleSignature
+	}
+	want := Sign(secret, signed, method, path, body)
+	if !hmac.Equal([]byte(want), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package banksdk
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+	body := []byte(`{"id":"1"}`)
+	signature := Sign("secret", now, "POST", TransfersPath, body)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	t.Run("should accept signature of the same request", func(t *testing.T) {
+		// act
+		err := Verify("secret", signature, timestamp, now.Add(time.Minute), "POST", TransfersPath, body)
+
+		// assert
+		assert.NoError(t, err)
+	})
+
+	t.Run("should return error, body was changed", func(t *testing.T) {
+		// act
+		err := Verify("secret", signature, timestamp, now, "POST", TransfersPath, []byte(`{"id":"2"}`))
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidSignature))
+	})
+
+	t.Run("should return error, signed with another secret", func(t *testing.T) {
+		// act
+		err := Verify("other", signature, timestamp, now, "POST", TransfersPath, body)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidSignature))
+	})
+
+	t.Run("should return error, signature is too old", func(t *testing.T) {
+		// act
+		err := Verify("secret", signature, timestamp, now.Add(MaxClockSkew+time.Second), "POST", TransfersPath, body)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrStaleSignature))
+	})
+}
+
+func TestSignRequest(t *testing.T) {
+	t.Run("should verify request signed for a bare host url", func(t *testing.T) {
+		// arrange
+		now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+		body := []byte(`{"transferId":"1"}`)
+		sent := httptest.NewRequest(http.MethodPost, "http://bank.local", bytes.NewReader(body))
+		sent.URL.Path = ""
+		SignRequest(sent, "secret", now, body)
+		received := httptest.NewRequest(http.MethodPost, "http://bank.local/", bytes.NewReader(body))
+		received.Header = sent.Header
+
+		// act
+		err := VerifyRequest(received, "secret", now, body)
+
+		// assert
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,40 @@
+package banksdk
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	TransferStatusSettled  = "settled"
+	TransferStatusReturned = "returned"
+)
+
+// TransferEvent is posted by the bank to the webhook once the outcome of the transfer is known,
+// it is delivered at least once and signed the same way as the requests sent to the bank
+type TransferEvent struct {
+	// TransferID is the id the transfer was sent with
+	TransferID string `json:"transferId"`
+	Reference  string `json:"reference"`
+	Status     string `json:"status"`
+	// Reason of the return, in the bank's own words
+	Reason string    `json:"reason,omitempty"`
+	Date   time.Time `json:"date"`
+}
+
+// SignRequest sets the timestamp and the signature headers of the request carrying the body
+func SignRequest(r *http.Request, secret string, now time.Time, body []byte) {
+	/* the receiver sees the root path of a bare host url as "/" */
+	path := r.URL.Path
+	if path == "" {
+		path = "/"
+	}
+	r.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	r.Header.Set(HeaderSignature, Sign(secret, now, r.Method, path, body))
+}
+
+// VerifyRequest checks the headers set by SignRequest
+func VerifyRequest(r *http.Request, secret string, now time.Time, body []byte) error {
+	return Verify(secret, r.Header.Get(HeaderSignature), r.Header.Get(HeaderTimestamp), now, r.Method, r.URL.Path, body)
+}
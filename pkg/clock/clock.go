@@ -0,0 +1,53 @@
+// Package clock lets time dependent code be driven by tests instead of the wall clock.
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	// Now returns the current time in UTC
+	Now() time.Time
+}
+
+type realClock struct{}
+
+func New() Clock {
+	return realClock{}
+}
+
+func (realClock) Now() time.Time {
+	return time.Now().UTC()
+}
+
+// Fake stands still until it is moved with Set or Advance, it is safe for concurrent use
+type Fake struct {
+	mu  sync.RWMutex
+	now time.Time
+}
+
+func NewFake(now time.Time) *Fake {
+	f := Fake{
+		now: now.UTC(),
+	}
+	return &f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.now
+}
+
+func (f *Fake) Set(now time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = now.UTC()
+}
+
+func (f *Fake) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
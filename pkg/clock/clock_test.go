@@ -0,0 +1,38 @@
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFake(t *testing.T) {
+	t.Run("should move only when advanced", func(t *testing.T) {
+		// arrange
+		start := time.Date(2021, 1, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+		c := NewFake(start)
+
+		// act
+		before := c.Now()
+		c.Advance(90 * time.Minute)
+		after := c.Now()
+
+		// assert
+		assert.Equal(t, time.Date(2021, 1, 1, 11, 0, 0, 0, time.UTC), before)
+		assert.Equal(t, time.UTC, before.Location())
+		assert.Equal(t, 90*time.Minute, after.Sub(before))
+	})
+
+	t.Run("should jump to the time set", func(t *testing.T) {
+		// arrange
+		c := NewFake(time.Time{})
+		at := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+
+		// act
+		c.Set(at)
+
+		// assert
+		assert.Equal(t, at, c.Now())
+	})
+}
@@ -1,11 +1,49 @@
 package transaction
 
+import (
+	"errors"
+	"regexp"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
 const MessageTypeTransaction = "transaction"
 
+var (
+	ErrNoID     = errors.New("no transaction id was provided")
+	ErrNoUserID = errors.New("no user id was provided")
+	// ErrInvalidMerchantCategory means the merchant category is not an ISO 18245 code
+	ErrInvalidMerchantCategory = errors.New("merchant category code has to be 4 digits")
+)
+
+var _merchantCategoryPattern = regexp.MustCompile(`^[0-9]{4}$`)
+
 type Transaction struct {
 	ID     string `json:"id"`
 	UserID string `json:"userId"`
 	Amount string `json:"amount"`
 	// Currency represented in ISO 4217 standard
 	Currency string `json:"currency"`
+	// MerchantCategory is the ISO 18245 code of the merchant, savings goals route the round-up by it
+	MerchantCategory string `json:"merchantCategory,omitempty"`
+}
+
+// Validate checks whether the transaction can be batched, it does not touch the storage
+func (t Transaction) Validate() error {
+	if t.ID == "" {
+		return ErrNoID
+	}
+	if t.UserID == "" {
+		return ErrNoUserID
+	}
+	if _, err := money.CurrencyFrom(t.Currency); err != nil {
+		return err
+	}
+	if _, err := money.CalculateInvestment(t.Amount); err != nil {
+		return err
+	}
+	if t.MerchantCategory != "" && !_merchantCategoryPattern.MatchString(t.MerchantCategory) {
+		return ErrInvalidMerchantCategory
+	}
+	return nil
 }
@@ -0,0 +1,69 @@
+package transaction
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mazxaxz/donut-batcher/pkg/money"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name      string
+		give      Transaction
+		wantError error
+	}{
+		{
+			name:      "no id",
+			give:      Transaction{UserID: "11", Amount: "1.11", Currency: "USD"},
+			wantError: ErrNoID,
+		},
+		{
+			name:      "no user id",
+			give:      Transaction{ID: "1", Amount: "1.11", Currency: "USD"},
+			wantError: ErrNoUserID,
+		},
+		{
+			name:      "invalid currency",
+			give:      Transaction{ID: "1", UserID: "11", Amount: "1.11", Currency: "US"},
+			wantError: money.ErrInvalidCurrencyCode,
+		},
+		{
+			name:      "negative amount",
+			give:      Transaction{ID: "1", UserID: "11", Amount: "-1.11", Currency: "USD"},
+			wantError: money.ErrNegativeAmount,
+		},
+		{
+			name:      "zero amount",
+			give:      Transaction{ID: "1", UserID: "11", Amount: "0", Currency: "USD"},
+			wantError: money.ErrZeroAmount,
+		},
+		{
+			name:      "invalid merchant category",
+			give:      Transaction{ID: "1", UserID: "11", Amount: "1.11", Currency: "USD", MerchantCategory: "58"},
+			wantError: ErrInvalidMerchantCategory,
+		},
+		{
+			name:      "valid with merchant category",
+			give:      Transaction{ID: "1", UserID: "11", Amount: "1.11", Currency: "USD", MerchantCategory: "5812"},
+			wantError: nil,
+		},
+		{
+			name:      "valid",
+			give:      Transaction{ID: "1", UserID: "11", Amount: "1.11", Currency: "usd"},
+			wantError: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantError, tt.give.Validate())
+		})
+	}
+
+	t.Run("malformed amount", func(t *testing.T) {
+		give := Transaction{ID: "1", UserID: "11", Amount: "x", Currency: "USD"}
+		assert.Error(t, give.Validate())
+	})
+}
@@ -2,6 +2,7 @@ package money
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/shopspring/decimal"
 )
@@ -9,6 +10,9 @@ import (
 var (
 	ErrZeroAmount     = errors.New("provided amount value is zero")
 	ErrNegativeAmount = errors.New("provided amount value is negative")
+	ErrTooPrecise     = errors.New("provided amount value has more decimal places than the currency")
+	ErrNoWeights      = errors.New("at least one weight is required to split the amount")
+	ErrInvalidWeight  = errors.New("weights have to be positive numbers")
 )
 
 func Add(a, b string) (string, error) {
@@ -23,6 +27,18 @@ func Add(a, b string) (string, error) {
 	return d1.Add(d2).String(), nil
 }
 
+func Sub(a, b string) (string, error) {
+	d1, err := decimal.NewFromString(a)
+	if err != nil {
+		return "", err
+	}
+	d2, err := decimal.NewFromString(b)
+	if err != nil {
+		return "", err
+	}
+	return d1.Sub(d2).String(), nil
+}
+
 func GreaterThanOrEqual(base, comparer string) (bool, error) {
 	d1, err := decimal.NewFromString(base)
 	if err != nil {
@@ -51,3 +67,73 @@ func CalculateInvestment(amount string) (string, error) {
 	investment := ceiled.Sub(value)
 	return investment.String(), nil
 }
+
+// MinorUnits converts the amount into the smallest unit of the currency, like cents for two decimal places
+func MinorUnits(amount string, places int32) (int64, error) {
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return 0, err
+	}
+	shifted := value.Shift(places)
+	if !shifted.Equal(shifted.Truncate(0)) {
+		return 0, ErrTooPrecise
+	}
+	return shifted.IntPart(), nil
+}
+
+// Split divides the amount in proportion to the weights, the parts always add up to the amount.
+// The amount is split in minor units of the places, or of its own last decimal place when it is more precise,
+// the units left over by rounding down go one by one to the parts with the largest remainders, the first ones on a tie.
+func Split(amount string, places int32, weights []string) ([]string, error) {
+	if len(weights) == 0 {
+		return nil, ErrNoWeights
+	}
+	value, err := decimal.NewFromString(amount)
+	if err != nil {
+		return nil, err
+	}
+	if value.IsNegative() {
+		return nil, ErrNegativeAmount
+	}
+	if exp := -value.Exponent(); exp > places {
+		places = exp
+	}
+	ws := make([]decimal.Decimal, len(weights))
+	total := decimal.Zero
+	for i, w := range weights {
+		ws[i], err = decimal.NewFromString(w)
+		if err != nil {
+			return nil, err
+		}
+		if !ws[i].IsPositive() {
+			return nil, ErrInvalidWeight
+		}
+		total = total.Add(ws[i])
+	}
+
+	units := value.Shift(places)
+	parts := make([]decimal.Decimal, len(ws))
+	remainders := make([]decimal.Decimal, len(ws))
+	left := units
+	for i, w := range ws {
+		share := units.Mul(w).DivRound(total, 16)
+		parts[i] = share.Floor()
+		remainders[i] = share.Sub(parts[i])
+		left = left.Sub(parts[i])
+	}
+	order := make([]int, len(ws))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return remainders[order[a]].GreaterThan(remainders[order[b]]) })
+	for n := int64(0); n < left.IntPart(); n++ {
+		i := order[n%int64(len(order))]
+		parts[i] = parts[i].Add(decimal.NewFromInt(1))
+	}
+
+	result := make([]string, len(parts))
+	for i, part := range parts {
+		result[i] = part.Shift(-places).String()
+	}
+	return result, nil
+}
@@ -71,6 +71,52 @@ func TestAdd(t *testing.T) {
 	}
 }
 
+func TestSub(t *testing.T) {
+	tests := []struct {
+		giveA     string
+		giveB     string
+		want      string
+		wantError bool
+	}{
+		{
+			giveA:     "100",
+			giveB:     "11.11",
+			want:      "88.89",
+			wantError: false,
+		},
+		{
+			giveA:     "0.01",
+			giveB:     "1.01",
+			want:      "-1",
+			wantError: false,
+		},
+		{
+			giveA:     "x",
+			giveB:     "1",
+			want:      "",
+			wantError: true,
+		},
+		{
+			giveA:     "1",
+			giveB:     "x",
+			want:      "",
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s - %s", tt.giveA, tt.giveB), func(t *testing.T) {
+			result, err := Sub(tt.giveA, tt.giveB)
+			assert.Equal(t, tt.want, result)
+			if tt.wantError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
 func TestGreaterThanOrEqual(t *testing.T) {
 	tests := []struct {
 		giveA     string
@@ -159,3 +205,105 @@ func TestCalculateInvestment(t *testing.T) {
 		})
 	}
 }
+
+func TestMinorUnits(t *testing.T) {
+	tests := []struct {
+		give      string
+		places    int32
+		want      int64
+		wantError error
+	}{
+		{
+			give:      "1.4",
+			places:    2,
+			want:      140,
+			wantError: nil,
+		},
+		{
+			give:      "12345678.99",
+			places:    2,
+			want:      1234567899,
+			wantError: nil,
+		},
+		{
+			give:      "7",
+			places:    0,
+			want:      7,
+			wantError: nil,
+		},
+		{
+			give:      "0.001",
+			places:    2,
+			want:      0,
+			wantError: ErrTooPrecise,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			result, err := MinorUnits(tt.give, tt.places)
+			assert.Equal(t, tt.want, result)
+			assert.Equal(t, tt.wantError, err)
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		give      string
+		weights   []string
+		want      []string
+		wantError error
+	}{
+		{
+			give:      "1.4",
+			weights:   []string{"70", "30"},
+			want:      []string{"0.98", "0.42"},
+			wantError: nil,
+		},
+		{
+			give:      "1",
+			weights:   []string{"33.33", "33.33", "33.34"},
+			want:      []string{"0.33", "0.33", "0.34"},
+			wantError: nil,
+		},
+		{
+			give:      "0.02",
+			weights:   []string{"1", "1", "1"},
+			want:      []string{"0.01", "0.01", "0"},
+			wantError: nil,
+		},
+		{
+			give:      "10.01",
+			weights:   []string{"50", "50"},
+			want:      []string{"5.01", "5"},
+			wantError: nil,
+		},
+		{
+			give:      "0.125",
+			weights:   []string{"50", "50"},
+			want:      []string{"0.063", "0.062"},
+			wantError: nil,
+		},
+		{
+			give:      "1",
+			weights:   []string{"100", "0"},
+			want:      nil,
+			wantError: ErrInvalidWeight,
+		},
+		{
+			give:      "1",
+			weights:   nil,
+			want:      nil,
+			wantError: ErrNoWeights,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%v", tt.give, tt.weights), func(t *testing.T) {
+			result, err := Split(tt.give, 2, tt.weights)
+			assert.Equal(t, tt.want, result)
+			assert.Equal(t, tt.wantError, err)
+		})
+	}
+}
@@ -0,0 +1,246 @@
+// Package nacha writes ACH files in the NACHA format, credits only.
+package nacha
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	RecordSize     = 94
+	BlockingFactor = 10
+
+	ServiceClassCredits = "220"
+	SECCodePPD          = "PPD"
+
+	TransactionCodeCheckingCredit = 22
+	TransactionCodeSavingsCredit  = 32
+
+	// MaxFilesPerDay which can be told apart by the file id modifier
+	MaxFilesPerDay = len(_fileIDModifiers)
+
+	_fileIDModifiers = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+)
+
+var (
+	ErrInvalidRoutingNumber = errors.New("routing number has to be 9 digits with a valid check digit")
+	ErrInvalidODFI          = errors.New("originating dfi has to be the first 8 digits of a routing number")
+	ErrInvalidFileSequence  = errors.New("only 36 files a day can be told apart by the file id modifier")
+	ErrNoEntries            = errors.New("ach batch has no entries")
+	ErrAmountTooLarge       = errors.New("entry amount does not fit into 10 digits")
+)
+
+// File is a single ACH file, Sequence of the file within the day becomes the file id modifier
+type File struct {
+	ImmediateDestination string
+	ImmediateOrigin      string
+	DestinationName      string
+	OriginName           string
+	CreationDate         time.Time
+	Sequence             int
+	Batches              []Batch
+}
+
+type Batch struct {
+	CompanyName      string
+	CompanyID        string
+	EntryDescription string
+	EffectiveDate    time.Time
+	// ODFI is the originating depository financial institution, the first 8 digits of its routing number
+	ODFI    string
+	Entries []Entry
+}
+
+// Entry is a PPD credit, Amount in cents
+type Entry struct {
+	TransactionCode int
+	RoutingNumber   string
+	AccountNumber   string
+	Amount          int64
+	IndividualID    string
+	IndividualName  string
+	TraceNumber     string
+}
+
+// TraceNumber is the ODFI followed by the sequence of the entry, it has to be ascending within the batch
+func TraceNumber(odfi string, seq int) string {
+	return fmt.Sprintf("%s%07d", odfi, seq)
+}
+
+// ValidRoutingNumber checks the ABA check digit, 3, 7 and 1 are the weights of the digits
+func ValidRoutingNumber(routing string) bool {
+	if len(routing) != 9 || !digits(routing) {
+		return false
+	}
+	weights := [3]int{3, 7, 1}
+	sum := 0
+	for i, r := range routing {
+		sum += int(r-'0') * weights[i%3]
+	}
+	return sum%10 == 0
+}
+
+// Marshal writes the file, padded to the blocking factor
+func Marshal(f File) ([]byte, error) {
+	if !ValidRoutingNumber(f.ImmediateDestination) {
+		return nil, errors.Wrap(ErrInvalidRoutingNumber, "immediate destination")
+	}
+	if f.Sequence < 1 || f.Sequence > MaxFilesPerDay {
+		return nil, ErrInvalidFileSequence
+	}
+
+	var buf bytes.Buffer
+	records := 0
+	write := func(fields ...string) {
+		buf.WriteString(strings.Join(fields, ""))
+		buf.WriteByte('\n')
+		records++
+	}
+
+	write(
+		"1", "01",
+		routingField(f.ImmediateDestination),
+		routingField(f.ImmediateOrigin),
+		f.CreationDate.Format("060102"),
+		f.CreationDate.Format("1504"),
+		string(_fileIDModifiers[f.Sequence-1]),
+		fmt.Sprintf("%03d", RecordSize),
+		fmt.Sprintf("%02d", BlockingFactor),
+		"1",
+		alpha(f.DestinationName, 23),
+		alpha(f.OriginName, 23),
+		alpha("", 8),
+	)
+
+	var entries, hash, credits int64
+	for i, b := range f.Batches {
+		if len(b.ODFI) != 8 || !digits(b.ODFI) {
+			return nil, ErrInvalidODFI
+		}
+		if len(b.Entries) == 0 {
+			return nil, ErrNoEntries
+		}
+		batchNumber := fmt.Sprintf("%07d", i+1)
+		write(
+			"5", ServiceClassCredits,
+			alpha(b.CompanyName, 16),
+			alpha("", 20),
+			alpha(b.CompanyID, 10),
+			SECCodePPD,
+			alpha(b.EntryDescription, 10),
+			alpha("", 6),
+			b.EffectiveDate.Format("060102"),
+			alpha("", 3),
+			"1",
+			b.ODFI,
+			batchNumber,
+		)
+
+		var batchHash, batchCredits int64
+		for _, e := range b.Entries {
+			if !ValidRoutingNumber(e.RoutingNumber) {
+				return nil, errors.Wrapf(ErrInvalidRoutingNumber, "entry %s", e.TraceNumber)
+			}
+			if e.Amount < 0 || e.Amount > 9999999999 {
+				return nil, errors.Wrapf(ErrAmountTooLarge, "entry %s", e.TraceNumber)
+			}
+			write(
+				"6", fmt.Sprintf("%02d", e.TransactionCode),
+				e.RoutingNumber,
+				alpha(e.AccountNumber, 17),
+				fmt.Sprintf("%010d", e.Amount),
+				alpha(e.IndividualID, 15),
+				alpha(e.IndividualName, 22),
+				alpha("", 2),
+				"0",
+				numeric(e.TraceNumber, 15),
+			)
+			batchHash += routingHash(e.RoutingNumber)
+			batchCredits += e.Amount
+		}
+		write(
+			"8", ServiceClassCredits,
+			fmt.Sprintf("%06d", len(b.Entries)),
+			fmt.Sprintf("%010d", batchHash%10000000000),
+			fmt.Sprintf("%012d", 0),
+			fmt.Sprintf("%012d", batchCredits),
+			alpha(b.CompanyID, 10),
+			alpha("", 19),
+			alpha("", 6),
+			b.ODFI,
+			batchNumber,
+		)
+		entries += int64(len(b.Entries))
+		hash += batchHash
+		credits += batchCredits
+	}
+
+	/* the file control record counts itself in the blocks */
+	blocks := (records + 1 + BlockingFactor - 1) / BlockingFactor
+	write(
+		"9",
+		fmt.Sprintf("%06d", len(f.Batches)),
+		fmt.Sprintf("%06d", blocks),
+		fmt.Sprintf("%08d", entries),
+		fmt.Sprintf("%010d", hash%10000000000),
+		fmt.Sprintf("%012d", 0),
+		fmt.Sprintf("%012d", credits),
+		alpha("", 39),
+	)
+	for records%BlockingFactor != 0 {
+		write(strings.Repeat("9", RecordSize))
+	}
+	return buf.Bytes(), nil
+}
+
+// routingHash is the 8 digit receiving dfi identification, the entry hash sums them up
+func routingHash(routing string) int64 {
+	var n int64
+	for _, r := range routing[:8] {
+		n = n*10 + int64(r-'0')
+	}
+	return n
+}
+
+// routingField is a routing number preceded by a space, other identifiers take up all 10 characters
+func routingField(value string) string {
+	if len(value) == 9 {
+		return " " + value
+	}
+	return numeric(value, 10)
+}
+
+// alpha left justifies the value, NACHA only allows upper case printable characters
+func alpha(value string, size int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < ' ' || r > '~' {
+			return ' '
+		}
+		return r
+	}, strings.ToUpper(value))
+	if len(value) > size {
+		return value[:size]
+	}
+	return value + strings.Repeat(" ", size-len(value))
+}
+
+// numeric right justifies the value padded with zeros
+func numeric(value string, size int) string {
+	if len(value) > size {
+		return value[len(value)-size:]
+	}
+	return strings.Repeat("0", size-len(value)) + value
+}
+
+func digits(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package nacha
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testNow = time.Date(2021, 3, 1, 9, 30, 0, 0, time.UTC)
+
+func newTestFile() File {
+	return File{
+		ImmediateDestination: "021000021",
+		ImmediateOrigin:      "121000358",
+		DestinationName:      "Chase",
+		OriginName:           "Donut",
+		CreationDate:         testNow,
+		Sequence:             1,
+		Batches: []Batch{{
+			CompanyName:      "Donut",
+			CompanyID:        "1234567890",
+			EntryDescription: "Roundups",
+			EffectiveDate:    testNow,
+			ODFI:             "12100035",
+			Entries: []Entry{
+				{
+					TransactionCode: TransactionCodeCheckingCredit,
+					RoutingNumber:   "011000015",
+					AccountNumber:   "123456789",
+					Amount:          140,
+					IndividualID:    "user:1",
+					IndividualName:  "Jane Doe",
+					TraceNumber:     TraceNumber("12100035", 1),
+				},
+				{
+					TransactionCode: TransactionCodeSavingsCredit,
+					RoutingNumber:   "021000021",
+					AccountNumber:   "987654321",
+					Amount:          225,
+					IndividualID:    "user:2",
+					IndividualName:  "John Doe",
+					TraceNumber:     TraceNumber("12100035", 2),
+				},
+			},
+		}},
+	}
+}
+
+func TestValidRoutingNumber(t *testing.T) {
+	tests := []struct {
+		give string
+		want bool
+	}{
+		{give: "021000021", want: true},
+		{give: "011000015", want: true},
+		{give: "021000022", want: false},
+		{give: "02100002", want: false},
+		{give: "02100002a", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.give, func(t *testing.T) {
+			assert.Equal(t, tt.want, ValidRoutingNumber(tt.give))
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	t.Run("should write records padded to the blocking factor", func(t *testing.T) {
+		// act
+		data, err := Marshal(newTestFile())
+
+		// assert
+		assert.NoError(t, err)
+		lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		assert.Len(t, lines, 10)
+		for _, l := range lines {
+			assert.Len(t, l, RecordSize)
+		}
+		assert.Equal(t, "101 021000021 1210003582103010930A094101CHASE                  DONUT                          ", lines[0])
+		assert.Equal(t, "5220DONUT                               1234567890PPDROUNDUPS        210301   1121000350000001", lines[1])
+		assert.Equal(t, "622011000015123456789        0000000140USER:1         JANE DOE                0121000350000001", lines[2])
+		assert.Equal(t, "632021000021987654321        0000000225USER:2         JOHN DOE                0121000350000002", lines[3])
+		assert.Equal(t, "822000000200032000030000000000000000000003651234567890                         121000350000001", lines[4])
+		assert.Equal(t, "9000001000001000000020003200003000000000000000000000365                                       ", lines[5])
+		assert.Equal(t, strings.Repeat("9", RecordSize), lines[9])
+		assert.NoError(t, Validate(data))
+	})
+
+	t.Run("should return error, receiving dfi has invalid check digit", func(t *testing.T) {
+		// arrange
+		f := newTestFile()
+		f.Batches[0].Entries[0].RoutingNumber = "011000016"
+
+		// act
+		_, err := Marshal(f)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidRoutingNumber))
+	})
+
+	t.Run("should return error, too many files in a day", func(t *testing.T) {
+		// arrange
+		f := newTestFile()
+		f.Sequence = 37
+
+		// act
+		_, err := Marshal(f)
+
+		// assert
+		assert.True(t, errors.Is(err, ErrInvalidFileSequence))
+	})
+}
@@ -0,0 +1,212 @@
+package nacha
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	ErrInvalidFile = errors.New("invalid ach file")
+)
+
+// ValidationError points at the first record which breaks the file
+type ValidationError struct {
+	Line   int
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid ach file, line %d: %s", e.Line, e.Reason)
+}
+
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidFile
+}
+
+// Validate checks the records, their order, the counts, hashes and totals of the controls and the padding
+func Validate(data []byte) error {
+	lines := strings.Split(strings.TrimSuffix(string(bytes.ReplaceAll(data, []byte("\r\n"), []byte("\n"))), "\n"), "\n")
+	v := validator{lines: lines}
+	return v.validate()
+}
+
+type validator struct {
+	lines []string
+	pos   int
+
+	batches int
+	entries int64
+	hash    int64
+	credits int64
+}
+
+func (v *validator) fail(format string, args ...interface{}) error {
+	return &ValidationError{Line: v.pos + 1, Reason: fmt.Sprintf(format, args...)}
+}
+
+func (v *validator) record() string {
+	if v.pos >= len(v.lines) {
+		return ""
+	}
+	return v.lines[v.pos]
+}
+
+func (v *validator) validate() error {
+	for v.pos = range v.lines {
+		if len(v.lines[v.pos]) != RecordSize {
+			return v.fail("record has %d characters instead of %d", len(v.lines[v.pos]), RecordSize)
+		}
+	}
+	v.pos = 0
+	if len(v.lines)%BlockingFactor != 0 {
+		return &ValidationError{Line: len(v.lines), Reason: "file is not padded to the blocking factor"}
+	}
+
+	if err := v.fileHeader(); err != nil {
+		return err
+	}
+	for strings.HasPrefix(v.record(), "5") {
+		if err := v.batch(); err != nil {
+			return err
+		}
+	}
+	if err := v.fileControl(); err != nil {
+		return err
+	}
+	for ; v.pos < len(v.lines); v.pos++ {
+		if v.record() != strings.Repeat("9", RecordSize) {
+			return v.fail("only padding records may follow the file control")
+		}
+	}
+	return nil
+}
+
+func (v *validator) fileHeader() error {
+	r := v.record()
+	switch {
+	case r[0] != '1':
+		return v.fail("file has to start with the file header")
+	case !ValidRoutingNumber(strings.TrimSpace(r[3:13])):
+		return v.fail("immediate destination is not a valid routing number")
+	case r[34:37] != fmt.Sprintf("%03d", RecordSize):
+		return v.fail("record size has to be %d", RecordSize)
+	case r[37:39] != fmt.Sprintf("%02d", BlockingFactor):
+		return v.fail("blocking factor has to be %d", BlockingFactor)
+	}
+	v.pos++
+	return nil
+}
+
+func (v *validator) batch() error {
+	header := v.record()
+	v.batches++
+	serviceClass, odfi, batchNumber := header[1:4], header[79:87], header[87:94]
+	if serviceClass != ServiceClassCredits {
+		return v.fail("service class %s is not supported, only credits are", serviceClass)
+	}
+	if header[50:53] != SECCodePPD {
+		return v.fail("standard entry class %s is not supported", header[50:53])
+	}
+	if batchNumber != fmt.Sprintf("%07d", v.batches) {
+		return v.fail("batch number %s is out of sequence", batchNumber)
+	}
+	v.pos++
+
+	var entries, hash, credits int64
+	var lastTrace string
+	for strings.HasPrefix(v.record(), "6") {
+		r := v.record()
+		switch code := r[1:3]; code {
+		case strconv.Itoa(TransactionCodeCheckingCredit), strconv.Itoa(TransactionCodeSavingsCredit):
+		default:
+			return v.fail("transaction code %s is not a credit", code)
+		}
+		if !ValidRoutingNumber(r[3:12]) {
+			return v.fail("receiving dfi %s has an invalid check digit", r[3:12])
+		}
+		amount, err := number(r[29:39])
+		if err != nil {
+			return v.fail("amount is not numeric")
+		}
+		trace := r[79:94]
+		if !digits(trace) || trace[:8] != odfi {
+			return v.fail("trace number %s does not start with the originating dfi", trace)
+		}
+		if trace <= lastTrace {
+			return v.fail("trace number %s is not ascending", trace)
+		}
+		if r[78] != '0' {
+			return v.fail("addenda records are not supported")
+		}
+		lastTrace = trace
+		entries++
+		hash += routingHash(r[3:12])
+		credits += amount
+		v.pos++
+	}
+	if entries == 0 {
+		return v.fail("batch has no entries")
+	}
+
+	control := v.record()
+	if !strings.HasPrefix(control, "8") {
+		return v.fail("batch has to end with the batch control")
+	}
+	if err := v.totals(control[4:10], control[10:20], control[20:32], control[32:44], entries, hash, credits); err != nil {
+		return err
+	}
+	if control[1:4] != serviceClass || control[79:87] != odfi || control[87:94] != batchNumber {
+		return v.fail("batch control does not match the batch header")
+	}
+	v.pos++
+
+	v.entries += entries
+	v.hash += hash
+	v.credits += credits
+	return nil
+}
+
+func (v *validator) fileControl() error {
+	r := v.record()
+	if !strings.HasPrefix(r, "9") || r == strings.Repeat("9", RecordSize) {
+		return v.fail("file has to end with the file control")
+	}
+	if r[1:7] != fmt.Sprintf("%06d", v.batches) {
+		return v.fail("batch count %s does not match %d batches", r[1:7], v.batches)
+	}
+	if r[7:13] != fmt.Sprintf("%06d", len(v.lines)/BlockingFactor) {
+		return v.fail("block count %s does not match %d blocks", r[7:13], len(v.lines)/BlockingFactor)
+	}
+	if err := v.totals(r[13:21], r[21:31], r[31:43], r[43:55], v.entries, v.hash, v.credits); err != nil {
+		return err
+	}
+	v.pos++
+	return nil
+}
+
+func (v *validator) totals(count, hash, debits, credits string, wantCount, wantHash, wantCredits int64) error {
+	if n, err := number(count); err != nil || n != wantCount {
+		return v.fail("entry count %s does not match %d entries", count, wantCount)
+	}
+	if n, err := number(hash); err != nil || n != wantHash%10000000000 {
+		return v.fail("entry hash %s does not match the receiving dfis", hash)
+	}
+	if n, err := number(debits); err != nil || n != 0 {
+		return v.fail("total debits %s have to be zero", debits)
+	}
+	if n, err := number(credits); err != nil || n != wantCredits {
+		return v.fail("total credits %s do not match the entries", credits)
+	}
+	return nil
+}
+
+func number(field string) (int64, error) {
+	if !digits(field) {
+		return 0, strconv.ErrSyntax
+	}
+	return strconv.ParseInt(field, 10, 64)
+}
@@ -0,0 +1,93 @@
+package nacha
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+	valid, err := Marshal(newTestFile())
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		give     func(lines []string) []string
+		wantLine int
+	}{
+		{
+			name: "record is too short",
+			give: func(lines []string) []string {
+				lines[2] = lines[2][:RecordSize-1]
+				return lines
+			},
+			wantLine: 3,
+		},
+		{
+			name: "file is not padded",
+			give: func(lines []string) []string {
+				return lines[:9]
+			},
+			wantLine: 9,
+		},
+		{
+			name: "entry amount does not add up",
+			give: func(lines []string) []string {
+				lines[2] = lines[2][:29] + "0000000141" + lines[2][39:]
+				return lines
+			},
+			wantLine: 5,
+		},
+		{
+			name: "trace numbers are not ascending",
+			give: func(lines []string) []string {
+				lines[2], lines[3] = lines[3], lines[2]
+				return lines
+			},
+			wantLine: 4,
+		},
+		{
+			name: "entry hash does not match",
+			give: func(lines []string) []string {
+				lines[5] = lines[5][:21] + "0003200004" + lines[5][31:]
+				return lines
+			},
+			wantLine: 6,
+		},
+		{
+			name: "block count does not match",
+			give: func(lines []string) []string {
+				lines[5] = lines[5][:7] + "000002" + lines[5][13:]
+				return lines
+			},
+			wantLine: 6,
+		},
+		{
+			name: "file has no control",
+			give: func(lines []string) []string {
+				lines[5] = strings.Repeat("9", RecordSize)
+				return lines
+			},
+			wantLine: 6,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			lines := strings.Split(strings.TrimSuffix(string(valid), "\n"), "\n")
+			data := strings.Join(tt.give(lines), "\n") + "\n"
+
+			// act
+			err := Validate([]byte(data))
+
+			// assert
+			var ve *ValidationError
+			assert.True(t, errors.Is(err, ErrInvalidFile))
+			assert.True(t, errors.As(err, &ve))
+			assert.Equal(t, tt.wantLine, ve.Line)
+		})
+	}
+}
@@ -1,11 +1,23 @@
 package rest
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Config struct {
 	Port int `json:"port"`
+	// WriteTimeout in seconds, streamed responses have to fit in it as well
+	WriteTimeout int `json:"write_timeout"`
 }
 
 func (c *Config) UnmarshalEnvironmentValue(data string) error {
 	return json.Unmarshal([]byte(data), &c)
 }
+
+func (c Config) WriteTimeoutOrDefault(d time.Duration) time.Duration {
+	if c.WriteTimeout <= 0 {
+		return d
+	}
+	return time.Duration(c.WriteTimeout) * time.Second
+}
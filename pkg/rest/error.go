@@ -12,3 +12,7 @@ func NewError(code string, err error) Error {
 	}
 	return e
 }
+
+func NewParameterError(parameter string, err error) Error {
+	return NewError("invalid_parameter__"+parameter, err)
+}